@@ -0,0 +1,81 @@
+package analysisservice
+
+import (
+	"context"
+
+	"github.com/alikarimi999/shahboard/analysisservice/delivery/http"
+	"github.com/alikarimi999/shahboard/analysisservice/repository"
+	analysis "github.com/alikarimi999/shahboard/analysisservice/service"
+	"github.com/alikarimi999/shahboard/analysisservice/services/game"
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/pkg/grpc"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+	"github.com/alikarimi999/shahboard/pkg/uci"
+	"github.com/redis/go-redis/v9"
+)
+
+type application struct {
+	*analysis.Service
+	*http.Router
+}
+
+func SetupApplication(cfg Config) (*application, error) {
+	l := log.NewLogger(cfg.Log.File, cfg.Log.Verbose)
+
+	v, err := jwt.NewValidator(cfg.JwtValidator)
+	if err != nil {
+		return nil, err
+	}
+
+	p, sub, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
+	if err != nil {
+		return nil, err
+	}
+
+	r := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	_, err = r.Ping(context.Background()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	v.WithDenylist(jwt.NewRedisDenylist(r))
+
+	db, err := postgres.Setup(cfg.AnalysisDB)
+	if err != nil {
+		return nil, err
+	}
+
+	gc, err := grpc.NewClient(cfg.GameService, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	factory := func() (analysis.Engine, error) {
+		return uci.NewEngine(cfg.Analysis.Engine)
+	}
+
+	s, err := analysis.NewService(cfg.Analysis, r, factory, game.NewService(gc),
+		repository.NewArchiveRepo(db, l), repository.NewFairPlayRepo(db, l), p, sub, l)
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := http.NewRouter(cfg.Http, s, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &application{Service: s, Router: router}, nil
+}
+
+func (a *application) Run() error {
+	defer a.Service.Close()
+	return a.Router.Run()
+}
@@ -0,0 +1,32 @@
+package analysisservice
+
+import (
+	"github.com/alikarimi999/shahboard/analysisservice/delivery/http"
+	analysis "github.com/alikarimi999/shahboard/analysisservice/service"
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/pkg/grpc"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+)
+
+type Config struct {
+	Analysis     analysis.Config     `json:"analysis_service"`
+	Http         http.Config         `json:"http"`
+	Redis        RedisConfg          `json:"redis"`
+	Kafka        kafka.Config        `json:"kafka"`
+	Log          LogConfig           `json:"log"`
+	JwtValidator jwt.ValidatorConfig `json:"jwt_validator"`
+	GameService  grpc.Config         `json:"game_service_grpc"`
+	AnalysisDB   postgres.Config     `json:"analysis_db"`
+}
+
+type RedisConfg struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
+
+type LogConfig struct {
+	File    string `json:"file"`
+	Verbose bool   `json:"verbose"`
+}
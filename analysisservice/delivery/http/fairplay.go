@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	analysis "github.com/alikarimi999/shahboard/analysisservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) setupFairPlayRoutes() {
+	g := r.gin.Group("/fairplay", middleware.RequireRole(types.RoleModerator))
+	g.GET("/queue", r.getFairPlayQueue)
+	g.DELETE("/queue/:id", r.dismissFairPlayAccount)
+	g.GET("/users/:id", r.getFairPlayAccount)
+	g.GET("/games/:id", r.getFairPlayReport)
+	g.POST("/games/:id/refund", r.refundGame)
+}
+
+func (r *Router) getFairPlayQueue(c *gin.Context) {
+	res, err := r.s.GetFairPlayQueue(c.Request.Context())
+	if err != nil {
+		c.JSON(errs.HTTP(fairPlayErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) dismissFairPlayAccount(c *gin.Context) {
+	u := getUser(c)
+
+	id, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := r.s.DismissFairPlayAccount(c.Request.Context(), u.ID, id); err != nil {
+		c.JSON(errs.HTTP(fairPlayErrCode(err), err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (r *Router) getFairPlayAccount(c *gin.Context) {
+	id, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.GetFairPlayAccount(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(errs.HTTP(fairPlayErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) getFairPlayReport(c *gin.Context) {
+	id, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.GetFairPlayReport(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(errs.HTTP(fairPlayErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) refundGame(c *gin.Context) {
+	u := getUser(c)
+
+	id, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	var req analysis.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := r.s.RefundGame(c.Request.Context(), u.ID, id, req); err != nil {
+		c.JSON(errs.HTTP(fairPlayErrCode(err), err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func fairPlayErrCode(err error) string {
+	switch {
+	case errors.Is(err, analysis.ErrNoFairPlayGames), errors.Is(err, analysis.ErrGameNotAnalyzed),
+		errors.Is(err, analysis.ErrNotInReviewQueue):
+		return errs.CodeNotFound
+	case errors.Is(err, analysis.ErrNotGamePlayer):
+		return errs.CodeInvalidInput
+	case errors.Is(err, analysis.ErrGameRefunded):
+		return errs.CodeConflict
+	}
+	return errs.CodeInternalError
+}
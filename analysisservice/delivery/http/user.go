@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	analysis "github.com/alikarimi999/shahboard/analysisservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) setupUserRoutes() {
+	r.gin.POST("/analyze", r.analyze)
+	r.gin.POST("/review", r.createReview)
+	r.gin.GET("/review/:id", r.getReview)
+}
+
+func (r *Router) analyze(c *gin.Context) {
+	u := getUser(c)
+
+	var req analysis.AnalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.Analyze(c.Request.Context(), u.ID, req)
+	if err != nil {
+		c.JSON(errs.HTTP(analysisErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) createReview(c *gin.Context) {
+	u := getUser(c)
+
+	var req analysis.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.CreateReview(c.Request.Context(), u.ID, req)
+	if err != nil {
+		c.JSON(errs.HTTP(analysisErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func (r *Router) getReview(c *gin.Context) {
+	id, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.GetReview(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(errs.HTTP(analysisErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func analysisErrCode(err error) string {
+	switch {
+	case errors.Is(err, analysis.ErrInvalidRequest):
+		return errs.CodeInvalidInput
+	case errors.Is(err, analysis.ErrGameNotFound), errors.Is(err, analysis.ErrReviewNotFound):
+		return errs.CodeNotFound
+	case errors.Is(err, analysis.ErrGameNotEnded):
+		return errs.CodeConflict
+	case errors.Is(err, analysis.ErrQuotaExceeded), errors.Is(err, analysis.ErrQueueFull):
+		return errs.CodeRateLimited
+	case errors.Is(err, context.DeadlineExceeded):
+		return errs.CodeTimeout
+	}
+	return errs.CodeInternalError
+}
+
+func getUser(c *gin.Context) types.User {
+	u, _ := c.Get("user")
+	return u.(types.User)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type archiveRepo struct {
+	db *sql.DB
+	l  log.Logger
+}
+
+func NewArchiveRepo(db *sql.DB, l log.Logger) *archiveRepo {
+	return &archiveRepo{
+		db: db,
+		l:  l,
+	}
+}
+
+// AddGame archives the PGN of a finished game, a game that is delivered again is ignored.
+func (r *archiveRepo) AddGame(ctx context.Context, id types.ObjectId, pgn string, endedAt time.Time) error {
+	query := `INSERT INTO archived_games (game_id, pgn, ended_at) VALUES ($1, $2, $3) ON CONFLICT (game_id) DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query, id.String(), pgn, endedAt); err != nil {
+		return fmt.Errorf("failed to archive game '%s': %w", id, err)
+	}
+	return nil
+}
+
+// GetGamePGN returns an empty string if the game is not archived.
+func (r *archiveRepo) GetGamePGN(ctx context.Context, id types.ObjectId) (string, error) {
+	var pgn string
+	err := r.db.QueryRowContext(ctx, "SELECT pgn FROM archived_games WHERE game_id = $1", id.String()).Scan(&pgn)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get archived game '%s': %w", id, err)
+	}
+	return pgn, nil
+}
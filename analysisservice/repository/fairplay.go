@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	analysis "github.com/alikarimi999/shahboard/analysisservice/service"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type fairPlayRepo struct {
+	db *sql.DB
+	l  log.Logger
+}
+
+func NewFairPlayRepo(db *sql.DB, l log.Logger) analysis.FairPlayRepository {
+	return &fairPlayRepo{
+		db: db,
+		l:  l,
+	}
+}
+
+// AddReport adds the report and the stats of its counted players in one transaction,
+// so a game that is delivered again isn't counted twice.
+func (r *fairPlayRepo) AddReport(ctx context.Context, report *analysis.FairPlayReport,
+	counted []analysis.FairPlayGameStats) (bool, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO fair_play_reports (game_id, report, analyzed_at) VALUES ($1, $2, $3)
+		ON CONFLICT (game_id) DO NOTHING`
+	res, err := tx.ExecContext(ctx, query, report.GameID.String(), data, time.Unix(report.AnalyzedAt, 0))
+	if err != nil {
+		return false, fmt.Errorf("failed to add fair play report of game '%s': %w", report.GameID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	for _, st := range counted {
+		query := `INSERT INTO fair_play_accounts (user_id, games, moves, matched, cpl) VALUES ($1, 1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET games = fair_play_accounts.games + 1,
+			moves = fair_play_accounts.moves + EXCLUDED.moves,
+			matched = fair_play_accounts.matched + EXCLUDED.matched,
+			cpl = fair_play_accounts.cpl + EXCLUDED.cpl`
+		if _, err := tx.ExecContext(ctx, query, st.UserID.String(), st.Moves, st.Matched, st.CPL); err != nil {
+			return false, fmt.Errorf("failed to add fair play stats of user '%s': %w", st.UserID, err)
+		}
+
+		if st.Suspicious {
+			query := `INSERT INTO fair_play_suspicious_games (user_id, game_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+			if _, err := tx.ExecContext(ctx, query, st.UserID.String(), report.GameID.String()); err != nil {
+				return false, fmt.Errorf("failed to add suspicious game of user '%s': %w", st.UserID, err)
+			}
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *fairPlayRepo) GetReport(ctx context.Context, gameId types.ObjectId) (*analysis.FairPlayReport, error) {
+	var (
+		data       []byte
+		refundedBy sql.NullString
+		refundedAt sql.NullTime
+	)
+	query := "SELECT report, refunded_by, refunded_at FROM fair_play_reports WHERE game_id = $1"
+	err := r.db.QueryRowContext(ctx, query, gameId.String()).Scan(&data, &refundedBy, &refundedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get fair play report of game '%s': %w", gameId, err)
+	}
+
+	report := &analysis.FairPlayReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+	report.RefundedBy = types.ObjectId(refundedBy.String)
+	if refundedAt.Valid {
+		report.RefundedAt = refundedAt.Time.Unix()
+	}
+	return report, nil
+}
+
+func (r *fairPlayRepo) Refund(ctx context.Context, gameId, moderator types.ObjectId, t time.Time) (bool, error) {
+	query := `UPDATE fair_play_reports SET refunded_by = $1, refunded_at = $2 WHERE game_id = $3 AND refunded_by IS NULL`
+	return r.exec(ctx, query, moderator.String(), t, gameId.String())
+}
+
+func (r *fairPlayRepo) GetAccount(ctx context.Context, userId types.ObjectId) (*analysis.FairPlayAccount, int64, error) {
+	var (
+		a                      = &analysis.FairPlayAccount{UserID: userId, SuspiciousGames: []types.ObjectId{}}
+		matched, cpl, reviewed int64
+		flaggedAt              sql.NullTime
+	)
+	query := "SELECT games, moves, matched, cpl, reviewed_games, flagged_at FROM fair_play_accounts WHERE user_id = $1"
+	err := r.db.QueryRowContext(ctx, query, userId.String()).Scan(&a.Games, &a.Moves, &matched, &cpl,
+		&reviewed, &flaggedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to get fair play account of user '%s': %w", userId, err)
+	}
+
+	if a.Moves > 0 {
+		a.TopMoveMatch = float64(matched) / float64(a.Moves)
+		a.ACPL = float64(cpl) / float64(a.Moves)
+	}
+	if flaggedAt.Valid {
+		a.FlaggedAt = flaggedAt.Time.Unix()
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT game_id FROM fair_play_suspicious_games WHERE user_id = $1",
+		userId.String())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get suspicious games of user '%s': %w", userId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id types.ObjectId
+		if err := rows.Scan(&id); err != nil {
+			return nil, 0, err
+		}
+		a.SuspiciousGames = append(a.SuspiciousGames, id)
+	}
+
+	return a, reviewed, rows.Err()
+}
+
+func (r *fairPlayRepo) Flag(ctx context.Context, userId types.ObjectId, t time.Time) (bool, error) {
+	query := "UPDATE fair_play_accounts SET flagged_at = $1 WHERE user_id = $2 AND flagged_at IS NULL"
+	return r.exec(ctx, query, t, userId.String())
+}
+
+func (r *fairPlayRepo) Queue(ctx context.Context) ([]types.ObjectId, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT user_id FROM fair_play_accounts WHERE flagged_at IS NOT NULL ORDER BY flagged_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fair play queue: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []types.ObjectId{}
+	for rows.Next() {
+		var id types.ObjectId
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *fairPlayRepo) Dismiss(ctx context.Context, userId types.ObjectId) (bool, error) {
+	query := `UPDATE fair_play_accounts SET flagged_at = NULL, reviewed_games = games
+		WHERE user_id = $1 AND flagged_at IS NOT NULL`
+	return r.exec(ctx, query, userId.String())
+}
+
+// exec returns true if a row is updated.
+func (r *fairPlayRepo) exec(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
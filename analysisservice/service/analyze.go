@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/pkg/uci"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+// Analyze returns the evaluation and the best lines of a FEN or a position of a game.
+// Results are cached by the position, only the requests that reach the engine are counted in the user's quota.
+func (s *Service) Analyze(ctx context.Context, userId types.ObjectId, req AnalyzeRequest) (AnalyzeResponse, error) {
+	depth := req.Depth
+	if depth == 0 {
+		depth = s.cfg.DefaultDepth
+	}
+	if depth < 0 || depth > s.cfg.MaxDepth {
+		return AnalyzeResponse{}, fmt.Errorf("%w: depth should be between 1 and %d", ErrInvalidRequest, s.cfg.MaxDepth)
+	}
+
+	multiPV := req.MultiPV
+	if multiPV == 0 {
+		multiPV = 1
+	}
+	if multiPV < 0 || multiPV > s.cfg.MaxMultiPV {
+		return AnalyzeResponse{}, fmt.Errorf("%w: multi pv should be between 1 and %d", ErrInvalidRequest, s.cfg.MaxMultiPV)
+	}
+
+	pos, err := s.requestedPosition(ctx, req)
+	if err != nil {
+		return AnalyzeResponse{}, err
+	}
+
+	if pos.Status() != chess.NoMethod {
+		return AnalyzeResponse{}, fmt.Errorf("%w: there is no move in this position: %s", ErrInvalidRequest, pos.Status())
+	}
+
+	res, err := s.cache.getAnalysis(ctx, pos.String(), depth, multiPV)
+	if err != nil {
+		s.l.Error(err.Error())
+	}
+	if res != nil {
+		res.FEN = pos.String()
+		res.Cached = true
+		return *res, nil
+	}
+
+	if err := s.checkQuota(ctx, quotaAnalysis, userId); err != nil {
+		return AnalyzeResponse{}, err
+	}
+
+	return s.analyze(ctx, s.pool, pos, depth, multiPV, false)
+}
+
+// analyze runs the engine of the pool on the position and caches the result.
+// If wait is false it fails immediately when all workers are busy and the queue is full.
+func (s *Service) analyze(ctx context.Context, p *enginePool, pos *chess.Position, depth, multiPV int, wait bool) (AnalyzeResponse, error) {
+	fen := pos.String()
+	lines, err := p.analyze(ctx, fen, depth, multiPV, wait)
+	if err != nil {
+		if !errors.Is(err, ErrQueueFull) {
+			s.l.Error(fmt.Sprintf("failed to analyze '%s': %v", fen, err))
+		}
+		return AnalyzeResponse{}, err
+	}
+
+	res := AnalyzeResponse{FEN: fen, Depth: depth}
+	for _, l := range lines {
+		moves, ucis := pvToSAN(pos, l.PV)
+		res.Lines = append(res.Lines, Line{
+			Eval:  whiteEval(pos, l.Score),
+			Moves: moves,
+			UCI:   ucis,
+		})
+	}
+
+	res.Eval = res.Lines[0].Eval
+	if len(res.Lines[0].Moves) > 0 {
+		res.BestMove = res.Lines[0].Moves[0]
+	}
+
+	if err := s.cache.addAnalysis(ctx, fen, depth, multiPV, res); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	return res, nil
+}
+
+func (s *Service) requestedPosition(ctx context.Context, req AnalyzeRequest) (*chess.Position, error) {
+	if req.GameID.IsZero() {
+		if req.FEN == "" {
+			return nil, fmt.Errorf("%w: fen or game id is required", ErrInvalidRequest)
+		}
+
+		f, err := chess.FEN(req.FEN)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid fen: %v", ErrInvalidRequest, err)
+		}
+
+		return chess.NewGame(f).Position(), nil
+	}
+
+	g, err := s.loadGame(ctx, req.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := g.Positions()
+	if req.Ply == nil {
+		return positions[len(positions)-1], nil
+	}
+
+	if *req.Ply < 0 || *req.Ply >= len(positions) {
+		return nil, fmt.Errorf("%w: invalid ply: %d", ErrInvalidRequest, *req.Ply)
+	}
+
+	return positions[*req.Ply], nil
+}
+
+// whiteEval converts a score from the side to move point of view to white point of view.
+func whiteEval(pos *chess.Position, score uci.Score) Eval {
+	if pos.Turn() == chess.Black {
+		score = score.Negate()
+	}
+	return Eval{CP: score.CP, Mate: score.Mate}
+}
+
+// pvToSAN converts the UCI moves of a line to algebraic notation,
+// it stops at the first move that is not valid in the position.
+func pvToSAN(pos *chess.Position, pv []string) ([]string, []string) {
+	moves := []string{}
+	ucis := []string{}
+	for _, u := range pv {
+		var move *chess.Move
+		for _, m := range pos.ValidMoves() {
+			if m.String() == u {
+				move = m
+				break
+			}
+		}
+		if move == nil {
+			break
+		}
+
+		moves = append(moves, chess.AlgebraicNotation{}.Encode(pos, move))
+		ucis = append(ucis, u)
+		pos = pos.Update(move)
+	}
+
+	return moves, ucis
+}
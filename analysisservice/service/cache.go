@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyAnalysisPrefix = "analysis:"
+	keyReviewPrefix   = "analysis_review:"
+	keyQuotaPrefix    = "analysis_quota:"
+
+	// games that wait for the fair play analysis, the results are kept in the database
+	keyFairPlayPending = "fairplay_pending"
+)
+
+type quotaKind string
+
+const (
+	quotaAnalysis quotaKind = "analyze"
+	quotaReview   quotaKind = "review"
+)
+
+type redisCache struct {
+	rc  *redis.Client
+	ttl time.Duration
+}
+
+func newRedisCache(rc *redis.Client, ttl time.Duration) *redisCache {
+	return &redisCache{rc: rc, ttl: ttl}
+}
+
+// fenHash returns the hash of the FEN without the move counters,
+// so the same position reached with different move numbers shares the result.
+func fenHash(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+	h := sha1.Sum([]byte(strings.Join(fields, " ")))
+	return hex.EncodeToString(h[:])
+}
+
+func analysisKey(fen string, depth, multiPV int) string {
+	return fmt.Sprintf("%s%s:%d:%d", keyAnalysisPrefix, fenHash(fen), depth, multiPV)
+}
+
+// getAnalysis returns nil if the result is not cached.
+func (c *redisCache) getAnalysis(ctx context.Context, fen string, depth, multiPV int) (*AnalyzeResponse, error) {
+	data, err := c.rc.Get(ctx, analysisKey(fen, depth, multiPV)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	res := &AnalyzeResponse{}
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *redisCache) addAnalysis(ctx context.Context, fen string, depth, multiPV int, res AnalyzeResponse) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	return c.rc.Set(ctx, analysisKey(fen, depth, multiPV), data, c.ttl).Err()
+}
+
+func (c *redisCache) getReview(ctx context.Context, id types.ObjectId) (*Review, error) {
+	data, err := c.rc.Get(ctx, keyReviewPrefix+id.String()).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	r := &Review{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (c *redisCache) setReview(ctx context.Context, r *Review) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return c.rc.Set(ctx, keyReviewPrefix+r.ID.String(), data, c.ttl).Err()
+}
+
+// incrQuota increments the user's usage in the current window and returns the new value.
+// The window starts with the first request and its length is the given window.
+func (c *redisCache) incrQuota(ctx context.Context, kind quotaKind, userId types.ObjectId, window time.Duration) (int64, error) {
+	key := fmt.Sprintf("%s%s:%s", keyQuotaPrefix, kind, userId)
+
+	script := `
+	local n = redis.call('INCR', KEYS[1])
+	if n == 1 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	end
+	return n
+	`
+
+	return c.rc.Eval(ctx, script, []string{key}, window.Milliseconds()).Int64()
+}
+
+// pushFairPlayGame adds a finished game to the games that wait for the fair play analysis,
+// the list is shared by all instances.
+func (c *redisCache) pushFairPlayGame(ctx context.Context, data []byte) error {
+	return c.rc.LPush(ctx, keyFairPlayPending, data).Err()
+}
+
+// popFairPlayGame returns nil if there is no game until the timeout.
+func (c *redisCache) popFairPlayGame(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	res, err := c.rc.BRPop(ctx, timeout, keyFairPlayPending).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return []byte(res[1]), nil
+}
+
+// requeueFairPlayGame puts back a game that its analysis is interrupted, it's the next one to be analyzed.
+func (c *redisCache) requeueFairPlayGame(ctx context.Context, data []byte) error {
+	return c.rc.RPush(ctx, keyFairPlayPending, data).Err()
+}
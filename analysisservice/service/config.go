@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/pkg/uci"
+)
+
+type Config struct {
+	Engine uci.Config `json:"engine"`
+
+	// number of engine processes, each worker owns one engine
+	Workers int `json:"workers"`
+	// number of analysis jobs that can wait for a free worker
+	QueueSize int `json:"queue_size"`
+	// number of engine processes of the game reviews and the fair play analyses, default is 1
+	ReviewWorkers int `json:"review_workers"`
+
+	DefaultDepth int `json:"default_depth"`
+	MaxDepth     int `json:"max_depth"`
+	MaxMultiPV   int `json:"max_multi_pv"`
+	// depth that is used for every position of a game review
+	ReviewDepth int `json:"review_depth"`
+
+	// CacheTTL is the number of seconds that an analysis result or a review is cached.
+	CacheTTL uint64 `json:"cache_ttl"`
+
+	// QuotaWindow is the number of seconds of the quota window,
+	// a user can request AnalysisQuota analyses and ReviewQuota reviews in each window.
+	QuotaWindow   uint64 `json:"quota_window"`
+	AnalysisQuota int64  `json:"analysis_quota"`
+	ReviewQuota   int64  `json:"review_quota"`
+
+	FairPlay FairPlayConfig `json:"fair_play"`
+}
+
+// FairPlayConfig configures the analysis of the finished games that flags the accounts
+// whose moves correlate with the engine.
+type FairPlayConfig struct {
+	Enabled bool `json:"enabled"`
+	// depth of the engine for every position, default is the review depth
+	Depth int `json:"depth"`
+	// opening half moves of each game that are not counted, they are usually book moves
+	SkipPlies int `json:"skip_plies"`
+	// a game is counted for a player if at least this number of the player's moves are analyzed
+	MinMoves int `json:"min_moves"`
+	// an account is flagged when at least this number of its games are counted
+	// and its moves are above both thresholds
+	MinGames int64 `json:"min_games"`
+	// rate of the moves that are the engine's top move, in the range of 0 to 1
+	TopMoveMatchThreshold float64 `json:"top_move_match_threshold"`
+	// average centipawn loss, lower is closer to the engine
+	ACPLThreshold float64 `json:"acpl_threshold"`
+}
+
+const defaultReviewWorkers = 1
+
+func (cfg Config) validate() error {
+	if cfg.Workers <= 0 {
+		return fmt.Errorf("workers is required")
+	}
+
+	if cfg.ReviewWorkers < 0 {
+		return fmt.Errorf("invalid review workers")
+	}
+
+	if cfg.QueueSize < 0 {
+		return fmt.Errorf("invalid queue size")
+	}
+
+	if cfg.MaxDepth <= 0 {
+		return fmt.Errorf("max depth is required")
+	}
+
+	if cfg.DefaultDepth <= 0 || cfg.DefaultDepth > cfg.MaxDepth {
+		return fmt.Errorf("default depth should be between 1 and max depth")
+	}
+
+	if cfg.ReviewDepth <= 0 || cfg.ReviewDepth > cfg.MaxDepth {
+		return fmt.Errorf("review depth should be between 1 and max depth")
+	}
+
+	if cfg.MaxMultiPV <= 0 {
+		return fmt.Errorf("max multi pv is required")
+	}
+
+	if cfg.CacheTTL == 0 {
+		return fmt.Errorf("cache ttl is required")
+	}
+
+	if cfg.QuotaWindow == 0 {
+		return fmt.Errorf("quota window is required")
+	}
+
+	if cfg.AnalysisQuota <= 0 || cfg.ReviewQuota <= 0 {
+		return fmt.Errorf("analysis and review quotas are required")
+	}
+
+	if cfg.FairPlay.Enabled {
+		return cfg.FairPlay.validate(cfg.MaxDepth)
+	}
+
+	return nil
+}
+
+func (cfg FairPlayConfig) validate(maxDepth int) error {
+	if cfg.Depth < 0 || cfg.Depth > maxDepth {
+		return fmt.Errorf("fair play depth should be between 1 and max depth")
+	}
+
+	if cfg.SkipPlies < 0 {
+		return fmt.Errorf("invalid fair play skip plies")
+	}
+
+	if cfg.MinMoves <= 0 || cfg.MinGames <= 0 {
+		return fmt.Errorf("fair play min moves and min games are required")
+	}
+
+	if cfg.TopMoveMatchThreshold <= 0 || cfg.TopMoveMatchThreshold > 1 {
+		return fmt.Errorf("fair play top move match threshold should be between 0 and 1")
+	}
+
+	if cfg.ACPLThreshold <= 0 {
+		return fmt.Errorf("fair play acpl threshold is required")
+	}
+
+	return nil
+}
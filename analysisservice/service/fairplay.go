@@ -0,0 +1,426 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+const (
+	// positions that one side is winning by more than this are not counted,
+	// any reasonable move keeps them won and matching the engine says little.
+	decidedCP = 500
+
+	fairPlayPollTimeout = 5 * time.Second
+	fairPlayRetryDelay  = 5 * time.Second
+)
+
+var (
+	ErrNoFairPlayGames  = errors.New("no analyzed games for this user")
+	ErrGameNotAnalyzed  = errors.New("game is not analyzed")
+	ErrNotInReviewQueue = errors.New("user is not in the review queue")
+	ErrNotGamePlayer    = errors.New("cheater is not a player of the game")
+	ErrGameRefunded     = errors.New("game is already refunded")
+)
+
+// FairPlayRepository keeps the fair play reports, the stats of the accounts and the review queue.
+type FairPlayRepository interface {
+	// AddReport adds the report and the stats of the counted players of the game,
+	// it returns false if the game already has a report.
+	AddReport(ctx context.Context, r *FairPlayReport, counted []FairPlayGameStats) (bool, error)
+	// returns nil if the game is not analyzed
+	GetReport(ctx context.Context, gameId types.ObjectId) (*FairPlayReport, error)
+	// returns false if the game is already refunded
+	Refund(ctx context.Context, gameId, moderator types.ObjectId, t time.Time) (bool, error)
+	// returns nil if none of the user's games are counted,
+	// the second value is the number of the games when the account was reviewed last time.
+	GetAccount(ctx context.Context, userId types.ObjectId) (*FairPlayAccount, int64, error)
+	// adds the user to the review queue, it returns false if the user is already in the queue
+	Flag(ctx context.Context, userId types.ObjectId, t time.Time) (bool, error)
+	// returns the flagged users, the most recent first
+	Queue(ctx context.Context) ([]types.ObjectId, error)
+	// removes the user from the review queue and records the number of the reviewed games,
+	// it returns false if the user is not in the queue
+	Dismiss(ctx context.Context, userId types.ObjectId) (bool, error)
+}
+
+// FairPlayGameStats is the engine correlation of a counted game that is added to the player's account.
+type FairPlayGameStats struct {
+	UserID     types.ObjectId
+	Moves      int
+	Matched    int
+	CPL        int64
+	Suspicious bool
+}
+
+// playerStats is the engine correlation of a player's moves in a game.
+type playerStats struct {
+	moves   int
+	matched int
+	// sum of the centipawn losses
+	cpl int64
+}
+
+func (st playerStats) report(userId types.ObjectId, cfg FairPlayConfig) PlayerFairPlay {
+	p := PlayerFairPlay{UserID: userId, Moves: st.moves}
+	if st.moves == 0 {
+		return p
+	}
+
+	p.TopMoveMatch = math.Round(float64(st.matched)/float64(st.moves)*1000) / 1000
+	p.ACPL = round(float64(st.cpl) / float64(st.moves))
+	p.Suspicious = st.moves >= cfg.MinMoves && isSuspicious(p.TopMoveMatch, p.ACPL, cfg)
+	return p
+}
+
+func isSuspicious(match, acpl float64, cfg FairPlayConfig) bool {
+	return match >= cfg.TopMoveMatchThreshold && acpl <= cfg.ACPLThreshold
+}
+
+func (s *Service) handleEvents(e event.Event) {
+	switch e.GetTopic().Domain() {
+	case event.DomainGame:
+		switch e.GetTopic().Action() {
+		case event.ActionEnded:
+			e := e.(*event.EventGameEnded)
+			s.archiveGame(e)
+			if s.cfg.FairPlay.Enabled {
+				s.handleGameEnded(e)
+			}
+		}
+	}
+}
+
+// archiveGame keeps the PGN of the game, so it can be analyzed and reviewed after Game Service removes it.
+func (s *Service) archiveGame(e *event.EventGameEnded) {
+	if e.PGN == "" {
+		return
+	}
+
+	if err := s.archive.AddGame(context.Background(), e.GameID, e.PGN, time.Unix(e.Timestamp, 0)); err != nil {
+		s.l.Error(err.Error())
+	}
+}
+
+// handleGameEnded queues the game for the fair play analysis, the analysis is slow
+// and it's done by the first free instance.
+func (s *Service) handleGameEnded(e *event.EventGameEnded) {
+	// aborted games are not rated
+	if !e.Outcome.IsResult() || e.PGN == "" {
+		return
+	}
+
+	if err := s.cache.pushFairPlayGame(context.Background(), e.Encode()); err != nil {
+		s.l.Error(fmt.Sprintf("failed to queue game '%s' for fair play analysis: %v", e.GameID, err))
+	}
+}
+
+// runFairPlay analyzes the queued games one by one, so the interactive analyses get most of the engines.
+func (s *Service) runFairPlay() {
+	defer s.wg.Done()
+
+	for s.ctx.Err() == nil {
+		data, err := s.cache.popFairPlayGame(s.ctx, fairPlayPollTimeout)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.l.Error(fmt.Sprintf("failed to get queued fair play game: %v", err))
+			s.sleep(fairPlayRetryDelay)
+			continue
+		}
+		if data == nil {
+			continue
+		}
+
+		e := &event.EventGameEnded{}
+		if err := json.Unmarshal(data, e); err != nil {
+			s.l.Error(fmt.Sprintf("invalid queued fair play game: %v", err))
+			continue
+		}
+
+		if err := s.analyzeFairPlay(e); err != nil {
+			if s.ctx.Err() != nil {
+				// the service is closing, the next instance continues the game
+				if err := s.cache.requeueFairPlayGame(context.Background(), data); err != nil {
+					s.l.Error(fmt.Sprintf("failed to requeue game '%s': %v", e.GameID, err))
+				}
+				return
+			}
+			s.l.Error(fmt.Sprintf("fair play analysis of game '%s' failed: %v", e.GameID, err))
+		}
+	}
+}
+
+func (s *Service) sleep(d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *Service) analyzeFairPlay(e *event.EventGameEnded) error {
+	g, err := parsePGN(e.PGN)
+	if err != nil {
+		// chess960 games are skipped
+		s.l.Debug(fmt.Sprintf("game '%s' is skipped by fair play analysis: %v", e.GameID, err))
+		return nil
+	}
+
+	cfg := s.cfg.FairPlay
+	positions := g.Positions()
+	moves := g.Moves()
+	if len(moves) <= cfg.SkipPlies {
+		return nil
+	}
+
+	evals := make([]positionEval, len(positions))
+	for i := cfg.SkipPlies; i < len(positions); i++ {
+		evals[i], err = s.evaluate(s.ctx, positions[i], cfg.Depth)
+		if err != nil {
+			return err
+		}
+	}
+
+	var white, black playerStats
+	for i := cfg.SkipPlies; i < len(moves); i++ {
+		pos := positions[i]
+		mover := pos.Turn()
+
+		// forced moves and decided positions are not counted
+		if len(pos.ValidMoves()) == 1 {
+			continue
+		}
+		before := moverCP(evals[i].eval, mover)
+		if math.Abs(float64(before)) >= decidedCP {
+			continue
+		}
+
+		st := &white
+		if mover == chess.Black {
+			st = &black
+		}
+
+		st.moves++
+		if moves[i].String() == evals[i].best {
+			st.matched++
+		}
+		st.cpl += int64(max(0, before-moverCP(evals[i+1].eval, mover)))
+	}
+
+	whiteId, blackId := e.Player1.ID, e.Player2.ID
+	if e.Player1.Color == types.ColorBlack {
+		whiteId, blackId = blackId, whiteId
+	}
+
+	r := &FairPlayReport{
+		GameID:     e.GameID,
+		Depth:      cfg.Depth,
+		White:      white.report(whiteId, cfg),
+		Black:      black.report(blackId, cfg),
+		AnalyzedAt: time.Now().Unix(),
+	}
+
+	counted := []FairPlayGameStats{}
+	for _, p := range []struct {
+		report PlayerFairPlay
+		stats  playerStats
+	}{{r.White, white}, {r.Black, black}} {
+		if p.report.Moves < cfg.MinMoves {
+			continue
+		}
+		counted = append(counted, FairPlayGameStats{
+			UserID:     p.report.UserID,
+			Moves:      p.stats.moves,
+			Matched:    p.stats.matched,
+			CPL:        p.stats.cpl,
+			Suspicious: p.report.Suspicious,
+		})
+	}
+
+	ok, err := s.fairPlay.AddReport(s.ctx, r, counted)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// the game is delivered again, it's already counted
+		return nil
+	}
+
+	for _, st := range counted {
+		s.checkFairPlayAccount(st.UserID)
+	}
+
+	s.l.Debug(fmt.Sprintf("fair play analysis of game '%s' is done", e.GameID))
+	return nil
+}
+
+// checkFairPlayAccount adds the user to the review queue if the counted games are above the thresholds,
+// a dismissed account is checked again after min games more games.
+func (s *Service) checkFairPlayAccount(userId types.ObjectId) {
+	a, reviewed, err := s.fairPlay.GetAccount(s.ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+
+	cfg := s.cfg.FairPlay
+	if a == nil || a.FlaggedAt != 0 || a.Games-reviewed < cfg.MinGames || !isSuspicious(a.TopMoveMatch, a.ACPL, cfg) {
+		return
+	}
+
+	ok, err := s.fairPlay.Flag(s.ctx, userId, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if err := s.pub.Publish(event.EventFairPlayFlagged{
+		ID:           types.NewObjectId(),
+		UserID:       userId,
+		Games:        a.Games,
+		TopMoveMatch: a.TopMoveMatch,
+		ACPL:         a.ACPL,
+		Timestamp:    time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	s.l.Info(fmt.Sprintf("user '%s' is flagged for fair play review", userId))
+}
+
+// GetFairPlayQueue returns the flagged accounts, the most recent first.
+func (s *Service) GetFairPlayQueue(ctx context.Context) ([]*FairPlayAccount, error) {
+	ids, err := s.fairPlay.Queue(ctx)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	res := make([]*FairPlayAccount, 0, len(ids))
+	for _, id := range ids {
+		a, _, err := s.fairPlay.GetAccount(ctx, id)
+		if err != nil {
+			s.l.Error(err.Error())
+			return nil, err
+		}
+		if a != nil {
+			res = append(res, a)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *Service) GetFairPlayAccount(ctx context.Context, userId types.ObjectId) (*FairPlayAccount, error) {
+	a, _, err := s.fairPlay.GetAccount(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	if a == nil {
+		return nil, ErrNoFairPlayGames
+	}
+
+	return a, nil
+}
+
+// DismissFairPlayAccount removes the user from the review queue after a moderator reviewed the account.
+func (s *Service) DismissFairPlayAccount(ctx context.Context, moderator, userId types.ObjectId) error {
+	ok, err := s.fairPlay.Dismiss(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if !ok {
+		return ErrNotInReviewQueue
+	}
+
+	s.l.Info(fmt.Sprintf("fair play review of user '%s' dismissed by '%s'", userId, moderator))
+	return nil
+}
+
+func (s *Service) GetFairPlayReport(ctx context.Context, gameId types.ObjectId) (*FairPlayReport, error) {
+	r, err := s.fairPlay.GetReport(ctx, gameId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, ErrGameNotAnalyzed
+	}
+
+	return r, nil
+}
+
+// RefundGame marks an analyzed game of the cheater for rating refund,
+// the profile service gives back the rating that the opponent lost.
+// The event is published before the game is marked, so a failed refund can be retried,
+// the profile service refunds each game once.
+func (s *Service) RefundGame(ctx context.Context, moderator, gameId types.ObjectId, req RefundRequest) error {
+	r, err := s.GetFairPlayReport(ctx, gameId)
+	if err != nil {
+		return err
+	}
+
+	if req.CheaterID != r.White.UserID && req.CheaterID != r.Black.UserID {
+		return ErrNotGamePlayer
+	}
+
+	if !r.RefundedBy.IsZero() {
+		return ErrGameRefunded
+	}
+
+	if err := s.pub.Publish(event.EventFairPlayRatingRefund{
+		ID:        types.NewObjectId(),
+		GameID:    gameId,
+		CheaterID: req.CheaterID,
+		IssuedBy:  moderator,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	ok, err := s.fairPlay.Refund(ctx, gameId, moderator, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return ErrGameRefunded
+	}
+
+	s.l.Info(fmt.Sprintf("game '%s' of cheater '%s' marked for rating refund by '%s'", gameId, req.CheaterID, moderator))
+	return nil
+}
+
+// moverCP returns the evaluation in centipawns from the point of view of the given color,
+// mates are treated as the max evaluation of the reviews.
+func moverCP(e Eval, c chess.Color) int {
+	cp := e.CP
+	if e.Mate > 0 {
+		cp = maxReviewCP
+	} else if e.Mate < 0 {
+		cp = -maxReviewCP
+	}
+
+	cp = max(-maxReviewCP, min(maxReviewCP, cp))
+	if c == chess.Black {
+		cp = -cp
+	}
+	return cp
+}
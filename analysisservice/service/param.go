@@ -0,0 +1,138 @@
+package analysis
+
+import "github.com/alikarimi999/shahboard/types"
+
+// AnalyzeRequest analyzes a FEN or a position of a game.
+// If GameID is set, Ply is the number of half moves from the start of the game,
+// if it's nil the last position of the game is analyzed.
+type AnalyzeRequest struct {
+	FEN     string         `json:"fen"`
+	GameID  types.ObjectId `json:"game_id"`
+	Ply     *int           `json:"ply"`
+	Depth   int            `json:"depth"`
+	MultiPV int            `json:"multi_pv"`
+}
+
+// Eval is the evaluation of a position from white point of view,
+// Mate is the number of moves to mate and is negative if black mates.
+type Eval struct {
+	CP   int `json:"cp"`
+	Mate int `json:"mate,omitempty"`
+}
+
+type Line struct {
+	Eval Eval `json:"eval"`
+	// moves in algebraic notation
+	Moves []string `json:"moves"`
+	// moves in UCI notation
+	UCI []string `json:"uci"`
+}
+
+type AnalyzeResponse struct {
+	FEN      string `json:"fen"`
+	Depth    int    `json:"depth"`
+	Eval     Eval   `json:"eval"`
+	BestMove string `json:"best_move"`
+	Lines    []Line `json:"lines"`
+	Cached   bool   `json:"cached"`
+}
+
+// ReviewRequest reviews a game by its ID or an imported PGN.
+type ReviewRequest struct {
+	GameID types.ObjectId `json:"game_id"`
+	PGN    string         `json:"pgn"`
+}
+
+type CreateReviewResponse struct {
+	ID types.ObjectId `json:"id"`
+}
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending ReviewStatus = "pending"
+	ReviewStatusDone    ReviewStatus = "done"
+	ReviewStatusFailed  ReviewStatus = "failed"
+)
+
+type MoveClass string
+
+const (
+	MoveClassBest       MoveClass = "best"
+	MoveClassGood       MoveClass = "good"
+	MoveClassInaccuracy MoveClass = "inaccuracy"
+	MoveClassMistake    MoveClass = "mistake"
+	MoveClassBlunder    MoveClass = "blunder"
+)
+
+type ReviewedMove struct {
+	Ply      int       `json:"ply"`
+	Color    string    `json:"color"`
+	Move     string    `json:"move"`
+	BestMove string    `json:"best_move"`
+	Eval     Eval      `json:"eval"`
+	Class    MoveClass `json:"class"`
+	Accuracy float64   `json:"accuracy"`
+}
+
+type SideSummary struct {
+	Accuracy     float64 `json:"accuracy"`
+	Best         int     `json:"best"`
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+}
+
+type Review struct {
+	ID        types.ObjectId `json:"id"`
+	Owner     types.ObjectId `json:"owner"`
+	GameID    types.ObjectId `json:"game_id,omitempty"`
+	Status    ReviewStatus   `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	Depth     int            `json:"depth"`
+	Analyzed  int            `json:"analyzed"`
+	Total     int            `json:"total"`
+	Moves     []ReviewedMove `json:"moves"`
+	White     SideSummary    `json:"white"`
+	Black     SideSummary    `json:"black"`
+	CreatedAt int64          `json:"created_at"`
+}
+
+// PlayerFairPlay is the engine correlation of a player's moves in a game.
+type PlayerFairPlay struct {
+	UserID types.ObjectId `json:"user_id"`
+	// number of the moves that are analyzed, opening, forced and already decided moves are skipped
+	Moves int `json:"moves"`
+	// rate of the moves that are the engine's top move, in the range of 0 to 1
+	TopMoveMatch float64 `json:"top_move_match"`
+	// average centipawn loss
+	ACPL       float64 `json:"acpl"`
+	Suspicious bool    `json:"suspicious"`
+}
+
+type FairPlayReport struct {
+	GameID     types.ObjectId `json:"game_id"`
+	Depth      int            `json:"depth"`
+	White      PlayerFairPlay `json:"white"`
+	Black      PlayerFairPlay `json:"black"`
+	RefundedBy types.ObjectId `json:"refunded_by,omitempty"`
+	RefundedAt int64          `json:"refunded_at,omitempty"`
+	AnalyzedAt int64          `json:"analyzed_at"`
+}
+
+// FairPlayAccount is the engine correlation of all counted games of the user.
+type FairPlayAccount struct {
+	UserID          types.ObjectId   `json:"user_id"`
+	Games           int64            `json:"games"`
+	Moves           int64            `json:"moves"`
+	TopMoveMatch    float64          `json:"top_move_match"`
+	ACPL            float64          `json:"acpl"`
+	SuspiciousGames []types.ObjectId `json:"suspicious_games"`
+	// zero if the account is not in the review queue
+	FlaggedAt int64 `json:"flagged_at,omitempty"`
+}
+
+// RefundRequest marks a game of the cheater for rating refund of the opponent.
+type RefundRequest struct {
+	CheaterID types.ObjectId `json:"cheater_id"`
+}
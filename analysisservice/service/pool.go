@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/uci"
+)
+
+// Engine is a UCI engine that is used by the workers, *uci.Engine implements it.
+type Engine interface {
+	Analyze(ctx context.Context, fen string, depth, multiPV int) ([]uci.Line, error)
+	Close() error
+}
+
+type EngineFactory func() (Engine, error)
+
+type job struct {
+	ctx     context.Context
+	fen     string
+	depth   int
+	multiPV int
+	res     chan jobResult
+}
+
+type jobResult struct {
+	lines []uci.Line
+	err   error
+}
+
+// enginePool runs analysis jobs on a fixed number of engines.
+type enginePool struct {
+	jobs    chan *job
+	factory EngineFactory
+	l       log.Logger
+
+	wg      sync.WaitGroup
+	closeCh chan struct{}
+}
+
+func newEnginePool(workers, queueSize int, factory EngineFactory, l log.Logger) (*enginePool, error) {
+	p := &enginePool{
+		jobs:    make(chan *job, queueSize),
+		factory: factory,
+		l:       l,
+		closeCh: make(chan struct{}),
+	}
+
+	engines := make([]Engine, 0, workers)
+	for i := 0; i < workers; i++ {
+		e, err := factory()
+		if err != nil {
+			for _, e := range engines {
+				e.Close()
+			}
+			return nil, fmt.Errorf("failed to start engine: %v", err)
+		}
+		engines = append(engines, e)
+	}
+
+	for i, e := range engines {
+		p.wg.Add(1)
+		go p.run(i, e)
+	}
+
+	return p, nil
+}
+
+// analyze runs the analysis on one of the engines.
+// If wait is false and there is no room in the queue, it returns ErrQueueFull instead of waiting.
+func (p *enginePool) analyze(ctx context.Context, fen string, depth, multiPV int, wait bool) ([]uci.Line, error) {
+	j := &job{
+		ctx:     ctx,
+		fen:     fen,
+		depth:   depth,
+		multiPV: multiPV,
+		res:     make(chan jobResult, 1),
+	}
+
+	if wait {
+		select {
+		case p.jobs <- j:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.closeCh:
+			return nil, fmt.Errorf("analysis service is closed")
+		}
+	} else {
+		select {
+		case p.jobs <- j:
+		default:
+			return nil, ErrQueueFull
+		}
+	}
+
+	select {
+	case r := <-j.res:
+		return r.lines, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *enginePool) run(id int, e Engine) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.closeCh:
+			if e != nil {
+				e.Close()
+			}
+			return
+		case j := <-p.jobs:
+			if err := j.ctx.Err(); err != nil {
+				j.res <- jobResult{err: err}
+				continue
+			}
+
+			if e == nil {
+				var err error
+				if e, err = p.factory(); err != nil {
+					p.l.Error(fmt.Sprintf("worker %d failed to restart engine: %v", id, err))
+					j.res <- jobResult{err: fmt.Errorf("engine is not available")}
+					continue
+				}
+			}
+
+			lines, err := e.Analyze(j.ctx, j.fen, j.depth, j.multiPV)
+			if err != nil && j.ctx.Err() == nil {
+				// the engine process is probably broken, it's restarted on the next job
+				p.l.Error(fmt.Sprintf("worker %d engine failed: %v", id, err))
+				e.Close()
+				e = nil
+			}
+
+			j.res <- jobResult{lines: lines, err: err}
+		}
+	}
+}
+
+func (p *enginePool) close() {
+	close(p.closeCh)
+	p.wg.Wait()
+}
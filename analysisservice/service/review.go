@@ -0,0 +1,244 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+// evaluations are clamped to this value before converting them to winning chances,
+// forced mates and checkmates are treated as this value too.
+const maxReviewCP = 1000
+
+// CreateReview starts reviewing every move of a game in the background and returns the review ID,
+// the review can be polled by GetReview until it's done.
+func (s *Service) CreateReview(ctx context.Context, userId types.ObjectId, req ReviewRequest) (CreateReviewResponse, error) {
+	var (
+		g   *chess.Game
+		err error
+	)
+
+	switch {
+	case !req.GameID.IsZero():
+		g, err = s.loadGame(ctx, req.GameID)
+	case req.PGN != "":
+		g, err = parsePGN(req.PGN)
+	default:
+		return CreateReviewResponse{}, fmt.Errorf("%w: game id or pgn is required", ErrInvalidRequest)
+	}
+	if err != nil {
+		return CreateReviewResponse{}, err
+	}
+
+	if len(g.Moves()) == 0 {
+		return CreateReviewResponse{}, fmt.Errorf("%w: game has no moves", ErrInvalidRequest)
+	}
+
+	if err := s.checkQuota(ctx, quotaReview, userId); err != nil {
+		return CreateReviewResponse{}, err
+	}
+
+	r := &Review{
+		ID:        types.NewObjectId(),
+		Owner:     userId,
+		GameID:    req.GameID,
+		Status:    ReviewStatusPending,
+		Depth:     s.cfg.ReviewDepth,
+		Total:     len(g.Positions()),
+		Moves:     []ReviewedMove{},
+		CreatedAt: time.Now().Unix(),
+	}
+
+	if err := s.cache.setReview(ctx, r); err != nil {
+		s.l.Error(err.Error())
+		return CreateReviewResponse{}, err
+	}
+
+	go s.review(r, g)
+
+	s.l.Debug(fmt.Sprintf("review '%s' created by '%s'", r.ID, userId))
+
+	return CreateReviewResponse{ID: r.ID}, nil
+}
+
+func (s *Service) GetReview(ctx context.Context, id types.ObjectId) (*Review, error) {
+	r, err := s.cache.getReview(ctx, id)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, ErrReviewNotFound
+	}
+
+	return r, nil
+}
+
+type positionEval struct {
+	eval Eval
+	// best move in UCI notation, empty if there is no move in the position
+	best string
+}
+
+func (s *Service) review(r *Review, g *chess.Game) {
+	positions := g.Positions()
+	evals := make([]positionEval, 0, len(positions))
+
+	for _, pos := range positions {
+		e, err := s.evaluate(s.ctx, pos, s.cfg.ReviewDepth)
+		if err != nil {
+			r.Status = ReviewStatusFailed
+			r.Error = err.Error()
+			if err := s.cache.setReview(context.Background(), r); err != nil {
+				s.l.Error(err.Error())
+			}
+			return
+		}
+
+		evals = append(evals, e)
+		r.Analyzed++
+		if err := s.cache.setReview(s.ctx, r); err != nil {
+			s.l.Error(err.Error())
+		}
+	}
+
+	white, black := []float64{}, []float64{}
+	for i, m := range g.Moves() {
+		pos := positions[i]
+		mover := pos.Turn()
+
+		before := winPercent(evals[i].eval, mover)
+		after := winPercent(evals[i+1].eval, mover)
+		drop := math.Max(0, before-after)
+
+		rm := ReviewedMove{
+			Ply:      i + 1,
+			Color:    mover.String(),
+			Move:     chess.AlgebraicNotation{}.Encode(pos, m),
+			Eval:     evals[i+1].eval,
+			Accuracy: round(moveAccuracy(drop)),
+		}
+
+		if best, _ := pvToSAN(pos, []string{evals[i].best}); len(best) > 0 {
+			rm.BestMove = best[0]
+		}
+
+		side := &r.White
+		if mover == chess.Black {
+			side = &r.Black
+		}
+
+		rm.Class = classify(m.String() == evals[i].best, drop)
+		switch rm.Class {
+		case MoveClassBest:
+			side.Best++
+		case MoveClassInaccuracy:
+			side.Inaccuracies++
+		case MoveClassMistake:
+			side.Mistakes++
+		case MoveClassBlunder:
+			side.Blunders++
+		}
+
+		if mover == chess.White {
+			white = append(white, rm.Accuracy)
+		} else {
+			black = append(black, rm.Accuracy)
+		}
+
+		r.Moves = append(r.Moves, rm)
+	}
+
+	r.White.Accuracy = round(mean(white))
+	r.Black.Accuracy = round(mean(black))
+	r.Status = ReviewStatusDone
+
+	if err := s.cache.setReview(context.Background(), r); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	s.l.Debug(fmt.Sprintf("review '%s' is done", r.ID))
+}
+
+// evaluate returns the evaluation of the position from white point of view,
+// positions that are over are evaluated without the engine.
+func (s *Service) evaluate(ctx context.Context, pos *chess.Position, depth int) (positionEval, error) {
+	switch pos.Status() {
+	case chess.Checkmate:
+		// the side to move is mated
+		if pos.Turn() == chess.White {
+			return positionEval{eval: Eval{CP: -maxReviewCP}}, nil
+		}
+		return positionEval{eval: Eval{CP: maxReviewCP}}, nil
+	case chess.Stalemate:
+		return positionEval{}, nil
+	}
+
+	res, err := s.cache.getAnalysis(ctx, pos.String(), depth, 1)
+	if err != nil {
+		s.l.Error(err.Error())
+	}
+
+	if res == nil {
+		r, err := s.analyze(ctx, s.reviewPool, pos, depth, 1, true)
+		if err != nil {
+			return positionEval{}, err
+		}
+		res = &r
+	}
+
+	e := positionEval{eval: res.Eval}
+	if len(res.Lines) > 0 && len(res.Lines[0].UCI) > 0 {
+		e.best = res.Lines[0].UCI[0]
+	}
+
+	return e, nil
+}
+
+// winPercent returns the winning chances of the given color in the range of 0 to 100.
+func winPercent(e Eval, c chess.Color) float64 {
+	cp := moverCP(e, c)
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy converts the drop of the winning chances of a move to an accuracy in the range of 0 to 100.
+func moveAccuracy(drop float64) float64 {
+	a := 103.1668*math.Exp(-0.04354*drop) - 3.1669
+	return math.Max(0, math.Min(100, a))
+}
+
+func classify(isBest bool, drop float64) MoveClass {
+	switch {
+	case isBest:
+		return MoveClassBest
+	case drop >= 30:
+		return MoveClassBlunder
+	case drop >= 20:
+		return MoveClassMistake
+	case drop >= 10:
+		return MoveClassInaccuracy
+	default:
+		return MoveClassGood
+	}
+}
+
+func mean(a []float64) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range a {
+		sum += v
+	}
+	return sum / float64(len(a))
+}
+
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
@@ -0,0 +1,187 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrGameNotFound   = errors.New("game not found")
+	ErrGameNotEnded   = errors.New("game is not ended yet, only ended games can be analyzed")
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+	ErrQueueFull      = errors.New("analysis queue is full, try again later")
+	ErrReviewNotFound = errors.New("review not found")
+)
+
+type GameService interface {
+	// GetGamePGN returns the PGN of a live game or a recently ended game, or an empty string if the game is not found.
+	GetGamePGN(ctx context.Context, id types.ObjectId) (string, error)
+}
+
+// Archive keeps the PGNs of the finished games, Game Service only keeps them for a while after they end.
+type Archive interface {
+	AddGame(ctx context.Context, id types.ObjectId, pgn string, endedAt time.Time) error
+	// GetGamePGN returns an empty string if the game is not archived.
+	GetGamePGN(ctx context.Context, id types.ObjectId) (string, error)
+}
+
+type Service struct {
+	cfg Config
+
+	pool *enginePool
+	// reviewPool runs the reviews and the fair play analyses, so they can't starve the interactive analyses
+	reviewPool *enginePool
+	cache      *redisCache
+	game       GameService
+	archive    Archive
+	fairPlay   FairPlayRepository
+
+	pub event.Publisher
+	sm  *event.SubscriptionManager
+	wg  sync.WaitGroup
+
+	l log.Logger
+
+	// ctx is used by the reviews that run in the background
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewService(cfg Config, redis *redis.Client, factory EngineFactory, game GameService, archive Archive,
+	fairPlay FairPlayRepository, pub event.Publisher, sub event.Subscriber, l log.Logger) (*Service, error) {
+	if cfg.ReviewWorkers == 0 {
+		cfg.ReviewWorkers = defaultReviewWorkers
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	pool, err := newEnginePool(cfg.Workers, cfg.QueueSize, factory, l)
+	if err != nil {
+		return nil, err
+	}
+
+	// the review jobs wait for a free worker, so the queue only holds the jobs of the running reviews
+	reviewPool, err := newEnginePool(cfg.ReviewWorkers, cfg.ReviewWorkers, factory, l)
+	if err != nil {
+		pool.close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Service{
+		cfg:        cfg,
+		pool:       pool,
+		reviewPool: reviewPool,
+		cache:      newRedisCache(redis, time.Duration(cfg.CacheTTL)*time.Second),
+		game:       game,
+		archive:    archive,
+		fairPlay:   fairPlay,
+		pub:        pub,
+		l:          l,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	// the finished games are archived, and queued for the fair play analysis if it's enabled
+	s.sm = event.NewManager(l, s.handleEvents)
+	s.sm.AddSubscription(sub.Subscribe(event.TopicGameEnded))
+
+	if cfg.FairPlay.Enabled {
+		if s.cfg.FairPlay.Depth == 0 {
+			s.cfg.FairPlay.Depth = cfg.ReviewDepth
+		}
+
+		s.wg.Add(1)
+		go s.runFairPlay()
+	}
+
+	return s, nil
+}
+
+func (s *Service) Close() {
+	s.sm.Stop()
+	s.cancel()
+	s.wg.Wait()
+	s.pool.close()
+	s.reviewPool.close()
+}
+
+// checkQuota returns an error if the user has used all of its quota in the current window.
+func (s *Service) checkQuota(ctx context.Context, kind quotaKind, userId types.ObjectId) error {
+	limit := s.cfg.AnalysisQuota
+	if kind == quotaReview {
+		limit = s.cfg.ReviewQuota
+	}
+
+	n, err := s.cache.incrQuota(ctx, kind, userId, time.Duration(s.cfg.QuotaWindow)*time.Second)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if n > limit {
+		return fmt.Errorf("%s %w, try again later", kind, ErrQuotaExceeded)
+	}
+
+	return nil
+}
+
+// loadGame parses the PGN of an ended game from the archive, or from the game service if the game
+// has just ended and is not archived yet. The live games are refused, so they can't be analyzed while they are played.
+func (s *Service) loadGame(ctx context.Context, id types.ObjectId) (*chess.Game, error) {
+	pgn, err := s.archive.GetGamePGN(ctx, id)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if pgn != "" {
+		return parsePGN(pgn)
+	}
+
+	pgn, err = s.game.GetGamePGN(ctx, id)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if pgn == "" {
+		return nil, ErrGameNotFound
+	}
+
+	g, err := parsePGN(pgn)
+	if err != nil {
+		return nil, err
+	}
+	// the game service keeps the live games too, they have no result
+	if g.Outcome() == chess.NoOutcome {
+		return nil, ErrGameNotEnded
+	}
+	return g, nil
+}
+
+func parsePGN(pgn string) (*chess.Game, error) {
+	// castling in chess960 games is not understood by the library and the engine needs UCI_Chess960
+	if strings.Contains(pgn, `[Variant "Chess960"]`) {
+		return nil, fmt.Errorf("%w: chess960 games are not supported", ErrInvalidRequest)
+	}
+
+	f, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid pgn: %v", ErrInvalidRequest, err)
+	}
+
+	return chess.NewGame(f), nil
+}
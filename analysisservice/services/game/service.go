@@ -0,0 +1,28 @@
+package game
+
+import (
+	"context"
+
+	pb "github.com/alikarimi999/shahboard/proto/game/gamepb"
+	"github.com/alikarimi999/shahboard/types"
+	"google.golang.org/grpc"
+)
+
+type GameService struct {
+	client pb.GameServiceClient
+}
+
+func NewService(client *grpc.ClientConn) *GameService {
+	return &GameService{client: pb.NewGameServiceClient(client)}
+}
+
+// GetGamePGN returns the PGN of a live game or an ended game that is still kept by the game service.
+// It returns an empty string if the game is not found.
+func (s *GameService) GetGamePGN(ctx context.Context, id types.ObjectId) (string, error) {
+	resp, err := s.client.GetGamePGN(ctx, &pb.GetGamePGNRequest{GameId: id.String()})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Pgn, nil
+}
@@ -1,13 +1,17 @@
 package authservice
 
 import (
+	"context"
+
 	"github.com/alikarimi999/shahboard/authservice/deliver/http"
 	"github.com/alikarimi999/shahboard/authservice/repository"
 	auth "github.com/alikarimi999/shahboard/authservice/service"
 	"github.com/alikarimi999/shahboard/event/kafka"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/mail"
 	"github.com/alikarimi999/shahboard/pkg/postgres"
+	"github.com/redis/go-redis/v9"
 )
 
 type application struct {
@@ -31,17 +35,46 @@ func SetupApplication(cfg Config) (*application, error) {
 	}
 
 	repo := repository.NewUserRepo(db)
+	tokens := repository.NewTokenRepo(db)
+
+	r := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
 
-	jwtGenerator, err := jwt.NewGenerator(cfg.JwtGenerator)
+	_, err = r.Ping(context.Background()).Result()
 	if err != nil {
 		return nil, err
 	}
 
 	l := log.NewLogger(cfg.Log.File, cfg.Log.Verbose)
 
-	svc := auth.NewAuthService(cfg.Auth, repo, jwtGenerator, p, l)
+	jwtGenerator, err := jwt.NewGenerator(cfg.JwtGenerator, l)
+	if err != nil {
+		return nil, err
+	}
+
+	ms, err := mail.NewSender(cfg.Mail, l)
+	if err != nil {
+		return nil, err
+	}
+
+	denylist := jwt.NewRedisDenylist(r)
+	svc, err := auth.NewAuthService(cfg.Auth, repo, tokens, jwtGenerator, denylist, r, ms, p, l)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.RotateTwoFactorSecrets(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if err := svc.SeedSanctions(context.Background()); err != nil {
+		return nil, err
+	}
 
-	handler, err := http.NewHandler(cfg.Http, svc)
+	handler, err := http.NewHandler(cfg.Http, svc, jwtGenerator.Validator().WithDenylist(denylist))
 	if err != nil {
 		return nil, err
 	}
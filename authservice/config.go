@@ -4,6 +4,7 @@ import (
 	auth "github.com/alikarimi999/shahboard/authservice/service"
 	"github.com/alikarimi999/shahboard/event/kafka"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/mail"
 	"github.com/alikarimi999/shahboard/pkg/postgres"
 	"github.com/alikarimi999/shahboard/pkg/router"
 )
@@ -14,6 +15,8 @@ type Config struct {
 	Log          LogConfig           `json:"log"`
 	JwtGenerator jwt.GeneratorConfig `json:"jwt_generator"`
 	PostgresDB   postgres.Config     `json:"postgres_db"`
+	Redis        RedisConfig         `json:"redis"`
+	Mail         mail.Config         `json:"mail"`
 	Http         router.Config       `json:"http"`
 }
 
@@ -21,3 +24,9 @@ type LogConfig struct {
 	File    string `json:"file"`
 	Verbose bool   `json:"verbose"`
 }
+
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
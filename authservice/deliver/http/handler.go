@@ -1,19 +1,25 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/alikarimi999/shahboard/authservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
 	"github.com/alikarimi999/shahboard/pkg/router"
+	"github.com/alikarimi999/shahboard/types"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
 	*router.Router
 	s *service.AuthService
+	v *jwt.Validator
 }
 
-func NewHandler(cfg router.Config, s *service.AuthService) (*Handler, error) {
+func NewHandler(cfg router.Config, s *service.AuthService, v *jwt.Validator) (*Handler, error) {
 	r, err := router.NewRouter(cfg)
 	if err != nil {
 		return nil, err
@@ -22,6 +28,7 @@ func NewHandler(cfg router.Config, s *service.AuthService) (*Handler, error) {
 	h := &Handler{
 		Router: r,
 		s:      s,
+		v:      v,
 	}
 	return h, h.setup()
 }
@@ -29,6 +36,40 @@ func NewHandler(cfg router.Config, s *service.AuthService) (*Handler, error) {
 func (h *Handler) setup() error {
 	h.Handle(http.MethodPost, "/", h.passwordLogin)
 	h.Handle(http.MethodGet, "/guest", h.guestLogin)
+	h.Handle(http.MethodPost, "/refresh", h.refresh)
+	h.Handle(http.MethodPost, "/logout", h.logout)
+	h.Handle(http.MethodGet, "/.well-known/jwks.json", h.jwks)
+
+	h.Handle(http.MethodPost, "/verify-email", h.verifyEmail)
+	h.Handle(http.MethodPost, "/verify-email/resend", h.resendVerification)
+	h.Handle(http.MethodPost, "/password-reset", h.requestPasswordReset)
+	h.Handle(http.MethodPost, "/password-reset/confirm", h.resetPassword)
+	h.Handle(http.MethodPost, "/2fa/login", h.twoFactorLogin)
+
+	twoFactor := h.Group("/2fa", middleware.ParsUserHeader(h.v))
+	{
+		twoFactor.POST("/setup", h.setupTwoFactor)
+		twoFactor.POST("/enable", h.enableTwoFactor)
+		twoFactor.POST("/disable", h.disableTwoFactor)
+		twoFactor.POST("/recovery-codes", h.regenerateRecoveryCodes)
+	}
+
+	// the guest signs up with the guest token, and the new account keeps the id of the guest
+	guest := h.Group("/guest/claim", middleware.ParsUserHeader(h.v))
+	{
+		guest.POST("", h.claimGuestWithPassword)
+		guest.POST("/google", h.claimGuestWithGoogle)
+	}
+
+	admin := h.Group("/admin", middleware.ParsUserHeader(h.v), middleware.RequireRole(types.RoleModerator))
+	{
+		admin.PUT("/users/:id/role", middleware.RequireRole(types.RoleAdmin), h.setRole)
+		admin.POST("/users/:id/sanctions", h.applySanction)
+		admin.GET("/users/:id/sanctions", h.getUserSanctions)
+		admin.GET("/users/:id/audit", h.getAuditLog)
+		admin.DELETE("/sanctions/:id", h.liftSanction)
+	}
+
 	auth := h.Group("/oauth")
 	{
 		auth.POST("/google", h.googleLogin)
@@ -40,13 +81,13 @@ func (h *Handler) setup() error {
 func (h *Handler) googleLogin(c *gin.Context) {
 	var req service.GoogleAuthRequest
 	if err := c.BindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 		return
 	}
 
 	res, err := h.s.GoogleAuth(c, req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
@@ -56,13 +97,14 @@ func (h *Handler) googleLogin(c *gin.Context) {
 func (h *Handler) passwordLogin(c *gin.Context) {
 	var req service.PasswordAuthRequest
 	if err := c.BindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 		return
 	}
 
+	req.IP = c.ClientIP()
 	res, err := h.s.PasswordAuth(c, req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
 		return
 	}
 
@@ -72,9 +114,389 @@ func (h *Handler) passwordLogin(c *gin.Context) {
 func (h *Handler) guestLogin(c *gin.Context) {
 	res, err := h.s.GuestLogin(c)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) claimGuestWithPassword(c *gin.Context) {
+	guest, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var req service.PasswordAuthRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.ClaimGuestWithPassword(c, guest, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) claimGuestWithGoogle(c *gin.Context) {
+	guest, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var req service.GoogleAuthRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.ClaimGuestWithGoogle(c, guest, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) refresh(c *gin.Context) {
+	var req service.RefreshRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.Refresh(c, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) logout(c *gin.Context) {
+	var req service.LogoutRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.Logout(c, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, h.s.JWKS())
+}
+
+func (h *Handler) verifyEmail(c *gin.Context) {
+	var req service.VerifyEmailRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.VerifyEmail(c, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) resendVerification(c *gin.Context) {
+	var req service.EmailRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.ResendVerification(c, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) requestPasswordReset(c *gin.Context) {
+	var req service.EmailRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.RequestPasswordReset(c, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) resetPassword(c *gin.Context) {
+	var req service.ResetPasswordRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.ResetPassword(c, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) twoFactorLogin(c *gin.Context) {
+	var req service.TwoFactorLoginRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.TwoFactorLogin(c, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) setupTwoFactor(c *gin.Context) {
+	user, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	res, err := h.s.SetupTwoFactor(c, user)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) enableTwoFactor(c *gin.Context) {
+	user, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var req service.TwoFactorCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.EnableTwoFactor(c, user, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
 		return
 	}
 
 	c.JSON(200, res)
 }
+
+func (h *Handler) disableTwoFactor(c *gin.Context) {
+	user, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var req service.TwoFactorCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.DisableTwoFactor(c, user, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) regenerateRecoveryCodes(c *gin.Context) {
+	user, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var req service.TwoFactorCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.RegenerateRecoveryCodes(c, user, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) setRole(c *gin.Context) {
+	admin, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	userId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid user id"))
+		return
+	}
+
+	var req service.SetRoleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.SetRole(c, admin, userId, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) applySanction(c *gin.Context) {
+	moderator, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	userId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid user id"))
+		return
+	}
+
+	var req service.ApplySanctionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.s.ApplySanction(c, moderator, userId, req)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(201, res)
+}
+
+func (h *Handler) liftSanction(c *gin.Context) {
+	moderator, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	sanctionId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid sanction id"))
+		return
+	}
+
+	var req service.LiftSanctionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := h.s.LiftSanction(c, moderator, sanctionId, req); err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.Status(204)
+}
+
+func (h *Handler) getUserSanctions(c *gin.Context) {
+	userId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid user id"))
+		return
+	}
+
+	res, err := h.s.GetUserSanctions(c, userId)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) getAuditLog(c *gin.Context) {
+	userId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid user id"))
+		return
+	}
+
+	res, err := h.s.GetAuditLog(c, userId)
+	if err != nil {
+		c.JSON(errs.HTTP(errCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func errCode(err error) string {
+	switch {
+	case errors.Is(err, service.ErrInvalidRefreshToken), errors.Is(err, service.ErrRefreshTokenReused),
+		errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidChallenge),
+		errors.Is(err, service.ErrInvalidTwoFactorCode):
+		return errs.CodeUnauthorized
+	case errors.Is(err, service.ErrEmailNotVerified), errors.Is(err, service.ErrNotGuest),
+		errors.Is(err, service.ErrTwoFactorUnavailable), errors.Is(err, service.ErrUserBanned),
+		errors.Is(err, service.ErrUserSuspended), errors.Is(err, service.ErrNotPermitted),
+		errors.Is(err, service.ErrCannotTargetSelf):
+		return errs.CodePermissionDenied
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrSanctionNotFound):
+		return errs.CodeNotFound
+	case errors.Is(err, service.ErrGuestClaimed), errors.Is(err, service.ErrEmailRegistered),
+		errors.Is(err, service.ErrUserExists),
+		errors.Is(err, service.ErrTwoFactorEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrTwoFactorNotSetup), errors.Is(err, service.ErrSanctionAlreadyLifted):
+		return errs.CodeConflict
+	case errors.Is(err, service.ErrTooManyAttempts):
+		return errs.CodeRateLimited
+	case errors.Is(err, service.ErrInvalidUserToken), errors.Is(err, service.ErrWeakPassword),
+		errors.Is(err, service.ErrPasswordIsEmail), errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrInvalidSanction), errors.Is(err, service.ErrReasonRequired),
+		errors.Is(err, service.ErrSuspensionDuration):
+		return errs.CodeInvalidInput
+	}
+	return errs.CodeInternalError
+}
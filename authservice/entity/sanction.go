@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// Sanction restricts the user until it expires or is lifted, zero ExpiresAt means it's permanent.
+type Sanction struct {
+	ID        types.ObjectId
+	UserID    types.ObjectId
+	Type      types.SanctionType
+	Reason    string
+	IssuedBy  types.ObjectId
+	ExpiresAt time.Time
+	LiftedBy  types.ObjectId
+	LiftedAt  time.Time
+	CreatedAt time.Time
+}
+
+func NewSanction(userId types.ObjectId, t types.SanctionType, reason string, issuedBy types.ObjectId,
+	duration time.Duration) *Sanction {
+	now := time.Now()
+	s := &Sanction{
+		ID:        types.NewObjectId(),
+		UserID:    userId,
+		Type:      t,
+		Reason:    reason,
+		IssuedBy:  issuedBy,
+		CreatedAt: now,
+	}
+	if duration > 0 {
+		s.ExpiresAt = now.Add(duration)
+	}
+	return s
+}
+
+func (s *Sanction) IsActive() bool {
+	return s.LiftedAt.IsZero() && (s.ExpiresAt.IsZero() || time.Now().Before(s.ExpiresAt))
+}
+
+type AuditAction string
+
+const (
+	AuditActionApplied AuditAction = "applied"
+	AuditActionLifted  AuditAction = "lifted"
+)
+
+// AuditEntry records who applied or lifted a sanction.
+type AuditEntry struct {
+	ID         types.ObjectId
+	SanctionID types.ObjectId
+	UserID     types.ObjectId
+	ActorID    types.ObjectId
+	Action     AuditAction
+	Reason     string
+	CreatedAt  time.Time
+}
+
+func NewAuditEntry(s *Sanction, actor types.ObjectId, action AuditAction, reason string) *AuditEntry {
+	return &AuditEntry{
+		ID:         types.NewObjectId(),
+		SanctionID: s.ID,
+		UserID:     s.UserID,
+		ActorID:    actor,
+		Action:     action,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// RefreshToken is a single use token that is exchanged for a new access token and a new refresh token.
+// All tokens that are rotated from the same login are in the same family, the family id is the id
+// of the login session and it's the `sid` claim of the access tokens.
+type RefreshToken struct {
+	ID types.ObjectId
+	// sha256 of the token, the token itself is never stored
+	Hash     string
+	FamilyID types.ObjectId
+	UserID   types.ObjectId
+	Email    string
+	IsGuest  bool
+	Role     types.Role
+
+	// the access token that is issued together with this refresh token,
+	// it's revoked when the session is revoked.
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
+
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	RevokedAt time.Time
+	CreatedAt time.Time
+}
+
+// NewRefreshToken returns the entity and the token that must be sent to the client.
+func NewRefreshToken(familyId types.ObjectId, u types.User, expiration time.Duration) (*RefreshToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	t := time.Now()
+	return &RefreshToken{
+		ID:        types.NewObjectId(),
+		Hash:      HashRefreshToken(token),
+		FamilyID:  familyId,
+		UserID:    u.ID,
+		Email:     u.Email,
+		IsGuest:   u.IsGuest,
+		Role:      u.Role,
+		ExpiresAt: t.Add(expiration),
+		CreatedAt: t,
+	}, token, nil
+}
+
+func HashRefreshToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func (t *RefreshToken) User() types.User {
+	return types.User{
+		ID:      t.UserID,
+		Email:   t.Email,
+		IsGuest: t.IsGuest,
+		Role:    t.Role,
+	}
+}
+
+func (t *RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+type TokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   TokenPurpose = "verify_email"
+	TokenPurposeResetPassword TokenPurpose = "reset_password"
+)
+
+// UserToken is the record of a single use token that is sent to the user by email,
+// the token itself is signed and only contains the id of the record.
+type UserToken struct {
+	ID        types.ObjectId
+	UserID    types.ObjectId
+	Purpose   TokenPurpose
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewUserToken returns a token with a random id, so the links can't be guessed even if the token secret leaks.
+func NewUserToken(userId types.ObjectId, purpose TokenPurpose, expiration time.Duration) (*UserToken, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	t := time.Now()
+	return &UserToken{
+		ID:        types.ObjectId(hex.EncodeToString(b)),
+		UserID:    userId,
+		Purpose:   purpose,
+		ExpiresAt: t.Add(expiration),
+		CreatedAt: t,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// TwoFactor is the TOTP setting of the user, it's created on setup and
+// the login requires a code only after it's enabled by the first valid code.
+type TwoFactor struct {
+	UserID types.ObjectId
+	// encrypted TOTP secret
+	Secret  string
+	Enabled bool
+	// the time step of the last accepted code, older codes are rejected
+	LastUsedStep int64
+	CreatedAt    time.Time
+	EnabledAt    time.Time
+}
+
+func NewTwoFactor(userId types.ObjectId, secret string) *TwoFactor {
+	return &TwoFactor{
+		UserID:    userId,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+}
@@ -7,11 +7,16 @@ import (
 )
 
 type User struct {
-	ID        types.ObjectId
-	Email     string
-	Password  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID       types.ObjectId
+	Email    string
+	Password string
+	// accounts that are created by google are verified
+	EmailVerified bool
+	// the account has claimed a guest and the claim is completed after the email is verified
+	PendingGuestClaim bool
+	Role              types.Role
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 func NewUser(email, pass string) *User {
@@ -19,7 +24,17 @@ func NewUser(email, pass string) *User {
 		ID:        types.NewObjectId(),
 		Email:     email,
 		Password:  pass,
+		Role:      types.RoleUser,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 }
+
+// Identity returns the user that the tokens are issued for.
+func (u *User) Identity() types.User {
+	return types.User{
+		ID:    u.ID,
+		Email: u.Email,
+		Role:  u.Role,
+	}
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const sanctionColumns = `id, user_id, type, reason, issued_by, expires_at, lifted_by, lifted_at, created_at`
+
+// CreateSanction creates the sanction and its audit entry.
+func (r *userRepo) CreateSanction(ctx context.Context, s *entity.Sanction, a *entity.AuditEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO sanctions (id, user_id, type, reason, issued_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.ExecContext(ctx, query, s.ID.String(), s.UserID.String(), s.Type.String(), s.Reason,
+		s.IssuedBy.String(), nullTime(s.ExpiresAt), s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert sanction: %w", err)
+	}
+
+	if err := createAuditEntry(ctx, tx, a); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// LiftSanction lifts the sanction and creates the audit entry, it returns false if the sanction is already lifted.
+func (r *userRepo) LiftSanction(ctx context.Context, s *entity.Sanction, a *entity.AuditEntry) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE sanctions SET lifted_by = $1, lifted_at = $2 WHERE id = $3 AND lifted_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, s.LiftedBy.String(), s.LiftedAt, s.ID.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to lift sanction %s: %w", s.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := createAuditEntry(ctx, tx, a); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *userRepo) GetSanction(ctx context.Context, id types.ObjectId) (*entity.Sanction, error) {
+	query := "SELECT " + sanctionColumns + " FROM sanctions WHERE id = $1"
+	s, err := scanSanction(r.db.QueryRowContext(ctx, query, id.String()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetSanctions returns all sanctions of the user, newest first.
+func (r *userRepo) GetSanctions(ctx context.Context, userId types.ObjectId) ([]*entity.Sanction, error) {
+	query := "SELECT " + sanctionColumns + " FROM sanctions WHERE user_id = $1 ORDER BY created_at DESC"
+	return r.getSanctions(ctx, query, userId.String())
+}
+
+// GetActiveSanctions returns the sanctions of the user that are not lifted or expired at the time.
+func (r *userRepo) GetActiveSanctions(ctx context.Context, userId types.ObjectId, t time.Time) ([]*entity.Sanction, error) {
+	query := "SELECT " + sanctionColumns + ` FROM sanctions
+		WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY created_at DESC`
+	return r.getSanctions(ctx, query, userId.String(), t)
+}
+
+func (r *userRepo) GetUnexpiredSanctions(ctx context.Context, t time.Time) ([]*entity.Sanction, error) {
+	query := "SELECT " + sanctionColumns + " FROM sanctions WHERE expires_at IS NULL OR expires_at > $1"
+	return r.getSanctions(ctx, query, t)
+}
+
+func (r *userRepo) getSanctions(ctx context.Context, query string, args ...interface{}) ([]*entity.Sanction, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sanctions: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*entity.Sanction{}
+	for rows.Next() {
+		s, err := scanSanction(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// GetAuditLog returns the audit entries of the user's sanctions, newest first.
+func (r *userRepo) GetAuditLog(ctx context.Context, userId types.ObjectId) ([]*entity.AuditEntry, error) {
+	query := `SELECT id, sanction_id, user_id, actor_id, action, reason, created_at
+		FROM sanction_audit_log WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userId.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*entity.AuditEntry{}
+	for rows.Next() {
+		var a entity.AuditEntry
+		if err := rows.Scan(&a.ID, &a.SanctionID, &a.UserID, &a.ActorID, &a.Action, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &a)
+	}
+	return list, rows.Err()
+}
+
+func createAuditEntry(ctx context.Context, db execer, a *entity.AuditEntry) error {
+	query := `INSERT INTO sanction_audit_log (id, sanction_id, user_id, actor_id, action, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := db.ExecContext(ctx, query, a.ID.String(), a.SanctionID.String(), a.UserID.String(),
+		a.ActorID.String(), string(a.Action), a.Reason, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+func scanSanction(row scanner) (*entity.Sanction, error) {
+	var (
+		s         entity.Sanction
+		expiresAt sql.NullTime
+		liftedBy  sql.NullString
+		liftedAt  sql.NullTime
+	)
+
+	err := row.Scan(&s.ID, &s.UserID, &s.Type, &s.Reason, &s.IssuedBy, &expiresAt, &liftedBy, &liftedAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ExpiresAt = expiresAt.Time
+	s.LiftedBy = types.ObjectId(liftedBy.String)
+	s.LiftedAt = liftedAt.Time
+	return &s, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
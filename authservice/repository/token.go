@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/authservice/service"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const refreshTokenColumns = `id, token_hash, family_id, user_id, email, is_guest, role, access_token_id,
+	access_token_expires_at, expires_at, used_at, revoked_at, created_at`
+
+type tokenRepo struct {
+	db *sql.DB
+}
+
+func NewTokenRepo(db *sql.DB) service.TokenRepository {
+	return &tokenRepo{
+		db: db,
+	}
+}
+
+func (r *tokenRepo) Create(ctx context.Context, t *entity.RefreshToken) error {
+	return createRefreshToken(ctx, r.db, t)
+}
+
+func (r *tokenRepo) GetByHash(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	query := "SELECT " + refreshTokenColumns + " FROM refresh_tokens WHERE token_hash = $1"
+	t, err := scanRefreshToken(r.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// Rotate marks the old token as used and creates the new one, it returns false if the old token
+// is already used or revoked (e.g. it's used by two concurrent requests).
+func (r *tokenRepo) Rotate(ctx context.Context, old, new *entity.RefreshToken) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE refresh_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL AND revoked_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, old.UsedAt, old.ID.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token %s as used: %w", old.ID, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := createRefreshToken(ctx, tx, new); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// RevokeFamily revokes all tokens of the family that are not revoked yet and returns them.
+func (r *tokenRepo) RevokeFamily(ctx context.Context, familyId types.ObjectId, t time.Time) ([]*entity.RefreshToken, error) {
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL RETURNING " +
+		refreshTokenColumns
+	return r.revoke(ctx, query, t, familyId.String())
+}
+
+func (r *tokenRepo) revoke(ctx context.Context, query string, args ...interface{}) ([]*entity.RefreshToken, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*entity.RefreshToken{}
+	for rows.Next() {
+		t, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+// RevokeUser revokes all sessions of the user and returns the revoked tokens.
+func (r *tokenRepo) RevokeUser(ctx context.Context, userId types.ObjectId, t time.Time) ([]*entity.RefreshToken, error) {
+	query := "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL RETURNING " +
+		refreshTokenColumns
+	return r.revoke(ctx, query, t, userId.String())
+}
+
+// CreateUserToken creates the token and invalidates the previous unused tokens of the user for the same purpose.
+func (r *tokenRepo) CreateUserToken(ctx context.Context, t *entity.UserToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE user_tokens SET used_at = $1 WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, t.CreatedAt, t.UserID.String(), string(t.Purpose)); err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %w", err)
+	}
+
+	query = `INSERT INTO user_tokens (id, user_id, purpose, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.ExecContext(ctx, query, t.ID.String(), t.UserID.String(), string(t.Purpose), t.ExpiresAt, t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert user token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// IsGuest returns true if the user has a guest session, guests have no account but their sessions are stored.
+func (r *tokenRepo) IsGuest(ctx context.Context, userId types.ObjectId) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE user_id = $1 AND is_guest)"
+	var ok bool
+	if err := r.db.QueryRowContext(ctx, query, userId.String()).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check guest %s: %w", userId, err)
+	}
+	return ok, nil
+}
+
+// UseUserToken marks the token as used and returns the user id of it,
+// it returns an empty id if the token is not found, already used or expired.
+func (r *tokenRepo) UseUserToken(ctx context.Context, id types.ObjectId, purpose entity.TokenPurpose, t time.Time) (types.ObjectId, error) {
+	query := `UPDATE user_tokens SET used_at = $1
+		WHERE id = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1 RETURNING user_id`
+	var userId types.ObjectId
+	err := r.db.QueryRowContext(ctx, query, t, id.String(), string(purpose)).Scan(&userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return types.ObjectZero, nil
+		}
+		return types.ObjectZero, fmt.Errorf("failed to use user token %s: %w", id, err)
+	}
+	return userId, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func createRefreshToken(ctx context.Context, db execer, t *entity.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, token_hash, family_id, user_id, email, is_guest, role, access_token_id,
+		access_token_expires_at, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := db.ExecContext(ctx, query, t.ID.String(), t.Hash, t.FamilyID.String(), t.UserID.String(), t.Email,
+		t.IsGuest, roleOrDefault(t.Role), t.AccessTokenID, t.AccessTokenExpiresAt, t.ExpiresAt, t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRefreshToken(row scanner) (*entity.RefreshToken, error) {
+	var (
+		t         entity.RefreshToken
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+
+	err := row.Scan(&t.ID, &t.Hash, &t.FamilyID, &t.UserID, &t.Email, &t.IsGuest, &t.Role, &t.AccessTokenID,
+		&t.AccessTokenExpiresAt, &t.ExpiresAt, &usedAt, &revokedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	t.UsedAt = usedAt.Time
+	t.RevokedAt = revokedAt.Time
+
+	return &t, nil
+}
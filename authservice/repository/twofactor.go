@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+func (r *userRepo) GetTwoFactor(ctx context.Context, userId types.ObjectId) (*entity.TwoFactor, error) {
+	query := `SELECT user_id, secret, enabled, last_used_step, created_at, enabled_at FROM two_factor WHERE user_id = $1`
+	var (
+		tf        entity.TwoFactor
+		enabledAt sql.NullTime
+	)
+	err := r.db.QueryRowContext(ctx, query, userId.String()).Scan(&tf.UserID, &tf.Secret, &tf.Enabled,
+		&tf.LastUsedStep, &tf.CreatedAt, &enabledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tf.EnabledAt = enabledAt.Time
+	return &tf, nil
+}
+
+// SaveTwoFactor creates the setting or replaces the secret of a setting that is not enabled yet.
+func (r *userRepo) SaveTwoFactor(ctx context.Context, tf *entity.TwoFactor) error {
+	query := `INSERT INTO two_factor (user_id, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+		WHERE two_factor.enabled = FALSE`
+	_, err := r.db.ExecContext(ctx, query, tf.UserID.String(), tf.Secret, tf.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save two factor of user %s: %w", tf.UserID, err)
+	}
+	return nil
+}
+
+func (r *userRepo) GetTwoFactorSecrets(ctx context.Context) (map[types.ObjectId]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id, secret FROM two_factor")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	secrets := make(map[types.ObjectId]string)
+	for rows.Next() {
+		var (
+			userId types.ObjectId
+			secret string
+		)
+		if err := rows.Scan(&userId, &secret); err != nil {
+			return nil, err
+		}
+		secrets[userId] = secret
+	}
+	return secrets, rows.Err()
+}
+
+func (r *userRepo) ReplaceTwoFactorSecret(ctx context.Context, userId types.ObjectId, old, new string) (bool, error) {
+	query := "UPDATE two_factor SET secret = $1 WHERE user_id = $2 AND secret = $3"
+	res, err := r.db.ExecContext(ctx, query, new, userId.String(), old)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace two factor secret of user %s: %w", userId, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EnableTwoFactor enables the setting and replaces the recovery codes of the user.
+func (r *userRepo) EnableTwoFactor(ctx context.Context, userId types.ObjectId, step int64, codes []string, t time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE two_factor SET enabled = TRUE, enabled_at = $1, last_used_step = $2 WHERE user_id = $3`
+	if _, err := tx.ExecContext(ctx, query, t, step, userId.String()); err != nil {
+		return fmt.Errorf("failed to enable two factor of user %s: %w", userId, err)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, codes, t); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepo) DisableTwoFactor(ctx context.Context, userId types.ObjectId) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userId.String()); err != nil {
+		return fmt.Errorf("failed to delete recovery codes of user %s: %w", userId, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM two_factor WHERE user_id = $1`, userId.String()); err != nil {
+		return fmt.Errorf("failed to delete two factor of user %s: %w", userId, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UseTwoFactorStep returns false if a code of the step or a later one is already used.
+func (r *userRepo) UseTwoFactorStep(ctx context.Context, userId types.ObjectId, step int64) (bool, error) {
+	query := `UPDATE two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+	res, err := r.db.ExecContext(ctx, query, step, userId.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to use two factor step of user %s: %w", userId, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *userRepo) ReplaceRecoveryCodes(ctx context.Context, userId types.ObjectId, codes []string, t time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, codes, t); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the code as used, it returns false if the code is not found or already used.
+func (r *userRepo) UseRecoveryCode(ctx context.Context, userId types.ObjectId, hash string, t time.Time) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, t, userId.String(), hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code of user %s: %w", userId, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, db execer, userId types.ObjectId, codes []string, t time.Time) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userId.String()); err != nil {
+		return fmt.Errorf("failed to delete recovery codes of user %s: %w", userId, err)
+	}
+
+	query := `INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)`
+	for _, c := range codes {
+		if _, err := db.ExecContext(ctx, query, userId.String(), c, t); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+	return nil
+}
@@ -3,11 +3,18 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/alikarimi999/shahboard/authservice/entity"
 	"github.com/alikarimi999/shahboard/authservice/service"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/lib/pq"
 )
 
+// uniqueViolation is the postgres error code of a duplicate key.
+const uniqueViolation = "23505"
+
 type userRepo struct {
 	db *sql.DB
 }
@@ -19,22 +26,74 @@ func NewUserRepo(db *sql.DB) service.Repository {
 }
 
 func (r *userRepo) Create(ctx context.Context, u *entity.User) error {
-	query := `INSERT INTO users (id, email, password, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.ExecContext(ctx, query, u.ID.String(), u.Email, u.Password, u.CreatedAt, u.UpdatedAt)
+	query := `INSERT INTO users (id, email, password, email_verified, pending_guest_claim, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query, u.ID.String(), u.Email, u.Password, u.EmailVerified, u.PendingGuestClaim,
+		roleOrDefault(u.Role), u.CreatedAt, u.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		if pqErr.Constraint == "users_email_key" {
+			return service.ErrEmailRegistered
+		}
+		return service.ErrUserExists
+	}
 	return err
 }
 
 func (r *userRepo) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	query := "SELECT id, email, password, created_at, updated_at FROM users WHERE email = $1"
-	row := r.db.QueryRowContext(ctx, query, email)
-	var u entity.User
-	err := row.Scan(&u.ID, &u.Email, &u.Password, &u.CreatedAt, &u.UpdatedAt)
+	query := "SELECT id, email, password, email_verified, pending_guest_claim, role, created_at, updated_at FROM users WHERE email = $1"
+	return r.getUser(ctx, query, email)
+}
+
+func (r *userRepo) GetByID(ctx context.Context, id types.ObjectId) (*entity.User, error) {
+	query := "SELECT id, email, password, email_verified, pending_guest_claim, role, created_at, updated_at FROM users WHERE id = $1"
+	return r.getUser(ctx, query, id.String())
+}
+
+func (r *userRepo) getUser(ctx context.Context, query string, args ...interface{}) (*entity.User, error) {
+	row := r.db.QueryRowContext(ctx, query, args...)
+	var (
+		u        entity.User
+		password sql.NullString
+	)
+	err := row.Scan(&u.ID, &u.Email, &password, &u.EmailVerified, &u.PendingGuestClaim, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	u.Password = password.String
 	return &u, nil
+}
+
+func (r *userRepo) UpdatePassword(ctx context.Context, id types.ObjectId, password string) error {
+	query := "UPDATE users SET password = $1, updated_at = $2 WHERE id = $3"
+	_, err := r.db.ExecContext(ctx, query, password, time.Now(), id.String())
+	return err
+}
 
+func (r *userRepo) SetEmailVerified(ctx context.Context, id types.ObjectId) error {
+	query := "UPDATE users SET email_verified = TRUE, updated_at = $1 WHERE id = $2"
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id.String())
+	return err
+}
+
+func (r *userRepo) CompleteGuestClaim(ctx context.Context, id types.ObjectId) error {
+	query := "UPDATE users SET pending_guest_claim = FALSE, updated_at = $1 WHERE id = $2"
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id.String())
+	return err
+}
+
+func (r *userRepo) SetRole(ctx context.Context, id types.ObjectId, role types.Role) error {
+	query := "UPDATE users SET role = $1, updated_at = $2 WHERE id = $3"
+	_, err := r.db.ExecContext(ctx, query, role.String(), time.Now(), id.String())
+	return err
+}
+
+func roleOrDefault(role types.Role) string {
+	if role == "" {
+		return types.RoleUser.String()
+	}
+	return role.String()
 }
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUserExists       = errors.New("user already exists")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrNotPermitted     = errors.New("not permitted to manage this user")
+	ErrCannotTargetSelf = errors.New("can't apply this action to yourself")
+)
+
+// SetRole changes the role of the user and revokes their sessions, since the tokens carry the old role.
+func (s *AuthService) SetRole(ctx context.Context, admin types.User, userId types.ObjectId, req SetRoleRequest) error {
+	role, ok := types.ParseRole(req.Role)
+	if !ok {
+		return ErrInvalidRole
+	}
+
+	user, err := s.getTargetUser(ctx, admin, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == role {
+		return nil
+	}
+
+	if err := s.repo.SetRole(ctx, user.ID, role); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if err := s.revokeUser(ctx, user.ID, user.Email); err != nil {
+		return err
+	}
+
+	s.l.Info(fmt.Sprintf("role of user '%s' changed from '%s' to '%s' by '%s'", user.ID, user.Role, role, admin.ID))
+	return nil
+}
+
+// getTargetUser returns the user that the actor manages, the actor's role must be higher than the user's role.
+func (s *AuthService) getTargetUser(ctx context.Context, actor types.User, userId types.ObjectId) (*entity.User, error) {
+	if actor.ID == userId {
+		return nil, ErrCannotTargetSelf
+	}
+
+	user, err := s.repo.GetByID(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.Role.Includes(actor.Role) {
+		return nil, ErrNotPermitted
+	}
+
+	return user, nil
+}
+
+// promoteAdmin gives the admin role to the accounts of the configured emails, it's how the first admins are created.
+func (s *AuthService) promoteAdmin(ctx context.Context, user *entity.User) {
+	if user.Role == types.RoleAdmin {
+		return
+	}
+
+	for _, e := range s.cfg.AdminEmails {
+		if strings.EqualFold(e, user.Email) {
+			if err := s.repo.SetRole(ctx, user.ID, types.RoleAdmin); err != nil {
+				s.l.Error(err.Error())
+				return
+			}
+			user.Role = types.RoleAdmin
+			s.l.Info(fmt.Sprintf("user '%s' promoted to admin", user.ID))
+			return
+		}
+	}
+}
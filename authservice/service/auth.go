@@ -13,52 +13,192 @@ import (
 	"github.com/alikarimi999/shahboard/event"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/mail"
 	"github.com/alikarimi999/shahboard/types"
 	pjwt "github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/api/idtoken"
 )
 
 type Repository interface {
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+	// return nil if not found
+	GetByID(ctx context.Context, id types.ObjectId) (*entity.User, error)
+	// returns ErrEmailRegistered or ErrUserExists if the email or the id is taken
 	Create(context.Context, *entity.User) error
+	UpdatePassword(ctx context.Context, id types.ObjectId, password string) error
+	SetEmailVerified(ctx context.Context, id types.ObjectId) error
+	CompleteGuestClaim(ctx context.Context, id types.ObjectId) error
+	SetRole(ctx context.Context, id types.ObjectId, role types.Role) error
+
+	// creates the sanction with its audit entry
+	CreateSanction(context.Context, *entity.Sanction, *entity.AuditEntry) error
+	// returns false if the sanction is already lifted
+	LiftSanction(context.Context, *entity.Sanction, *entity.AuditEntry) (bool, error)
+	// return nil if not found
+	GetSanction(ctx context.Context, id types.ObjectId) (*entity.Sanction, error)
+	GetSanctions(ctx context.Context, userId types.ObjectId) ([]*entity.Sanction, error)
+	GetActiveSanctions(ctx context.Context, userId types.ObjectId, t time.Time) ([]*entity.Sanction, error)
+	// returns the sanctions of all users that are not expired at the time, including the lifted ones
+	GetUnexpiredSanctions(ctx context.Context, t time.Time) ([]*entity.Sanction, error)
+	GetAuditLog(ctx context.Context, userId types.ObjectId) ([]*entity.AuditEntry, error)
+
+	// return nil if not found
+	GetTwoFactor(ctx context.Context, userId types.ObjectId) (*entity.TwoFactor, error)
+	// replaces the secret only if it's not enabled
+	SaveTwoFactor(context.Context, *entity.TwoFactor) error
+	// returns the encrypted secrets of all users by their ids
+	GetTwoFactorSecrets(ctx context.Context) (map[types.ObjectId]string, error)
+	// returns false if the secret isn't the old one anymore
+	ReplaceTwoFactorSecret(ctx context.Context, userId types.ObjectId, old, new string) (bool, error)
+	// codes are the hashes of the recovery codes
+	EnableTwoFactor(ctx context.Context, userId types.ObjectId, step int64, codes []string, t time.Time) error
+	DisableTwoFactor(ctx context.Context, userId types.ObjectId) error
+	// returns false if a code of the step or a later step is already used
+	UseTwoFactorStep(ctx context.Context, userId types.ObjectId, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId types.ObjectId, codes []string, t time.Time) error
+	// returns false if the code is not found or already used
+	UseRecoveryCode(ctx context.Context, userId types.ObjectId, hash string, t time.Time) (bool, error)
 }
 
+type TokenRepository interface {
+	Create(context.Context, *entity.RefreshToken) error
+	// return nil if not found
+	GetByHash(ctx context.Context, hash string) (*entity.RefreshToken, error)
+	// returns false if the old token is already used or revoked
+	Rotate(ctx context.Context, old, new *entity.RefreshToken) (bool, error)
+	// returns the tokens that are revoked by this call
+	RevokeFamily(ctx context.Context, familyId types.ObjectId, t time.Time) ([]*entity.RefreshToken, error)
+	// returns the tokens that are revoked by this call
+	RevokeUser(ctx context.Context, userId types.ObjectId, t time.Time) ([]*entity.RefreshToken, error)
+
+	CreateUserToken(context.Context, *entity.UserToken) error
+	// returns an empty id if the token is not valid anymore
+	UseUserToken(ctx context.Context, id types.ObjectId, purpose entity.TokenPurpose, t time.Time) (types.ObjectId, error)
+
+	// returns true if the guest has a session
+	IsGuest(ctx context.Context, userId types.ObjectId) (bool, error)
+}
+
+const (
+	defaultRefreshTokenExpiration  = 30 * 24 * time.Hour
+	defaultVerificationExpiration  = 24 * time.Hour
+	defaultPasswordResetExpiration = time.Hour
+)
+
 type Config struct {
 	GoogleClientID string `json:"google_client_id"`
 
 	// this is just because, google cert endpoint has blocked iran ip (should implement a better solution)
 	VerifyPubKey bool `json:"verify_pub_key"`
+
+	// default is 30 days
+	RefreshTokenExpiration uint `json:"refresh_token_expiration_in_seconds"`
+
+	// TokenSecret signs the email verification and password reset tokens, it's used only for development,
+	// the secret is read from the AUTH_TOKEN_SECRET env or from the TokenSecretFile otherwise
+	TokenSecret string `json:"token_secret"`
+	// file of the secret, e.g. a docker secret
+	TokenSecretFile string `json:"token_secret_file"`
+	// AppUrl is the url of the frontend, the links in the emails point to it.
+	AppUrl string `json:"app_url"`
+	// default is 24 hours
+	VerificationExpiration uint `json:"verification_expiration_in_seconds"`
+	// default is 1 hour
+	PasswordResetExpiration uint `json:"password_reset_expiration_in_seconds"`
+
+	Throttle  ThrottleConfig  `json:"throttle"`
+	TwoFactor TwoFactorConfig `json:"two_factor"`
+
+	// accounts of these emails are promoted to admin when they log in
+	AdminEmails []string `json:"admin_emails"`
 }
 
 type AuthService struct {
 	cfg          Config
 	repo         Repository
+	tokens       TokenRepository
 	jwtGenerator *jwt.Generator
-	pub          event.Publisher
-	l            log.Logger
+	denylist     jwt.Denylist
+	throttle     *throttler
+	mail         mail.Sender
+	secrets      *secretBox
+	rc           *redis.Client
+
+	refreshExpiration       time.Duration
+	verificationExpiration  time.Duration
+	passwordResetExpiration time.Duration
+	challengeExpiration     time.Duration
+	twoFactorIssuer         string
+
+	pub event.Publisher
+	l   log.Logger
 }
 
-func NewAuthService(cfg Config, repo Repository, jwtGenerator *jwt.Generator, pub event.Publisher, l log.Logger) *AuthService {
-	return &AuthService{
-		cfg:          cfg,
-		repo:         repo,
-		jwtGenerator: jwtGenerator,
-		pub:          pub,
-		l:            l,
+func NewAuthService(cfg Config, repo Repository, tokens TokenRepository, jwtGenerator *jwt.Generator,
+	denylist jwt.Denylist, r *redis.Client, ms mail.Sender, pub event.Publisher, l log.Logger) (*AuthService, error) {
+	secret, err := cfg.tokenSecret()
+	if err != nil {
+		return nil, err
 	}
-}
+	cfg.TokenSecret = secret
 
-func (s *AuthService) GoogleAuth(ctx context.Context, req GoogleAuthRequest) (GoogleAuthResponse, error) {
+	key, previous, err := cfg.TwoFactor.encryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := newSecretBox(key, previous...)
+	if err != nil {
+		return nil, err
+	}
 
-	var token *tokenInfo
-	var err error
+	s := &AuthService{
+		cfg:                     cfg,
+		repo:                    repo,
+		tokens:                  tokens,
+		jwtGenerator:            jwtGenerator,
+		denylist:                denylist,
+		throttle:                newThrottler(r, cfg.Throttle),
+		mail:                    ms,
+		secrets:                 secrets,
+		rc:                      r,
+		refreshExpiration:       defaultRefreshTokenExpiration,
+		verificationExpiration:  defaultVerificationExpiration,
+		passwordResetExpiration: defaultPasswordResetExpiration,
+		challengeExpiration:     defaultChallengeExpiration,
+		twoFactorIssuer:         defaultTwoFactorIssuer,
+		pub:                     pub,
+		l:                       l,
+	}
 
-	if s.cfg.VerifyPubKey {
-		token, err = s.validateGoogleJWT(req.Token)
-	} else {
-		token, err = parseGoogleJWT(req.Token, s.cfg.GoogleClientID)
+	if cfg.RefreshTokenExpiration > 0 {
+		s.refreshExpiration = time.Duration(cfg.RefreshTokenExpiration) * time.Second
+	}
+	if cfg.VerificationExpiration > 0 {
+		s.verificationExpiration = time.Duration(cfg.VerificationExpiration) * time.Second
 	}
+	if cfg.PasswordResetExpiration > 0 {
+		s.passwordResetExpiration = time.Duration(cfg.PasswordResetExpiration) * time.Second
+	}
+	if cfg.TwoFactor.ChallengeExpiration > 0 {
+		s.challengeExpiration = time.Duration(cfg.TwoFactor.ChallengeExpiration) * time.Second
+	}
+	if cfg.TwoFactor.Issuer != "" {
+		s.twoFactorIssuer = cfg.TwoFactor.Issuer
+	}
+
+	return s, nil
+}
+
+// JWKS returns the public keys that the access tokens are signed with.
+func (s *AuthService) JWKS() jwt.JWKS {
+	return s.jwtGenerator.JWKS()
+}
+
+func (s *AuthService) GoogleAuth(ctx context.Context, req GoogleAuthRequest) (GoogleAuthResponse, error) {
+
+	token, err := s.googleToken(req.Token)
 	if err != nil {
 		return GoogleAuthResponse{}, err
 	}
@@ -72,6 +212,8 @@ func (s *AuthService) GoogleAuth(ctx context.Context, req GoogleAuthRequest) (Go
 	exists := user != nil
 	if !exists {
 		user = entity.NewUser(token.Email, "")
+		// google has verified the email
+		user.EmailVerified = true
 		if err := s.repo.Create(ctx, user); err != nil {
 			s.l.Error(err.Error())
 			return GoogleAuthResponse{}, err
@@ -89,26 +231,69 @@ func (s *AuthService) GoogleAuth(ctx context.Context, req GoogleAuthRequest) (Go
 			s.l.Error(err.Error())
 		}
 	} else {
+		if err := s.checkAccount(ctx, user.ID); err != nil {
+			return GoogleAuthResponse{}, err
+		}
+
+		if !user.EmailVerified {
+			if err := s.repo.SetEmailVerified(ctx, user.ID); err != nil {
+				s.l.Error(err.Error())
+				return GoogleAuthResponse{}, err
+			}
+		}
+
+		if err := s.completePendingClaim(ctx, user, token.Name, token.Picture); err != nil {
+			return GoogleAuthResponse{}, err
+		}
 		s.l.Debug(fmt.Sprintf("user logged in: %s", user.Email))
+
+		challenge, err := s.twoFactorChallenge(ctx, user, challengeMethodGoogle)
+		if err != nil {
+			return GoogleAuthResponse{}, err
+		}
+		if challenge != "" {
+			return GoogleAuthResponse{
+				Id:                user.ID.String(),
+				Email:             user.Email,
+				Name:              token.Name,
+				Picture:           token.Picture,
+				Exists:            true,
+				TwoFactorRequired: true,
+				ChallengeToken:    challenge,
+			}, nil
+		}
+	}
+
+	access, refresh, err := s.login(ctx, user)
+	if err != nil {
+		return GoogleAuthResponse{}, err
 	}
 
 	return GoogleAuthResponse{
-		Id:       user.ID.String(),
-		Email:    user.Email,
-		Name:     token.Name,
-		Picture:  token.Picture,
-		JwtToken: s.jwtGenerator.GenerateJWT(types.User{ID: user.ID, Email: user.Email}),
-		Exists:   exists,
+		Id:           user.ID.String(),
+		Email:        user.Email,
+		Name:         token.Name,
+		Picture:      token.Picture,
+		JwtToken:     access,
+		RefreshToken: refresh,
+		Exists:       exists,
 	}, nil
 
 }
 
 func (s *AuthService) GuestLogin(ctx context.Context) (GuestLoginResponse, error) {
 	id := types.NewObjectId()
-	email := fmt.Sprintf("guest_%s", id)
+	email := guestEmail(id)
+
+	access, refresh, err := s.issueTokens(ctx, types.User{ID: id, Email: email, IsGuest: true}, types.NewObjectId())
+	if err != nil {
+		return GuestLoginResponse{}, err
+	}
+
 	res := GuestLoginResponse{
-		Id:       id.String(),
-		JwtToken: s.jwtGenerator.GenerateJWT(types.User{ID: id, Email: email, IsGuest: true}),
+		Id:           id.String(),
+		JwtToken:     access,
+		RefreshToken: refresh,
 	}
 
 	if err := s.pub.Publish(event.EventUserCreated{
@@ -126,30 +311,29 @@ func (s *AuthService) GuestLogin(ctx context.Context) (GuestLoginResponse, error
 	return res, nil
 }
 
+// PasswordAuth logs in the user, or signs up if the email is not registered yet.
+// New accounts can't log in until the email is verified, the verification link is sent on sign up.
+// If two factor authentication is enabled, a challenge token is returned instead of the tokens.
 func (s *AuthService) PasswordAuth(ctx context.Context, req PasswordAuthRequest) (PasswordAuthResponse, error) {
 	if req.Email == "" || req.Password == "" {
 		return PasswordAuthResponse{}, errors.New("email and password are required")
 	}
 
-	user, err := s.repo.GetByEmail(ctx, req.Email)
-	if err != nil {
-		s.l.Error(err.Error())
+	if err := s.throttle.checkLogin(ctx, req.Email, req.IP); err != nil {
 		return PasswordAuthResponse{}, err
 	}
 
-	hPass, err := hashPassword(req.Password)
+	user, err := s.repo.GetByEmail(ctx, req.Email)
 	if err != nil {
+		s.l.Error(err.Error())
 		return PasswordAuthResponse{}, err
 	}
 
-	exists := user != nil
-	if !exists {
-		user = entity.NewUser(req.Email, hPass)
-		if err := s.repo.Create(ctx, user); err != nil {
-			s.l.Error(err.Error())
+	if user == nil {
+		user, err := s.createPasswordUser(ctx, types.NewObjectId(), req.Email, req.Password, false)
+		if err != nil {
 			return PasswordAuthResponse{}, err
 		}
-		s.l.Debug(fmt.Sprintf("user created: %s", user.Email))
 
 		if err := s.pub.Publish(event.EventUserCreated{
 			ID:        types.NewObjectId(),
@@ -159,22 +343,91 @@ func (s *AuthService) PasswordAuth(ctx context.Context, req PasswordAuthRequest)
 		}); err != nil {
 			s.l.Error(err.Error())
 		}
-	} else {
-		if !checkPassword(user.Password, req.Password) {
-			return PasswordAuthResponse{}, errors.New("invalid password")
+
+		if err := s.sendVerification(ctx, user); err != nil {
+			return PasswordAuthResponse{}, err
 		}
 
-		// s.l.Debug(fmt.Sprintf("user logged in: %s", user.Email))
+		return PasswordAuthResponse{
+			Id:    user.ID.String(),
+			Email: user.Email,
+		}, nil
+	}
+
+	if !checkPassword(user.Password, req.Password) {
+		s.throttle.loginFailed(ctx, req.Email, req.IP)
+		return PasswordAuthResponse{}, ErrInvalidCredentials
+	}
+
+	if err := s.checkAccount(ctx, user.ID); err != nil {
+		return PasswordAuthResponse{}, err
+	}
+
+	if !user.EmailVerified {
+		return PasswordAuthResponse{}, ErrEmailNotVerified
+	}
+
+	if err := s.completePendingClaim(ctx, user, "", ""); err != nil {
+		return PasswordAuthResponse{}, err
+	}
+
+	s.throttle.loginSucceeded(ctx, req.Email)
+	// s.l.Debug(fmt.Sprintf("user logged in: %s", user.Email))
+
+	challenge, err := s.twoFactorChallenge(ctx, user, challengeMethodPassword)
+	if err != nil {
+		return PasswordAuthResponse{}, err
+	}
+	if challenge != "" {
+		return PasswordAuthResponse{
+			Id:                user.ID.String(),
+			Email:             user.Email,
+			Exists:            true,
+			EmailVerified:     true,
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	access, refresh, err := s.login(ctx, user)
+	if err != nil {
+		return PasswordAuthResponse{}, err
 	}
 
 	return PasswordAuthResponse{
-		Id:       user.ID.String(),
-		Email:    user.Email,
-		JwtToken: s.jwtGenerator.GenerateJWT(types.User{ID: user.ID, Email: user.Email}),
-		Exists:   exists,
+		Id:            user.ID.String(),
+		Email:         user.Email,
+		JwtToken:      access,
+		RefreshToken:  refresh,
+		Exists:        true,
+		EmailVerified: true,
 	}, nil
 }
 
+// createPasswordUser creates an account that can't log in until the email is verified.
+func (s *AuthService) createPasswordUser(ctx context.Context, id types.ObjectId, email, password string,
+	guestClaim bool) (*entity.User, error) {
+	if err := validatePassword(email, password); err != nil {
+		return nil, err
+	}
+
+	hPass, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := entity.NewUser(email, hPass)
+	user.ID = id
+	user.PendingGuestClaim = guestClaim
+	if err := s.repo.Create(ctx, user); err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	s.l.Debug(fmt.Sprintf("user created: %s", user.Email))
+
+	return user, nil
+}
+
 func checkPassword(hashedPassword, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
@@ -188,6 +441,13 @@ func hashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
+func (s *AuthService) googleToken(token string) (*tokenInfo, error) {
+	if s.cfg.VerifyPubKey {
+		return s.validateGoogleJWT(token)
+	}
+	return parseGoogleJWT(token, s.cfg.GoogleClientID)
+}
+
 type tokenInfo struct {
 	Email         string `json:"email"`
 	EmailVerified bool   `json:"email_verified"`
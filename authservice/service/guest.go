@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	ErrNotGuest        = errors.New("only guest accounts can be claimed")
+	ErrGuestClaimed    = errors.New("guest account is already claimed")
+	ErrEmailRegistered = errors.New("email is already registered")
+)
+
+// ClaimGuestWithGoogle upgrades the guest account into a google account that keeps the id of the guest.
+// The guest sessions are revoked and the tokens of the new account are returned.
+// The account is created with a pending claim, if revoking the guest sessions or publishing the claim fails,
+// the claim stays pending and is completed on the next login of the account.
+func (s *AuthService) ClaimGuestWithGoogle(ctx context.Context, guest types.User, req GoogleAuthRequest) (GoogleAuthResponse, error) {
+	token, err := s.googleToken(req.Token)
+	if err != nil {
+		return GoogleAuthResponse{}, err
+	}
+
+	if err := s.checkClaim(ctx, guest, token.Email); err != nil {
+		return GoogleAuthResponse{}, err
+	}
+
+	user := entity.NewUser(token.Email, "")
+	user.ID = guest.ID
+	// google has verified the email
+	user.EmailVerified = true
+	user.PendingGuestClaim = true
+	if err := s.repo.Create(ctx, user); err != nil {
+		s.l.Error(err.Error())
+		return GoogleAuthResponse{}, claimErr(err)
+	}
+
+	if err := s.completePendingClaim(ctx, user, token.Name, token.Picture); err != nil {
+		return GoogleAuthResponse{}, err
+	}
+
+	access, refresh, err := s.login(ctx, user)
+	if err != nil {
+		return GoogleAuthResponse{}, err
+	}
+
+	return GoogleAuthResponse{
+		Id:           user.ID.String(),
+		Email:        user.Email,
+		Name:         token.Name,
+		Picture:      token.Picture,
+		JwtToken:     access,
+		RefreshToken: refresh,
+	}, nil
+}
+
+// ClaimGuestWithPassword upgrades the guest account into a password account that keeps the id of the guest.
+// Like the sign up, no tokens are returned and the new account can log in after the email is verified.
+// The claim is pending until then, so the guest sessions stay valid and
+// other services don't treat the guest as registered before the email is proved.
+func (s *AuthService) ClaimGuestWithPassword(ctx context.Context, guest types.User, req PasswordAuthRequest) (PasswordAuthResponse, error) {
+	if req.Email == "" || req.Password == "" {
+		return PasswordAuthResponse{}, errors.New("email and password are required")
+	}
+
+	if err := s.checkClaim(ctx, guest, req.Email); err != nil {
+		return PasswordAuthResponse{}, err
+	}
+
+	user, err := s.createPasswordUser(ctx, guest.ID, req.Email, req.Password, true)
+	if err != nil {
+		return PasswordAuthResponse{}, claimErr(err)
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		return PasswordAuthResponse{}, err
+	}
+
+	return PasswordAuthResponse{
+		Id:    user.ID.String(),
+		Email: user.Email,
+	}, nil
+}
+
+func (s *AuthService) checkClaim(ctx context.Context, guest types.User, email string) error {
+	if !guest.IsGuest {
+		return ErrNotGuest
+	}
+
+	user, err := s.repo.GetByID(ctx, guest.ID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if user != nil {
+		return ErrGuestClaimed
+	}
+
+	user, err = s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if user != nil {
+		return ErrEmailRegistered
+	}
+
+	return nil
+}
+
+// completePendingClaim completes the guest claim of the account if it's pending.
+// The claim is marked completed only after the guest sessions are revoked and the claim is published,
+// so a failed claim is completed again on the next login or email verification of the account.
+func (s *AuthService) completePendingClaim(ctx context.Context, user *entity.User, name, picture string) error {
+	if !user.PendingGuestClaim {
+		return nil
+	}
+
+	guest := types.User{ID: user.ID, Email: guestEmail(user.ID), IsGuest: true}
+	if err := s.completeClaim(ctx, guest, user, name, picture); err != nil {
+		return err
+	}
+
+	if err := s.repo.CompleteGuestClaim(ctx, user.ID); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	user.PendingGuestClaim = false
+
+	return nil
+}
+
+// claimErr maps the error of creating the account of the guest,
+// the id is taken if another claim of the guest has won the race.
+func claimErr(err error) error {
+	if errors.Is(err, ErrUserExists) {
+		return ErrGuestClaimed
+	}
+	return err
+}
+
+func guestEmail(id types.ObjectId) string {
+	return fmt.Sprintf("guest_%s", id)
+}
+
+// completeClaim revokes the guest sessions, since their tokens still carry the guest identity,
+// and notifies other services that the guest is a registered user now.
+func (s *AuthService) completeClaim(ctx context.Context, guest types.User, user *entity.User, name, picture string) error {
+	if err := s.revokeUser(ctx, guest.ID, guest.Email); err != nil {
+		return err
+	}
+
+	if err := s.pub.Publish(event.EventUserGuestClaimed{
+		ID:        types.NewObjectId(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Name:      name,
+		Picture:   picture,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("guest '%s' claimed by '%s'", guest.ID, user.Email))
+	return nil
+}
@@ -5,27 +5,131 @@ type GoogleAuthRequest struct {
 }
 
 type GoogleAuthResponse struct {
-	Id       string `json:"id"`
-	Email    string `json:"email"`
-	Name     string `json:"name"`
-	Picture  string `json:"picture"`
-	JwtToken string `json:"jwt_token"`
-	Exists   bool   `json:"exists"`
+	Id           string `json:"id"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	Picture      string `json:"picture"`
+	JwtToken     string `json:"jwt_token"`
+	RefreshToken string `json:"refresh_token"`
+	Exists       bool   `json:"exists"`
+	// the login must be completed by a two factor code and the challenge token
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type PasswordAuthRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// ip of the client, it's set by the handler for throttling
+	IP string `json:"-"`
 }
 
+// PasswordAuthResponse has no tokens on sign up, the email must be verified first.
 type PasswordAuthResponse struct {
-	Id       string `json:"id"`
-	Email    string `json:"email"`
-	JwtToken string `json:"jwt_token"`
-	Exists   bool   `json:"exists"`
+	Id            string `json:"id"`
+	Email         string `json:"email"`
+	JwtToken      string `json:"jwt_token"`
+	RefreshToken  string `json:"refresh_token"`
+	Exists        bool   `json:"exists"`
+	EmailVerified bool   `json:"email_verified"`
+	// the login must be completed by a two factor code and the challenge token
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type GuestLoginResponse struct {
-	Id       string `json:"id"`
-	JwtToken string `json:"jwt_token"`
+	Id           string `json:"id"`
+	JwtToken     string `json:"jwt_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshResponse struct {
+	JwtToken     string `json:"jwt_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// otpauth uri to show as a QR code
+	Uri string `json:"uri"`
+}
+
+// TwoFactorCodeRequest has a code of the authenticator app or a recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TwoFactorLoginResponse struct {
+	Id           string `json:"id"`
+	Email        string `json:"email"`
+	JwtToken     string `json:"jwt_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type ApplySanctionRequest struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	// zero means permanent, suspensions must have a duration
+	Duration uint `json:"duration_in_seconds"`
+}
+
+type LiftSanctionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type SanctionResponse struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	IssuedBy  string `json:"issued_by"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	LiftedBy  string `json:"lifted_by,omitempty"`
+	LiftedAt  int64  `json:"lifted_at,omitempty"`
+	Active    bool   `json:"active"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type AuditEntryResponse struct {
+	ID         string `json:"id"`
+	SanctionID string `json:"sanction_id"`
+	UserID     string `json:"user_id"`
+	ActorID    string `json:"actor_id"`
+	Action     string `json:"action"`
+	Reason     string `json:"reason"`
+	CreatedAt  int64  `json:"created_at"`
 }
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/mail"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores the bytes after 72
+	maxPasswordLength = 72
+
+	minTokenSecretLength = 32
+	// the placeholder of the old configs
+	placeholderTokenSecret = "change-me"
+)
+
+// tokenSecretEnv overrides the token secret of the config.
+const tokenSecretEnv = "AUTH_TOKEN_SECRET"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailNotVerified   = errors.New("email is not verified")
+	ErrTooManyAttempts    = errors.New("too many attempts, try again later")
+	ErrInvalidUserToken   = errors.New("invalid or expired token")
+	ErrWeakPassword       = errors.New("password must be 8 to 72 characters and contain a letter and a digit")
+	ErrPasswordIsEmail    = errors.New("password must not be the email")
+)
+
+func validatePassword(email, password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !letter || !digit {
+		return ErrWeakPassword
+	}
+
+	if strings.EqualFold(password, email) {
+		return ErrPasswordIsEmail
+	}
+
+	return nil
+}
+
+// VerifyEmail verifies the email of the account by the token of the verification link.
+func (s *AuthService) VerifyEmail(ctx context.Context, req VerifyEmailRequest) error {
+	userId, err := s.useUserToken(ctx, req.Token, entity.TokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetEmailVerified(ctx, userId); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.completePendingClaim(ctx, user, "", ""); err != nil {
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("email of user '%s' verified", userId))
+	return nil
+}
+
+// ResendVerification sends the verification link again, it doesn't return an error
+// if the email is not registered to not reveal the registered emails.
+func (s *AuthService) ResendVerification(ctx context.Context, req EmailRequest) error {
+	user, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if user == nil || user.EmailVerified {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// RequestPasswordReset sends the password reset link, it doesn't return an error
+// if the email is not registered to not reveal the registered emails.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, req EmailRequest) error {
+	user, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	token, err := s.newUserToken(ctx, user, entity.TokenPurposeResetPassword, s.passwordResetExpiration)
+	if err != nil || token == "" {
+		return err
+	}
+
+	return s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your ShahBoard password",
+		Body: fmt.Sprintf("Use the link below to reset your password, it expires in %s.\n\n%s\n\n"+
+			"If you didn't request it, you can ignore this email.",
+			s.passwordResetExpiration, s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets the new password by the token of the reset link and revokes all sessions of the user.
+// The email is verified too, since the user has received the link.
+func (s *AuthService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	// the token is single use, so the password is checked before using it
+	if err := validatePassword("", req.Password); err != nil {
+		return err
+	}
+
+	userId, err := s.useUserToken(ctx, req.Token, entity.TokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if user == nil {
+		return ErrInvalidUserToken
+	}
+
+	if err := validatePassword(user.Email, req.Password); err != nil {
+		return err
+	}
+
+	hPass, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, hPass); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if !user.EmailVerified {
+		if err := s.repo.SetEmailVerified(ctx, user.ID); err != nil {
+			s.l.Error(err.Error())
+		}
+	}
+
+	if err := s.revokeUser(ctx, user.ID, user.Email); err != nil {
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("password of user '%s' reset", user.ID))
+	return nil
+}
+
+func (s *AuthService) sendVerification(ctx context.Context, user *entity.User) error {
+	token, err := s.newUserToken(ctx, user, entity.TokenPurposeVerifyEmail, s.verificationExpiration)
+	if err != nil || token == "" {
+		return err
+	}
+
+	return s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your ShahBoard email",
+		Body: fmt.Sprintf("Welcome to ShahBoard!\n\nUse the link below to verify your email, it expires in %s.\n\n%s",
+			s.verificationExpiration, s.link("/verify-email", token)),
+	})
+}
+
+// newUserToken returns an empty token if too many emails are sent to the user.
+func (s *AuthService) newUserToken(ctx context.Context, user *entity.User, purpose entity.TokenPurpose,
+	expiration time.Duration) (string, error) {
+	ok, err := s.throttle.allowMail(ctx, user.Email)
+	if err != nil {
+		s.l.Error(err.Error())
+		return "", err
+	}
+	if !ok {
+		s.l.Debug(fmt.Sprintf("too many emails for user '%s'", user.ID))
+		return "", nil
+	}
+
+	t, err := entity.NewUserToken(user.ID, purpose, expiration)
+	if err != nil {
+		s.l.Error(err.Error())
+		return "", err
+	}
+	if err := s.tokens.CreateUserToken(ctx, t); err != nil {
+		s.l.Error(err.Error())
+		return "", err
+	}
+
+	return s.signToken(t.Purpose, t.ID, t.ExpiresAt), nil
+}
+
+func (s *AuthService) sendMail(ctx context.Context, msg mail.Message) error {
+	if err := s.mail.Send(ctx, msg); err != nil {
+		s.l.Error(err.Error())
+		return errors.New("failed to send email")
+	}
+	return nil
+}
+
+func (s *AuthService) link(path, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", strings.TrimSuffix(s.cfg.AppUrl, "/"), path, url.QueryEscape(token))
+}
+
+// useUserToken checks the signature of the token and marks it as used.
+func (s *AuthService) useUserToken(ctx context.Context, token string, purpose entity.TokenPurpose) (types.ObjectId, error) {
+	id, err := s.parseUserToken(token, purpose)
+	if err != nil {
+		return types.ObjectZero, err
+	}
+
+	userId, err := s.tokens.UseUserToken(ctx, id, purpose, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return types.ObjectZero, err
+	}
+	if userId.IsZero() {
+		return types.ObjectZero, ErrInvalidUserToken
+	}
+
+	return userId, nil
+}
+
+// tokenSecret returns the secret that signs the email tokens, the service doesn't start
+// with an empty, short or placeholder secret since anyone who knows it can reset any password.
+func (c Config) tokenSecret() (string, error) {
+	secret := os.Getenv(tokenSecretEnv)
+	if secret == "" && c.TokenSecretFile != "" {
+		s, err := readSecretFile(c.TokenSecretFile, "token secret")
+		if err != nil {
+			return "", err
+		}
+		secret = s
+	}
+	if secret == "" {
+		secret = c.TokenSecret
+	}
+
+	switch {
+	case secret == "":
+		return "", fmt.Errorf("token secret is required, set %s or token_secret_file", tokenSecretEnv)
+	case secret == placeholderTokenSecret:
+		return "", errors.New("token secret is the placeholder, set a random secret")
+	case len(secret) < minTokenSecretLength:
+		return "", fmt.Errorf("token secret must be at least %d characters", minTokenSecretLength)
+	}
+	return secret, nil
+}
+
+// signToken returns `payload.signature` where payload is `purpose.id.expiration` and
+// signature is the HMAC-SHA256 of the payload, both base64url encoded.
+func (s *AuthService) signToken(purpose entity.TokenPurpose, id types.ObjectId, exp time.Time) string {
+	payload := fmt.Sprintf("%s.%s.%d", purpose, id, exp.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// parseUserToken checks the signature, purpose and expiration of the token and returns the id of it.
+func (s *AuthService) parseUserToken(token string, purpose entity.TokenPurpose) (types.ObjectId, error) {
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return types.ObjectZero, ErrInvalidUserToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return types.ObjectZero, ErrInvalidUserToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(signature, s.sign(string(payload))) {
+		return types.ObjectZero, ErrInvalidUserToken
+	}
+
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 3 || fields[0] != string(purpose) {
+		return types.ObjectZero, ErrInvalidUserToken
+	}
+
+	exp, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return types.ObjectZero, ErrInvalidUserToken
+	}
+
+	return types.ObjectId(fields[1]), nil
+}
+
+func (s *AuthService) sign(payload string) []byte {
+	h := hmac.New(sha256.New, []byte(s.cfg.TokenSecret))
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/sanction"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	ErrUserBanned            = errors.New("account is banned")
+	ErrUserSuspended         = errors.New("account is suspended")
+	ErrInvalidSanction       = errors.New("invalid sanction type")
+	ErrReasonRequired        = errors.New("reason is required")
+	ErrSuspensionDuration    = errors.New("suspension requires a duration")
+	ErrSanctionNotFound      = errors.New("sanction not found")
+	ErrSanctionAlreadyLifted = errors.New("sanction is already lifted or expired")
+)
+
+// ApplySanction restricts the user and records the moderator in the audit log. Sanctions that block
+// the account revoke the user's sessions, other services are notified to enforce the sanction.
+func (s *AuthService) ApplySanction(ctx context.Context, moderator types.User, userId types.ObjectId,
+	req ApplySanctionRequest) (SanctionResponse, error) {
+	t, ok := types.ParseSanctionType(req.Type)
+	if !ok {
+		return SanctionResponse{}, ErrInvalidSanction
+	}
+	if req.Reason == "" {
+		return SanctionResponse{}, ErrReasonRequired
+	}
+	if t == types.SanctionSuspension && req.Duration == 0 {
+		return SanctionResponse{}, ErrSuspensionDuration
+	}
+
+	user, err := s.getSanctionTarget(ctx, moderator, userId)
+	if err != nil {
+		return SanctionResponse{}, err
+	}
+
+	sanction := entity.NewSanction(user.ID, t, req.Reason, moderator.ID, time.Duration(req.Duration)*time.Second)
+	audit := entity.NewAuditEntry(sanction, moderator.ID, entity.AuditActionApplied, req.Reason)
+	if err := s.repo.CreateSanction(ctx, sanction, audit); err != nil {
+		s.l.Error(err.Error())
+		return SanctionResponse{}, err
+	}
+
+	if t.BlocksAccount() {
+		if err := s.revokeUser(ctx, user.ID, user.Email); err != nil {
+			return SanctionResponse{}, err
+		}
+	}
+
+	if err := s.pub.Publish(event.EventUserSanctioned{
+		ID:         types.NewObjectId(),
+		SanctionID: sanction.ID,
+		UserID:     user.ID,
+		Type:       t,
+		Reason:     req.Reason,
+		IssuedBy:   moderator.ID,
+		ExpiresAt:  unixOrZero(sanction.ExpiresAt),
+		Timestamp:  time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	s.l.Info(fmt.Sprintf("sanction '%s' of type '%s' applied to user '%s' by '%s'", sanction.ID, t, user.ID, moderator.ID))
+	return newSanctionResponse(sanction), nil
+}
+
+// LiftSanction ends the sanction before it expires and records the moderator in the audit log.
+func (s *AuthService) LiftSanction(ctx context.Context, moderator types.User, sanctionId types.ObjectId,
+	req LiftSanctionRequest) error {
+	if req.Reason == "" {
+		return ErrReasonRequired
+	}
+
+	sanction, err := s.repo.GetSanction(ctx, sanctionId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if sanction == nil {
+		return ErrSanctionNotFound
+	}
+	if !sanction.IsActive() {
+		return ErrSanctionAlreadyLifted
+	}
+
+	if _, err := s.getSanctionTarget(ctx, moderator, sanction.UserID); err != nil {
+		return err
+	}
+
+	sanction.LiftedBy = moderator.ID
+	sanction.LiftedAt = time.Now()
+	audit := entity.NewAuditEntry(sanction, moderator.ID, entity.AuditActionLifted, req.Reason)
+	ok, err := s.repo.LiftSanction(ctx, sanction, audit)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return ErrSanctionAlreadyLifted
+	}
+
+	if err := s.pub.Publish(event.EventUserSanctionLifted{
+		ID:         types.NewObjectId(),
+		SanctionID: sanction.ID,
+		UserID:     sanction.UserID,
+		Type:       sanction.Type,
+		LiftedBy:   moderator.ID,
+		Timestamp:  time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	s.l.Info(fmt.Sprintf("sanction '%s' of user '%s' lifted by '%s'", sanction.ID, sanction.UserID, moderator.ID))
+	return nil
+}
+
+// GetUserSanctions returns all sanctions of the user, including the lifted and expired ones.
+func (s *AuthService) GetUserSanctions(ctx context.Context, userId types.ObjectId) ([]SanctionResponse, error) {
+	list, err := s.repo.GetSanctions(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	res := make([]SanctionResponse, 0, len(list))
+	for _, sanction := range list {
+		res = append(res, newSanctionResponse(sanction))
+	}
+	return res, nil
+}
+
+// GetAuditLog returns who applied and lifted the sanctions of the user.
+func (s *AuthService) GetAuditLog(ctx context.Context, userId types.ObjectId) ([]AuditEntryResponse, error) {
+	list, err := s.repo.GetAuditLog(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	res := make([]AuditEntryResponse, 0, len(list))
+	for _, a := range list {
+		res = append(res, AuditEntryResponse{
+			ID:         a.ID.String(),
+			SanctionID: a.SanctionID.String(),
+			UserID:     a.UserID.String(),
+			ActorID:    a.ActorID.String(),
+			Action:     string(a.Action),
+			Reason:     a.Reason,
+			CreatedAt:  a.CreatedAt.Unix(),
+		})
+	}
+	return res, nil
+}
+
+// SeedSanctions restores the shared sanction registry from the database.
+func (s *AuthService) SeedSanctions(ctx context.Context) error {
+	list, err := s.repo.GetUnexpiredSanctions(ctx, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	var active, lifted []sanction.Entry
+	for _, sn := range list {
+		e := sanction.Entry{SanctionID: sn.ID, UserID: sn.UserID, Type: sn.Type, ExpiresAt: sn.ExpiresAt}
+		if sn.IsActive() {
+			active = append(active, e)
+		} else {
+			lifted = append(lifted, e)
+		}
+	}
+
+	if err := sanction.Seed(ctx, s.rc, active, lifted); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Info(fmt.Sprintf("sanction registry seeded with %d active sanctions", len(active)))
+	return nil
+}
+
+// getSanctionTarget returns the user that the moderator sanctions. Guests have no account,
+// they're found by their sessions and can be sanctioned like the users.
+func (s *AuthService) getSanctionTarget(ctx context.Context, moderator types.User, userId types.ObjectId) (*entity.User, error) {
+	user, err := s.getTargetUser(ctx, moderator, userId)
+	if !errors.Is(err, ErrUserNotFound) {
+		return user, err
+	}
+
+	guest, err := s.tokens.IsGuest(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if !guest {
+		return nil, ErrUserNotFound
+	}
+
+	return &entity.User{ID: userId, Email: guestEmail(userId), Role: types.RoleUser}, nil
+}
+
+// checkAccount returns an error if the user has an active sanction that blocks the account.
+func (s *AuthService) checkAccount(ctx context.Context, userId types.ObjectId) error {
+	list, err := s.repo.GetActiveSanctions(ctx, userId, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	var suspension *entity.Sanction
+	for _, sanction := range list {
+		switch sanction.Type {
+		case types.SanctionBan:
+			return ErrUserBanned
+		case types.SanctionSuspension:
+			if suspension == nil || sanction.ExpiresAt.After(suspension.ExpiresAt) {
+				suspension = sanction
+			}
+		}
+	}
+
+	if suspension != nil {
+		return fmt.Errorf("%w until %s", ErrUserSuspended, suspension.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func newSanctionResponse(s *entity.Sanction) SanctionResponse {
+	return SanctionResponse{
+		ID:        s.ID.String(),
+		UserID:    s.UserID.String(),
+		Type:      s.Type.String(),
+		Reason:    s.Reason,
+		IssuedBy:  s.IssuedBy.String(),
+		ExpiresAt: unixOrZero(s.ExpiresAt),
+		LiftedBy:  s.LiftedBy.String(),
+		LiftedAt:  unixOrZero(s.LiftedAt),
+		Active:    s.IsActive(),
+		CreatedAt: s.CreatedAt.Unix(),
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultThrottleWindow     = 15 * time.Minute
+	defaultMaxAccountAttempts = 5
+	defaultMaxIPAttempts      = 20
+	defaultMaxMails           = 3
+
+	throttleKeyPrefix = "auth_throttle:"
+)
+
+type ThrottleConfig struct {
+	// default is 15 minutes
+	Window uint `json:"window_in_seconds"`
+	// failed logins of an account in the window, default is 5, it limits the two factor codes too
+	MaxAccountAttempts int64 `json:"max_account_attempts"`
+	// failed logins from an ip in the window, default is 20
+	MaxIPAttempts int64 `json:"max_ip_attempts"`
+	// verification and password reset emails of an account in the window, default is 3
+	MaxMails int64 `json:"max_mails"`
+}
+
+// throttler counts the failed logins and the sent emails in redis, so the limits are shared
+// between the instances of the service.
+type throttler struct {
+	c      *redis.Client
+	window time.Duration
+	cfg    ThrottleConfig
+}
+
+func newThrottler(c *redis.Client, cfg ThrottleConfig) *throttler {
+	t := &throttler{
+		c:      c,
+		window: defaultThrottleWindow,
+		cfg:    cfg,
+	}
+
+	if cfg.Window > 0 {
+		t.window = time.Duration(cfg.Window) * time.Second
+	}
+	if t.cfg.MaxAccountAttempts <= 0 {
+		t.cfg.MaxAccountAttempts = defaultMaxAccountAttempts
+	}
+	if t.cfg.MaxIPAttempts <= 0 {
+		t.cfg.MaxIPAttempts = defaultMaxIPAttempts
+	}
+	if t.cfg.MaxMails <= 0 {
+		t.cfg.MaxMails = defaultMaxMails
+	}
+
+	return t
+}
+
+// increments the counter and sets the expiration of the window on the first hit
+var hitScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (t *throttler) checkLogin(ctx context.Context, email, ip string) error {
+	n, err := t.count(ctx, accountKey(email))
+	if err != nil {
+		return err
+	}
+	if n >= t.cfg.MaxAccountAttempts {
+		return ErrTooManyAttempts
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	n, err = t.count(ctx, ipKey(ip))
+	if err != nil {
+		return err
+	}
+	if n >= t.cfg.MaxIPAttempts {
+		return ErrTooManyAttempts
+	}
+
+	return nil
+}
+
+func (t *throttler) loginFailed(ctx context.Context, email, ip string) {
+	t.hit(ctx, accountKey(email))
+	if ip != "" {
+		t.hit(ctx, ipKey(ip))
+	}
+}
+
+func (t *throttler) loginSucceeded(ctx context.Context, email string) {
+	t.c.Del(ctx, accountKey(email))
+}
+
+func (t *throttler) checkTwoFactor(ctx context.Context, userId types.ObjectId) error {
+	n, err := t.count(ctx, twoFactorKey(userId))
+	if err != nil {
+		return err
+	}
+	if n >= t.cfg.MaxAccountAttempts {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+func (t *throttler) twoFactorFailed(ctx context.Context, userId types.ObjectId) {
+	t.hit(ctx, twoFactorKey(userId))
+}
+
+func (t *throttler) twoFactorSucceeded(ctx context.Context, userId types.ObjectId) {
+	t.c.Del(ctx, twoFactorKey(userId))
+}
+
+// allowMail returns false if too many emails are sent to the account in the window.
+func (t *throttler) allowMail(ctx context.Context, email string) (bool, error) {
+	n, err := t.hit(ctx, throttleKeyPrefix+"mail:"+normalizeEmail(email))
+	if err != nil {
+		return false, err
+	}
+	return n <= t.cfg.MaxMails, nil
+}
+
+func (t *throttler) count(ctx context.Context, key string) (int64, error) {
+	n, err := t.c.Get(ctx, key).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to get throttle counter: %w", err)
+	}
+	return n, nil
+}
+
+func (t *throttler) hit(ctx context.Context, key string) (int64, error) {
+	n, err := hitScript.Run(ctx, t.c, []string{key}, t.window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment throttle counter: %w", err)
+	}
+	return n, nil
+}
+
+func accountKey(email string) string {
+	return throttleKeyPrefix + "account:" + normalizeEmail(email)
+}
+
+func twoFactorKey(userId types.ObjectId) string {
+	return throttleKeyPrefix + "2fa:" + userId.String()
+}
+
+func ipKey(ip string) string {
+	return throttleKeyPrefix + "ip:" + ip
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
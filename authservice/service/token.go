@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token is already used, session is revoked")
+)
+
+// issueTokens generates an access token and a refresh token for the login session.
+func (s *AuthService) issueTokens(ctx context.Context, u types.User, sessionId types.ObjectId) (string, string, error) {
+	rt, refresh, err := s.newRefreshToken(u, sessionId)
+	if err != nil {
+		return "", "", err
+	}
+
+	access := s.attachAccessToken(rt)
+	if err := s.tokens.Create(ctx, rt); err != nil {
+		s.l.Error(err.Error())
+		return "", "", err
+	}
+
+	return access, refresh, nil
+}
+
+// login issues the tokens of a new login session for the registered user.
+func (s *AuthService) login(ctx context.Context, user *entity.User) (string, string, error) {
+	s.promoteAdmin(ctx, user)
+	return s.issueTokens(ctx, user.Identity(), types.NewObjectId())
+}
+
+// Refresh exchanges the refresh token with a new access token and a new refresh token.
+// Each refresh token can be used only once, using it again means it's stolen,
+// so the whole session is revoked.
+func (s *AuthService) Refresh(ctx context.Context, req RefreshRequest) (RefreshResponse, error) {
+	old, err := s.getRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return RefreshResponse{}, err
+	}
+
+	if old.IsRevoked() || old.IsExpired() {
+		return RefreshResponse{}, ErrInvalidRefreshToken
+	}
+
+	if old.IsUsed() {
+		return RefreshResponse{}, s.handleReuse(ctx, old)
+	}
+
+	rt, refresh, err := s.newRefreshToken(old.User(), old.FamilyID)
+	if err != nil {
+		return RefreshResponse{}, err
+	}
+	access := s.attachAccessToken(rt)
+
+	old.UsedAt = time.Now()
+	ok, err := s.tokens.Rotate(ctx, old, rt)
+	if err != nil {
+		s.l.Error(err.Error())
+		return RefreshResponse{}, err
+	}
+	if !ok {
+		// it's used by another request at the same time
+		return RefreshResponse{}, s.handleReuse(ctx, old)
+	}
+
+	return RefreshResponse{
+		JwtToken:     access,
+		RefreshToken: refresh,
+	}, nil
+}
+
+// Logout revokes the login session of the refresh token.
+func (s *AuthService) Logout(ctx context.Context, req LogoutRequest) error {
+	rt, err := s.getRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	if rt.IsRevoked() {
+		return nil
+	}
+
+	if err := s.revokeSession(ctx, rt); err != nil {
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("user '%s' logged out from session '%s'", rt.UserID, rt.FamilyID))
+	return nil
+}
+
+func (s *AuthService) handleReuse(ctx context.Context, rt *entity.RefreshToken) error {
+	s.l.Warn(fmt.Sprintf("refresh token reuse detected for user '%s' in session '%s'", rt.UserID, rt.FamilyID))
+
+	if err := s.revokeSession(ctx, rt); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// revokeSession revokes all refresh tokens of the session, adds their access tokens to the denylist
+// and notifies other services to close the connections of the session.
+func (s *AuthService) revokeSession(ctx context.Context, rt *entity.RefreshToken) error {
+	revoked, err := s.tokens.RevokeFamily(ctx, rt.FamilyID, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.revokeAccessTokens(ctx, revoked)
+	s.publishLoggedOut(rt.UserID, rt.FamilyID, rt.Email)
+	return nil
+}
+
+// revokeUser revokes all sessions of the user.
+func (s *AuthService) revokeUser(ctx context.Context, userId types.ObjectId, email string) error {
+	revoked, err := s.tokens.RevokeUser(ctx, userId, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.revokeAccessTokens(ctx, revoked)
+	// empty session id means all sessions of the user
+	s.publishLoggedOut(userId, types.ObjectZero, email)
+	return nil
+}
+
+func (s *AuthService) revokeAccessTokens(ctx context.Context, revoked []*entity.RefreshToken) {
+	for _, t := range revoked {
+		if err := s.denylist.Revoke(ctx, t.AccessTokenID, t.AccessTokenExpiresAt); err != nil {
+			s.l.Error(err.Error())
+		}
+	}
+}
+
+func (s *AuthService) publishLoggedOut(userId, sessionId types.ObjectId, email string) {
+	if err := s.pub.Publish(event.EventUserLoggedOut{
+		ID:        types.NewObjectId(),
+		UserID:    userId,
+		SessionID: sessionId,
+		Email:     email,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+}
+
+func (s *AuthService) getRefreshToken(ctx context.Context, token string) (*entity.RefreshToken, error) {
+	if token == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rt, err := s.tokens.GetByHash(ctx, entity.HashRefreshToken(token))
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	if rt == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return rt, nil
+}
+
+func (s *AuthService) newRefreshToken(u types.User, sessionId types.ObjectId) (*entity.RefreshToken, string, error) {
+	rt, token, err := entity.NewRefreshToken(sessionId, u, s.refreshExpiration)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, "", err
+	}
+	return rt, token, nil
+}
+
+// attachAccessToken generates the access token of the refresh token.
+func (s *AuthService) attachAccessToken(rt *entity.RefreshToken) string {
+	access, claims := s.jwtGenerator.GenerateJWT(rt.User(), rt.FamilyID)
+	rt.AccessTokenID = claims.TokenId
+	rt.AccessTokenExpiresAt = claims.ExpiresAt
+	return access
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TOTP of RFC 6238 with the defaults that the authenticator apps support.
+const (
+	totpDigits     = 6
+	totpPeriod     = 30
+	totpSecretSize = 20
+	// codes of the previous and the next step are accepted for clock drift
+	totpSkew = 1
+
+	recoveryCodesCount = 10
+	recoveryCodeSize   = 5
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(b), nil
+}
+
+// totpUri returns the provisioning uri that the authenticator apps scan as a QR code.
+func totpUri(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", strconv.Itoa(totpDigits))
+	v.Set("period", strconv.Itoa(totpPeriod))
+
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + v.Encode()
+}
+
+// validateTOTP returns the time step of the code if it's valid at the time.
+func validateTOTP(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	step := t.Unix() / totpPeriod
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step+i)), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode is the HOTP of RFC 4226 for the time step.
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	h := hmac.New(sha1.New, key)
+	h.Write(msg[:])
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, n%mod)
+}
+
+// newRecoveryCodes returns the codes that are shown to the user once, and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(base32NoPadding.EncodeToString(b))
+		codes = append(codes, c[:4]+"-"+c[4:])
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	h := sha256.Sum256([]byte(normalizeCode(code)))
+	return hex.EncodeToString(h[:])
+}
+
+func normalizeCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// secretBox encrypts the TOTP secrets with AES-GCM, the nonce is prepended to the cipher text.
+// The rotated keys only decrypt the secrets until they're re-encrypted by the current key.
+type secretBox struct {
+	aead     cipher.AEAD
+	previous []cipher.AEAD
+}
+
+// newSecretBox gets the base64 encoded 32 bytes keys.
+func newSecretBox(key string, previous ...string) (*secretBox, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &secretBox{aead: aead}
+	for _, k := range previous {
+		p, err := newAEAD(k)
+		if err != nil {
+			return nil, err
+		}
+		b.previous = append(b.previous, p)
+	}
+	return b, nil
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(k) != 32 {
+		return nil, errors.New("two factor encryption key must be 32 bytes base64 encoded")
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (b *secretBox) hasPrevious() bool {
+	return len(b.previous) > 0
+}
+
+func (b *secretBox) seal(plain string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func (b *secretBox) open(sealed string) (string, error) {
+	plain, _, err := b.openAny(sealed)
+	return plain, err
+}
+
+// openAny decrypts the secret by the current key or a rotated key, it reports whether the current key is used.
+func (b *secretBox) openAny(sealed string) (string, bool, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", false, errors.New("invalid encrypted secret")
+	}
+
+	for i, aead := range append([]cipher.AEAD{b.aead}, b.previous...) {
+		n := aead.NonceSize()
+		if len(data) < n {
+			return "", false, errors.New("invalid encrypted secret")
+		}
+		if plain, err := aead.Open(nil, data[:n], data[n:], nil); err == nil {
+			return string(plain), i == 0, nil
+		}
+	}
+	return "", false, errors.New("failed to decrypt secret")
+}
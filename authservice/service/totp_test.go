@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// rfc6238Secret is the SHA1 seed of the test vectors of RFC 6238, "12345678901234567890".
+var rfc6238Secret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+// the vectors are 8 digits, the codes are their last 6 digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestTOTPCode(t *testing.T) {
+	key, err := base32NoPadding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range rfc6238Vectors {
+		if got := totpCode(key, v.unix/totpPeriod); got != v.code {
+			t.Errorf("code at %d: expected %s, got %s", v.unix, v.code, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		step, ok := validateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if !ok {
+			t.Errorf("code %s at %d is rejected", v.code, v.unix)
+			continue
+		}
+		if step != v.unix/totpPeriod {
+			t.Errorf("step of code %s: expected %d, got %d", v.code, v.unix/totpPeriod, step)
+		}
+	}
+
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"wrong code", "123456", false},
+		{"short code", "28708", false},
+		{"8 digits code", "94287082", false},
+		{"not digits", "abcdef", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := validateTOTP(rfc6238Secret, tt.code, time.Unix(59, 0)); ok != tt.ok {
+				t.Errorf("expected %v, got %v", tt.ok, ok)
+			}
+		})
+	}
+
+	if _, ok := validateTOTP("not base32!", "287082", time.Unix(59, 0)); ok {
+		t.Error("code of an invalid secret is accepted")
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// 1111111111 is in the step 37037037, and 1111111109 in the previous one
+	code := "050471"
+	step := int64(1111111111 / totpPeriod)
+
+	tests := []struct {
+		name string
+		at   int64
+		ok   bool
+	}{
+		{"same step", step * totpPeriod, true},
+		{"next step", (step + 1) * totpPeriod, true},
+		{"previous step", (step - 1) * totpPeriod, true},
+		{"two steps later", (step + 2) * totpPeriod, false},
+		{"two steps earlier", (step - 2) * totpPeriod, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := validateTOTP(rfc6238Secret, code, time.Unix(tt.at, 0))
+			if ok != tt.ok {
+				t.Fatalf("expected %v, got %v", tt.ok, ok)
+			}
+			// the step of the code is returned, not the step of the time
+			if ok && got != step {
+				t.Errorf("expected step %d, got %d", step, got)
+			}
+		})
+	}
+}
+
+// stepRepo keeps the last used step like the two_factor table.
+type stepRepo struct {
+	Repository
+	lastUsedStep map[types.ObjectId]int64
+}
+
+func (r *stepRepo) UseTwoFactorStep(_ context.Context, userId types.ObjectId, step int64) (bool, error) {
+	if r.lastUsedStep[userId] >= step {
+		return false, nil
+	}
+	r.lastUsedStep[userId] = step
+	return true, nil
+}
+
+func newTestBox(t *testing.T, previous ...string) *secretBox {
+	t.Helper()
+	b, err := newSecretBox(testKey(1), previous...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testKey(b byte) string {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = b
+	}
+	return base64.StdEncoding.EncodeToString(k)
+}
+
+func TestCheckTwoFactorCodeReplay(t *testing.T) {
+	box := newTestBox(t)
+	sealed, err := box.seal(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &stepRepo{lastUsedStep: make(map[types.ObjectId]int64)}
+	s := &AuthService{
+		repo:    repo,
+		secrets: box,
+		l:       log.NewLogger(filepath.Join(t.TempDir(), "auth.log"), false),
+	}
+	tf := &entity.TwoFactor{UserID: types.NewObjectId(), Secret: sealed, Enabled: true}
+
+	key, _ := base32NoPadding.DecodeString(rfc6238Secret)
+	step := time.Now().Unix() / totpPeriod
+	current := totpCode(key, step)
+	previous := totpCode(key, step-1)
+
+	ok, err := s.checkTwoFactorCode(context.Background(), tf, current)
+	if err != nil || !ok {
+		t.Fatalf("current code is rejected: %v", err)
+	}
+	if repo.lastUsedStep[tf.UserID] != step {
+		t.Errorf("expected last used step %d, got %d", step, repo.lastUsedStep[tf.UserID])
+	}
+
+	ok, err = s.checkTwoFactorCode(context.Background(), tf, current)
+	if err != nil || ok {
+		t.Errorf("used code is accepted again")
+	}
+
+	// the previous step is in the skew but it's older than the used step
+	if previous != current {
+		ok, err = s.checkTwoFactorCode(context.Background(), tf, previous)
+		if err != nil || ok {
+			t.Errorf("code of an older step is accepted after a newer one is used")
+		}
+	}
+}
+
+func TestSecretBoxRotation(t *testing.T) {
+	old, err := newSecretBox(testKey(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := old.seal(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestBox(t).open(sealed); err == nil {
+		t.Fatal("secret of another key is decrypted")
+	}
+
+	box := newTestBox(t, testKey(2))
+	plain, current, err := box.openAny(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain != rfc6238Secret || current {
+		t.Errorf("expected the secret by the previous key, got %q current %v", plain, current)
+	}
+
+	resealed, err := box.seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, current, err := box.openAny(resealed); err != nil || !current {
+		t.Errorf("resealed secret isn't encrypted by the current key")
+	}
+}
@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/authservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultTwoFactorIssuer     = "ShahBoard"
+	defaultChallengeExpiration = 5 * time.Minute
+
+	challengeKeyPrefix = "auth_challenge:"
+)
+
+type challengeMethod string
+
+const (
+	challengeMethodPassword challengeMethod = "password"
+	challengeMethodGoogle   challengeMethod = "google"
+)
+
+// loginChallenge is the first step of a two factor login, it's stored in redis by the hash of the
+// challenge token and is removed by the first attempt to complete it.
+type loginChallenge struct {
+	UserID types.ObjectId  `json:"user_id"`
+	Method challengeMethod `json:"method"`
+	// sha256 of the password hash at the password login, so a password change invalidates the challenge
+	Password string `json:"password,omitempty"`
+}
+
+var (
+	ErrTwoFactorUnavailable = errors.New("two factor authentication is only available for registered accounts")
+	ErrTwoFactorEnabled     = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two factor authentication is not enabled")
+	ErrTwoFactorNotSetup    = errors.New("two factor authentication is not set up")
+	ErrInvalidTwoFactorCode = errors.New("invalid two factor code")
+	ErrInvalidChallenge     = errors.New("invalid or expired login challenge")
+)
+
+// twoFactorKeyEnv overrides the encryption key of the config.
+const twoFactorKeyEnv = "TWO_FACTOR_ENCRYPTION_KEY"
+
+type TwoFactorConfig struct {
+	// base64 encoded 32 bytes key that encrypts the TOTP secrets, it's used only for development,
+	// the key is read from the TWO_FACTOR_ENCRYPTION_KEY env or from the EncryptionKeyFile otherwise
+	EncryptionKey string `json:"encryption_key"`
+	// file of the key, e.g. a docker secret
+	EncryptionKeyFile string `json:"encryption_key_file"`
+	// files of the rotated keys, the secrets that are encrypted by them are re-encrypted by the current key at startup
+	PreviousEncryptionKeyFiles []string `json:"previous_encryption_key_files"`
+	// name of the account in the authenticator apps, default is ShahBoard
+	Issuer string `json:"issuer"`
+	// default is 5 minutes
+	ChallengeExpiration uint `json:"challenge_expiration_in_seconds"`
+}
+
+// encryptionKeys returns the current key and the rotated keys.
+func (c TwoFactorConfig) encryptionKeys() (string, []string, error) {
+	key := os.Getenv(twoFactorKeyEnv)
+	if key == "" && c.EncryptionKeyFile != "" {
+		k, err := readSecretFile(c.EncryptionKeyFile, "two factor encryption key")
+		if err != nil {
+			return "", nil, err
+		}
+		key = k
+	}
+	if key == "" {
+		key = c.EncryptionKey
+	}
+	if key == "" {
+		return "", nil, fmt.Errorf("two factor encryption key is required, set %s or encryption_key_file", twoFactorKeyEnv)
+	}
+
+	previous := make([]string, 0, len(c.PreviousEncryptionKeyFiles))
+	for _, f := range c.PreviousEncryptionKeyFiles {
+		k, err := readSecretFile(f, "two factor encryption key")
+		if err != nil {
+			return "", nil, err
+		}
+		previous = append(previous, k)
+	}
+	return key, previous, nil
+}
+
+func readSecretFile(path, name string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// RotateTwoFactorSecrets re-encrypts the secrets that are encrypted by a rotated key with the current key.
+func (s *AuthService) RotateTwoFactorSecrets(ctx context.Context) error {
+	if !s.secrets.hasPrevious() {
+		return nil
+	}
+
+	secrets, err := s.repo.GetTwoFactorSecrets(ctx)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	rotated := 0
+	for userId, sealed := range secrets {
+		plain, current, err := s.secrets.openAny(sealed)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt two factor secret of user %s: %w", userId, err)
+		}
+		if current {
+			continue
+		}
+
+		resealed, err := s.secrets.seal(plain)
+		if err != nil {
+			return err
+		}
+		// the secret may be replaced by a new setup meanwhile, which is encrypted by the current key
+		if _, err := s.repo.ReplaceTwoFactorSecret(ctx, userId, sealed, resealed); err != nil {
+			s.l.Error(err.Error())
+			return err
+		}
+		rotated++
+	}
+
+	s.l.Info(fmt.Sprintf("two factor secrets of %d users re-encrypted by the current key", rotated))
+	return nil
+}
+
+// SetupTwoFactor generates a new TOTP secret for the user, the secret is not required for login
+// until it's enabled by a valid code. Setting up again replaces the secret that is not enabled yet.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, u types.User) (TwoFactorSetupResponse, error) {
+	user, err := s.getRegisteredUser(ctx, u)
+	if err != nil {
+		return TwoFactorSetupResponse{}, err
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return TwoFactorSetupResponse{}, err
+	}
+	if tf != nil && tf.Enabled {
+		return TwoFactorSetupResponse{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		s.l.Error(err.Error())
+		return TwoFactorSetupResponse{}, err
+	}
+
+	sealed, err := s.secrets.seal(secret)
+	if err != nil {
+		s.l.Error(err.Error())
+		return TwoFactorSetupResponse{}, err
+	}
+
+	if err := s.repo.SaveTwoFactor(ctx, entity.NewTwoFactor(user.ID, sealed)); err != nil {
+		s.l.Error(err.Error())
+		return TwoFactorSetupResponse{}, err
+	}
+
+	return TwoFactorSetupResponse{
+		Secret: secret,
+		Uri:    totpUri(s.twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor enables the secret of the setup by the first code of the authenticator app
+// and returns the recovery codes, they are shown to the user only once.
+func (s *AuthService) EnableTwoFactor(ctx context.Context, u types.User, req TwoFactorCodeRequest) (RecoveryCodesResponse, error) {
+	user, err := s.getRegisteredUser(ctx, u)
+	if err != nil {
+		return RecoveryCodesResponse{}, err
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return RecoveryCodesResponse{}, err
+	}
+	if tf == nil {
+		return RecoveryCodesResponse{}, ErrTwoFactorNotSetup
+	}
+	if tf.Enabled {
+		return RecoveryCodesResponse{}, ErrTwoFactorEnabled
+	}
+
+	if err := s.throttle.checkTwoFactor(ctx, user.ID); err != nil {
+		return RecoveryCodesResponse{}, err
+	}
+
+	secret, err := s.secrets.open(tf.Secret)
+	if err != nil {
+		s.l.Error(err.Error())
+		return RecoveryCodesResponse{}, err
+	}
+
+	step, ok := validateTOTP(secret, normalizeCode(req.Code), time.Now())
+	if !ok {
+		s.throttle.twoFactorFailed(ctx, user.ID)
+		return RecoveryCodesResponse{}, ErrInvalidTwoFactorCode
+	}
+	s.throttle.twoFactorSucceeded(ctx, user.ID)
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		s.l.Error(err.Error())
+		return RecoveryCodesResponse{}, err
+	}
+
+	if err := s.repo.EnableTwoFactor(ctx, user.ID, step, hashes, time.Now()); err != nil {
+		s.l.Error(err.Error())
+		return RecoveryCodesResponse{}, err
+	}
+
+	s.l.Debug(fmt.Sprintf("two factor authentication enabled for user '%s'", user.ID))
+	return RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// DisableTwoFactor removes the secret and the recovery codes, it requires a code or a recovery code.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, u types.User, req TwoFactorCodeRequest) error {
+	tf, err := s.getEnabledTwoFactor(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyTwoFactor(ctx, tf, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DisableTwoFactor(ctx, tf.UserID); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("two factor authentication disabled for user '%s'", tf.UserID))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, it requires a code or a recovery code.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, u types.User, req TwoFactorCodeRequest) (RecoveryCodesResponse, error) {
+	tf, err := s.getEnabledTwoFactor(ctx, u)
+	if err != nil {
+		return RecoveryCodesResponse{}, err
+	}
+
+	if err := s.verifyTwoFactor(ctx, tf, req.Code); err != nil {
+		return RecoveryCodesResponse{}, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		s.l.Error(err.Error())
+		return RecoveryCodesResponse{}, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, tf.UserID, hashes, time.Now()); err != nil {
+		s.l.Error(err.Error())
+		return RecoveryCodesResponse{}, err
+	}
+
+	return RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// TwoFactorLogin completes the login of an account with two factor authentication,
+// the challenge token is returned by the first step of the login.
+func (s *AuthService) TwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest) (TwoFactorLoginResponse, error) {
+	c, err := s.useChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return TwoFactorLoginResponse{}, err
+	}
+
+	user, err := s.repo.GetByID(ctx, c.UserID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return TwoFactorLoginResponse{}, err
+	}
+	if user == nil {
+		return TwoFactorLoginResponse{}, ErrInvalidChallenge
+	}
+	if c.Method == challengeMethodPassword && c.Password != passwordFingerprint(user.Password) {
+		return TwoFactorLoginResponse{}, ErrInvalidChallenge
+	}
+	if err := s.checkAccount(ctx, user.ID); err != nil {
+		return TwoFactorLoginResponse{}, err
+	}
+
+	tf, err := s.getEnabledTwoFactor(ctx, user.Identity())
+	if err != nil {
+		// it's disabled after the challenge is issued
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			return TwoFactorLoginResponse{}, ErrInvalidChallenge
+		}
+		return TwoFactorLoginResponse{}, err
+	}
+
+	if err := s.verifyTwoFactor(ctx, tf, req.Code); err != nil {
+		return TwoFactorLoginResponse{}, err
+	}
+
+	access, refresh, err := s.login(ctx, user)
+	if err != nil {
+		return TwoFactorLoginResponse{}, err
+	}
+
+	return TwoFactorLoginResponse{
+		Id:           user.ID.String(),
+		Email:        user.Email,
+		JwtToken:     access,
+		RefreshToken: refresh,
+	}, nil
+}
+
+// twoFactorChallenge returns the challenge token of the login if two factor authentication
+// is enabled for the user, and an empty token otherwise.
+func (s *AuthService) twoFactorChallenge(ctx context.Context, user *entity.User, method challengeMethod) (string, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return "", err
+	}
+	if tf == nil || !tf.Enabled {
+		return "", nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		s.l.Error(err.Error())
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	c := loginChallenge{UserID: user.ID, Method: method}
+	if method == challengeMethodPassword {
+		c.Password = passwordFingerprint(user.Password)
+	}
+	v, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.rc.Set(ctx, challengeKey(token), v, s.challengeExpiration).Err(); err != nil {
+		s.l.Error(err.Error())
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useChallenge removes the challenge and returns it, so each challenge is used once
+// whether the code is valid or not.
+func (s *AuthService) useChallenge(ctx context.Context, token string) (loginChallenge, error) {
+	if token == "" {
+		return loginChallenge{}, ErrInvalidChallenge
+	}
+
+	v, err := s.rc.GetDel(ctx, challengeKey(token)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return loginChallenge{}, ErrInvalidChallenge
+		}
+		s.l.Error(err.Error())
+		return loginChallenge{}, err
+	}
+
+	var c loginChallenge
+	if err := json.Unmarshal(v, &c); err != nil {
+		s.l.Error(err.Error())
+		return loginChallenge{}, ErrInvalidChallenge
+	}
+	return c, nil
+}
+
+func challengeKey(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return challengeKeyPrefix + hex.EncodeToString(h[:])
+}
+
+func passwordFingerprint(hash string) string {
+	h := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(h[:])
+}
+
+// verifyTwoFactor accepts a TOTP code or an unused recovery code, the failed attempts are throttled
+// like the logins since the codes are short.
+func (s *AuthService) verifyTwoFactor(ctx context.Context, tf *entity.TwoFactor, code string) error {
+	if err := s.throttle.checkTwoFactor(ctx, tf.UserID); err != nil {
+		return err
+	}
+
+	ok, err := s.checkTwoFactorCode(ctx, tf, normalizeCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.throttle.twoFactorFailed(ctx, tf.UserID)
+		return ErrInvalidTwoFactorCode
+	}
+
+	s.throttle.twoFactorSucceeded(ctx, tf.UserID)
+	return nil
+}
+
+func (s *AuthService) checkTwoFactorCode(ctx context.Context, tf *entity.TwoFactor, code string) (bool, error) {
+	if len(code) != totpDigits {
+		ok, err := s.repo.UseRecoveryCode(ctx, tf.UserID, hashRecoveryCode(code), time.Now())
+		if err != nil {
+			s.l.Error(err.Error())
+			return false, err
+		}
+		if ok {
+			s.l.Debug(fmt.Sprintf("recovery code used by user '%s'", tf.UserID))
+		}
+		return ok, nil
+	}
+
+	secret, err := s.secrets.open(tf.Secret)
+	if err != nil {
+		s.l.Error(err.Error())
+		return false, err
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	// each code is accepted once
+	ok, err = s.repo.UseTwoFactorStep(ctx, tf.UserID, step)
+	if err != nil {
+		s.l.Error(err.Error())
+		return false, err
+	}
+	return ok, nil
+}
+
+func (s *AuthService) getEnabledTwoFactor(ctx context.Context, u types.User) (*entity.TwoFactor, error) {
+	if u.IsGuest {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, u.ID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if tf == nil || !tf.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return tf, nil
+}
+
+func (s *AuthService) getRegisteredUser(ctx context.Context, u types.User) (*entity.User, error) {
+	if u.IsGuest {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	user, err := s.repo.GetByID(ctx, u.ID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	return user, nil
+}
@@ -72,6 +72,17 @@ func (s *Service) handleMsgSent(e *event.EventGameChatMsgeSent) {
 		return
 	}
 
+	muted, err := s.sr.Has(context.Background(), e.SenderID, types.SanctionChatMute, types.SanctionBan,
+		types.SanctionSuspension)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if muted {
+		s.l.Debug(fmt.Sprintf("message dropped, sender '%s' is muted, gameID: %s", e.SenderID, e.GameID))
+		return
+	}
+
 	t := time.Now()
 	msg := &entity.Message{
 		SenderId:  e.SenderID,
@@ -5,6 +5,7 @@ import (
 
 	"github.com/alikarimi999/shahboard/event"
 	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/sanction"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/redis/go-redis/v9"
 )
@@ -18,6 +19,7 @@ type Service struct {
 	cm    *chatsManager
 	sm    *event.SubscriptionManager
 	cache *redisChatCache
+	sr    *sanction.Registry
 
 	pub event.Publisher
 	sub event.Subscriber
@@ -30,6 +32,7 @@ func NewService(cfg Config, pub event.Publisher, sub event.Subscriber, rc *redis
 		cfg:   cfg,
 		cm:    newChatsManager(),
 		cache: newRedisChatCache(cfg.InstanceID, rc),
+		sr:    sanction.NewRegistry(rc, sub, l),
 		pub:   pub,
 		sub:   sub,
 		l:     l,
@@ -61,4 +64,5 @@ func (s *Service) CreateGameChat(ctx context.Context, gameId types.ObjectId, pla
 
 func (s *Service) Stop() {
 	s.sm.Stop()
+	s.sr.Stop()
 }
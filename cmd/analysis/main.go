@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/alikarimi999/shahboard/analysisservice"
+	"github.com/alikarimi999/shahboard/pkg/utils"
+)
+
+func main() {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = "./deploy/analysis/development/config.json"
+	}
+
+	cfg := &analysisservice.Config{}
+	if err := utils.LoadConfigs(file, cfg); err != nil {
+		panic(err)
+	}
+
+	app, err := analysisservice.SetupApplication(*cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := app.Run(); err != nil {
+		panic(err)
+	}
+}
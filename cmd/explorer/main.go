@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/alikarimi999/shahboard/explorerservice"
+	"github.com/alikarimi999/shahboard/pkg/utils"
+)
+
+func main() {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = "./deploy/explorer/development/config.json"
+	}
+
+	cfg := &explorerservice.Config{}
+	if err := utils.LoadConfigs(file, cfg); err != nil {
+		panic(err)
+	}
+
+	app, err := explorerservice.SetupApplication(*cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := app.Run(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/alikarimi999/shahboard/notificationservice"
+	"github.com/alikarimi999/shahboard/pkg/utils"
+)
+
+func main() {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = "./deploy/notification/development/config.json"
+	}
+
+	cfg := &notificationservice.Config{}
+	if err := utils.LoadConfigs(file, cfg); err != nil {
+		panic(err)
+	}
+
+	app, err := notificationservice.SetupApplication(*cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := app.Run(); err != nil {
+		panic(err)
+	}
+}
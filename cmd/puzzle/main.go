@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"github.com/alikarimi999/shahboard/pkg/utils"
+	"github.com/alikarimi999/shahboard/puzzleservice"
+)
+
+func main() {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = "./deploy/puzzle/development/config.json"
+	}
+
+	cfg := &puzzleservice.Config{}
+	if err := utils.LoadConfigs(file, cfg); err != nil {
+		panic(err)
+	}
+
+	app, err := puzzleservice.SetupApplication(*cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := app.Run(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alikarimi999/shahboard/pkg/utils"
+	"github.com/alikarimi999/shahboard/puzzleservice"
+)
+
+// usage: puzzleimport <csv file>
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: puzzleimport <csv file>")
+		os.Exit(1)
+	}
+
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = "./deploy/puzzle/development/config.json"
+	}
+
+	cfg := &puzzleservice.Config{}
+	if err := utils.LoadConfigs(file, cfg); err != nil {
+		panic(err)
+	}
+
+	res, err := puzzleservice.ImportPuzzles(*cfg, os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("imported: %d, duplicated: %d, invalid: %d\n", res.Imported, res.Duplicated, res.Invalid)
+}
@@ -1,6 +1,10 @@
 package event
 
-import "github.com/alikarimi999/shahboard/types"
+import (
+	"encoding/json"
+
+	"github.com/alikarimi999/shahboard/types"
+)
 
 var (
 	TopicDirectChat            = NewTopic(DomainDirectChat, ActionAny)
@@ -24,3 +28,16 @@ func (e EventDirectChatMsgSent) GetResource() string {
 func (e EventDirectChatMsgSent) GetTopic() Topic {
 	return TopicDirectChatMsgSent.SetResource(e.GetResource())
 }
+
+func (e EventDirectChatMsgSent) GetAction() Action {
+	return ActionMsgSent
+}
+
+func (e EventDirectChatMsgSent) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventDirectChatMsgSent) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
@@ -3,11 +3,15 @@ package event
 type Domain string
 
 const (
-	DomainUser       = "user"
-	DomainGame       = "game"
-	DomainMatch      = "match"
-	DomainGameChat   = "game_chat"
-	DomainDirectChat = "direct_chat"
+	DomainUser         = "user"
+	DomainGame         = "game"
+	DomainMatch        = "match"
+	DomainGameChat     = "game_chat"
+	DomainDirectChat   = "direct_chat"
+	DomainFairPlay     = "fair_play"
+	DomainPresence     = "presence"
+	DomainRelation     = "relation"
+	DomainNotification = "notification"
 )
 
 func (d Domain) String() string {
@@ -0,0 +1,84 @@
+package event
+
+import (
+	"encoding/json"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const (
+	// an account is added to the fair play review queue
+	ActionFairPlayFlagged = "flagged"
+	// a moderator marked a game for rating refund
+	ActionFairPlayRatingRefund = "ratingRefund"
+)
+
+var (
+	TopicFairPlay             = NewTopic(DomainFairPlay, ActionAny)
+	TopicFairPlayFlagged      = NewTopic(DomainFairPlay, ActionFairPlayFlagged)
+	TopicFairPlayRatingRefund = NewTopic(DomainFairPlay, ActionFairPlayRatingRefund)
+)
+
+// EventFairPlayFlagged is published when the engine correlation of the user's games is above the thresholds.
+type EventFairPlayFlagged struct {
+	ID     types.ObjectId `json:"id"`
+	UserID types.ObjectId `json:"user_id"`
+	Games  int64          `json:"games"`
+	// rate of the moves that are the engine's top move, in the range of 0 to 1
+	TopMoveMatch float64 `json:"top_move_match"`
+	// average centipawn loss
+	ACPL      float64 `json:"acpl"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+func (e EventFairPlayFlagged) GetResource() string {
+	return e.UserID.String()
+}
+
+func (e EventFairPlayFlagged) GetTopic() Topic {
+	return TopicFairPlayFlagged.SetResource(e.GetResource())
+}
+
+func (e EventFairPlayFlagged) GetAction() Action {
+	return ActionFairPlayFlagged
+}
+
+func (e EventFairPlayFlagged) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventFairPlayFlagged) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EventFairPlayRatingRefund is published when a moderator marks a game that is played by a cheater,
+// the rating that the opponent lost in the game is given back.
+type EventFairPlayRatingRefund struct {
+	ID        types.ObjectId `json:"id"`
+	GameID    types.ObjectId `json:"game_id"`
+	CheaterID types.ObjectId `json:"cheater_id"`
+	IssuedBy  types.ObjectId `json:"issued_by"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+func (e EventFairPlayRatingRefund) GetResource() string {
+	return e.GameID.String()
+}
+
+func (e EventFairPlayRatingRefund) GetTopic() Topic {
+	return TopicFairPlayRatingRefund.SetResource(e.GetResource())
+}
+
+func (e EventFairPlayRatingRefund) GetAction() Action {
+	return ActionFairPlayRatingRefund
+}
+
+func (e EventFairPlayRatingRefund) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventFairPlayRatingRefund) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
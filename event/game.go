@@ -40,6 +40,7 @@ type EventGameCreated struct {
 	MatchID   types.ObjectId `json:"match_id"`
 	Player1   types.Player   `json:"player1"`
 	Player2   types.Player   `json:"player2"`
+	Variant   types.Variant  `json:"variant"`
 	Timestamp int64          `json:"timestamp"`
 }
 
@@ -152,14 +153,24 @@ func (e EventGamePlayerJoined) Encode() []byte {
 	return b
 }
 
+// EventGameEnded carries the PGN of the finished game, ECO and Opening are empty if the opening is unknown.
+// TimeControl is in seconds and FEN is the start position, empty for the start position of the variant.
 type EventGameEnded struct {
-	ID        types.ObjectId    `json:"id"`
-	GameID    types.ObjectId    `json:"game_id"`
-	Player1   types.Player      `json:"player1"`
-	Player2   types.Player      `json:"player2"`
-	Outcome   types.GameOutcome `json:"outcome"`
-	Desc      string            `json:"desc"`
-	Timestamp int64             `json:"timestamp"`
+	ID          types.ObjectId    `json:"id"`
+	GameID      types.ObjectId    `json:"game_id"`
+	Player1     types.Player      `json:"player1"`
+	Player2     types.Player      `json:"player2"`
+	Outcome     types.GameOutcome `json:"outcome"`
+	Desc        string            `json:"desc"`
+	Variant     types.Variant     `json:"variant"`
+	FEN         string            `json:"fen,omitempty"`
+	PGN         string            `json:"pgn"`
+	TimeControl int64             `json:"time_control"`
+	ECO         string            `json:"eco"`
+	Opening     string            `json:"opening"`
+	// the player that didn't make their first move in time, empty for other aborted games
+	AbortedBy types.ObjectId `json:"aborted_by,omitempty"`
+	Timestamp int64          `json:"timestamp"`
 }
 
 func (e EventGameEnded) GetResource() string {
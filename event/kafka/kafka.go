@@ -26,3 +26,13 @@ func NewKafkaPublisherAndSubscriber(cfg Config, l log.Logger) (event.Publisher,
 
 	return p, s, nil
 }
+
+// NewKafkaSubscriber creates a subscriber with its own consumer group,
+// the instances that share the group id consume each event once.
+func NewKafkaSubscriber(cfg Config, l log.Logger) (event.Subscriber, error) {
+	s, err := newKafkaSubscriber(cfg.Brokers, cfg.GroupID, l)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
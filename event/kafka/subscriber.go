@@ -123,7 +123,7 @@ func (ch *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, c
 			continue
 		}
 
-		e, err := decodeEvent(message.Topic, action, message.Value)
+		e, err := DecodeEvent(message.Topic, action, message.Value)
 		if err != nil {
 			ch.l.Error(err.Error())
 			continue
@@ -238,7 +238,9 @@ func (s *feedSub) unsubscribe() {
 	close(s.err)
 }
 
-func decodeEvent(domain, action string, data []byte) (event.Event, error) {
+// DecodeEvent decodes the event of the domain and action, it's also used by the services that
+// relay the consumed events to their instances.
+func DecodeEvent(domain, action string, data []byte) (event.Event, error) {
 	var e event.Event
 	switch domain {
 	case event.DomainGame:
@@ -287,6 +289,10 @@ func decodeEvent(domain, action string, data []byte) (event.Event, error) {
 		switch event.Action(action) {
 		case event.ActionCreated:
 			e = &event.EventUsersMatchCreated{}
+		case event.ActionRematchOffered:
+			e = &event.EventRematchOffered{}
+		case event.ActionRematchDeclined:
+			e = &event.EventRematchDeclined{}
 		default:
 			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
 		}
@@ -298,6 +304,62 @@ func decodeEvent(domain, action string, data []byte) (event.Event, error) {
 			e = &event.EventUserLoggedIn{}
 		case event.ActionLoggedOut:
 			e = &event.EventUserLoggedOut{}
+		case event.ActionGuestClaimed:
+			e = &event.EventUserGuestClaimed{}
+		case event.ActionSanctioned:
+			e = &event.EventUserSanctioned{}
+		case event.ActionSanctionLifted:
+			e = &event.EventUserSanctionLifted{}
+		default:
+			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
+		}
+
+	case event.DomainFairPlay:
+		switch event.Action(action) {
+		case event.ActionFairPlayFlagged:
+			e = &event.EventFairPlayFlagged{}
+		case event.ActionFairPlayRatingRefund:
+			e = &event.EventFairPlayRatingRefund{}
+		default:
+			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
+		}
+
+	case event.DomainPresence:
+		switch event.Action(action) {
+		case event.ActionPresenceChanged:
+			e = &event.EventUserPresenceChanged{}
+		default:
+			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
+		}
+
+	case event.DomainRelation:
+		switch event.Action(action) {
+		case event.ActionFollowed:
+			e = &event.EventUserFollowed{}
+		case event.ActionUnfollowed:
+			e = &event.EventUserUnfollowed{}
+		case event.ActionBlocked:
+			e = &event.EventUserBlocked{}
+		case event.ActionUnblocked:
+			e = &event.EventUserUnblocked{}
+		case event.ActionFollowingGameStarted:
+			e = &event.EventFollowingGameStarted{}
+		default:
+			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
+		}
+
+	case event.DomainDirectChat:
+		switch event.Action(action) {
+		case event.ActionMsgSent:
+			e = &event.EventDirectChatMsgSent{}
+		default:
+			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
+		}
+
+	case event.DomainNotification:
+		switch event.Action(action) {
+		case event.ActionCreated:
+			e = &event.EventNotificationCreated{}
 		default:
 			return nil, fmt.Errorf("unknown event type for topic: %s.%s", domain, action)
 		}
@@ -6,15 +6,33 @@ import (
 	"github.com/alikarimi999/shahboard/types"
 )
 
+const (
+	ActionRematchOffered  Action = "rematchOffered"
+	ActionRematchDeclined Action = "rematchDeclined"
+)
+
 var (
+	TopicMatch               = NewTopic(DomainMatch, ActionAny)
 	TopicUsersMatchedCreated = NewTopic(DomainMatch, ActionCreated)
+	TopicRematchOffered      = NewTopic(DomainMatch, ActionRematchOffered)
+	TopicRematchDeclined     = NewTopic(DomainMatch, ActionRematchDeclined)
 )
 
 type EventUsersMatchCreated struct {
-	ID        types.ObjectId `json:"id"`
-	User1     types.User     `json:"user1"`
-	User2     types.User     `json:"user2"`
-	Timestamp int64          `json:"timestamp"`
+	ID      types.ObjectId `json:"id"`
+	User1   types.User     `json:"user1"`
+	User2   types.User     `json:"user2"`
+	Variant types.Variant  `json:"variant"`
+	// FEN is the start position that the players queued for, empty means the start position of the variant
+	FEN string `json:"fen,omitempty"`
+
+	// RematchOf is the game that the players agreed to play again, the rematch keeps
+	// the time control of the game and White is the player that plays white.
+	RematchOf   types.ObjectId `json:"rematch_of,omitempty"`
+	White       types.ObjectId `json:"white,omitempty"`
+	TimeControl int64          `json:"time_control,omitempty"`
+
+	Timestamp int64 `json:"timestamp"`
 }
 
 func (e EventUsersMatchCreated) GetTopic() Topic {
@@ -33,3 +51,66 @@ func (e EventUsersMatchCreated) Encode() []byte {
 	b, _ := json.Marshal(e)
 	return b
 }
+
+// EventRematchOffered is published when a player offers a rematch of an ended game to the opponent.
+type EventRematchOffered struct {
+	ID     types.ObjectId `json:"id"`
+	GameID types.ObjectId `json:"game_id"`
+	From   types.ObjectId `json:"from"`
+	To     types.ObjectId `json:"to"`
+	// the offer can be accepted until this time, unix seconds
+	ExpiresAt int64 `json:"expires_at"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+func (e EventRematchOffered) GetResource() string {
+	return e.GameID.String()
+}
+
+func (e EventRematchOffered) GetTopic() Topic {
+	return TopicRematchOffered.SetResource(e.GetResource())
+}
+
+func (e EventRematchOffered) GetAction() Action {
+	return ActionRematchOffered
+}
+
+func (e EventRematchOffered) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventRematchOffered) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EventRematchDeclined is published when a player declines the opponent's rematch offer
+// or withdraws their own offer.
+type EventRematchDeclined struct {
+	ID        types.ObjectId `json:"id"`
+	GameID    types.ObjectId `json:"game_id"`
+	By        types.ObjectId `json:"by"`
+	To        types.ObjectId `json:"to"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+func (e EventRematchDeclined) GetResource() string {
+	return e.GameID.String()
+}
+
+func (e EventRematchDeclined) GetTopic() Topic {
+	return TopicRematchDeclined.SetResource(e.GetResource())
+}
+
+func (e EventRematchDeclined) GetAction() Action {
+	return ActionRematchDeclined
+}
+
+func (e EventRematchDeclined) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventRematchDeclined) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
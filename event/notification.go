@@ -0,0 +1,46 @@
+package event
+
+import (
+	"encoding/json"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	TopicNotification        = NewTopic(DomainNotification, ActionAny)
+	TopicNotificationCreated = NewTopic(DomainNotification, ActionCreated)
+)
+
+// EventNotificationCreated is published when a notification is added to the inbox of a user that is online,
+// WS Gateway delivers it to the sessions of the user.
+type EventNotificationCreated struct {
+	ID             types.ObjectId  `json:"id"`
+	NotificationID types.ObjectId  `json:"notification_id"`
+	UserID         types.ObjectId  `json:"user_id"`
+	Kind           string          `json:"kind"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Data           json.RawMessage `json:"data,omitempty"`
+	Timestamp      int64           `json:"timestamp"`
+}
+
+func (e EventNotificationCreated) GetResource() string {
+	return e.UserID.String()
+}
+
+func (e EventNotificationCreated) GetTopic() Topic {
+	return TopicNotificationCreated.SetResource(e.GetResource())
+}
+
+func (e EventNotificationCreated) GetAction() Action {
+	return ActionCreated
+}
+
+func (e EventNotificationCreated) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventNotificationCreated) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
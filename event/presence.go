@@ -0,0 +1,47 @@
+package event
+
+import (
+	"encoding/json"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const (
+	ActionPresenceChanged = "changed"
+)
+
+var (
+	TopicPresence        = NewTopic(DomainPresence, ActionAny)
+	TopicPresenceChanged = NewTopic(DomainPresence, ActionPresenceChanged)
+)
+
+// EventUserPresenceChanged is published when the presence of the user across all of its sessions changes,
+// GameID is the game that the user plays if the status is in game.
+type EventUserPresenceChanged struct {
+	ID        types.ObjectId       `json:"id"`
+	UserID    types.ObjectId       `json:"user_id"`
+	Status    types.PresenceStatus `json:"status"`
+	GameID    types.ObjectId       `json:"game_id,omitempty"`
+	Timestamp int64                `json:"timestamp"`
+}
+
+func (e EventUserPresenceChanged) GetResource() string {
+	return e.UserID.String()
+}
+
+func (e EventUserPresenceChanged) GetTopic() Topic {
+	return TopicPresenceChanged.SetResource(e.GetResource())
+}
+
+func (e EventUserPresenceChanged) GetAction() Action {
+	return ActionPresenceChanged
+}
+
+func (e EventUserPresenceChanged) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserPresenceChanged) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
@@ -0,0 +1,171 @@
+package event
+
+import (
+	"encoding/json"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const (
+	ActionFollowed   = "followed"
+	ActionUnfollowed = "unfollowed"
+	ActionBlocked    = "blocked"
+	ActionUnblocked  = "unblocked"
+	// a player that is followed by the users started a game
+	ActionFollowingGameStarted = "followingGameStarted"
+)
+
+var (
+	TopicRelation             = NewTopic(DomainRelation, ActionAny)
+	TopicUserFollowed         = NewTopic(DomainRelation, ActionFollowed)
+	TopicUserUnfollowed       = NewTopic(DomainRelation, ActionUnfollowed)
+	TopicUserBlocked          = NewTopic(DomainRelation, ActionBlocked)
+	TopicUserUnblocked        = NewTopic(DomainRelation, ActionUnblocked)
+	TopicFollowingGameStarted = NewTopic(DomainRelation, ActionFollowingGameStarted)
+)
+
+type EventUserFollowed struct {
+	ID         types.ObjectId `json:"id"`
+	FollowerID types.ObjectId `json:"follower_id"`
+	FolloweeID types.ObjectId `json:"followee_id"`
+	Timestamp  int64          `json:"timestamp"`
+}
+
+func (e EventUserFollowed) GetResource() string {
+	return e.FollowerID.String()
+}
+
+func (e EventUserFollowed) GetTopic() Topic {
+	return TopicUserFollowed.SetResource(e.GetResource())
+}
+
+func (e EventUserFollowed) GetAction() Action {
+	return ActionFollowed
+}
+
+func (e EventUserFollowed) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserFollowed) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+type EventUserUnfollowed struct {
+	ID         types.ObjectId `json:"id"`
+	FollowerID types.ObjectId `json:"follower_id"`
+	FolloweeID types.ObjectId `json:"followee_id"`
+	Timestamp  int64          `json:"timestamp"`
+}
+
+func (e EventUserUnfollowed) GetResource() string {
+	return e.FollowerID.String()
+}
+
+func (e EventUserUnfollowed) GetTopic() Topic {
+	return TopicUserUnfollowed.SetResource(e.GetResource())
+}
+
+func (e EventUserUnfollowed) GetAction() Action {
+	return ActionUnfollowed
+}
+
+func (e EventUserUnfollowed) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserUnfollowed) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EventUserBlocked is published when a user blocks another user, the follows between them are removed
+// and they can't challenge, message or be paired with each other.
+type EventUserBlocked struct {
+	ID        types.ObjectId `json:"id"`
+	BlockerID types.ObjectId `json:"blocker_id"`
+	BlockedID types.ObjectId `json:"blocked_id"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+func (e EventUserBlocked) GetResource() string {
+	return e.BlockerID.String()
+}
+
+func (e EventUserBlocked) GetTopic() Topic {
+	return TopicUserBlocked.SetResource(e.GetResource())
+}
+
+func (e EventUserBlocked) GetAction() Action {
+	return ActionBlocked
+}
+
+func (e EventUserBlocked) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserBlocked) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+type EventUserUnblocked struct {
+	ID        types.ObjectId `json:"id"`
+	BlockerID types.ObjectId `json:"blocker_id"`
+	BlockedID types.ObjectId `json:"blocked_id"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+func (e EventUserUnblocked) GetResource() string {
+	return e.BlockerID.String()
+}
+
+func (e EventUserUnblocked) GetTopic() Topic {
+	return TopicUserUnblocked.SetResource(e.GetResource())
+}
+
+func (e EventUserUnblocked) GetAction() Action {
+	return ActionUnblocked
+}
+
+func (e EventUserUnblocked) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserUnblocked) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EventFollowingGameStarted is published when a player starts a game, Followers are the users that
+// follow the player. The followers of a player with many followers are split between several events.
+type EventFollowingGameStarted struct {
+	ID        types.ObjectId   `json:"id"`
+	GameID    types.ObjectId   `json:"game_id"`
+	PlayerID  types.ObjectId   `json:"player_id"`
+	Variant   types.Variant    `json:"variant"`
+	Followers []types.ObjectId `json:"followers"`
+	Timestamp int64            `json:"timestamp"`
+}
+
+func (e EventFollowingGameStarted) GetResource() string {
+	return e.GameID.String()
+}
+
+func (e EventFollowingGameStarted) GetTopic() Topic {
+	return TopicFollowingGameStarted.SetResource(e.GetResource())
+}
+
+func (e EventFollowingGameStarted) GetAction() Action {
+	return ActionFollowingGameStarted
+}
+
+func (e EventFollowingGameStarted) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventFollowingGameStarted) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
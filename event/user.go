@@ -9,15 +9,22 @@ import (
 const (
 	ActionLoggedIn  = "loggedIn"
 	ActionLoggedOut = "loggedOut"
+	// a guest account is upgraded into a registered account
+	ActionGuestClaimed   = "guestClaimed"
+	ActionSanctioned     = "sanctioned"
+	ActionSanctionLifted = "sanctionLifted"
 )
 
 var (
-	TopicUser          = NewTopic(DomainUser, ActionAny)
-	TopicUserCreated   = NewTopic(DomainUser, ActionCreated)
-	TopicUserUpdated   = NewTopic(DomainUser, ActionUpdated)
-	TopicUserDeleted   = NewTopic(DomainUser, ActionDeleted)
-	TopicUserLoggedIn  = NewTopic(DomainUser, ActionLoggedIn)
-	TopicUserLoggedOut = NewTopic(DomainUser, ActionLoggedOut)
+	TopicUser               = NewTopic(DomainUser, ActionAny)
+	TopicUserCreated        = NewTopic(DomainUser, ActionCreated)
+	TopicUserUpdated        = NewTopic(DomainUser, ActionUpdated)
+	TopicUserDeleted        = NewTopic(DomainUser, ActionDeleted)
+	TopicUserLoggedIn       = NewTopic(DomainUser, ActionLoggedIn)
+	TopicUserLoggedOut      = NewTopic(DomainUser, ActionLoggedOut)
+	TopicUserGuestClaimed   = NewTopic(DomainUser, ActionGuestClaimed)
+	TopicUserSanctioned     = NewTopic(DomainUser, ActionSanctioned)
+	TopicUserSanctionLifted = NewTopic(DomainUser, ActionSanctionLifted)
 )
 
 type EventUserCreated struct {
@@ -77,9 +84,12 @@ func (e EventUserLoggedIn) Encode() []byte {
 	return b
 }
 
+// EventUserLoggedOut is published when a login session of the user is revoked,
+// all connections that are authenticated by the session must be closed.
 type EventUserLoggedOut struct {
 	ID        types.ObjectId `json:"id"`
 	UserID    types.ObjectId `json:"user_id"`
+	SessionID types.ObjectId `json:"session_id"`
 	Email     string         `json:"email"`
 	Timestamp int64          `json:"timestamp"`
 }
@@ -103,3 +113,100 @@ func (e EventUserLoggedOut) Encode() []byte {
 	b, _ := json.Marshal(e)
 	return b
 }
+
+// EventUserGuestClaimed is published when a guest signs up, the registered account keeps
+// the id of the guest, so everything the guest did belongs to the new account.
+type EventUserGuestClaimed struct {
+	ID        types.ObjectId `json:"id"`
+	UserID    types.ObjectId `json:"user_id"`
+	Email     string         `json:"email"`
+	Name      string         `json:"name"`
+	Picture   string         `json:"picture"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+func (e EventUserGuestClaimed) GetResource() string {
+	return e.UserID.String()
+}
+
+func (e EventUserGuestClaimed) GetTopic() Topic {
+	return TopicUserGuestClaimed.SetResource(e.GetResource())
+}
+
+func (e EventUserGuestClaimed) GetAction() Action {
+	return ActionGuestClaimed
+}
+
+func (e EventUserGuestClaimed) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserGuestClaimed) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EventUserSanctioned is published when a moderator applies a sanction to the user,
+// ExpiresAt is zero for the permanent sanctions.
+type EventUserSanctioned struct {
+	ID         types.ObjectId     `json:"id"`
+	SanctionID types.ObjectId     `json:"sanction_id"`
+	UserID     types.ObjectId     `json:"user_id"`
+	Type       types.SanctionType `json:"type"`
+	Reason     string             `json:"reason"`
+	IssuedBy   types.ObjectId     `json:"issued_by"`
+	ExpiresAt  int64              `json:"expires_at"`
+	Timestamp  int64              `json:"timestamp"`
+}
+
+func (e EventUserSanctioned) GetResource() string {
+	return e.UserID.String()
+}
+
+func (e EventUserSanctioned) GetTopic() Topic {
+	return TopicUserSanctioned.SetResource(e.GetResource())
+}
+
+func (e EventUserSanctioned) GetAction() Action {
+	return ActionSanctioned
+}
+
+func (e EventUserSanctioned) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserSanctioned) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EventUserSanctionLifted is published when a sanction is lifted before it expires.
+type EventUserSanctionLifted struct {
+	ID         types.ObjectId     `json:"id"`
+	SanctionID types.ObjectId     `json:"sanction_id"`
+	UserID     types.ObjectId     `json:"user_id"`
+	Type       types.SanctionType `json:"type"`
+	LiftedBy   types.ObjectId     `json:"lifted_by"`
+	Timestamp  int64              `json:"timestamp"`
+}
+
+func (e EventUserSanctionLifted) GetResource() string {
+	return e.UserID.String()
+}
+
+func (e EventUserSanctionLifted) GetTopic() Topic {
+	return TopicUserSanctionLifted.SetResource(e.GetResource())
+}
+
+func (e EventUserSanctionLifted) GetAction() Action {
+	return ActionSanctionLifted
+}
+
+func (e EventUserSanctionLifted) TimeStamp() int64 {
+	return e.Timestamp
+}
+
+func (e EventUserSanctionLifted) Encode() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
@@ -0,0 +1,56 @@
+package explorerservice
+
+import (
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/explorerservice/delivery/http"
+	"github.com/alikarimi999/shahboard/explorerservice/repository"
+	explorer "github.com/alikarimi999/shahboard/explorerservice/service"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+	"github.com/redis/go-redis/v9"
+)
+
+type application struct {
+	*explorer.Service
+	*http.Router
+}
+
+func SetupApplication(cfg Config) (*application, error) {
+	l := log.NewLogger(cfg.Log.File, cfg.Log.Verbose)
+
+	v, err := jwt.NewValidator(cfg.JwtValidator)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	v.WithDenylist(jwt.NewRedisDenylist(rc))
+
+	_, sub, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := postgres.Setup(cfg.ExplorerDB)
+	if err != nil {
+		return nil, err
+	}
+
+	s := explorer.NewService(cfg.Explorer, repository.NewExplorerRepo(db, l), sub, l)
+
+	r, err := http.NewRouter(cfg.Http, s, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &application{Service: s, Router: r}, nil
+}
+
+func (a *application) Run() error {
+	return a.Router.Run()
+}
@@ -0,0 +1,30 @@
+package explorerservice
+
+import (
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/explorerservice/delivery/http"
+	explorer "github.com/alikarimi999/shahboard/explorerservice/service"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+)
+
+type Config struct {
+	Explorer     explorer.Config     `json:"explorer_service"`
+	Http         http.Config         `json:"http"`
+	Kafka        kafka.Config        `json:"kafka"`
+	Log          LogConfig           `json:"log"`
+	JwtValidator jwt.ValidatorConfig `json:"jwt_validator"`
+	ExplorerDB   postgres.Config     `json:"explorer_db"`
+	Redis        RedisConfig         `json:"redis"`
+}
+
+type LogConfig struct {
+	File    string `json:"file"`
+	Verbose bool   `json:"verbose"`
+}
+
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
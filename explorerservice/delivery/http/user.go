@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	explorer "github.com/alikarimi999/shahboard/explorerservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) setupUserRoutes() {
+	r.gin.GET("/moves", r.explore)
+}
+
+// explore accepts the fen query and multiple level and time_control queries,
+// e.g. /moves?fen=...&level=knight&level=bishop&time_control=300
+func (r *Router) explore(c *gin.Context) {
+	req := explorer.ExploreRequest{FEN: c.Query("fen")}
+
+	for _, s := range c.QueryArray("level") {
+		l, err := types.ParseLevel(s)
+		if err != nil {
+			c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+			return
+		}
+		req.Levels = append(req.Levels, l)
+	}
+
+	for _, s := range c.QueryArray("time_control") {
+		tc, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid time control"))
+			return
+		}
+		req.TimeControls = append(req.TimeControls, tc)
+	}
+
+	res, err := r.s.Explore(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// Game is a finished game that is indexed by the explorer.
+type Game struct {
+	GameId      types.ObjectId
+	WhiteId     types.ObjectId
+	BlackId     types.ObjectId
+	WhiteRating int64
+	BlackRating int64
+	// rating band of the average rating of the players
+	Level types.Level
+	// seconds
+	TimeControl int64
+	ECO         string
+	Opening     string
+	Outcome     types.GameOutcome
+	Plies       int
+	EndedAt     time.Time
+}
+
+func (g *Game) AverageRating() int64 {
+	return (g.WhiteRating + g.BlackRating) / 2
+}
+
+// PositionMove is a move that is played in a position of a game.
+type PositionMove struct {
+	// zobrist hash of the position
+	Hash uint64
+	// UCI notation
+	Move string
+	SAN  string
+}
+
+// MoveStats is the statistics of a move in a position over the indexed games.
+type MoveStats struct {
+	Move      string
+	SAN       string
+	Games     int64
+	WhiteWins int64
+	Draws     int64
+	BlackWins int64
+	RatingSum int64
+}
+
+// MoveFilter filters the statistics by rating band and time control, empty slices match everything.
+type MoveFilter struct {
+	Levels       []types.Level
+	TimeControls []int64
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/explorerservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/lib/pq"
+)
+
+type explorerRepo struct {
+	db *sql.DB
+	l  log.Logger
+}
+
+func NewExplorerRepo(db *sql.DB, l log.Logger) *explorerRepo {
+	return &explorerRepo{
+		db: db,
+		l:  l,
+	}
+}
+
+// AddGame stores the game and adds its moves to the statistics atomically.
+// It returns false if the game is already indexed.
+func (r *explorerRepo) AddGame(ctx context.Context, g *entity.Game, moves []entity.PositionMove) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO explorer_games (game_id, white_id, black_id, white_rating, black_rating, level, time_control,
+			eco, opening, outcome, plies, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (game_id) DO NOTHING
+	`
+	res, err := tx.ExecContext(ctx, query, g.GameId.String(), g.WhiteId.String(), g.BlackId.String(), g.WhiteRating,
+		g.BlackRating, g.Level, g.TimeControl, g.ECO, g.Opening, g.Outcome.String(), g.Plies, g.EndedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert game %s: %w", g.GameId, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	var white, draw, black int64
+	switch g.Outcome {
+	case types.WhiteWon:
+		white = 1
+	case types.BlackWon:
+		black = 1
+	default:
+		draw = 1
+	}
+
+	for _, m := range moves {
+		query := `
+			INSERT INTO explorer_moves (hash, level, time_control, move, san, games, white_wins, draws, black_wins, rating_sum)
+			VALUES ($1, $2, $3, $4, $5, 1, $6, $7, $8, $9) ON CONFLICT (hash, level, time_control, move)
+			DO UPDATE SET
+			games = explorer_moves.games + 1,
+			white_wins = explorer_moves.white_wins + EXCLUDED.white_wins,
+			draws = explorer_moves.draws + EXCLUDED.draws,
+			black_wins = explorer_moves.black_wins + EXCLUDED.black_wins,
+			rating_sum = explorer_moves.rating_sum + EXCLUDED.rating_sum
+		`
+		_, err := tx.ExecContext(ctx, query, int64(m.Hash), g.Level, g.TimeControl, m.Move, m.SAN,
+			white, draw, black, g.AverageRating())
+		if err != nil {
+			return false, fmt.Errorf("failed to update move %s of game %s: %w", m.SAN, g.GameId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetMoveStats returns the statistics of the moves that are played in the position, the most played move is the first one.
+func (r *explorerRepo) GetMoveStats(ctx context.Context, hash uint64, f entity.MoveFilter) ([]*entity.MoveStats, error) {
+	query := `SELECT move, san, SUM(games), SUM(white_wins), SUM(draws), SUM(black_wins), SUM(rating_sum)
+		FROM explorer_moves WHERE hash = $1`
+	args := []interface{}{int64(hash)}
+
+	if len(f.Levels) > 0 {
+		levels := make([]int64, 0, len(f.Levels))
+		for _, l := range f.Levels {
+			levels = append(levels, int64(l))
+		}
+		args = append(args, pq.Array(levels))
+		query += fmt.Sprintf(" AND level = ANY($%d)", len(args))
+	}
+
+	if len(f.TimeControls) > 0 {
+		args = append(args, pq.Array(f.TimeControls))
+		query += fmt.Sprintf(" AND time_control = ANY($%d)", len(args))
+	}
+
+	query += " GROUP BY move, san ORDER BY SUM(games) DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*entity.MoveStats{}
+	for rows.Next() {
+		var s entity.MoveStats
+		if err := rows.Scan(&s.Move, &s.SAN, &s.Games, &s.WhiteWins, &s.Draws, &s.BlackWins, &s.RatingSum); err != nil {
+			r.l.Error(fmt.Sprintf("failed to scan row: %v", err))
+			continue
+		}
+		stats = append(stats, &s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	return stats, nil
+}
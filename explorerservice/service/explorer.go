@@ -0,0 +1,193 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/explorerservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/eco"
+	"github.com/alikarimi999/shahboard/pkg/elo"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/zobrist"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+const defaultMaxPly = 30
+
+type Repository interface {
+	// AddGame returns false if the game is already added
+	AddGame(ctx context.Context, g *entity.Game, moves []entity.PositionMove) (bool, error)
+	GetMoveStats(ctx context.Context, hash uint64, f entity.MoveFilter) ([]*entity.MoveStats, error)
+}
+
+type Config struct {
+	// MaxPly is the number of half moves of each game that are indexed, default is 30.
+	MaxPly int `json:"max_ply"`
+}
+
+type Service struct {
+	cfg  Config
+	repo Repository
+	sub  event.Subscriber
+	sm   *event.SubscriptionManager
+	l    log.Logger
+}
+
+func NewService(cfg Config, repo Repository, sub event.Subscriber, l log.Logger) *Service {
+	if cfg.MaxPly <= 0 {
+		cfg.MaxPly = defaultMaxPly
+	}
+
+	s := &Service{
+		cfg:  cfg,
+		repo: repo,
+		sub:  sub,
+		l:    l,
+	}
+
+	go eco.Load()
+
+	// only the ended games are indexed, by their final PGN, the redelivered events are skipped by the repository
+	s.sm = event.NewManager(l, s.handleEvent)
+	s.sm.AddSubscription(s.sub.Subscribe(event.TopicGameEnded))
+
+	return s
+}
+
+// Explore returns the statistics of the next moves of the position.
+// An empty FEN means the standard starting position.
+func (s *Service) Explore(ctx context.Context, req ExploreRequest) (ExploreResponse, error) {
+	pos := chess.NewGame().Position()
+	if req.FEN != "" {
+		f, err := chess.FEN(req.FEN)
+		if err != nil {
+			return ExploreResponse{}, fmt.Errorf("invalid fen: %v", err)
+		}
+		pos = chess.NewGame(f).Position()
+	}
+
+	stats, err := s.repo.GetMoveStats(ctx, zobrist.Hash(pos), entity.MoveFilter{
+		Levels:       req.Levels,
+		TimeControls: req.TimeControls,
+	})
+	if err != nil {
+		s.l.Error(err.Error())
+		return ExploreResponse{}, err
+	}
+
+	res := ExploreResponse{FEN: pos.String(), Moves: []MoveStats{}}
+	if o, ok := eco.FindPosition(pos); ok {
+		res.Opening = &o
+	}
+
+	for _, st := range stats {
+		res.Games += st.Games
+		res.Moves = append(res.Moves, MoveStats{
+			UCI:           st.Move,
+			SAN:           st.SAN,
+			Games:         st.Games,
+			White:         percent(st.WhiteWins, st.Games),
+			Draw:          percent(st.Draws, st.Games),
+			Black:         percent(st.BlackWins, st.Games),
+			AverageRating: st.RatingSum / st.Games,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Service) handleEvent(e event.Event) {
+	switch e.GetTopic().Domain() {
+	case event.DomainGame:
+		switch e.GetTopic().Action() {
+		case event.ActionEnded:
+			s.handleGameEnded(e.(*event.EventGameEnded))
+		}
+	}
+}
+
+func (s *Service) handleGameEnded(e *event.EventGameEnded) {
+	// games that are ended by leaving without a result and chess960 games are not indexed,
+	// events that are published before the PGN was added to them don't have one
+	if e.Variant.Normalize() != types.VariantStandard || !e.Outcome.IsResult() || e.PGN == "" {
+		return
+	}
+
+	g, moves, err := s.parseGame(e)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("failed to parse game '%s': %v", e.GameID, err))
+		return
+	}
+	if g == nil {
+		return
+	}
+
+	added, err := s.repo.AddGame(context.Background(), g, moves)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+
+	if added {
+		s.l.Debug(fmt.Sprintf("game '%s' added to the explorer", e.GameID))
+	}
+}
+
+// parseGame returns nil if the game doesn't start from the standard position.
+func (s *Service) parseGame(e *event.EventGameEnded) (*entity.Game, []entity.PositionMove, error) {
+	f, err := chess.PGN(strings.NewReader(e.PGN))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cg := chess.NewGame(f)
+	if tp := cg.GetTagPair("FEN"); tp != nil {
+		return nil, nil, nil
+	}
+
+	g := &entity.Game{
+		GameId:      e.GameID,
+		TimeControl: e.TimeControl,
+		ECO:         e.ECO,
+		Opening:     e.Opening,
+		Outcome:     e.Outcome,
+		Plies:       len(cg.Moves()),
+		EndedAt:     time.Unix(e.Timestamp, 0),
+	}
+
+	for _, p := range []types.Player{e.Player1, e.Player2} {
+		if p.Color == types.ColorWhite {
+			g.WhiteId, g.WhiteRating = p.ID, p.Score
+		} else {
+			g.BlackId, g.BlackRating = p.ID, p.Score
+		}
+	}
+	g.Level = elo.GetPlayerLevel(g.AverageRating())
+
+	positions := cg.Positions()
+	moves := []entity.PositionMove{}
+	for i, m := range cg.Moves() {
+		if i >= s.cfg.MaxPly {
+			break
+		}
+
+		moves = append(moves, entity.PositionMove{
+			Hash: zobrist.Hash(positions[i]),
+			Move: m.String(),
+			SAN:  chess.AlgebraicNotation{}.Encode(positions[i], m),
+		})
+	}
+
+	return g, moves, nil
+}
+
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n*1000/total) / 10
+}
@@ -0,0 +1,32 @@
+package explorer
+
+import (
+	"github.com/alikarimi999/shahboard/pkg/eco"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// ExploreRequest filters the games by the rating band of the players and the time control in seconds,
+// empty filters match all games.
+type ExploreRequest struct {
+	FEN          string
+	Levels       []types.Level
+	TimeControls []int64
+}
+
+type MoveStats struct {
+	UCI   string `json:"uci"`
+	SAN   string `json:"san"`
+	Games int64  `json:"games"`
+	// percentages of the results
+	White         float64 `json:"white"`
+	Draw          float64 `json:"draw"`
+	Black         float64 `json:"black"`
+	AverageRating int64   `json:"average_rating"`
+}
+
+type ExploreResponse struct {
+	FEN     string       `json:"fen"`
+	Opening *eco.Opening `json:"opening,omitempty"`
+	Games   int64        `json:"games"`
+	Moves   []MoveStats  `json:"moves"`
+}
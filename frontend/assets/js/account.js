@@ -0,0 +1,83 @@
+import { config } from './config.js';
+
+// token returns the token of the link which the auth service has emailed.
+function token() {
+    return new URLSearchParams(window.location.search).get("token");
+}
+
+function setStatus(msg, isError = false) {
+    const status = document.getElementById("status");
+    status.textContent = msg;
+    status.classList.toggle("error", isError);
+}
+
+async function post(path, body) {
+    const response = await fetch(`${config.baseUrl}/auth${path}`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(body),
+    });
+
+    if (!response.ok) {
+        const data = await response.json().catch(() => ({}));
+        throw new Error(data.error || "Something went wrong, please try again.");
+    }
+}
+
+async function verifyEmail() {
+    const t = token();
+    if (!t) {
+        setStatus("The verification link is invalid.", true);
+        return;
+    }
+
+    setStatus("Verifying your email...");
+    try {
+        await post("/verify-email", { token: t });
+        setStatus("Your email is verified, you can close this page.");
+    } catch (error) {
+        setStatus(error.message, true);
+    }
+}
+
+function resetPassword() {
+    const form = document.getElementById("reset-form");
+    const t = token();
+    if (!t) {
+        form.style.display = "none";
+        setStatus("The reset link is invalid.", true);
+        return;
+    }
+
+    form.addEventListener("submit", async (e) => {
+        e.preventDefault();
+
+        const password = document.getElementById("password").value;
+        if (password !== document.getElementById("confirm-password").value) {
+            setStatus("The passwords don't match.", true);
+            return;
+        }
+
+        const button = form.querySelector("button");
+        button.disabled = true;
+        try {
+            await post("/password-reset/confirm", { token: t, password: password });
+            form.style.display = "none";
+            setStatus("Your password is changed, you can sign in with it now.");
+        } catch (error) {
+            setStatus(error.message, true);
+            button.disabled = false;
+        }
+    });
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+    switch (document.body.dataset.page) {
+        case "verify-email":
+            verifyEmail();
+            break;
+        case "reset-password":
+            resetPassword();
+            break;
+    }
+});
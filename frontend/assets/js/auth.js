@@ -1,6 +1,7 @@
 
 import { user } from './user.js';
 import { config } from './config.js';
+import { revokeSession } from './session.js';
 
 // Function to dynamically load Google Sign-In SDK
 function loadGoogleSDK() {
@@ -130,6 +131,7 @@ function parseJwt(token) {
 
 // Function to log out
 export function logout() {
+    revokeSession();
     user.clean();
     localStorage.removeItem("user"); // Clear stored data
     const popupMessage = document.getElementById("popup-message");
@@ -8,6 +8,7 @@ const GameOutcome = Object.freeze({
     WhiteWon: "1-0",
     BlackWon: "0-1",
     Draw: "1/2-1/2",
+    Aborted: "aborted",
 });
 
 export function handleGameCreated(base64Data) {
@@ -123,6 +124,7 @@ export function handleGameEnded(base64Data) {
         [GameOutcome.WhiteWon]: currentGame.color === ColorWhite ? "win" : "lose",
         [GameOutcome.BlackWon]: currentGame.color === ColorBlack ? "win" : "lose",
         [GameOutcome.Draw]: "draw",
+        [GameOutcome.Aborted]: "aborted",
     };
 
     const result = eventMap[gameData.outcome];
@@ -144,6 +146,8 @@ export function handleGameEnded(base64Data) {
 export function handleResumeGame(base64Data) {
     const gameData = JSON.parse(atob(base64Data));
     if (gameData.game_id !== currentGame.gameId) return;
+    // resumed from the last received message, the missed messages are replayed
+    if (!gameData.pgn) return;
     const event = new CustomEvent("pgn_received", {
         detail: {
             gameId: gameData.game_id,
@@ -158,6 +162,8 @@ export function handleResumeGame(base64Data) {
 export function handleViewGame(base64Data) {
     const gameData = JSON.parse(atob(base64Data));
     if (gameData.game_id !== currentGame.gameId) return;
+    // resumed from the last received message, the missed messages are replayed
+    if (!gameData.pgn) return;
     const event = new CustomEvent("pgn_received", {
         detail: {
             gameId: gameData.game_id,
@@ -184,9 +190,45 @@ export function handleViewersList(base64Data) {
 
 export function handleError(base64Data) {
     try {
-        showErrorMessage(atob(base64Data));
+        const text = atob(base64Data);
+        let message = text;
+        try {
+            // the errors are {code, message}
+            const err = JSON.parse(text);
+            if (err && err.message) {
+                message = err.message;
+            }
+        } catch (_) { }
+        showErrorMessage(message);
     } catch (e) {
         console.error("Error decoding error message:", e);
     }
 
 }
+
+export function handleRematchOffered(base64Data) {
+    const data = JSON.parse(atob(base64Data));
+    if (data.game_id !== currentGame.gameId) return;
+
+    document.dispatchEvent(new CustomEvent("rematch_offered", {
+        detail: { gameId: data.game_id, byOpponent: data.from !== user.id },
+    }));
+}
+
+export function handleRematchDeclined(base64Data) {
+    const data = JSON.parse(atob(base64Data));
+    if (data.game_id !== currentGame.gameId) return;
+
+    document.dispatchEvent(new CustomEvent("rematch_declined", {
+        detail: { gameId: data.game_id, byOpponent: data.by !== user.id },
+    }));
+}
+
+export function handleRematchAccepted(base64Data) {
+    const data = JSON.parse(atob(base64Data));
+    if (data.rematch_of !== currentGame.gameId) return;
+
+    // the game_created message of the rematch belongs to this match
+    currentGame.matchId = data.id;
+    document.dispatchEvent(new Event("rematch_accepted"));
+}
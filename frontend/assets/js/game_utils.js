@@ -1,13 +1,13 @@
 import { user } from './user.js'
 import { config } from './config.js'
+import { authFetch } from './session.js';
 
 export async function getUserLiveGameId() {
     try {
         const apiUrl = `${config.baseUrl}/game/live/user/${user.id}`;
-        const response = await fetch(apiUrl, {
+        const response = await authFetch(apiUrl, {
             method: 'GET',
             headers: {
-                "Authorization": `Bearer ${user.jwt_token}`,
                 'Content-Type': 'application/json',
             },
         });
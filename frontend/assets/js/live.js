@@ -1,7 +1,7 @@
 import { getUserProfile } from "./user_info.js";
 import { showProfileSummary } from "./profile-summary.js";
-import { user } from "./user.js";
 import { config } from "./config.js";
+import { authFetch } from "./session.js";
 
 const profileCache = new Map();
 
@@ -10,10 +10,9 @@ async function fetchLiveGamesData() {
     spinner.classList.remove('hidden');
 
     try {
-        const response = await fetch(`${config.baseUrl}/game/live/data`, {
+        const response = await authFetch(`${config.baseUrl}/game/live/data`, {
             method: 'GET',
             headers: {
-                "Authorization": `Bearer ${user.jwt_token}`,
                 'Content-Type': 'application/json',
             },
         });
@@ -1,12 +1,11 @@
 import { currentGame } from './gameState.js';
-import { user } from './user.js'
 import { config } from './config.js';
+import { authFetch } from './session.js';
 
 export async function findMatch() {
-    const response = await fetch(`${config.baseUrl}/match/find`, {
+    const response = await authFetch(`${config.baseUrl}/match/find`, {
         method: "GET",
         headers: {
-            "Authorization": `Bearer ${user.jwt_token}`,
             "Content-Type": "application/json"
         }
     });
@@ -34,3 +33,25 @@ export async function findMatch() {
     currentGame.ws.sendMessage(message);
 }
 
+
+// offerRematch offers a rematch of the ended game, or accepts the opponent's offer.
+export async function offerRematch(gameId) {
+    const response = await authFetch(`${config.baseUrl}/match/rematch/${gameId}`, {
+        method: "POST",
+        headers: {
+            "Content-Type": "application/json"
+        }
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+        throw new Error(data.error || `Failed to offer rematch: ${response.statusText}`);
+    }
+
+    // the created game is delivered by the rematch match
+    if (data.match) {
+        currentGame.matchId = data.match.id;
+    }
+
+    return data;
+}
@@ -4,7 +4,8 @@ import { config } from './config.js';
 import { initializeBoard } from './board.js';
 import {
     handleGameCreated, handleGameChatCreated, handleGameChatMsg, handleMoveApproved,
-    handlePlayerJoined, handlePlayerLeft, handleGameEnded, handleError, handleResumeGame, handleViewersList
+    handlePlayerJoined, handlePlayerLeft, handleGameEnded, handleError, handleResumeGame, handleViewersList,
+    handleRematchOffered, handleRematchDeclined, handleRematchAccepted
 } from './eventHandlers.js';
 
 connectWebSocket(`${config.baseUrl}/wsgateway/ws`).then(connection => {
@@ -19,6 +20,9 @@ connectWebSocket(`${config.baseUrl}/wsgateway/ws`).then(connection => {
     currentGame.ws.registerMessageHandler("player_left", handlePlayerLeft);
     currentGame.ws.registerMessageHandler("resume_game", handleResumeGame);
     currentGame.ws.registerMessageHandler("viewers_list", handleViewersList);
+    currentGame.ws.registerMessageHandler("rematch_offered", handleRematchOffered);
+    currentGame.ws.registerMessageHandler("rematch_declined", handleRematchDeclined);
+    currentGame.ws.registerMessageHandler("rematch_accepted", handleRematchAccepted);
     currentGame.ws.registerMessageHandler("err", handleError);
 
     initializeBoard(true);
@@ -36,5 +40,8 @@ window.addEventListener("beforeunload", () => {
     currentGame.ws.unregisterMessageHandler("player_connection_updated");
     currentGame.ws.unregisterMessageHandler("resume_game");
     currentGame.ws.unregisterMessageHandler("viewers_list");
+    currentGame.ws.unregisterMessageHandler("rematch_offered");
+    currentGame.ws.unregisterMessageHandler("rematch_declined");
+    currentGame.ws.unregisterMessageHandler("rematch_accepted");
     currentGame.ws.unregisterMessageHandler("err");
 });
\ No newline at end of file
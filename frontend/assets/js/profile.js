@@ -1,9 +1,9 @@
 import { showProfileSummary } from './profile-summary.js';
 import { getUserProfile, getUserRating } from './user_info.js';
 import { showErrorMessage } from './error.js';
-import { user } from './user.js';
 import { formatDate } from './utils.js';
 import { config } from './config.js';
+import { authFetch } from './session.js';
 
 const opponentProfiles = new Map();
 
@@ -67,11 +67,10 @@ async function fetchGameHistory(userId, page) {
     try {
         if (page < 1) return;
 
-        const response = await fetch(`${config.baseUrl}/profile/rating/history/${userId}?page=${page}&limit=${pageSize}`,
+        const response = await authFetch(`${config.baseUrl}/profile/rating/history/${userId}?page=${page}&limit=${pageSize}`,
             {
                 method: 'GET',
                 headers: {
-                    "Authorization": `Bearer ${user.jwt_token}`,
                     'Content-Type': 'application/json',
                 },
             }
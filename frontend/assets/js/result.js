@@ -14,7 +14,7 @@ export function showGameResult(winnerId, loserId, winnerProfile, loserProfile, r
     winnerAvatar.alt = `${winnerProfile.name}'s Avatar`;
     winnerName.textContent = winnerProfile.name;
     winnerAvatar.style.backgroundImage = `url(${winnerProfile.avatar_url})`;
-    if (result == "draw") {
+    if (result == "draw" || result == "aborted") {
         winnerAvatar.className = "result-avatar draw";
     } else {
         winnerAvatar.className = "result-avatar winner";
@@ -24,7 +24,7 @@ export function showGameResult(winnerId, loserId, winnerProfile, loserProfile, r
     loserAvatar.alt = `${loserProfile.name}'s Avatar`;
     loserName.textContent = loserProfile.name;
     loserAvatar.style.backgroundImage = `url(${loserProfile.avatar_url})`;
-    if (result == "draw") {
+    if (result == "draw" || result == "aborted") {
         loserAvatar.className = "result-avatar draw";
     } else {
         loserAvatar.className = "result-avatar loser";
@@ -35,6 +35,8 @@ export function showGameResult(winnerId, loserId, winnerProfile, loserProfile, r
 
     if (result == "draw") {
         resultDescription.textContent = "It's a draw!";
+    } else if (result == "aborted") {
+        resultDescription.textContent = "Game aborted";
     } else {
         resultDescription.textContent = desc
     }
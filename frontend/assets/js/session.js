@@ -0,0 +1,133 @@
+import { user } from './user.js';
+import { config } from './config.js';
+
+// the access token is refreshed this many milliseconds before it expires
+const refreshMargin = 60 * 1000;
+
+let refreshing = null;
+let refreshTimer = null;
+
+// tokenExpiresAt returns the expiration of the access token in milliseconds, or 0 if it can't be read.
+function tokenExpiresAt(token) {
+    try {
+        const payload = JSON.parse(atob(token.split('.')[1].replace(/-/g, '+').replace(/_/g, '/')));
+        return payload.exp ? payload.exp * 1000 : 0;
+    } catch (error) {
+        return 0;
+    }
+}
+
+// refreshSession rotates the refresh token and gets a new access token, the concurrent calls share one request.
+export function refreshSession() {
+    if (refreshing) {
+        return refreshing;
+    }
+
+    refreshing = (async () => {
+        if (!user.refresh_token) {
+            return false;
+        }
+
+        try {
+            const response = await fetch(`${config.baseUrl}/auth/refresh`, {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ refresh_token: user.refresh_token }),
+            });
+
+            if (response.status === 401) {
+                // the refresh token is expired or revoked, the user must log in again
+                user.clean();
+                localStorage.removeItem("user");
+                return false;
+            }
+            if (!response.ok) {
+                throw new Error(`Server returned ${response.status}`);
+            }
+
+            const data = await response.json();
+            user.save({ jwt_token: data.jwt_token, refresh_token: data.refresh_token });
+            scheduleRefresh();
+            return true;
+        } catch (error) {
+            console.error("Error refreshing session:", error);
+            return false;
+        } finally {
+            refreshing = null;
+        }
+    })();
+
+    return refreshing;
+}
+
+// ensureFreshToken refreshes the access token if it's expired or about to expire.
+export async function ensureFreshToken() {
+    if (!user.jwt_token) {
+        return;
+    }
+
+    const exp = tokenExpiresAt(user.jwt_token);
+    if (exp !== 0 && exp - Date.now() <= refreshMargin) {
+        await refreshSession();
+    }
+}
+
+// authFetch sends the request with the access token, and retries it once with a new token if it's rejected.
+export async function authFetch(url, options = {}) {
+    await ensureFreshToken();
+
+    const request = () => fetch(url, {
+        ...options,
+        headers: {
+            ...(options.headers || {}),
+            ...(user.jwt_token ? { "Authorization": `Bearer ${user.jwt_token}` } : {}),
+        },
+    });
+
+    const response = await request();
+    if (response.status === 401 && user.refresh_token && await refreshSession()) {
+        return request();
+    }
+    return response;
+}
+
+// revokeSession revokes the refresh token on logout, the access token expires by itself.
+export async function revokeSession() {
+    if (!user.refresh_token) {
+        return;
+    }
+
+    try {
+        await fetch(`${config.baseUrl}/auth/logout`, {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ refresh_token: user.refresh_token }),
+        });
+    } catch (error) {
+        console.error("Error revoking session:", error);
+    }
+}
+
+function scheduleRefresh() {
+    clearTimeout(refreshTimer);
+    if (!user.jwt_token || !user.refresh_token) {
+        return;
+    }
+
+    const exp = tokenExpiresAt(user.jwt_token);
+    if (exp === 0) {
+        return;
+    }
+
+    // the websocket reconnects and the requests use the token that is refreshed in the background
+    refreshTimer = setTimeout(refreshSession, Math.max(0, exp - Date.now() - refreshMargin));
+}
+
+// the timers of the background tabs are throttled, so the token is checked when the tab is shown again
+document.addEventListener("visibilitychange", () => {
+    if (document.visibilityState === "visible") {
+        ensureFreshToken();
+    }
+});
+
+scheduleRefresh();
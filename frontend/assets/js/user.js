@@ -4,6 +4,7 @@ export const user = {
     name: null,
     avatar_url: null,
     jwt_token: null,
+    refresh_token: null,
 
     update: function () {
         const storedUser = localStorage.getItem("user");
@@ -15,15 +16,24 @@ export const user = {
             this.is_guest = parsed.is_guest || false;
             this.avatar_url = parsed.picture || null;
             this.jwt_token = parsed.jwt_token || null;
+            this.refresh_token = parsed.refresh_token || null;
         }
     },
 
+    // save updates the stored user, e.g. with the tokens of a refresh
+    save: function (fields) {
+        const stored = JSON.parse(localStorage.getItem("user") || "{}");
+        localStorage.setItem("user", JSON.stringify({ ...stored, ...fields }));
+        this.update();
+    },
+
     clean: function () {
         this.id = null;
         this.email = null;
         this.name = null;
         this.avatar_url = null;
         this.jwt_token = null;
+        this.refresh_token = null;
     },
 
     loggedIn: function () {
@@ -1,5 +1,5 @@
-import { user } from "./user.js";
 import { config } from "./config.js";
+import { authFetch } from "./session.js";
 
 export async function getUserProfile(userId) {
     if (!userId) {
@@ -8,11 +8,10 @@ export async function getUserProfile(userId) {
     }
 
     try {
-        const response = await fetch(`${config.baseUrl}/profile/users/${userId}`,
+        const response = await authFetch(`${config.baseUrl}/profile/users/${userId}`,
             {
                 method: 'GET',
                 headers: {
-                    "Authorization": `Bearer ${user.jwt_token}`,
                     'Content-Type': 'application/json',
                 },
             }
@@ -39,11 +38,10 @@ export async function getUserRating(userId) {
     }
 
     try {
-        const response = await fetch(`${config.baseUrl}/profile/rating/${userId}`,
+        const response = await authFetch(`${config.baseUrl}/profile/rating/${userId}`,
             {
                 method: 'GET',
                 headers: {
-                    "Authorization": `Bearer ${user.jwt_token}`,
                     'Content-Type': 'application/json',
                 },
             }
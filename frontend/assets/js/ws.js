@@ -1,5 +1,6 @@
 import { user } from './user.js';
 import { logout } from './auth.js';
+import { ensureFreshToken } from './session.js';
 
 export function connectWebSocket(url) {
     if (user.jwt_token === null || user.jwt_token === "") {
@@ -7,7 +8,6 @@ export function connectWebSocket(url) {
         return null;
     }
 
-    const wsUrl = `${url}?token=${user.jwt_token}`;
     let socket;
     let lastReceivedTime = Date.now();
     let pingInterval;
@@ -16,16 +16,33 @@ export function connectWebSocket(url) {
     const messageHandlers = {};
     let resolveConnection;
     let isFirstPong = true; // Add flag to track first pong after connection
+    // last sequence number of the game stream, it's sent on reconnect so only the missed messages are replayed
+    let lastSeq = 0;
+    // the gateway sends the moves of the player's game before its chat, so the messages may come out of order
+    let receivedSeqs = new Set();
+    let streamRequest = null;
+    // the gateway drops the messages of the viewed games for slow clients, the game is resynced from lastSeq
+    let resyncing = false;
+    // ids of the sent messages, the errors of the gateway have the id of the request
+    let msgSeq = Date.now() * 1000;
 
     const connectionPromise = new Promise((resolve) => {
         resolveConnection = resolve;
     });
 
+    // the access token may be refreshed between the reconnects, so the url is built on each connect
     function initializeSocket() {
-        socket = new WebSocket(wsUrl);
+        ensureFreshToken().finally(openSocket);
+    }
+
+    function openSocket() {
+        socket = new WebSocket(`${url}?token=${user.jwt_token}`);
 
         socket.onopen = () => {
             console.log("Connected to WebSocket");
+            if (streamRequest) {
+                resumeStream();
+            }
             pingInterval = setInterval(() => {
                 if (socket.readyState === WebSocket.OPEN && pongReceived) {
                     socket.send(new Uint8Array([0x0]));
@@ -72,12 +89,35 @@ export function connectWebSocket(url) {
 
     initializeSocket();
 
+    function nextMsgId() {
+        msgSeq += 1;
+        return String(msgSeq);
+    }
+
     function sendMessage(msg) {
-        if (socket.readyState === WebSocket.OPEN) {
+        // the errors of the server have the id of the request that failed
+        if (!msg.id) {
+            msg.id = nextMsgId();
+        }
+        if (msg.type === "resume_game" || msg.type === "view_game") {
+            streamRequest = { type: msg.type, data: JSON.parse(atob(msg.data)) };
+            lastSeq = 0;
+            receivedSeqs = new Set();
+        }
+        if (socket && socket.readyState === WebSocket.OPEN) {
             socket.send(JSON.stringify(msg));
         }
     }
 
+    function resumeStream() {
+        const data = { ...streamRequest.data, last_seq: lastSeq, timestamp: Date.now() };
+        socket.send(JSON.stringify({
+            id: nextMsgId(),
+            type: streamRequest.type,
+            data: btoa(JSON.stringify(data))
+        }));
+    }
+
     function registerMessageHandler(type, handler) {
         messageHandlers[type] = handler;
     }
@@ -87,12 +127,40 @@ export function connectWebSocket(url) {
     }
 
     function handleTextMessage(message) {
-        const { type, data } = message;
+        const { type, data, seq } = message;
+        if (seq) {
+            // already received before the reconnect or by the replay
+            if (receivedSeqs.has(seq) || resyncing) return;
+            if (lastSeq > 0 && seq > lastSeq + 1 && streamRequest && streamRequest.type === "view_game") {
+                resyncing = true;
+                resumeStream();
+                return;
+            }
+            receivedSeqs.add(seq);
+            lastSeq = Math.max(lastSeq, seq);
+            if (receivedSeqs.size > 500) {
+                receivedSeqs.forEach((s) => {
+                    if (s < lastSeq - 250) receivedSeqs.delete(s);
+                });
+            }
+        }
+
         if (messageHandlers[type]) {
             messageHandlers[type](data);
         } else {
             console.warn("Unhandled message type:", type);
         }
+
+        if (type === "err") {
+            resyncing = false;
+        }
+
+        if (type === "resume_game" || type === "view_game") {
+            resyncing = false;
+            const res = JSON.parse(atob(data));
+            lastSeq = Math.max(lastSeq, res.seq || 0);
+            (res.replay || []).forEach(handleTextMessage);
+        }
     }
 
     function handleBinaryMessage(byteArray) {
@@ -127,10 +195,12 @@ export function connectWebSocket(url) {
             return;
         }
 
-        if (user.jwt_token != null && Date.now() - lastReceivedTime > 10000) {
+        if (socket && user.jwt_token != null && Date.now() - lastReceivedTime > 10000) {
             console.log("Server unresponsive: ", Date.now() - lastReceivedTime);
             // WebSocket disconnected event 
             document.dispatchEvent(new Event("websocket_disconnected"));
+            // reconnect and resume the stream instead of reloading the page
+            socket.onclose = null;
             socket.close();
             clearInterval(pingInterval);
             lastReceivedTime = Date.now();
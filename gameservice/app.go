@@ -44,6 +44,8 @@ func SetupApplication(cfg Config) (*application, error) {
 		return nil, err
 	}
 
+	v.WithDenylist(jwt.NewRedisDenylist(r))
+
 	wc, err := gc.NewClient(cfg.WsGatewayService, nil)
 	if err != nil {
 		return nil, err
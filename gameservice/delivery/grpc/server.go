@@ -6,12 +6,10 @@ import (
 	"net"
 
 	game "github.com/alikarimi999/shahboard/gameservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	pb "github.com/alikarimi999/shahboard/proto/game/gamepb"
 	"github.com/alikarimi999/shahboard/types"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-
 	"google.golang.org/grpc"
 )
 
@@ -55,11 +53,11 @@ func (s *Server) Run() error {
 func (s *Server) GetUserLiveGameID(ctx context.Context, req *pb.GetUserLiveGameIdRequest) (*pb.GetUserLiveGameIdResponse, error) {
 	userId, err := types.ParseObjectId(req.UserId)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %v", err)
+		return nil, errs.GRPC(errs.CodeInvalidInput, fmt.Sprintf("invalid user id: %v", err))
 	}
 	res, err := s.svc.GetLiveGameIdByUserId(ctx, userId)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get user live game: %v", err)
+		return nil, errs.GRPC(errs.CodeInternalError, fmt.Sprintf("failed to get user live game: %v", err))
 	}
 
 	if res.GameId.IsZero() {
@@ -76,12 +74,12 @@ func (s *Server) GetUserLiveGameID(ctx context.Context, req *pb.GetUserLiveGameI
 func (s *Server) GetUserLiveGamePGN(ctx context.Context, req *pb.GetUserLiveGamePgnRequest) (*pb.GetLiveGamePGNResponse, error) {
 	userId, err := types.ParseObjectId(req.UserId)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid user id: %v", err)
+		return nil, errs.GRPC(errs.CodeInvalidInput, fmt.Sprintf("invalid user id: %v", err))
 	}
 
 	res, err := s.svc.GetLiveGamePgnByUserID(ctx, userId)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get user live game: %v", err)
+		return nil, errs.GRPC(errs.CodeInternalError, fmt.Sprintf("failed to get user live game: %v", err))
 	}
 
 	if res.ID.IsZero() {
@@ -108,12 +106,12 @@ func (s *Server) GetUserLiveGamePGN(ctx context.Context, req *pb.GetUserLiveGame
 func (s *Server) GetLiveGamePGN(ctx context.Context, req *pb.GetLiveGamePGNRequest) (*pb.GetLiveGamePGNResponse, error) {
 	gameId, err := types.ParseObjectId(req.GameId)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid game id: %v", err)
+		return nil, errs.GRPC(errs.CodeInvalidInput, fmt.Sprintf("invalid game id: %v", err))
 	}
 
 	res, err := s.svc.GetLiveGamePGN(ctx, gameId)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get live game: %v", err)
+		return nil, errs.GRPC(errs.CodeInternalError, fmt.Sprintf("failed to get live game: %v", err))
 	}
 
 	if res.ID.IsZero() {
@@ -136,3 +134,26 @@ func (s *Server) GetLiveGamePGN(ctx context.Context, req *pb.GetLiveGamePGNReque
 		PlayersDisconnection: ds,
 	}, nil
 }
+
+func (s *Server) GetGamePGN(ctx context.Context, req *pb.GetGamePGNRequest) (*pb.GetGamePGNResponse, error) {
+	gameId, err := types.ParseObjectId(req.GameId)
+	if err != nil {
+		return nil, errs.GRPC(errs.CodeInvalidInput, fmt.Sprintf("invalid game id: %v", err))
+	}
+
+	res, err := s.svc.GetGamePGN(ctx, gameId)
+	if err != nil {
+		return nil, errs.GRPC(errs.CodeInternalError, fmt.Sprintf("failed to get game: %v", err))
+	}
+
+	if res.ID.IsZero() {
+		return &pb.GetGamePGNResponse{
+			GameId: types.ObjectZero.String(),
+		}, nil
+	}
+
+	return &pb.GetGamePGNResponse{
+		GameId: res.ID.String(),
+		Pgn:    res.PGN,
+	}, nil
+}
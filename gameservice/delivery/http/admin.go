@@ -1,6 +1,94 @@
 package http
 
+import (
+	"errors"
+	"net/http"
+
+	game "github.com/alikarimi999/shahboard/gameservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
 func (r *Router) setupAdminRoutes() {
-	r.gin.Group("/admin")
+	admin := r.gin.Group("/admin", middleware.RequireRole(types.RoleModerator))
+
+	admin.GET("/games/:id", r.getGameState)
+	admin.POST("/games/:id/end", r.forceEndGame)
+	admin.POST("/games/:id/abort", r.abortGame)
+}
+
+func (r *Router) getGameState(ctx *gin.Context) {
+	gid, err := types.ParseObjectId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return
+	}
+
+	res, err := r.s.GetGameState(ctx, gid)
+	if err != nil {
+		ctx.JSON(errs.HTTP(adminErrCode(err), err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (r *Router) forceEndGame(ctx *gin.Context) {
+	admin, ok := middleware.ExtractUser(ctx)
+	if !ok {
+		ctx.JSON(errs.HTTP(errs.CodeUnauthorized, ""))
+		return
+	}
+
+	gid, err := types.ParseObjectId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return
+	}
+
+	req := game.ForceEndGameRequest{}
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	if err := r.s.ForceEndGame(ctx, admin.ID, gid, req); err != nil {
+		ctx.JSON(errs.HTTP(adminErrCode(err), err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "ok")
+}
+
+func (r *Router) abortGame(ctx *gin.Context) {
+	admin, ok := middleware.ExtractUser(ctx)
+	if !ok {
+		ctx.JSON(errs.HTTP(errs.CodeUnauthorized, ""))
+		return
+	}
+
+	gid, err := types.ParseObjectId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return
+	}
+
+	if err := r.s.AbortGame(ctx, admin.ID, gid); err != nil {
+		ctx.JSON(errs.HTTP(adminErrCode(err), err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, "ok")
+}
 
+func adminErrCode(err error) string {
+	switch {
+	case errors.Is(err, game.ErrGameNotFound):
+		return errs.CodeNotFound
+	case errors.Is(err, game.ErrInvalidOutcome):
+		return errs.CodeInvalidInput
+	}
+	return errs.CodeInternalError
 }
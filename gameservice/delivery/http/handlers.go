@@ -2,7 +2,10 @@ package http
 
 import (
 	"net/http"
+	"strconv"
 
+	game "github.com/alikarimi999/shahboard/gameservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/pkg/middleware"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/gin-gonic/gin"
@@ -31,13 +34,13 @@ func (r *Router) getLiveGames(ctx *gin.Context) {
 	if userId != "" {
 		uid, err := types.ParseObjectId(userId)
 		if err != nil {
-			ctx.JSON(400, err)
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 			return
 		}
 
 		res, err := r.s.GetLiveGamePgnByUserID(ctx, uid)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, err)
+			ctx.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 			return
 		}
 
@@ -49,12 +52,12 @@ func (r *Router) getLiveGames(ctx *gin.Context) {
 	if gameId != "" {
 		gid, err := types.ParseObjectId(gameId)
 		if err != nil {
-			ctx.JSON(400, err)
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 			return
 		}
 		res, err := r.s.GetLiveGamePGN(ctx, gid)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, err)
+			ctx.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 			return
 		}
 
@@ -62,13 +65,13 @@ func (r *Router) getLiveGames(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusBadRequest, "invalid request")
+	ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid request"))
 }
 
 func (r *Router) getLiveGamesData(ctx *gin.Context) {
 	res, err := r.s.GetLiveGamesData(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+		ctx.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
@@ -79,13 +82,13 @@ func (r *Router) getLiveGameByUserId(ctx *gin.Context) {
 	userID := ctx.Param("id")
 	uid, err := types.ParseObjectId(userID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, err)
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 		return
 	}
 
 	res, err := r.s.GetLiveGameIdByUserId(ctx, uid)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+		ctx.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
@@ -96,25 +99,85 @@ func (r *Router) resignByPlayer(ctx *gin.Context) {
 	gameId := ctx.Param("gameId")
 	gid, err := types.ParseObjectId(gameId)
 	if err != nil {
-		ctx.JSON(400, err)
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 		return
 	}
 
 	userID, ok := middleware.ExtractUser(ctx)
 	if !ok {
-		ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+		ctx.JSON(errs.HTTP(errs.CodeUnauthorized, ""))
 		return
 	}
 
 	err = r.s.ResingByPlayer(ctx, gid, userID.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+		ctx.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
 	ctx.JSON(200, "ok")
 }
 
+func (r *Router) createStudy(ctx *gin.Context) {
+	user, ok := middleware.ExtractUser(ctx)
+	if !ok {
+		ctx.JSON(errs.HTTP(errs.CodeUnauthorized, ""))
+		return
+	}
+
+	req := game.CreateStudyRequest{}
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.CreateStudy(ctx, user.ID, req)
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (r *Router) getStudy(ctx *gin.Context) {
+	id, err := types.ParseObjectId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := r.s.GetStudy(ctx, id)
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeNotFound, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (r *Router) getStudyPosition(ctx *gin.Context) {
+	id, err := types.ParseObjectId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	ply, err := strconv.Atoi(ctx.Param("ply"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid ply"))
+		return
+	}
+
+	res, err := r.s.GetStudyPosition(ctx, id, ply)
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeNotFound, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
 // func (r *Router) getGamesFen(ctx *gin.Context) {
 // 	req := &getGamesFENRequest{}
 // 	if err := ctx.BindJSON(req); err != nil {
@@ -11,4 +11,8 @@ func (r *Router) setupUserRoutes() {
 	r.gin.GET("/live/resign/:gameId", r.resignByPlayer)
 	// r.gin.POST("/fen", r.getGamesFen)
 
+	r.gin.POST("/study", r.createStudy)
+	r.gin.GET("/study/:id", r.getStudy)
+	r.gin.GET("/study/:id/position/:ply", r.getStudyPosition)
+
 }
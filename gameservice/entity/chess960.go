@@ -0,0 +1,526 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// The chess library only knows the standard castling rules, so chess960 games are played on
+// library games with castling disabled and castling is handled here.
+// Every castling move starts a new library game from the position after castling,
+// the full move list and castling rights of the game are kept by chess960.
+//
+// Castling rights are written in Shredder-FEN notation (the files of the castling rooks, e.g. "HAha").
+type chess960 struct {
+	rooks map[chess.Color][]chess.File
+	moves []string
+}
+
+const (
+	variantTag           = "Variant"
+	variantTagChess960   = "Chess960"
+	chess960PositionsNum = 960
+)
+
+var (
+	tagPairRegex    = regexp.MustCompile(`\[(\S+)\s+"(.*)"\]`)
+	pgnCommentRegex = regexp.MustCompile(`\{[^}]*\}`)
+
+	// knights placement on the 5 remaining squares by the Scharnagl numbering
+	chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+)
+
+// chess960StartFEN returns the start position with the given Scharnagl number (0-959),
+// the standard starting position is number 518.
+func chess960StartFEN(n int) string {
+	var rank [8]chess.PieceType
+
+	n, b := n/4, n%4
+	rank[b*2+1] = chess.Bishop
+	n, b = n/4, n%4
+	rank[b*2] = chess.Bishop
+
+	n, q := n/6, n%6
+	placeOnEmpty(&rank, q, chess.Queen)
+
+	// place the second knight first so the index of the first one doesn't shift
+	kn := chess960Knights[n]
+	placeOnEmpty(&rank, kn[1], chess.Knight)
+	placeOnEmpty(&rank, kn[0], chess.Knight)
+
+	placeOnEmpty(&rank, 0, chess.Rook)
+	placeOnEmpty(&rank, 0, chess.King)
+	placeOnEmpty(&rank, 0, chess.Rook)
+
+	white, black := "", ""
+	rooks := []chess.File{}
+	for f, t := range rank {
+		white += strings.ToUpper(t.String())
+		black += t.String()
+		if t == chess.Rook {
+			rooks = append(rooks, chess.File(f))
+		}
+	}
+
+	c := &chess960{rooks: map[chess.Color][]chess.File{chess.White: rooks, chess.Black: rooks}}
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w %s - 0 1", black, white, c.castlingRights())
+}
+
+func placeOnEmpty(rank *[8]chess.PieceType, i int, t chess.PieceType) {
+	for f := range rank {
+		if rank[f] != chess.NoPieceType {
+			continue
+		}
+		if i == 0 {
+			rank[f] = t
+			return
+		}
+		i--
+	}
+}
+
+// newChess960 sets up a chess960 game from a FEN, the castling field can be in Shredder-FEN or X-FEN notation.
+func newChess960(fen string) (*chess.Game, *chess960, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return nil, nil, fmt.Errorf("invalid fen: %s", fen)
+	}
+
+	castling := fields[2]
+	fields[2] = "-"
+	f, err := chess.FEN(strings.Join(fields, " "))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid fen: %v", err)
+	}
+
+	g := chess.NewGame(f, chess.UseNotation(defaultNotation))
+	c := &chess960{rooks: make(map[chess.Color][]chess.File)}
+	if err := c.parseCastlingRights(castling, g.Position().Board()); err != nil {
+		return nil, nil, err
+	}
+
+	return g, c, nil
+}
+
+func (c *chess960) parseCastlingRights(s string, b *chess.Board) error {
+	if s == "-" {
+		return nil
+	}
+
+	for _, r := range s {
+		color := chess.White
+		if r >= 'a' && r <= 'z' {
+			color = chess.Black
+		}
+		rank := backRank(color)
+
+		king, ok := findKing(b, color, rank)
+		if !ok {
+			return fmt.Errorf("invalid castling rights: %s king is not on its back rank", color.Name())
+		}
+
+		var file chess.File
+		switch r {
+		case 'K', 'k', 'Q', 'q':
+			// X-FEN, the outermost rook on that side of the king
+			kingside := r == 'K' || r == 'k'
+			file = -1
+			for f := chess.FileA; f <= chess.FileH; f++ {
+				if b.Piece(chess.NewSquare(f, rank)) != chess.NewPiece(chess.Rook, color) {
+					continue
+				}
+				if kingside && f > king.File() {
+					file = f
+				}
+				if !kingside && f < king.File() && file == -1 {
+					file = f
+				}
+			}
+			if file == -1 {
+				return fmt.Errorf("invalid castling rights: %c", r)
+			}
+		default:
+			l := strings.ToLower(string(r))
+			if len(l) != 1 || l[0] < 'a' || l[0] > 'h' {
+				return fmt.Errorf("invalid castling rights: %c", r)
+			}
+			file = chess.File(l[0] - 'a')
+			if b.Piece(chess.NewSquare(file, rank)) != chess.NewPiece(chess.Rook, color) || file == king.File() {
+				return fmt.Errorf("invalid castling rights: no %s rook on %s", color.Name(), chess.NewSquare(file, rank))
+			}
+		}
+
+		c.rooks[color] = append(c.rooks[color], file)
+	}
+
+	return nil
+}
+
+func (c *chess960) castlingRights() string {
+	s := ""
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		files := append([]chess.File{}, c.rooks[color]...)
+		sort.Slice(files, func(i, j int) bool { return files[i] > files[j] })
+		for _, f := range files {
+			if color == chess.White {
+				s += strings.ToUpper(f.String())
+			} else {
+				s += f.String()
+			}
+		}
+	}
+
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// move plays the move on the game and returns the game that should be used from now on.
+func (c *chess960) move(g *chess.Game, move string) (*chess.Game, error) {
+	if kingside, ok := parseCastling(move); ok {
+		return c.castle(g, kingside)
+	}
+
+	pos := g.Position()
+	if err := g.MoveStr(move); err != nil {
+		return nil, err
+	}
+
+	m := g.Moves()[len(g.Moves())-1]
+	c.moves = append(c.moves, defaultNotation.Encode(pos, m))
+
+	// moving the king or a castling rook, or capturing a castling rook removes the castling rights
+	if p := pos.Board().Piece(m.S1()); p.Type() == chess.King {
+		delete(c.rooks, p.Color())
+	}
+	c.removeRook(m.S1())
+	c.removeRook(m.S2())
+
+	return g, nil
+}
+
+func (c *chess960) removeRook(sq chess.Square) {
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if sq.Rank() != backRank(color) {
+			continue
+		}
+
+		files := []chess.File{}
+		for _, f := range c.rooks[color] {
+			if f != sq.File() {
+				files = append(files, f)
+			}
+		}
+		c.rooks[color] = files
+	}
+}
+
+func (c *chess960) castle(g *chess.Game, kingside bool) (*chess.Game, error) {
+	fen, check, err := c.castlePosition(g.Position(), kingside)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	color := g.Position().Turn()
+	ng := chess.NewGame(f, chess.UseNotation(defaultNotation), chess.TagPairs(g.TagPairs()))
+
+	move := "O-O-O"
+	if kingside {
+		move = "O-O"
+	}
+	if ng.Method() == chess.Checkmate {
+		move += "#"
+	} else if check {
+		move += "+"
+	}
+
+	c.moves = append(c.moves, move)
+	delete(c.rooks, color)
+
+	return ng, nil
+}
+
+// castlePosition returns the FEN of the position after castling and if the opponent is in check.
+func (c *chess960) castlePosition(pos *chess.Position, kingside bool) (string, bool, error) {
+	color := pos.Turn()
+	rank := backRank(color)
+	b := pos.Board().SquareMap()
+
+	king, ok := findKing(pos.Board(), color, rank)
+	if !ok {
+		return "", false, fmt.Errorf("castling is not allowed")
+	}
+
+	rookFile := chess.File(-1)
+	for _, f := range c.rooks[color] {
+		if (kingside && f > king.File()) || (!kingside && f < king.File()) {
+			rookFile = f
+		}
+	}
+	if rookFile == -1 {
+		return "", false, fmt.Errorf("castling is not allowed")
+	}
+
+	rook := chess.NewSquare(rookFile, rank)
+	kingTo, rookTo := chess.NewSquare(chess.FileC, rank), chess.NewSquare(chess.FileD, rank)
+	if kingside {
+		kingTo, rookTo = chess.NewSquare(chess.FileG, rank), chess.NewSquare(chess.FileF, rank)
+	}
+
+	// all squares between the king, the rook and their destinations should be empty
+	lo, hi := minFile(king.File(), kingTo.File(), rook.File(), rookTo.File()), maxFile(king.File(), kingTo.File(), rook.File(), rookTo.File())
+	for f := lo; f <= hi; f++ {
+		sq := chess.NewSquare(f, rank)
+		if sq == king || sq == rook {
+			continue
+		}
+		if _, ok := b[sq]; ok {
+			return "", false, fmt.Errorf("castling is not allowed, %s is not empty", sq)
+		}
+	}
+
+	// the king can't castle out of, through or into check
+	delete(b, king)
+	lo, hi = minFile(king.File(), kingTo.File()), maxFile(king.File(), kingTo.File())
+	for f := lo; f <= hi; f++ {
+		if isAttacked(b, chess.NewSquare(f, rank), color.Other()) {
+			return "", false, fmt.Errorf("castling is not allowed, king is in check or passes through check")
+		}
+	}
+
+	delete(b, rook)
+	b[kingTo] = chess.NewPiece(chess.King, color)
+	b[rookTo] = chess.NewPiece(chess.Rook, color)
+	if isAttacked(b, kingTo, color.Other()) {
+		return "", false, fmt.Errorf("castling is not allowed, king is in check after castling")
+	}
+
+	check := false
+	for sq, p := range b {
+		if p == chess.NewPiece(chess.King, color.Other()) {
+			check = isAttacked(b, sq, color)
+		}
+	}
+
+	fields := strings.Fields(pos.String())
+	halfMove, _ := strconv.Atoi(fields[4])
+	fullMove, _ := strconv.Atoi(fields[5])
+	if color == chess.Black {
+		fullMove++
+	}
+
+	return fmt.Sprintf("%s %s - - %d %d", chess.NewBoard(b).String(), color.Other(), halfMove+1, fullMove), check, nil
+}
+
+func (c *chess960) validCastlingMoves(pos *chess.Position) []string {
+	moves := []string{}
+	if _, _, err := c.castlePosition(pos, true); err == nil {
+		moves = append(moves, "O-O")
+	}
+	if _, _, err := c.castlePosition(pos, false); err == nil {
+		moves = append(moves, "O-O-O")
+	}
+	return moves
+}
+
+func (c *chess960) fen(g *chess.Game) string {
+	fields := strings.Fields(g.FEN())
+	fields[2] = c.castlingRights()
+	return strings.Join(fields, " ")
+}
+
+// pgn encodes the game in the same format as the chess library.
+func (c *chess960) pgn(g *chess.Game) string {
+	s := ""
+	for _, tag := range g.TagPairs() {
+		s += fmt.Sprintf("[%s \"%s\"]\n", tag.Key, tag.Value)
+	}
+	s += "\n"
+	for i, m := range c.moves {
+		if i%2 == 0 {
+			s += fmt.Sprintf("%d. %s", (i/2)+1, m)
+		} else {
+			s += fmt.Sprintf(" %s ", m)
+		}
+	}
+	s += " " + g.Outcome().String()
+	return s
+}
+
+// decodeChess960 replays a chess960 game from its PGN.
+func decodeChess960(pgn string) (*chess.Game, *chess960, error) {
+	tags := []*chess.TagPair{}
+	fen := ""
+	for _, m := range tagPairRegex.FindAllStringSubmatch(pgn, -1) {
+		tags = append(tags, &chess.TagPair{Key: m[1], Value: m[2]})
+		if m[1] == fenTag {
+			fen = m[2]
+		}
+	}
+
+	if fen == "" {
+		return nil, nil, fmt.Errorf("chess960 game without fen tag")
+	}
+
+	g, c, err := newChess960(fen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	moveText := tagPairRegex.ReplaceAllString(pgn, "")
+	moveText = pgnCommentRegex.ReplaceAllString(moveText, "")
+
+	outcome := chess.NoOutcome
+	for _, tok := range strings.Fields(moveText) {
+		if strings.HasSuffix(tok, ".") {
+			continue
+		}
+
+		switch chess.Outcome(tok) {
+		case chess.WhiteWon, chess.BlackWon, chess.Draw, chess.NoOutcome:
+			outcome = chess.Outcome(tok)
+			continue
+		}
+
+		if g, err = c.move(g, tok); err != nil {
+			return nil, nil, fmt.Errorf("invalid move '%s': %v", tok, err)
+		}
+	}
+
+	for _, tag := range tags {
+		g.AddTagPair(tag.Key, tag.Value)
+	}
+
+	// resigns and draw agreements are only recorded by the result
+	if g.Outcome() == chess.NoOutcome {
+		switch outcome {
+		case chess.WhiteWon:
+			g.Resign(chess.Black)
+		case chess.BlackWon:
+			g.Resign(chess.White)
+		case chess.Draw:
+			g.Draw(chess.DrawOffer)
+		}
+	}
+
+	return g, c, nil
+}
+
+func parseCastling(move string) (kingside bool, ok bool) {
+	switch strings.TrimRight(move, "+#") {
+	case "O-O", "0-0":
+		return true, true
+	case "O-O-O", "0-0-0":
+		return false, true
+	}
+	return false, false
+}
+
+func backRank(c chess.Color) chess.Rank {
+	if c == chess.White {
+		return chess.Rank1
+	}
+	return chess.Rank8
+}
+
+func findKing(b *chess.Board, c chess.Color, r chess.Rank) (chess.Square, bool) {
+	for f := chess.FileA; f <= chess.FileH; f++ {
+		sq := chess.NewSquare(f, r)
+		if b.Piece(sq) == chess.NewPiece(chess.King, c) {
+			return sq, true
+		}
+	}
+	return chess.NoSquare, false
+}
+
+// isAttacked reports whether the square is attacked by any piece of the given color.
+func isAttacked(b map[chess.Square]chess.Piece, sq chess.Square, by chess.Color) bool {
+	f, r := int(sq.File()), int(sq.Rank())
+
+	at := func(f, r int) chess.Piece {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoPiece
+		}
+		return b[chess.NewSquare(chess.File(f), chess.Rank(r))]
+	}
+
+	// pawns
+	dir := -1
+	if by == chess.Black {
+		dir = 1
+	}
+	for _, df := range []int{-1, 1} {
+		if at(f+df, r+dir) == chess.NewPiece(chess.Pawn, by) {
+			return true
+		}
+	}
+
+	// knights and king
+	for _, d := range [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+		if at(f+d[0], r+d[1]) == chess.NewPiece(chess.Knight, by) {
+			return true
+		}
+	}
+	for df := -1; df <= 1; df++ {
+		for dr := -1; dr <= 1; dr++ {
+			if (df != 0 || dr != 0) && at(f+df, r+dr) == chess.NewPiece(chess.King, by) {
+				return true
+			}
+		}
+	}
+
+	// sliding pieces
+	slide := func(dirs [][2]int, t chess.PieceType) bool {
+		for _, d := range dirs {
+			for i := 1; i < 8; i++ {
+				nf, nr := f+d[0]*i, r+d[1]*i
+				if nf < 0 || nf > 7 || nr < 0 || nr > 7 {
+					break
+				}
+				p := at(nf, nr)
+				if p == chess.NoPiece {
+					continue
+				}
+				if p.Color() == by && (p.Type() == t || p.Type() == chess.Queen) {
+					return true
+				}
+				break
+			}
+		}
+		return false
+	}
+
+	return slide([][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}, chess.Rook) ||
+		slide([][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}, chess.Bishop)
+}
+
+func minFile(files ...chess.File) chess.File {
+	m := files[0]
+	for _, f := range files[1:] {
+		if f < m {
+			m = f
+		}
+	}
+	return m
+}
+
+func maxFile(files ...chess.File) chess.File {
+	m := files[0]
+	for _, f := range files[1:] {
+		if f > m {
+			m = f
+		}
+	}
+	return m
+}
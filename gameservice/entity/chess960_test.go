@@ -0,0 +1,366 @@
+package entity
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestChess960StartFEN(t *testing.T) {
+	tests := []struct {
+		n   int
+		fen string
+	}{
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1"},
+		{518, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w CAca - 0 1"},
+	}
+
+	for _, tt := range tests {
+		if got := chess960StartFEN(tt.n); got != tt.fen {
+			t.Errorf("position %d: expected %s, got %s", tt.n, tt.fen, got)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for n := 0; n < chess960PositionsNum; n++ {
+		fen := chess960StartFEN(n)
+		if seen[fen] {
+			t.Fatalf("position %d is repeated: %s", n, fen)
+		}
+		seen[fen] = true
+
+		if _, _, err := newChess960(fen); err != nil {
+			t.Fatalf("position %d: %v", n, err)
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		// empty if castling is not allowed
+		want string
+		// the move that is recorded in the PGN
+		san string
+	}{
+		{
+			name: "kingside",
+			fen:  "4k3/8/8/8/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O",
+			want: "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 1",
+			san:  "O-O",
+		},
+		{
+			name: "queenside with the rook on b1",
+			fen:  "4k3/8/8/8/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O-O",
+			want: "4k3/8/8/8/8/8/8/2KR3R b - - 1 1",
+			san:  "O-O-O",
+		},
+		{
+			name: "kingside with the king on its destination",
+			fen:  "6k1/8/8/8/8/8/8/R5KR w HA - 0 1",
+			move: "O-O",
+			want: "6k1/8/8/8/8/8/8/R4RK1 b - - 1 1",
+			san:  "O-O",
+		},
+		{
+			name: "queenside from g1",
+			fen:  "6k1/8/8/8/8/8/8/R5KR w HA - 0 1",
+			move: "O-O-O",
+			want: "6k1/8/8/8/8/8/8/2KR3R b - - 1 1",
+			san:  "O-O-O",
+		},
+		{
+			name: "queenside with the king moving right",
+			fen:  "4k3/8/8/8/8/8/8/RK5R w HA - 0 1",
+			move: "O-O-O",
+			want: "4k3/8/8/8/8/8/8/2KR3R b - - 1 1",
+			san:  "O-O-O",
+		},
+		{
+			name: "king and rook swap",
+			fen:  "4k3/8/8/8/8/8/8/5KR1 w G - 0 1",
+			move: "O-O",
+			want: "4k3/8/8/8/8/8/8/5RK1 b - - 1 1",
+			san:  "O-O",
+		},
+		{
+			name: "black keeps the rights of white",
+			fen:  "r3k1r1/8/8/8/8/8/8/R3K2R b HAga - 0 1",
+			move: "O-O",
+			want: "r4rk1/8/8/8/8/8/8/R3K2R w HA - 1 2",
+			san:  "O-O",
+		},
+		{
+			name: "castling gives check",
+			fen:  "5k2/8/8/8/8/8/8/4K2R w H - 0 1",
+			move: "O-O",
+			want: "5k2/8/8/8/8/8/8/5RK1 b - - 1 1",
+			san:  "O-O+",
+		},
+		{
+			name: "through check",
+			fen:  "4kr2/8/8/8/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O",
+		},
+		{
+			name: "through check on the other side is allowed",
+			fen:  "4kr2/8/8/8/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O-O",
+			want: "4kr2/8/8/8/8/8/8/2KR3R b - - 1 1",
+			san:  "O-O-O",
+		},
+		{
+			name: "into check",
+			fen:  "4k1r1/8/8/8/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O",
+		},
+		{
+			name: "attacked rook is allowed",
+			fen:  "1r2k3/8/8/8/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O-O",
+			want: "1r2k3/8/8/8/8/8/8/2KR3R b - - 1 1",
+			san:  "O-O-O",
+		},
+		{
+			name: "out of check",
+			fen:  "4k3/8/8/4q3/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O",
+		},
+		{
+			name: "out of check queenside",
+			fen:  "4k3/8/8/4q3/8/8/8/1R2K2R w HB - 0 1",
+			move: "O-O-O",
+		},
+		{
+			name: "blocked",
+			fen:  "4k3/8/8/8/8/8/8/RN2K2R w HA - 0 1",
+			move: "O-O-O",
+		},
+		{
+			name: "destination of the king is blocked",
+			fen:  "6k1/8/8/8/8/8/8/1RN1K2R w HB - 0 1",
+			move: "O-O-O",
+		},
+		{
+			name: "without the right",
+			fen:  "4k3/8/8/8/8/8/8/1R2K2R w B - 0 1",
+			move: "O-O",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, c, err := newChess960(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			allowed := false
+			for _, m := range c.validCastlingMoves(g.Position()) {
+				if m == tt.move {
+					allowed = true
+				}
+			}
+			if allowed != (tt.want != "") {
+				t.Errorf("expected %s to be allowed: %t", tt.move, tt.want != "")
+			}
+
+			g, err = c.move(g, tt.move)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("expected %s to fail", tt.move)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := c.fen(g); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if got := c.moves[len(c.moves)-1]; got != tt.san {
+				t.Errorf("expected move %s, got %s", tt.san, got)
+			}
+		})
+	}
+}
+
+func TestChess960CastlingRights(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		moves []string
+		want  string
+	}{
+		{"rook move", "4k3/8/8/8/8/8/8/1R2K2R w HB - 0 1", []string{"Rh2"}, "B"},
+		{"king move", "4k3/8/8/8/8/8/8/1R2K2R w HB - 0 1", []string{"Kd1"}, "-"},
+		{"captured rook", "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", []string{"Rxa8+"}, "Hh"},
+		{"castling removes both rights", "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", []string{"O-O"}, "ha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, c, err := newChess960(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, m := range tt.moves {
+				if g, err = c.move(g, m); err != nil {
+					t.Fatalf("move %s: %v", m, err)
+				}
+			}
+
+			if got := strings.Fields(c.fen(g))[2]; got != tt.want {
+				t.Errorf("expected castling rights %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChess960PGNRoundTrip(t *testing.T) {
+	tests := []struct {
+		n     int
+		moves []string
+	}{
+		{518, []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5", "O-O", "Nf6", "d3", "O-O"}},
+		{518, []string{"d4", "d5", "Nc3", "Nc6", "Bf4", "Bf5", "Qd2", "Qd7", "O-O-O", "O-O-O"}},
+		{518, []string{"f4", "e5", "g4", "Qh4#"}},
+	}
+
+	// random games that castle whenever they can
+	castled := false
+	for _, n := range []int{0, 105, 518, 777, 959} {
+		r := rand.New(rand.NewSource(int64(n)))
+		g, c, err := newChess960(chess960StartFEN(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		moves := []string{}
+		for i := 0; i < 60 && g.Outcome() == chess.NoOutcome; i++ {
+			move := ""
+			if castling := c.validCastlingMoves(g.Position()); len(castling) > 0 {
+				move = castling[r.Intn(len(castling))]
+				castled = true
+			} else {
+				valid := g.ValidMoves()
+				move = defaultNotation.Encode(g.Position(), valid[r.Intn(len(valid))])
+			}
+
+			if g, err = c.move(g, move); err != nil {
+				t.Fatalf("position %d, move %s: %v", n, move, err)
+			}
+			moves = append(moves, move)
+		}
+		tests = append(tests, struct {
+			n     int
+			moves []string
+		}{n, moves})
+	}
+	if !castled {
+		t.Error("none of the random games has castling")
+	}
+
+	for _, tt := range tests {
+		fen := chess960StartFEN(tt.n)
+		g, c, err := newChess960(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.AddTagPair(variantTag, variantTagChess960)
+		g.AddTagPair(fenTag, fen)
+
+		for _, m := range tt.moves {
+			if g, err = c.move(g, m); err != nil {
+				t.Fatalf("position %d, move %s: %v", tt.n, m, err)
+			}
+		}
+
+		pgn := c.pgn(g)
+		dg, dc, err := decodeChess960(pgn)
+		if err != nil {
+			t.Fatalf("position %d: %v\n%s", tt.n, err, pgn)
+		}
+
+		if got, want := dc.fen(dg), c.fen(g); got != want {
+			t.Errorf("position %d: expected fen %s, got %s", tt.n, want, got)
+		}
+		if got := dc.pgn(dg); got != pgn {
+			t.Errorf("position %d: expected pgn\n%s\ngot\n%s", tt.n, pgn, got)
+		}
+		if dg.Outcome() != g.Outcome() {
+			t.Errorf("position %d: expected outcome %s, got %s", tt.n, g.Outcome(), dg.Outcome())
+		}
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	tests := []struct {
+		fen   string
+		nodes []int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", []int{20, 400, 8902}},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471}},
+	}
+
+	for _, tt := range tests {
+		g, c, err := newChess960(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, want := range tt.nodes {
+			if got := perft(t, g, c, i+1); got != want {
+				t.Errorf("%s depth %d: expected %d, got %d", tt.fen, i+1, want, got)
+			}
+		}
+	}
+}
+
+// perft counts the leaf nodes of the move tree by playing the moves as the game does.
+func perft(t *testing.T, g *chess.Game, c *chess960, depth int) int {
+	moves := []string{}
+	for _, m := range g.ValidMoves() {
+		moves = append(moves, defaultNotation.Encode(g.Position(), m))
+	}
+	moves = append(moves, c.validCastlingMoves(g.Position())...)
+
+	if depth == 1 {
+		return len(moves)
+	}
+
+	n := 0
+	for _, m := range moves {
+		cg, cc := cloneChess960(t, g, c)
+		cg, err := cc.move(cg, m)
+		if err != nil {
+			t.Fatalf("%s: move %s: %v", c.fen(g), m, err)
+		}
+		n += perft(t, cg, cc, depth-1)
+	}
+	return n
+}
+
+func cloneChess960(t *testing.T, g *chess.Game, c *chess960) (*chess.Game, *chess960) {
+	f, err := chess.FEN(g.FEN())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc := &chess960{rooks: make(map[chess.Color][]chess.File)}
+	for color, files := range c.rooks {
+		cc.rooks[color] = append([]chess.File{}, files...)
+	}
+	return chess.NewGame(f, chess.UseNotation(defaultNotation)), cc
+}
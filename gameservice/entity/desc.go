@@ -10,6 +10,9 @@ const (
 	EndDescriptionPlayerLeft     endDescription = "player_left"
 	EndDescriptionPlayerTimeout  endDescription = "player_timeout"
 	EndDescriptionGameTimeout    endDescription = "game_timeout"
+	EndDescriptionEndedByAdmin   endDescription = "ended_by_admin"
+	// aborted games have no result and don't change the ratings
+	EndDescriptionAborted endDescription = "game_aborted"
 )
 
 func (e endDescription) String() string {
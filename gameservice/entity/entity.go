@@ -9,12 +9,28 @@ import (
 
 	"math/rand"
 
+	"github.com/alikarimi999/shahboard/pkg/eco"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/notnil/chess"
 )
 
 var defaultNotation = chess.AlgebraicNotation{}
 
+// standard PGN tags for games that don't start from the standard position
+const (
+	setUpTag = "SetUp"
+	fenTag   = "FEN"
+)
+
+// standard PGN tags that are kept on every game
+const (
+	timeControlTag = "TimeControl"
+	whiteEloTag    = "WhiteElo"
+	blackEloTag    = "BlackElo"
+	ecoTag         = "ECO"
+	openingTag     = "Opening"
+)
+
 type GameStatus uint8
 
 const (
@@ -24,6 +40,32 @@ const (
 
 type GameSettings struct {
 	Time time.Duration
+
+	// Variant of the game, empty means the standard chess.
+	Variant types.Variant
+
+	// FEN is the starting position of the game, empty means the standard starting position
+	// or a random starting position for chess960.
+	FEN string
+}
+
+func (s GameSettings) Validate() error {
+	switch s.Variant.Normalize() {
+	case types.VariantStandard:
+		if s.FEN == "" {
+			return nil
+		}
+		_, err := chess.FEN(s.FEN)
+		return err
+	case types.VariantChess960:
+		if s.FEN == "" {
+			return nil
+		}
+		_, _, err := newChess960(s.FEN)
+		return err
+	default:
+		return fmt.Errorf("unsupported variant: %s", s.Variant)
+	}
 }
 
 type Game struct {
@@ -38,16 +80,57 @@ type Game struct {
 	lock sync.RWMutex
 	game *chess.Game
 
+	// only set for chess960 games
+	c960 *chess960
+
 	do *drawOffer
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
-func NewGame(u1 types.User, u2 types.User, s GameSettings) *Game {
+func NewGame(u1 types.User, u2 types.User, s GameSettings) (*Game, error) {
+	return newGame(u1, u2, s, types.ObjectZero)
+}
+
+// NewRematch creates a game like NewGame, but the given player plays white instead of a random color.
+func NewRematch(u1 types.User, u2 types.User, white types.ObjectId, s GameSettings) (*Game, error) {
+	if white != u1.ID && white != u2.ID {
+		return nil, fmt.Errorf("white player '%s' is not a player of the game", white)
+	}
+	return newGame(u1, u2, s, white)
+}
+
+func newGame(u1 types.User, u2 types.User, s GameSettings, white types.ObjectId) (*Game, error) {
+	var (
+		cg   *chess.Game
+		c960 *chess960
+		err  error
+	)
+
+	switch s.Variant.Normalize() {
+	case types.VariantStandard:
+		cg, err = newChessGame(s.FEN)
+	case types.VariantChess960:
+		if s.FEN == "" {
+			s.FEN = chess960StartFEN(rand.Intn(chess960PositionsNum))
+		}
+		cg, c960, err = newChess960Game(s.FEN)
+	default:
+		err = fmt.Errorf("unsupported variant: %s", s.Variant)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	p1, p2 := setPlayersId(u1, u2)
 	c1, c2 := setColors()
+	if !white.IsZero() {
+		c1, c2 = types.ColorBlack, types.ColorWhite
+		if p1.ID == white {
+			c1, c2 = types.ColorWhite, types.ColorBlack
+		}
+	}
 	p1.Color = c1
 	p2.Color = c2
 	t := time.Now()
@@ -57,7 +140,8 @@ func NewGame(u1 types.User, u2 types.User, s GameSettings) *Game {
 		player1:   p1,
 		player2:   p2,
 		setting:   s,
-		game:      chess.NewGame(chess.UseNotation(defaultNotation)),
+		game:      cg,
+		c960:      c960,
 		CreatedAt: t,
 		UpdatedAt: t,
 	}
@@ -67,8 +151,51 @@ func NewGame(u1 types.User, u2 types.User, s GameSettings) *Game {
 	g.game.AddTagPair("b", g.black().ID.String())
 	g.game.AddTagPair("created_at", t.Format(time.RFC3339))
 	g.game.AddTagPair("updated_at", t.Format(time.RFC3339))
+	g.game.AddTagPair(timeControlTag, strconv.FormatInt(int64(s.Time.Seconds()), 10))
+	g.game.AddTagPair(whiteEloTag, strconv.FormatInt(g.white().Score, 10))
+	g.game.AddTagPair(blackEloTag, strconv.FormatInt(g.black().Score, 10))
 
-	return g
+	return g, nil
+}
+
+// newChessGame creates a chess game from the given FEN, an empty FEN means the standard starting position.
+// The SetUp and FEN tag pairs are recorded so the game can be decoded from its PGN later.
+func newChessGame(fen string) (*chess.Game, error) {
+	if fen == "" {
+		return chess.NewGame(chess.UseNotation(defaultNotation)), nil
+	}
+
+	f, err := chess.FEN(fen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fen: %v", err)
+	}
+
+	g := chess.NewGame(f, chess.UseNotation(defaultNotation))
+	if g.Outcome() != chess.NoOutcome {
+		return nil, fmt.Errorf("invalid fen: game is already over in this position")
+	}
+
+	g.AddTagPair(setUpTag, "1")
+	g.AddTagPair(fenTag, fen)
+
+	return g, nil
+}
+
+func newChess960Game(fen string) (*chess.Game, *chess960, error) {
+	g, c, err := newChess960(fen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if g.Outcome() != chess.NoOutcome {
+		return nil, nil, fmt.Errorf("invalid fen: game is already over in this position")
+	}
+
+	g.AddTagPair(variantTag, variantTagChess960)
+	g.AddTagPair(setUpTag, "1")
+	g.AddTagPair(fenTag, fen)
+
+	return g, c, nil
 }
 
 func (g *Game) ID() types.ObjectId {
@@ -87,6 +214,13 @@ func (g *Game) Player2() types.Player {
 	return g.player2
 }
 
+func (g *Game) Variant() types.Variant {
+	if g.c960 != nil {
+		return types.VariantChess960
+	}
+	return types.VariantStandard
+}
+
 func (g *Game) IsPlayer(player types.ObjectId) bool {
 	return g.player1.ID == player || g.player2.ID == player
 }
@@ -99,17 +233,62 @@ func (g *Game) Move(playerId types.ObjectId, move string, index int) error {
 		return fmt.Errorf("it's not your turn")
 	}
 
-	if (index - 1) != len(g.game.Moves()) {
+	if (index - 1) != g.movesCount() {
 		return fmt.Errorf("invalid move index")
 	}
 
-	if err := g.game.MoveStr(move); err != nil {
-		return err
+	if g.c960 != nil {
+		cg, err := g.c960.move(g.game, move)
+		if err != nil {
+			return err
+		}
+		g.game = cg
+	} else {
+		if err := g.game.MoveStr(move); err != nil {
+			return err
+		}
+		g.tagOpening()
 	}
 	g.UpdatedAt = time.Now()
 	return nil
 }
 
+// tagOpening sets the ECO code and the name of the opening of the game,
+// it's only known for the games that start from the standard position.
+func (g *Game) tagOpening() {
+	if g.c960 != nil || g.setting.FEN != "" {
+		return
+	}
+
+	if o, ok := eco.Find(g.game.Moves()); ok {
+		g.game.AddTagPair(ecoTag, o.ECO)
+		g.game.AddTagPair(openingTag, o.Name)
+	}
+}
+
+// Opening returns the ECO code and the name of the opening, they're empty if the opening is unknown.
+func (g *Game) Opening() eco.Opening {
+	o := eco.Opening{}
+	if tp := g.game.GetTagPair(ecoTag); tp != nil {
+		o.ECO = tp.Value
+	}
+	if tp := g.game.GetTagPair(openingTag); tp != nil {
+		o.Name = tp.Value
+	}
+	return o
+}
+
+func (g *Game) TimeControl() time.Duration {
+	return g.setting.Time
+}
+
+func (g *Game) movesCount() int {
+	if g.c960 != nil {
+		return len(g.c960.moves)
+	}
+	return len(g.game.Moves())
+}
+
 func (g *Game) turn() types.Player {
 	if g.game.Position().Turn() == chess.White {
 		return g.white()
@@ -118,13 +297,29 @@ func (g *Game) turn() types.Player {
 }
 
 func (g *Game) PGN() string {
+	if g.c960 != nil {
+		return g.c960.pgn(g.game)
+	}
 	return g.game.String()
 }
 
 func (g *Game) FEN() string {
+	if g.c960 != nil {
+		return g.c960.fen(g.game)
+	}
 	return g.game.FEN()
 }
 
+// SetupFEN returns the FEN that the game is set up from, empty for the standard starting position.
+func (g *Game) SetupFEN() string {
+	return g.setting.FEN
+}
+
+// StartFEN returns the FEN of the position that the game started from.
+func (g *Game) StartFEN() string {
+	return startFEN(g.game)
+}
+
 // TODO: implement some rules and limitations for sending draw offer by players
 func (g *Game) OfferDraw(offerer types.ObjectId) bool {
 	if !g.IsPlayer(offerer) {
@@ -232,14 +427,58 @@ func (g *Game) black() types.Player {
 }
 
 func (g *Game) Outcome() types.GameOutcome {
+	if tp := g.game.GetTagPair(endDescriptionTag); tp != nil && tp.Value == EndDescriptionAborted.String() {
+		return types.Aborted
+	}
 	return types.GameOutcome(g.game.Outcome().String())
 }
 
+// FirstMoveDeadline returns the player that must make their first move and the time that
+// the game is aborted if they don't. The window starts from the creation of the game for the side to move
+// in the start position, and from the first move for the other side. It returns false if both players have moved.
+func (g *Game) FirstMoveDeadline(window time.Duration) (types.Player, time.Time, bool) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	first, second := g.firstMovers()
+	switch g.movesCount() {
+	case 0:
+		return first, g.CreatedAt.Add(window), true
+	case 1:
+		return second, g.UpdatedAt.Add(window), true
+	}
+	return types.Player{}, time.Time{}, false
+}
+
+// HasMoved returns true if the player has made at least one move.
+func (g *Game) HasMoved(player types.ObjectId) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	n := g.movesCount()
+	if first, _ := g.firstMovers(); first.ID == player {
+		return n >= 1
+	}
+	return n >= 2
+}
+
+// firstMovers returns the players in the order of their first moves, which is set by
+// the active color of the start position, custom positions can start with black to move.
+func (g *Game) firstMovers() (types.Player, types.Player) {
+	if fields := strings.Fields(startFEN(g.game)); len(fields) > 1 && fields[1] == "b" {
+		return g.black(), g.white()
+	}
+	return g.white(), g.black()
+}
+
 func (g *Game) ValidMoves() []string {
 	moves := []string{}
 	for _, m := range g.game.ValidMoves() {
 		moves = append(moves, defaultNotation.Encode(g.game.Position(), m))
 	}
+	if g.c960 != nil {
+		moves = append(moves, g.c960.validCastlingMoves(g.game.Position())...)
+	}
 	return moves
 }
 
@@ -256,6 +495,28 @@ func (g *Game) EndGame() bool {
 	return g.deactivate(EndDescriptionEmpty)
 }
 
+// ForceEnd ends the game with the outcome, it returns false if the outcome is not a result.
+func (g *Game) ForceEnd(outcome types.GameOutcome) bool {
+	switch outcome {
+	case types.WhiteWon:
+		g.game.Resign(chess.Black)
+	case types.BlackWon:
+		g.game.Resign(chess.White)
+	case types.Draw:
+		if err := g.game.Draw(chess.DrawOffer); err != nil {
+			return false
+		}
+	default:
+		return false
+	}
+	return g.deactivate(EndDescriptionEndedByAdmin)
+}
+
+// Abort ends the game without a result, the outcome of the game is aborted.
+func (g *Game) Abort() bool {
+	return g.deactivate(EndDescriptionAborted)
+}
+
 func (g *Game) resign(player types.ObjectId, desc endDescription) bool {
 	if g.player1.ID == player {
 		g.game.Resign(colorToChessColor(g.player1.Color))
@@ -277,8 +538,7 @@ func (g *Game) deactivate(desc endDescription) bool {
 func (g *Game) Encode() []byte {
 	s := fmt.Sprintf("%s:%d:%s:%d:%s:%d\n", g.id.String(), g.status,
 		g.player1.ID.String(), g.player1.Color, g.player2.ID.String(), g.player2.Color)
-	txt, _ := g.game.MarshalText()
-	return []byte(s + string(txt))
+	return []byte(s + g.PGN())
 }
 
 func (g *Game) Decode(data []byte) error {
@@ -328,10 +588,41 @@ func (g *Game) Decode(data []byte) error {
 	g.player1 = types.Player{ID: types.ObjectId(player1), Color: types.Color(color1)}
 	g.player2 = types.Player{ID: types.ObjectId(player2), Color: types.Color(color2)}
 
-	g.game = chess.NewGame(chess.UseNotation(defaultNotation))
+	if strings.Contains(parts[1], fmt.Sprintf("[%s \"%s\"]", variantTag, variantTagChess960)) {
+		cg, c960, err := decodeChess960(parts[1])
+		if err != nil {
+			return fmt.Errorf("failed to decode game text: %v", err)
+		}
+		g.game, g.c960 = cg, c960
+		g.setting.Variant = types.VariantChess960
+	} else {
+		g.game = chess.NewGame(chess.UseNotation(defaultNotation))
+
+		// the FEN tag pair is used by the decoder to set up the starting position
+		if err := g.game.UnmarshalText([]byte(parts[1])); err != nil {
+			return fmt.Errorf("failed to decode game text: %v", err)
+		}
+	}
+
+	if tp := g.game.GetTagPair(fenTag); tp != nil {
+		g.setting.FEN = tp.Value
+	}
 
-	if err := g.game.UnmarshalText([]byte(parts[1])); err != nil {
-		return fmt.Errorf("failed to decode game text: %v", err)
+	if tp := g.game.GetTagPair(timeControlTag); tp != nil {
+		if sec, err := strconv.ParseInt(tp.Value, 10, 64); err == nil {
+			g.setting.Time = time.Duration(sec) * time.Second
+		}
+	}
+
+	// scores are not in the header, they're restored from the PGN
+	for _, p := range []*types.Player{&g.player1, &g.player2} {
+		tag := whiteEloTag
+		if p.Color == types.ColorBlack {
+			tag = blackEloTag
+		}
+		if tp := g.game.GetTagPair(tag); tp != nil {
+			p.Score, _ = strconv.ParseInt(tp.Value, 10, 64)
+		}
 	}
 
 	return nil
@@ -354,6 +645,13 @@ func setColors() (types.Color, types.Color) {
 	return types.ColorBlack, types.ColorWhite
 }
 
+func startFEN(g *chess.Game) string {
+	if tp := g.GetTagPair(fenTag); tp != nil {
+		return tp.Value
+	}
+	return chess.StartingPosition().String()
+}
+
 func colorToChessColor(c types.Color) chess.Color {
 	if c == types.ColorWhite {
 		return chess.White
@@ -0,0 +1,155 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+// Study is an unrated game that is imported from a PGN or a FEN for analysis.
+// It's not played by players, it's only stepped through by its owner and anyone who has its ID.
+type Study struct {
+	id    types.ObjectId
+	owner types.ObjectId
+
+	game *chess.Game
+
+	CreatedAt time.Time
+}
+
+func NewStudyFromPGN(owner types.ObjectId, pgn string) (*Study, error) {
+	f, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pgn: %v", err)
+	}
+
+	return newStudy(owner, chess.NewGame(f, chess.UseNotation(defaultNotation))), nil
+}
+
+func NewStudyFromFEN(owner types.ObjectId, fen string) (*Study, error) {
+	if fen == "" {
+		return nil, fmt.Errorf("fen is required")
+	}
+
+	f, err := chess.FEN(fen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fen: %v", err)
+	}
+
+	g := chess.NewGame(f, chess.UseNotation(defaultNotation))
+	g.AddTagPair(setUpTag, "1")
+	g.AddTagPair(fenTag, fen)
+
+	return newStudy(owner, g), nil
+}
+
+func newStudy(owner types.ObjectId, g *chess.Game) *Study {
+	t := time.Now()
+	s := &Study{
+		id:        types.NewObjectId(),
+		owner:     owner,
+		game:      g,
+		CreatedAt: t,
+	}
+
+	s.game.AddTagPair("id", s.id.String())
+	s.game.AddTagPair("created_at", t.Format(time.RFC3339))
+
+	return s
+}
+
+func (s *Study) ID() types.ObjectId {
+	return s.id
+}
+
+func (s *Study) Owner() types.ObjectId {
+	return s.owner
+}
+
+func (s *Study) PGN() string {
+	return s.game.String()
+}
+
+func (s *Study) StartFEN() string {
+	return startFEN(s.game)
+}
+
+func (s *Study) Outcome() types.GameOutcome {
+	return types.GameOutcome(s.game.Outcome().String())
+}
+
+// Moves returns the moves of the study in algebraic notation.
+func (s *Study) Moves() []string {
+	positions := s.game.Positions()
+	moves := []string{}
+	for i, m := range s.game.Moves() {
+		moves = append(moves, defaultNotation.Encode(positions[i], m))
+	}
+	return moves
+}
+
+// Positions returns the FEN of every position in the study,
+// the first one is the starting position and position i is the one after the i'th move.
+func (s *Study) Positions() []string {
+	fens := []string{}
+	for _, p := range s.game.Positions() {
+		fens = append(fens, p.String())
+	}
+	return fens
+}
+
+// Position returns the FEN and the valid moves of the position after the given ply.
+func (s *Study) Position(ply int) (string, []string, error) {
+	positions := s.game.Positions()
+	if ply < 0 || ply >= len(positions) {
+		return "", nil, fmt.Errorf("invalid ply: %d", ply)
+	}
+
+	p := positions[ply]
+	moves := []string{}
+	for _, m := range p.ValidMoves() {
+		moves = append(moves, defaultNotation.Encode(p, m))
+	}
+
+	return p.String(), moves, nil
+}
+
+func (s *Study) Encode() []byte {
+	h := fmt.Sprintf("%s:%s\n", s.id.String(), s.owner.String())
+	txt, _ := s.game.MarshalText()
+	return []byte(h + string(txt))
+}
+
+func (s *Study) Decode(data []byte) error {
+	parts := strings.SplitN(string(data), "\n", 2)
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid encoded data")
+	}
+
+	headerFields := strings.Split(parts[0], ":")
+	if len(headerFields) != 2 {
+		return fmt.Errorf("invalid header: expected 2 fields, got %d", len(headerFields))
+	}
+
+	id, err := types.ParseObjectId(headerFields[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse id: %v", err)
+	}
+
+	s.id = id
+	s.owner = types.ObjectId(headerFields[1])
+	s.game = chess.NewGame(chess.UseNotation(defaultNotation))
+
+	if err := s.game.UnmarshalText([]byte(parts[1])); err != nil {
+		return fmt.Errorf("failed to decode study text: %v", err)
+	}
+
+	if tp := s.game.GetTagPair("created_at"); tp != nil {
+		s.CreatedAt, _ = time.Parse(time.RFC3339, tp.Value)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/gameservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	ErrGameNotFound   = errors.New("game not found")
+	ErrInvalidOutcome = errors.New("outcome must be one of '1-0', '0-1' or '1/2-1/2'")
+)
+
+// ForceEndGame ends the live game with the outcome, the ratings are updated like a normal game.
+//
+// TODO: like resign, it only works for the games that are handled by this instance or
+// are in the cache, make this ready for a multi instance setup.
+func (s *Service) ForceEndGame(ctx context.Context, admin types.ObjectId, gameId types.ObjectId, req ForceEndGameRequest) error {
+	game, err := s.getLiveGame(ctx, gameId)
+	if err != nil {
+		return err
+	}
+
+	if !game.ForceEnd(req.Outcome) {
+		return ErrInvalidOutcome
+	}
+
+	s.l.Info(fmt.Sprintf("game '%s' ended with '%s' by admin '%s'", gameId, req.Outcome, admin))
+	return s.endGame(ctx, game, entity.EndDescriptionEndedByAdmin.String())
+}
+
+// AbortGame ends the live game without a result, so the ratings don't change.
+func (s *Service) AbortGame(ctx context.Context, admin types.ObjectId, gameId types.ObjectId) error {
+	game, err := s.getLiveGame(ctx, gameId)
+	if err != nil {
+		return err
+	}
+
+	game.Abort()
+
+	s.l.Info(fmt.Sprintf("game '%s' aborted by admin '%s'", gameId, admin))
+	return s.endGame(ctx, game, entity.EndDescriptionAborted.String())
+}
+
+// GetGameState returns the full state of a live game or an ended game that is still in the cache.
+func (s *Service) GetGameState(ctx context.Context, gameId types.ObjectId) (GameStateResponse, error) {
+	game, err := s.getGameByID(ctx, gameId)
+	if err != nil {
+		return GameStateResponse{}, err
+	}
+	if game == nil {
+		return GameStateResponse{}, ErrGameNotFound
+	}
+
+	res := GameStateResponse{
+		ID:          game.ID(),
+		Player1:     game.Player1(),
+		Player2:     game.Player2(),
+		Variant:     game.Variant(),
+		Active:      game.Status() == entity.GameStatusActive,
+		Outcome:     game.Outcome(),
+		FEN:         game.FEN(),
+		PGN:         game.PGN(),
+		TimeControl: int64(game.TimeControl().Seconds()),
+		CreatedAt:   game.CreatedAt.Unix(),
+	}
+
+	for p, t := range s.ct.get(game.ID()) {
+		res.PlayersDisconnection = append(res.PlayersDisconnection, PlayerDisconnection{
+			PlayerId:       p,
+			DisconnectedAt: t.Unix(),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Service) getLiveGame(ctx context.Context, gameId types.ObjectId) (*entity.Game, error) {
+	game, err := s.getGameByID(ctx, gameId)
+	if err != nil {
+		return nil, err
+	}
+
+	if game == nil || game.Status() == entity.GameStatusDeactive {
+		return nil, ErrGameNotFound
+	}
+	return game, nil
+}
+
+func (s *Service) endGame(ctx context.Context, game *entity.Game, desc string) error {
+	if err := s.cache.updateAndDeactivateGame(ctx, game); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.gm.removeGame(game.ID())
+
+	if err := s.pub.Publish(event.EventGameEnded{
+		ID:          types.NewObjectId(),
+		GameID:      game.ID(),
+		Player1:     game.Player1(),
+		Player2:     game.Player2(),
+		Outcome:     game.Outcome(),
+		Desc:        desc,
+		Variant:     game.Variant(),
+		FEN:         game.SetupFEN(),
+		PGN:         game.PGN(),
+		TimeControl: int64(game.TimeControl().Seconds()),
+		ECO:         game.Opening().ECO,
+		Opening:     game.Opening().Name,
+		Timestamp:   time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 const (
 	keyPlayerGamePrefix = "player_game:"
 	keyGamePrefix       = "game:"
+	keyStudyPrefix      = "study:"
 	// keyLiveGamesDataHash = "live_games_data"
 )
 
@@ -344,6 +345,27 @@ func (c *redisGameCache) getGameByID(ctx context.Context, id types.ObjectId) (*e
 	return game, nil
 }
 
+func (c *redisGameCache) addStudy(ctx context.Context, s *entity.Study, ttl time.Duration) error {
+	return c.rc.Set(ctx, fmt.Sprintf("%s%s", keyStudyPrefix, s.ID()), s.Encode(), ttl).Err()
+}
+
+func (c *redisGameCache) getStudyByID(ctx context.Context, id types.ObjectId) (*entity.Study, error) {
+	data, err := c.rc.Get(ctx, fmt.Sprintf("%s%s", keyStudyPrefix, id.String())).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s := &entity.Study{}
+	if err := s.Decode([]byte(data)); err != nil {
+		c.l.Debug(fmt.Sprintf("failed to decode study: %v", err))
+		return nil, err
+	}
+	return s, nil
+}
+
 type inCacheGame struct {
 	Status entity.GameStatus
 	Game   []byte
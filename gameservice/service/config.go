@@ -11,6 +11,13 @@ type Config struct {
 	GamesCap                 uint64              `json:"games_cap"`
 	DefaultGameSettings      entity.GameSettings `json:"default_game_settings"`
 	PlayerDisconnectTreshold uint64              `json:"player_disconnect_threshold"`
+
+	// StudyTTL is the number of seconds that an imported study is kept, default is 24 hours.
+	StudyTTL uint64 `json:"study_ttl"`
+
+	// AbortWindow is the number of seconds that each player has to make their first move
+	// before the game is aborted, default is 30 seconds.
+	AbortWindow uint64 `json:"abort_window"`
 }
 
 func (cfg Config) validate() error {
@@ -30,5 +37,9 @@ func (cfg Config) validate() error {
 		return fmt.Errorf("default game setting time is required")
 	}
 
+	if err := cfg.DefaultGameSettings.Validate(); err != nil {
+		return fmt.Errorf("invalid default game settings: %v", err)
+	}
+
 	return nil
 }
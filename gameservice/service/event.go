@@ -24,7 +24,34 @@ func (s *Service) handleEventUsersMatched(d *event.EventUsersMatchCreated) {
 	}
 
 	// create a new game
-	game := entity.NewGame(d.User1, d.User2, s.cfg.DefaultGameSettings)
+	settings := s.cfg.DefaultGameSettings
+	if settings.Variant.Normalize() != d.Variant.Normalize() {
+		// the default start position belongs to the default variant
+		settings.Variant = d.Variant.Normalize()
+		settings.FEN = ""
+	}
+	if d.FEN != "" {
+		// the players queued for this position
+		settings.FEN = d.FEN
+	}
+
+	var (
+		game *entity.Game
+		err  error
+	)
+	if d.RematchOf.IsZero() {
+		game, err = entity.NewGame(d.User1, d.User2, settings)
+	} else {
+		// the rematch keeps the time control of the previous game
+		if d.TimeControl > 0 {
+			settings.Time = time.Duration(d.TimeControl) * time.Second
+		}
+		game, err = entity.NewRematch(d.User1, d.User2, d.White, settings)
+	}
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
 
 	// add the game to the cache
 	if ok, err := s.cache.addGame(context.Background(), game); err != nil {
@@ -47,6 +74,7 @@ func (s *Service) handleEventUsersMatched(d *event.EventUsersMatchCreated) {
 		MatchID:   d.ID,
 		Player1:   game.Player1(),
 		Player2:   game.Player2(),
+		Variant:   game.Variant(),
 		Timestamp: time.Now().Unix(),
 	}); err != nil {
 		s.l.Error(err.Error())
@@ -90,12 +118,18 @@ func (s *Service) handleEventGamePlayerMoved(d *event.EventGamePlayerMoved) {
 			Timestamp: time.Now().Unix(),
 		},
 			event.EventGameEnded{
-				ID:        types.NewObjectId(),
-				GameID:    game.ID(),
-				Player1:   game.Player1(),
-				Player2:   game.Player2(),
-				Outcome:   game.Outcome(),
-				Timestamp: time.Now().Unix(),
+				ID:          types.NewObjectId(),
+				GameID:      game.ID(),
+				Player1:     game.Player1(),
+				Player2:     game.Player2(),
+				Outcome:     game.Outcome(),
+				Variant:     game.Variant(),
+				FEN:         game.SetupFEN(),
+				PGN:         game.PGN(),
+				TimeControl: int64(game.TimeControl().Seconds()),
+				ECO:         game.Opening().ECO,
+				Opening:     game.Opening().Name,
+				Timestamp:   time.Now().Unix(),
 			}); err != nil {
 			s.l.Error(err.Error())
 			return
@@ -172,12 +206,18 @@ func (s *Service) handleEventGamePlayerClaimDraw(d *event.EventGamePlayerClaimDr
 		}
 
 		if err := s.pub.Publish(ea, event.EventGameEnded{
-			ID:        types.NewObjectId(),
-			GameID:    game.ID(),
-			Player1:   game.Player1(),
-			Player2:   game.Player2(),
-			Outcome:   game.Outcome(),
-			Timestamp: t,
+			ID:          types.NewObjectId(),
+			GameID:      game.ID(),
+			Player1:     game.Player1(),
+			Player2:     game.Player2(),
+			Outcome:     game.Outcome(),
+			Variant:     game.Variant(),
+			FEN:         game.SetupFEN(),
+			PGN:         game.PGN(),
+			TimeControl: int64(game.TimeControl().Seconds()),
+			ECO:         game.Opening().ECO,
+			Opening:     game.Opening().Name,
+			Timestamp:   t,
 		}); err != nil {
 			s.l.Error(err.Error())
 			return
@@ -211,12 +251,18 @@ func (s *Service) handleEventGamePlayerResponsedDrawOffer(d *event.EventGamePlay
 	}
 
 	if err := s.pub.Publish(event.EventGameEnded{
-		ID:        types.NewObjectId(),
-		GameID:    game.ID(),
-		Player1:   game.Player1(),
-		Player2:   game.Player2(),
-		Outcome:   game.Outcome(),
-		Timestamp: time.Now().Unix(),
+		ID:          types.NewObjectId(),
+		GameID:      game.ID(),
+		Player1:     game.Player1(),
+		Player2:     game.Player2(),
+		Outcome:     game.Outcome(),
+		Variant:     game.Variant(),
+		FEN:         game.SetupFEN(),
+		PGN:         game.PGN(),
+		TimeControl: int64(game.TimeControl().Seconds()),
+		ECO:         game.Opening().ECO,
+		Opening:     game.Opening().Name,
+		Timestamp:   time.Now().Unix(),
 	}); err != nil {
 		s.l.Error(err.Error())
 		return
@@ -244,13 +290,19 @@ func (s *Service) handleEventGamePlayerResigned(d *event.EventGamePlayerResigned
 	s.l.Debug(fmt.Sprintf("player '%s' resigned from game '%s'", d.PlayerID, d.GameID))
 
 	if err := s.pub.Publish(event.EventGameEnded{
-		ID:        types.NewObjectId(),
-		GameID:    d.GameID,
-		Player1:   game.Player1(),
-		Player2:   game.Player2(),
-		Outcome:   game.Outcome(),
-		Desc:      entity.EndDescriptionPlayerResigned.String(),
-		Timestamp: time.Now().Unix(),
+		ID:          types.NewObjectId(),
+		GameID:      d.GameID,
+		Player1:     game.Player1(),
+		Player2:     game.Player2(),
+		Outcome:     game.Outcome(),
+		Desc:        entity.EndDescriptionPlayerResigned.String(),
+		Variant:     game.Variant(),
+		FEN:         game.SetupFEN(),
+		PGN:         game.PGN(),
+		TimeControl: int64(game.TimeControl().Seconds()),
+		ECO:         game.Opening().ECO,
+		Opening:     game.Opening().Name,
+		Timestamp:   time.Now().Unix(),
 	}); err != nil {
 		s.l.Error(fmt.Sprintf("failed to publish game ended event: '%s'", d.GameID))
 	}
@@ -12,25 +12,31 @@ import (
 	"github.com/alikarimi999/shahboard/types"
 )
 
+const defaultAbortWindow = 30 * time.Second
+
 type gameManager struct {
 	gMu   sync.RWMutex
 	games map[types.ObjectId]*entity.Game
 
-	ct     *playersConnectionTracker
+	ct          *playersConnectionTracker
+	abortWindow time.Duration
+
 	pub    event.Publisher
 	cache  *redisGameCache
 	l      log.Logger
 	stopCh chan struct{}
 }
 
-func newGameManager(cache *redisGameCache, pub event.Publisher, ct *playersConnectionTracker, l log.Logger) *gameManager {
+func newGameManager(cache *redisGameCache, pub event.Publisher, ct *playersConnectionTracker,
+	abortWindow time.Duration, l log.Logger) *gameManager {
 	gm := &gameManager{
-		games:  make(map[types.ObjectId]*entity.Game),
-		ct:     ct,
-		pub:    pub,
-		cache:  cache,
-		l:      l,
-		stopCh: make(chan struct{}),
+		games:       make(map[types.ObjectId]*entity.Game),
+		ct:          ct,
+		abortWindow: abortWindow,
+		pub:         pub,
+		cache:       cache,
+		l:           l,
+		stopCh:      make(chan struct{}),
 	}
 	gm.run()
 
@@ -46,27 +52,67 @@ func (gm *gameManager) run() {
 				t.Stop()
 				return
 			case <-t.C:
+				gm.checkAbortWindow()
 				gm.checkPlayersConnection()
 			}
 		}
 	}()
 }
 
+// checkAbortWindow aborts the games that a player hasn't made their first move in the abort window,
+// aborted games have no result so the ratings don't change.
+func (gm *gameManager) checkAbortWindow() {
+	now := time.Now()
+	endedGames := []*entity.Game{}
+	events := []event.Event{}
+
+	for _, game := range gm.getList() {
+		if game.Status() == entity.GameStatusDeactive {
+			continue
+		}
+
+		player, deadline, ok := game.FirstMoveDeadline(gm.abortWindow)
+		if !ok || now.Before(deadline) {
+			continue
+		}
+
+		game.Abort()
+		gm.l.Debug(fmt.Sprintf("game %s aborted, player %s didn't move", game.ID(), player.ID))
+
+		endedGames = append(endedGames, game)
+		events = append(events, gameAbortedEvent(game, player.ID))
+	}
+
+	gm.endGames(endedGames, events)
+}
+
 // checkPlayersConnection find games that their players are disconnected are disconnected for more than disconnectThreshold
 // and remove them from cache and publish game ended event.
+// If the player left before making their first move the game is aborted.
 func (gm *gameManager) checkPlayersConnection() {
 	games := gm.getList()
 	needToEnd := gm.ct.getGamesNeedsToRemove()
 
 	endedGames := []*entity.Game{}
-	endedGamesId := []types.ObjectId{}
 	events := []event.Event{}
 
 	// only proccess games that handling by this instance and let each instance handle its own games
 	for _, game := range games {
 		if playerId, ok := needToEnd[game.ID()]; ok {
+			if game.Status() == entity.GameStatusDeactive {
+				continue
+			}
 
-			if game.Status() == entity.GameStatusDeactive || !game.PlayerLeft(playerId) {
+			if !game.HasMoved(playerId) {
+				game.Abort()
+				gm.l.Debug(fmt.Sprintf("game %s aborted, player %s left before moving", game.ID(), playerId))
+
+				endedGames = append(endedGames, game)
+				events = append(events, gameAbortedEvent(game, playerId))
+				continue
+			}
+
+			if !game.PlayerLeft(playerId) {
 				continue
 			}
 
@@ -75,18 +121,31 @@ func (gm *gameManager) checkPlayersConnection() {
 			endedGames = append(endedGames, game)
 
 			events = append(events, event.EventGameEnded{
-				GameID:    game.ID(),
-				Player1:   game.Player1(),
-				Player2:   game.Player2(),
-				Outcome:   game.Outcome(),
-				Desc:      entity.EndDescriptionPlayerLeft.String(),
-				Timestamp: time.Now().Unix(),
+				GameID:      game.ID(),
+				Player1:     game.Player1(),
+				Player2:     game.Player2(),
+				Outcome:     game.Outcome(),
+				Desc:        entity.EndDescriptionPlayerLeft.String(),
+				Variant:     game.Variant(),
+				FEN:         game.SetupFEN(),
+				PGN:         game.PGN(),
+				TimeControl: int64(game.TimeControl().Seconds()),
+				ECO:         game.Opening().ECO,
+				Opening:     game.Opening().Name,
+				Timestamp:   time.Now().Unix(),
 			})
-
-			endedGamesId = append(endedGamesId, game.ID())
 		}
 	}
 
+	gm.endGames(endedGames, events)
+}
+
+func (gm *gameManager) endGames(endedGames []*entity.Game, events []event.Event) {
+	endedGamesId := make([]types.ObjectId, 0, len(endedGames))
+	for _, game := range endedGames {
+		endedGamesId = append(endedGamesId, game.ID())
+	}
+
 	if len(endedGames) > 0 {
 		if err := gm.cache.updateAndDeactivateGame(context.Background(), endedGames...); err != nil {
 			gm.l.Error(err.Error())
@@ -104,6 +163,23 @@ func (gm *gameManager) checkPlayersConnection() {
 	}
 }
 
+func gameAbortedEvent(game *entity.Game, abortedBy types.ObjectId) event.EventGameEnded {
+	return event.EventGameEnded{
+		ID:          types.NewObjectId(),
+		GameID:      game.ID(),
+		Player1:     game.Player1(),
+		Player2:     game.Player2(),
+		Outcome:     game.Outcome(),
+		Desc:        entity.EndDescriptionAborted.String(),
+		Variant:     game.Variant(),
+		FEN:         game.SetupFEN(),
+		PGN:         game.PGN(),
+		TimeControl: int64(game.TimeControl().Seconds()),
+		AbortedBy:   abortedBy,
+		Timestamp:   time.Now().Unix(),
+	}
+}
+
 func (gm *gameManager) addGame(g *entity.Game) bool {
 	gm.gMu.Lock()
 	defer gm.gMu.Unlock()
@@ -81,13 +81,19 @@ func (s *Service) ResingByPlayer(ctx context.Context, gameId, playerId types.Obj
 	s.gm.removeGame(gameId)
 
 	if err := s.pub.Publish(event.EventGameEnded{
-		ID:        types.NewObjectId(),
-		GameID:    gameId,
-		Player1:   game.Player1(),
-		Player2:   game.Player2(),
-		Outcome:   game.Outcome(),
-		Desc:      entity.EndDescriptionPlayerResigned.String(),
-		Timestamp: time.Now().Unix(),
+		ID:          types.NewObjectId(),
+		GameID:      gameId,
+		Player1:     game.Player1(),
+		Player2:     game.Player2(),
+		Outcome:     game.Outcome(),
+		Desc:        entity.EndDescriptionPlayerResigned.String(),
+		Variant:     game.Variant(),
+		FEN:         game.SetupFEN(),
+		PGN:         game.PGN(),
+		TimeControl: int64(game.TimeControl().Seconds()),
+		ECO:         game.Opening().ECO,
+		Opening:     game.Opening().Name,
+		Timestamp:   time.Now().Unix(),
 	}); err != nil {
 		s.l.Error(err.Error())
 	}
@@ -119,6 +125,21 @@ func (s *Service) GetLiveGamePGN(ctx context.Context, id types.ObjectId) (GetGam
 	return s.getGamePGN(ctx, id)
 }
 
+// GetGamePGN returns the PGN of a live game or an ended game that is still in the cache.
+// It returns an empty response with a zero ID if the game is not found.
+func (s *Service) GetGamePGN(ctx context.Context, id types.ObjectId) (GetGamePGNResponse, error) {
+	game, err := s.cache.getGameByID(ctx, id)
+	if err != nil {
+		return GetGamePGNResponse{}, err
+	}
+
+	if game == nil {
+		return GetGamePGNResponse{ID: types.ObjectZero}, nil
+	}
+
+	return GetGamePGNResponse{ID: game.ID(), PGN: game.PGN()}, nil
+}
+
 func (s *Service) getGamePGN(ctx context.Context, id types.ObjectId) (GetGamePGNResponse, error) {
 	game, err := s.cache.getGameByID(ctx, id)
 	if err != nil {
@@ -37,3 +37,46 @@ type GetGameFenResponse struct {
 	ID  types.ObjectId `json:"id"`
 	FEN string         `json:"fen"`
 }
+
+type CreateStudyRequest struct {
+	PGN string `json:"pgn"`
+	FEN string `json:"fen"`
+}
+
+type StudyResponse struct {
+	ID        types.ObjectId    `json:"id"`
+	Owner     types.ObjectId    `json:"owner"`
+	PGN       string            `json:"pgn"`
+	StartFEN  string            `json:"start_fen"`
+	Moves     []string          `json:"moves"`
+	Positions []string          `json:"positions"`
+	Outcome   types.GameOutcome `json:"outcome"`
+	CreatedAt int64             `json:"created_at"`
+}
+
+type StudyPositionResponse struct {
+	ID         types.ObjectId `json:"id"`
+	Ply        int            `json:"ply"`
+	Move       string         `json:"move"`
+	FEN        string         `json:"fen"`
+	ValidMoves []string       `json:"valid_moves"`
+}
+
+type ForceEndGameRequest struct {
+	Outcome types.GameOutcome `json:"outcome"`
+}
+
+type GameStateResponse struct {
+	ID          types.ObjectId    `json:"id"`
+	Player1     types.Player      `json:"player1"`
+	Player2     types.Player      `json:"player2"`
+	Variant     types.Variant     `json:"variant"`
+	Active      bool              `json:"active"`
+	Outcome     types.GameOutcome `json:"outcome"`
+	FEN         string            `json:"fen"`
+	PGN         string            `json:"pgn"`
+	TimeControl int64             `json:"time_control"`
+	CreatedAt   int64             `json:"created_at"`
+
+	PlayersDisconnection []PlayerDisconnection `json:"players_disconnection"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/eco"
 	"github.com/alikarimi999/shahboard/pkg/log"
 	"github.com/redis/go-redis/v9"
 )
@@ -45,9 +46,17 @@ func NewGameService(cfg Config, redis *redis.Client, pub event.Publisher, sub ev
 
 		closeCh: make(chan struct{}),
 	}
-	s.gm = newGameManager(s.cache, pub, ct, l)
+	abortWindow := defaultAbortWindow
+	if cfg.AbortWindow > 0 {
+		abortWindow = time.Duration(cfg.AbortWindow) * time.Second
+	}
+
+	s.gm = newGameManager(s.cache, pub, ct, abortWindow, l)
 	s.live = newLiveGamesService(s.cache, ws, l)
 
+	// the opening book is used to tag the games, load it before the first move
+	go eco.Load()
+
 	s.sm = event.NewManager(l, s.handleEvents)
 	s.sm.AddSubscription(s.sub.Subscribe(event.TopicUsersMatchedCreated))
 	s.sm.AddSubscription(s.sub.Subscribe(event.TopicGame))
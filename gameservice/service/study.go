@@ -0,0 +1,108 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/gameservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const defaultStudyTTL = 24 * time.Hour
+
+// CreateStudy imports a PGN or a FEN as an unrated study that can be stepped through.
+// If both of them are provided the PGN is used.
+func (s *Service) CreateStudy(ctx context.Context, owner types.ObjectId, req CreateStudyRequest) (StudyResponse, error) {
+	var (
+		study *entity.Study
+		err   error
+	)
+
+	switch {
+	case strings.TrimSpace(req.PGN) != "":
+		study, err = entity.NewStudyFromPGN(owner, req.PGN)
+	case req.FEN != "":
+		study, err = entity.NewStudyFromFEN(owner, req.FEN)
+	default:
+		return StudyResponse{}, fmt.Errorf("pgn or fen is required")
+	}
+	if err != nil {
+		return StudyResponse{}, err
+	}
+
+	ttl := defaultStudyTTL
+	if s.cfg.StudyTTL > 0 {
+		ttl = time.Duration(s.cfg.StudyTTL) * time.Second
+	}
+
+	if err := s.cache.addStudy(ctx, study, ttl); err != nil {
+		s.l.Error(err.Error())
+		return StudyResponse{}, err
+	}
+
+	s.l.Debug(fmt.Sprintf("study '%s' created by '%s'", study.ID(), owner))
+
+	return studyToResponse(study), nil
+}
+
+func (s *Service) GetStudy(ctx context.Context, id types.ObjectId) (StudyResponse, error) {
+	study, err := s.getStudyByID(ctx, id)
+	if err != nil {
+		return StudyResponse{}, err
+	}
+
+	return studyToResponse(study), nil
+}
+
+// GetStudyPosition returns the position after the given ply of a study, ply 0 is the starting position.
+func (s *Service) GetStudyPosition(ctx context.Context, id types.ObjectId, ply int) (StudyPositionResponse, error) {
+	study, err := s.getStudyByID(ctx, id)
+	if err != nil {
+		return StudyPositionResponse{}, err
+	}
+
+	fen, validMoves, err := study.Position(ply)
+	if err != nil {
+		return StudyPositionResponse{}, err
+	}
+
+	res := StudyPositionResponse{
+		ID:         study.ID(),
+		Ply:        ply,
+		FEN:        fen,
+		ValidMoves: validMoves,
+	}
+	if ply > 0 {
+		res.Move = study.Moves()[ply-1]
+	}
+
+	return res, nil
+}
+
+func (s *Service) getStudyByID(ctx context.Context, id types.ObjectId) (*entity.Study, error) {
+	study, err := s.cache.getStudyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if study == nil {
+		return nil, fmt.Errorf("study not found")
+	}
+
+	return study, nil
+}
+
+func studyToResponse(s *entity.Study) StudyResponse {
+	return StudyResponse{
+		ID:        s.ID(),
+		Owner:     s.Owner(),
+		PGN:       s.PGN(),
+		StartFEN:  s.StartFEN(),
+		Moves:     s.Moves(),
+		Positions: s.Positions(),
+		Outcome:   s.Outcome(),
+		CreatedAt: s.CreatedAt.Unix(),
+	}
+}
@@ -1,15 +1,20 @@
 package matchservice
 
 import (
+	"context"
+
 	"github.com/alikarimi999/shahboard/event/kafka"
 	"github.com/alikarimi999/shahboard/matchservice/delivery/http"
 	match "github.com/alikarimi999/shahboard/matchservice/service"
 	"github.com/alikarimi999/shahboard/matchservice/services"
 	"github.com/alikarimi999/shahboard/matchservice/services/game"
 
+	"github.com/alikarimi999/shahboard/pkg/block"
 	"github.com/alikarimi999/shahboard/pkg/grpc"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/sanction"
+	"github.com/redis/go-redis/v9"
 )
 
 type application struct {
@@ -26,7 +31,7 @@ func SetupApplication(cfg Config) (*application, error) {
 		return nil, err
 	}
 
-	p, _, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
+	p, sub, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +46,22 @@ func SetupApplication(cfg Config) (*application, error) {
 		return nil, err
 	}
 
-	s, err := match.NewService(cfg.Match, p, services.NewRatingService(rc), game.NewService(gc), l)
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+
+	v.WithDenylist(jwt.NewRedisDenylist(rdb))
+
+	sr := sanction.NewRegistry(rdb, sub, l)
+	br := block.NewRegistry(rdb, sub, l)
+
+	s, err := match.NewService(cfg.Match, p, sub, rdb, services.NewRatingService(rc), game.NewService(gc), sr, br, l)
 	if err != nil {
 		return nil, err
 	}
@@ -12,12 +12,19 @@ type Config struct {
 	Match         match.Config        `json:"match_service"`
 	Http          http.Config         `json:"http"`
 	Kafka         kafka.Config        `json:"kafka"`
+	Redis         RedisConfig         `json:"redis"`
 	Log           LogConfig           `json:"log"`
 	JwtValidator  jwt.ValidatorConfig `json:"jwt_validator"`
 	GameService   grpc.Config         `json:"game_service_grpc"`
 	RatingService grpc.Config         `json:"rating_service_grpc"`
 }
 
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
+
 type LogConfig struct {
 	File    string `json:"file"`
 	Verbose bool   `json:"verbose"`
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	match "github.com/alikarimi999/shahboard/matchservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) setupRematchRoutes() {
+	g := r.gin.Group("/rematch")
+	g.GET("/:id", r.getRematch)
+	g.POST("/:id", r.offerRematch)
+	g.DELETE("/:id", r.declineRematch)
+}
+
+func (r *Router) getRematch(c *gin.Context) {
+	gameId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return
+	}
+
+	res, err := r.s.GetRematch(c.Request.Context(), gameId)
+	if err != nil {
+		c.JSON(errs.HTTP(rematchErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// offerRematch offers a rematch to the opponent, if the opponent has already offered
+// the rematch is accepted and the match is returned.
+func (r *Router) offerRematch(c *gin.Context) {
+	u := getUser(c)
+
+	gameId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return
+	}
+
+	res, err := r.s.OfferRematch(c.Request.Context(), u.ID, gameId)
+	if err != nil {
+		c.JSON(errs.HTTP(rematchErrCode(err), err.Error()))
+		return
+	}
+
+	if res.Match != nil {
+		c.JSON(http.StatusCreated, res)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) declineRematch(c *gin.Context) {
+	u := getUser(c)
+
+	gameId, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return
+	}
+
+	if err := r.s.DeclineRematch(c.Request.Context(), u.ID, gameId); err != nil {
+		c.JSON(errs.HTTP(rematchErrCode(err), err.Error()))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func rematchErrCode(err error) string {
+	switch {
+	case errors.Is(err, match.ErrRematchNotFound):
+		return errs.CodeNotFound
+	case errors.Is(err, match.ErrRematchNotPlayer), errors.Is(err, match.ErrUserSanctioned),
+		errors.Is(err, match.ErrUserBlocked):
+		return errs.CodePermissionDenied
+	case errors.Is(err, match.ErrMatchCooldown):
+		return errs.CodeRateLimited
+	case errors.Is(err, match.ErrRematchAlreadyOffered), errors.Is(err, match.ErrUserInGame),
+		errors.Is(err, match.ErrOpponentCantPlay):
+		return errs.CodeConflict
+	default:
+		return errs.CodeInternalError
+	}
+}
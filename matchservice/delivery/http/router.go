@@ -41,6 +41,7 @@ func (r *Router) Run() error {
 
 func (r *Router) setup() error {
 	r.setupUserRoutes()
+	r.setupRematchRoutes()
 
 	return nil
 }
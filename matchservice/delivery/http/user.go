@@ -1,8 +1,11 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
+	match "github.com/alikarimi999/shahboard/matchservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/gin-gonic/gin"
 )
@@ -14,9 +17,32 @@ func (r *Router) setupUserRoutes() {
 func (r *Router) newMatchRequest(c *gin.Context) {
 	u := getUser(c)
 
-	m, err := r.s.NewMatchRequest(c.Request.Context(), u.ID)
+	variant, err := types.ParseVariant(c.Query("variant"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	// e.g. /find?variant=standard&fen=... starts the game from a thematic opening or a puzzle position
+	m, err := r.s.NewMatchRequest(c.Request.Context(), u.ID, variant, c.Query("fen"))
+	if err != nil {
+		if errors.Is(err, match.ErrInvalidPosition) {
+			c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+			return
+		}
+		if errors.Is(err, match.ErrUserSanctioned) {
+			c.JSON(errs.HTTP(errs.CodePermissionDenied, err.Error()))
+			return
+		}
+		if errors.Is(err, match.ErrUserInGame) {
+			c.JSON(errs.HTTP(errs.CodeConflict, err.Error()))
+			return
+		}
+		if errors.Is(err, match.ErrMatchCooldown) {
+			c.JSON(errs.HTTP(errs.CodeRateLimited, err.Error()))
+			return
+		}
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
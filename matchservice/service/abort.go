@@ -0,0 +1,85 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyAbortsPrefix   = "match_aborts:"
+	keyCooldownPrefix = "match_cooldown:"
+)
+
+var ErrMatchCooldown = errors.New("matchmaking is paused for aborting too many games")
+
+// abortTracker counts the games that each user aborted by not making their first move,
+// users that abort too many games in the period can't queue until the cooldown is over.
+type abortTracker struct {
+	rc       *redis.Client
+	limit    int64
+	period   time.Duration
+	cooldown time.Duration
+}
+
+func newAbortTracker(rc *redis.Client, cfg Config) *abortTracker {
+	return &abortTracker{
+		rc:       rc,
+		limit:    cfg.AbortLimit,
+		period:   time.Duration(cfg.AbortPeriod) * time.Second,
+		cooldown: time.Duration(cfg.AbortCooldown) * time.Second,
+	}
+}
+
+// add counts an abort of the user and returns true if the user's cooldown is started.
+func (t *abortTracker) add(ctx context.Context, userId types.ObjectId) (bool, error) {
+	script := `
+	local n = redis.call('INCR', KEYS[1])
+	if n == 1 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	end
+	if n >= tonumber(ARGV[2]) then
+		redis.call('DEL', KEYS[1])
+		redis.call('SET', KEYS[2], 1, 'PX', ARGV[3])
+		return 1
+	end
+	return 0
+	`
+
+	n, err := t.rc.Eval(ctx, script, []string{keyAbortsPrefix + userId.String(), keyCooldownPrefix + userId.String()},
+		t.period.Milliseconds(), t.limit, t.cooldown.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to count abort of user '%s': %w", userId, err)
+	}
+
+	return n == 1, nil
+}
+
+// remaining returns the remaining time of the user's cooldown, zero if the user is not in cooldown.
+func (t *abortTracker) remaining(ctx context.Context, userId types.ObjectId) (time.Duration, error) {
+	d, err := t.rc.PTTL(ctx, keyCooldownPrefix+userId.String()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cooldown of user '%s': %w", userId, err)
+	}
+
+	// negative values mean the key doesn't exist or has no expiry
+	return max(d, 0), nil
+}
+
+// countAbort counts the abort of the player that didn't make their first move.
+func (s *Service) countAbort(e *event.EventGameEnded) {
+	started, err := s.aborts.add(context.Background(), e.AbortedBy)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+
+	if started {
+		s.l.Debug(fmt.Sprintf("user '%s' aborted too many games, matchmaking cooldown started", e.AbortedBy))
+	}
+}
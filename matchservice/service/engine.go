@@ -10,19 +10,26 @@ import (
 )
 
 type engine struct {
-	t     time.Ticker
-	mu    sync.Mutex
-	queue map[types.Level][]*matchRequest
+	t  time.Ticker
+	mu sync.Mutex
+	// each variant and start position has its own queues, players are only matched with players
+	// that queued for the same variant and start position
+	queue map[queueKey]map[types.Level][]*matchRequest
 
 	matchCh chan []*event.EventUsersMatchCreated
 	stopCh  chan struct{}
 	wg      sync.WaitGroup
 }
 
+type queueKey struct {
+	variant types.Variant
+	fen     string
+}
+
 func newEngine(ticker time.Duration) *engine {
 	e := &engine{
 		t:       *time.NewTicker(ticker),
-		queue:   make(map[types.Level][]*matchRequest, 0),
+		queue:   make(map[queueKey]map[types.Level][]*matchRequest, 0),
 		matchCh: make(chan []*event.EventUsersMatchCreated),
 		stopCh:  make(chan struct{}),
 	}
@@ -55,21 +62,29 @@ func (e *engine) stop() {
 	e.wg.Wait()
 }
 
-func (e *engine) addToQueue(pId types.ObjectId, s int64) (*matchRequest, bool) {
+// addToQueue adds the request of the player, fen is the start position of the game and empty for the start position
+// of the variant, blocked are the players that it can't be paired with.
+func (e *engine) addToQueue(pId types.ObjectId, s int64, v types.Variant, fen string, blocked []types.ObjectId) (*matchRequest, bool) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	for _, q := range e.queue {
-		for _, req := range q {
-			if req.userId == pId {
-				return nil, false
+	for _, vq := range e.queue {
+		for _, q := range vq {
+			for _, req := range q {
+				if req.userId == pId {
+					return nil, false
+				}
 			}
 		}
 	}
 
 	l := elo.GetPlayerLevel(s)
-	r := newMatchRequest(pId, s, l)
-	e.queue[l] = append(e.queue[l], r)
+	r := newMatchRequest(pId, s, l, v, fen, blocked)
+	k := r.queueKey()
+	if e.queue[k] == nil {
+		e.queue[k] = make(map[types.Level][]*matchRequest)
+	}
+	e.queue[k][l] = append(e.queue[k][l], r)
 
 	return r, true
 }
@@ -78,10 +93,11 @@ func (e *engine) cancelRequest(r *matchRequest) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	users := e.queue[r.level]
+	k := r.queueKey()
+	users := e.queue[k][r.level]
 	for i, req := range users {
 		if req.userId == r.userId {
-			e.queue[r.level] = append(users[:i], users[i+1:]...)
+			e.queue[k][r.level] = append(users[:i], users[i+1:]...)
 			return
 		}
 	}
@@ -91,21 +107,45 @@ func (e *engine) findMatches() []*event.EventUsersMatchCreated {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	var matches []*event.EventUsersMatchCreated
+	for k, q := range e.queue {
+		matches = append(matches, findMatches(k, q)...)
+	}
+
+	return matches
+}
+
+// findMatches matches the players of a variant and start position queue from the highest level to the lowest,
+// players that can't be matched in their level are moved to the lower level.
+func findMatches(k queueKey, queue map[types.Level][]*matchRequest) []*event.EventUsersMatchCreated {
 	var leftover []*matchRequest
 	var matches []*event.EventUsersMatchCreated
 
 	t := time.Now().Unix()
 	for l := types.LevelKing; l >= types.LevelPawn; l-- {
-		currentQueue := append(leftover, e.queue[l]...)
+		currentQueue := append(leftover, queue[l]...)
 		leftover = nil
 
 		for len(currentQueue) > 1 {
 			u1 := currentQueue[0]
-			u2 := currentQueue[1]
+			// the first player in the queue that u1 can be paired with
+			j := 1
+			for j < len(currentQueue) && !u1.canPair(currentQueue[j]) {
+				j++
+			}
+			if j == len(currentQueue) {
+				leftover = append(leftover, u1)
+				currentQueue = currentQueue[1:]
+				continue
+			}
+
+			u2 := currentQueue[j]
 			m := &event.EventUsersMatchCreated{
 				ID:        types.NewObjectId(),
 				User1:     types.User{ID: u1.userId, Score: u1.score},
 				User2:     types.User{ID: u2.userId, Score: u2.score},
+				Variant:   k.variant,
+				FEN:       k.fen,
 				Timestamp: t,
 			}
 
@@ -113,18 +153,18 @@ func (e *engine) findMatches() []*event.EventUsersMatchCreated {
 			u2.sendResponse(m)
 
 			matches = append(matches, m)
-			currentQueue = currentQueue[2:]
+			currentQueue = append(currentQueue[1:j], currentQueue[j+1:]...)
 		}
 
 		if len(currentQueue) > 0 {
 			leftover = append(leftover, currentQueue...)
 		}
 
-		e.queue[l] = nil
+		queue[l] = nil
 
 		if l == types.LevelPawn {
 			for _, r := range leftover {
-				e.queue[r.level] = append(e.queue[r.level], r)
+				queue[r.level] = append(queue[r.level], r)
 			}
 		}
 	}
@@ -133,19 +173,41 @@ func (e *engine) findMatches() []*event.EventUsersMatchCreated {
 }
 
 type matchRequest struct {
-	userId types.ObjectId
-	score  int64
-	level  types.Level
-	ch     chan *event.EventUsersMatchCreated
+	userId  types.ObjectId
+	score   int64
+	level   types.Level
+	variant types.Variant
+	fen     string
+	blocked map[types.ObjectId]struct{}
+	ch      chan *event.EventUsersMatchCreated
 }
 
-func newMatchRequest(pId types.ObjectId, s int64, l types.Level) *matchRequest {
-	return &matchRequest{
-		userId: pId,
-		score:  s,
-		level:  l,
-		ch:     make(chan *event.EventUsersMatchCreated, 1),
+func newMatchRequest(pId types.ObjectId, s int64, l types.Level, v types.Variant, fen string, blocked []types.ObjectId) *matchRequest {
+	r := &matchRequest{
+		userId:  pId,
+		score:   s,
+		level:   l,
+		variant: v,
+		fen:     fen,
+		blocked: make(map[types.ObjectId]struct{}, len(blocked)),
+		ch:      make(chan *event.EventUsersMatchCreated, 1),
+	}
+	for _, id := range blocked {
+		r.blocked[id] = struct{}{}
 	}
+	return r
+}
+
+func (m *matchRequest) queueKey() queueKey {
+	return queueKey{variant: m.variant, fen: m.fen}
+}
+
+// canPair returns false if any of the players blocked the other one, both sides are checked
+// since a block can be added after one of the requests.
+func (m *matchRequest) canPair(o *matchRequest) bool {
+	_, b1 := m.blocked[o.userId]
+	_, b2 := o.blocked[m.userId]
+	return !b1 && !b2
 }
 
 func (m matchRequest) sendResponse(r *event.EventUsersMatchCreated) {
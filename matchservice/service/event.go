@@ -0,0 +1,35 @@
+package match
+
+import (
+	"github.com/alikarimi999/shahboard/event"
+)
+
+func (s *Service) handleEvents(e event.Event) {
+	switch e.GetTopic().Domain() {
+	case event.DomainGame:
+		switch e.GetTopic().Action() {
+		case event.ActionCreated:
+			s.handleGameCreated(e.(*event.EventGameCreated))
+		case event.ActionEnded:
+			s.handleGameEnded(e.(*event.EventGameEnded))
+		}
+	}
+}
+
+func (s *Service) handleGameCreated(e *event.EventGameCreated) {
+	if s.rematches != nil {
+		s.linkRematchGame(e)
+	}
+}
+
+func (s *Service) handleGameEnded(e *event.EventGameEnded) {
+	// only the players that didn't make their first move are counted
+	if s.aborts != nil && !e.AbortedBy.IsZero() {
+		s.countAbort(e)
+	}
+
+	// aborted games can't be rematched
+	if s.rematches != nil && e.Outcome.IsResult() {
+		s.addRematch(e)
+	}
+}
@@ -0,0 +1,43 @@
+package match
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+var ErrInvalidPosition = errors.New("invalid start position")
+
+// startPosition validates the start position that the user queues for and returns it in the form
+// that Game Service records, so the requests of the same position are matched together.
+// An empty FEN or the standard starting position means the start position of the variant.
+func startPosition(v types.Variant, fen string) (string, error) {
+	fen = strings.TrimSpace(fen)
+	if fen == "" {
+		return "", nil
+	}
+
+	// the chess960 games start from a random position of the variant
+	if v.Normalize() != types.VariantStandard {
+		return "", fmt.Errorf("%w: custom start positions are only supported in %s", ErrInvalidPosition, types.VariantStandard)
+	}
+
+	f, err := chess.FEN(fen)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPosition, err)
+	}
+
+	g := chess.NewGame(f)
+	if g.Outcome() != chess.NoOutcome {
+		return "", fmt.Errorf("%w: game is already over in this position", ErrInvalidPosition)
+	}
+
+	fen = g.Position().String()
+	if fen == chess.StartingPosition().String() {
+		return "", nil
+	}
+	return fen, nil
+}
@@ -0,0 +1,556 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/elo"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ended games that can be rematched, by game id
+	keyRematchPrefix = "match_rematch:"
+	// series of the accepted rematches until their game is created, by match id
+	keyRematchMatchPrefix = "match_rematch_match:"
+	// series of the games, by game id
+	keyRematchGamePrefix   = "match_rematch_game:"
+	keyRematchSeriesPrefix = "match_rematch_series:"
+
+	// a series is kept for a day after its last game
+	rematchSeriesTTL = 24 * time.Hour
+	rematchMatchTTL  = 10 * time.Minute
+)
+
+var (
+	ErrUserInGame            = errors.New("user is already in a game")
+	ErrRematchNotFound       = errors.New("rematch is not available for this game")
+	ErrRematchNotPlayer      = errors.New("only the players of the game can rematch")
+	ErrRematchAlreadyOffered = errors.New("rematch is already offered")
+	ErrOpponentCantPlay      = errors.New("opponent can't play a game now")
+)
+
+// Rematch is the rematch state of an ended game, Match is set when the rematch is accepted.
+type Rematch struct {
+	GameID    types.ObjectId                `json:"game_id"`
+	OfferedBy types.ObjectId                `json:"offered_by,omitempty"`
+	ExpiresAt int64                         `json:"expires_at,omitempty"`
+	Match     *event.EventUsersMatchCreated `json:"match,omitempty"`
+	Series    *RematchSeries                `json:"series,omitempty"`
+}
+
+// RematchSeries is the score of the games that a pair played by rematching each other.
+type RematchSeries struct {
+	ID      types.ObjectId `json:"id"`
+	Games   int64          `json:"games"`
+	Draws   int64          `json:"draws"`
+	Players []SeriesPlayer `json:"players"`
+}
+
+type SeriesPlayer struct {
+	ID    types.ObjectId `json:"id"`
+	Wins  int64          `json:"wins"`
+	Score float64        `json:"score"`
+}
+
+type rematch struct {
+	gameId      types.ObjectId
+	white       types.ObjectId
+	black       types.ObjectId
+	variant     types.Variant
+	fen         string
+	timeControl int64
+	seriesId    types.ObjectId
+	offeredBy   types.ObjectId
+	expiresAt   int64
+}
+
+// opponent returns the opponent of the user, and zero if the user is not a player of the game.
+func (r *rematch) opponent(userId types.ObjectId) types.ObjectId {
+	switch userId {
+	case r.white:
+		return r.black
+	case r.black:
+		return r.white
+	}
+	return types.ObjectZero
+}
+
+type rematchStore struct {
+	rc     *redis.Client
+	window time.Duration
+}
+
+func newRematchStore(rc *redis.Client, window time.Duration) *rematchStore {
+	return &rematchStore{rc: rc, window: window}
+}
+
+func (st *rematchStore) add(ctx context.Context, r *rematch) error {
+	key := keyRematchPrefix + r.gameId.String()
+	_, err := st.rc.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key,
+			"white", r.white.String(),
+			"black", r.black.String(),
+			"variant", r.variant.String(),
+			"fen", r.fen,
+			"time_control", r.timeControl,
+			"series", r.seriesId.String(),
+			"expires_at", r.expiresAt,
+		)
+		p.Expire(ctx, key, st.window)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add rematch of game '%s': %w", r.gameId, err)
+	}
+	return nil
+}
+
+func (st *rematchStore) get(ctx context.Context, gameId types.ObjectId) (*rematch, error) {
+	m, err := st.rc.HGetAll(ctx, keyRematchPrefix+gameId.String()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rematch of game '%s': %w", gameId, err)
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+
+	r := &rematch{
+		gameId:    gameId,
+		white:     types.ObjectId(m["white"]),
+		black:     types.ObjectId(m["black"]),
+		variant:   types.Variant(m["variant"]),
+		fen:       m["fen"],
+		seriesId:  types.ObjectId(m["series"]),
+		offeredBy: types.ObjectId(m["offered_by"]),
+	}
+	r.timeControl, _ = strconv.ParseInt(m["time_control"], 10, 64)
+	r.expiresAt, _ = strconv.ParseInt(m["expires_at"], 10, 64)
+	return r, nil
+}
+
+const (
+	rematchNotFound int64 = iota
+	rematchOffered
+	rematchAlreadyOffered
+	rematchAccepted
+)
+
+// offer records the user's offer, if the opponent has already offered the rematch is accepted
+// and removed, so only one of the concurrent requests accepts it.
+func (st *rematchStore) offer(ctx context.Context, gameId, userId types.ObjectId) (int64, error) {
+	script := `
+	if redis.call('EXISTS', KEYS[1]) == 0 then
+		return 0
+	end
+	local by = redis.call('HGET', KEYS[1], 'offered_by')
+	if not by then
+		redis.call('HSET', KEYS[1], 'offered_by', ARGV[1])
+		return 1
+	end
+	if by == ARGV[1] then
+		return 2
+	end
+	redis.call('DEL', KEYS[1])
+	return 3
+	`
+
+	res, err := st.rc.Eval(ctx, script, []string{keyRematchPrefix + gameId.String()}, userId.String()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to offer rematch of game '%s': %w", gameId, err)
+	}
+	return res, nil
+}
+
+func (st *rematchStore) remove(ctx context.Context, gameId types.ObjectId) (bool, error) {
+	n, err := st.rc.Del(ctx, keyRematchPrefix+gameId.String()).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove rematch of game '%s': %w", gameId, err)
+	}
+	return n > 0, nil
+}
+
+func (st *rematchStore) setMatchSeries(ctx context.Context, matchId, seriesId types.ObjectId) error {
+	if err := st.rc.Set(ctx, keyRematchMatchPrefix+matchId.String(), seriesId.String(), rematchMatchTTL).Err(); err != nil {
+		return fmt.Errorf("failed to set series of match '%s': %w", matchId, err)
+	}
+	return nil
+}
+
+func (st *rematchStore) matchSeries(ctx context.Context, matchId types.ObjectId) (types.ObjectId, error) {
+	return st.getSeriesId(ctx, keyRematchMatchPrefix+matchId.String())
+}
+
+func (st *rematchStore) setGameSeries(ctx context.Context, gameId, seriesId types.ObjectId) error {
+	if err := st.rc.Set(ctx, keyRematchGamePrefix+gameId.String(), seriesId.String(), rematchSeriesTTL).Err(); err != nil {
+		return fmt.Errorf("failed to set series of game '%s': %w", gameId, err)
+	}
+	return nil
+}
+
+func (st *rematchStore) gameSeries(ctx context.Context, gameId types.ObjectId) (types.ObjectId, error) {
+	return st.getSeriesId(ctx, keyRematchGamePrefix+gameId.String())
+}
+
+func (st *rematchStore) getSeriesId(ctx context.Context, key string) (types.ObjectId, error) {
+	id, err := st.rc.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return types.ObjectZero, nil
+		}
+		return types.ObjectZero, fmt.Errorf("failed to get series id: %w", err)
+	}
+	return types.ObjectId(id), nil
+}
+
+// addResult counts the result of the game in the series, an empty winner means a draw.
+// It returns false if the game is already counted.
+func (st *rematchStore) addResult(ctx context.Context, seriesId, gameId, white, black, winner types.ObjectId) (bool, error) {
+	script := `
+	if redis.call('HSETNX', KEYS[1], 'game:' .. ARGV[1], 1) == 0 then
+		return 0
+	end
+	redis.call('HSETNX', KEYS[1], 'player1', ARGV[2])
+	redis.call('HSETNX', KEYS[1], 'player2', ARGV[3])
+	redis.call('HINCRBY', KEYS[1], 'games', 1)
+	if ARGV[4] == '' then
+		redis.call('HINCRBY', KEYS[1], 'draws', 1)
+	else
+		redis.call('HINCRBY', KEYS[1], 'wins:' .. ARGV[4], 1)
+	end
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+	return 1
+	`
+
+	n, err := st.rc.Eval(ctx, script, []string{keyRematchSeriesPrefix + seriesId.String()},
+		gameId.String(), white.String(), black.String(), winner.String(), rematchSeriesTTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to add result of game '%s' to series '%s': %w", gameId, seriesId, err)
+	}
+	return n == 1, nil
+}
+
+func (st *rematchStore) getSeries(ctx context.Context, seriesId types.ObjectId) (*RematchSeries, error) {
+	m, err := st.rc.HGetAll(ctx, keyRematchSeriesPrefix+seriesId.String()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series '%s': %w", seriesId, err)
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+
+	s := &RematchSeries{ID: seriesId}
+	s.Games, _ = strconv.ParseInt(m["games"], 10, 64)
+	s.Draws, _ = strconv.ParseInt(m["draws"], 10, 64)
+	for _, f := range []string{"player1", "player2"} {
+		p := SeriesPlayer{ID: types.ObjectId(m[f])}
+		p.Wins, _ = strconv.ParseInt(m["wins:"+p.ID.String()], 10, 64)
+		p.Score = float64(p.Wins) + float64(s.Draws)/2
+		s.Players = append(s.Players, p)
+	}
+	return s, nil
+}
+
+// addRematch counts the result of the ended game in the series of the pair,
+// and lets the players rematch in the rematch window.
+func (s *Service) addRematch(e *event.EventGameEnded) {
+	ctx := context.Background()
+
+	white, black := e.Player1.ID, e.Player2.ID
+	if e.Player1.Color == types.ColorBlack {
+		white, black = black, white
+	}
+
+	var winner types.ObjectId
+	switch e.Outcome {
+	case types.WhiteWon:
+		winner = white
+	case types.BlackWon:
+		winner = black
+	}
+
+	// the first game of the pair starts a new series
+	seriesId, err := s.rematches.gameSeries(ctx, e.GameID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if seriesId.IsZero() {
+		seriesId = types.NewObjectId()
+		if err := s.rematches.setGameSeries(ctx, e.GameID, seriesId); err != nil {
+			s.l.Error(err.Error())
+			return
+		}
+	}
+
+	ok, err := s.rematches.addResult(ctx, seriesId, e.GameID, white, black, winner)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if !ok {
+		// the game is delivered again
+		return
+	}
+
+	if err := s.rematches.add(ctx, &rematch{
+		gameId:      e.GameID,
+		white:       white,
+		black:       black,
+		variant:     e.Variant,
+		fen:         e.FEN,
+		timeControl: e.TimeControl,
+		seriesId:    seriesId,
+		expiresAt:   time.Now().Add(s.rematches.window).Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+}
+
+// linkRematchGame adds the game that is created by an accepted rematch to the series of the pair.
+func (s *Service) linkRematchGame(e *event.EventGameCreated) {
+	ctx := context.Background()
+
+	seriesId, err := s.rematches.matchSeries(ctx, e.MatchID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if seriesId.IsZero() {
+		return
+	}
+
+	if err := s.rematches.setGameSeries(ctx, e.GameID, seriesId); err != nil {
+		s.l.Error(err.Error())
+	}
+}
+
+// GetRematch returns the rematch offer of the game and the series score of its players.
+func (s *Service) GetRematch(ctx context.Context, gameId types.ObjectId) (*Rematch, error) {
+	if s.rematches == nil {
+		return nil, ErrRematchNotFound
+	}
+
+	r, err := s.rematches.get(ctx, gameId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	res := &Rematch{GameID: gameId}
+	seriesId := types.ObjectZero
+	if r != nil {
+		res.OfferedBy = r.offeredBy
+		res.ExpiresAt = r.expiresAt
+		seriesId = r.seriesId
+	} else {
+		seriesId, err = s.rematches.gameSeries(ctx, gameId)
+		if err != nil {
+			s.l.Error(err.Error())
+			return nil, err
+		}
+	}
+
+	if seriesId.IsZero() {
+		return nil, ErrRematchNotFound
+	}
+
+	res.Series, err = s.rematches.getSeries(ctx, seriesId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// OfferRematch offers a rematch of the ended game to the opponent, or accepts the opponent's offer.
+// The accepted rematch is created like a match, with the same variant and time control and the colors swapped.
+func (s *Service) OfferRematch(ctx context.Context, userId, gameId types.ObjectId) (*Rematch, error) {
+	r, err := s.getRematch(ctx, userId, gameId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCanPlay(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	// accepting the offer creates the game, so the opponent is checked again,
+	// they may have started another game or been sanctioned since the offer
+	if r.offeredBy == r.opponent(userId) {
+		if err := s.checkCanPlay(ctx, r.offeredBy); err != nil {
+			if errors.Is(err, ErrUserSanctioned) || errors.Is(err, ErrUserInGame) || errors.Is(err, ErrMatchCooldown) {
+				return nil, ErrOpponentCantPlay
+			}
+			return nil, err
+		}
+	}
+
+	blocked, err := s.blocks.Blocked(ctx, userId, r.opponent(userId))
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("internal error")
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
+	res, err := s.rematches.offer(ctx, gameId, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	switch res {
+	case rematchNotFound:
+		return nil, ErrRematchNotFound
+	case rematchAlreadyOffered:
+		return nil, ErrRematchAlreadyOffered
+	case rematchOffered:
+		if err := s.p.Publish(event.EventRematchOffered{
+			ID:        types.NewObjectId(),
+			GameID:    gameId,
+			From:      userId,
+			To:        r.opponent(userId),
+			ExpiresAt: r.expiresAt,
+			Timestamp: time.Now().Unix(),
+		}); err != nil {
+			s.l.Error(err.Error())
+			return nil, err
+		}
+
+		s.l.Debug(fmt.Sprintf("user '%s' offered a rematch of game '%s'", userId, gameId))
+		return &Rematch{GameID: gameId, OfferedBy: userId, ExpiresAt: r.expiresAt}, nil
+	}
+
+	// colors are swapped in the rematch
+	m := &event.EventUsersMatchCreated{
+		ID:          types.NewObjectId(),
+		User1:       types.User{ID: r.black, Score: s.getScore(r.black, r.variant)},
+		User2:       types.User{ID: r.white, Score: s.getScore(r.white, r.variant)},
+		Variant:     r.variant,
+		FEN:         r.fen,
+		RematchOf:   gameId,
+		White:       r.black,
+		TimeControl: r.timeControl,
+		Timestamp:   time.Now().Unix(),
+	}
+
+	if err := s.rematches.setMatchSeries(ctx, m.ID, r.seriesId); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	if err := s.p.Publish(m); err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	s.l.Debug(fmt.Sprintf("rematch of game '%s' accepted by '%s', match '%s'", gameId, userId, m.ID))
+	return &Rematch{GameID: gameId, Match: m}, nil
+}
+
+// DeclineRematch declines the opponent's offer or withdraws the user's offer, the game can't be rematched after that.
+func (s *Service) DeclineRematch(ctx context.Context, userId, gameId types.ObjectId) error {
+	r, err := s.getRematch(ctx, userId, gameId)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.rematches.remove(ctx, gameId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return ErrRematchNotFound
+	}
+
+	if err := s.p.Publish(event.EventRematchDeclined{
+		ID:        types.NewObjectId(),
+		GameID:    gameId,
+		By:        userId,
+		To:        r.opponent(userId),
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) getRematch(ctx context.Context, userId, gameId types.ObjectId) (*rematch, error) {
+	if s.rematches == nil {
+		return nil, ErrRematchNotFound
+	}
+
+	r, err := s.rematches.get(ctx, gameId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrRematchNotFound
+	}
+
+	if r.opponent(userId).IsZero() {
+		return nil, ErrRematchNotPlayer
+	}
+
+	return r, nil
+}
+
+// checkCanPlay returns an error if the user is not allowed to play a rated game, is in the cooldown
+// of aborting too many games or is already in a game. The match requests and the rematches share it.
+func (s *Service) checkCanPlay(ctx context.Context, userId types.ObjectId) error {
+	sanctioned, err := s.sanctions.Has(ctx, userId, types.SanctionBan, types.SanctionSuspension, types.SanctionRatedBan)
+	if err != nil {
+		s.l.Error(err.Error())
+		return fmt.Errorf("internal error")
+	}
+
+	if sanctioned {
+		s.l.Debug(fmt.Sprintf("user '%s' is not allowed to play rated games", userId))
+		return ErrUserSanctioned
+	}
+
+	if s.aborts != nil {
+		d, err := s.aborts.remaining(ctx, userId)
+		if err != nil {
+			s.l.Error(err.Error())
+			return fmt.Errorf("internal error")
+		}
+
+		if d > 0 {
+			return fmt.Errorf("%w, try again in %s", ErrMatchCooldown, d.Round(time.Second))
+		}
+	}
+
+	currentGameId, err := s.game.GetUserLiveGameID(ctx, userId)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("failed to get user '%s' live game id: %s", userId, err.Error()))
+		return fmt.Errorf("internal error")
+	}
+
+	if currentGameId != types.ObjectZero {
+		s.l.Debug(fmt.Sprintf("user '%s' is already in a game", userId))
+		return ErrUserInGame
+	}
+
+	return nil
+}
+
+func (s *Service) getScore(userId types.ObjectId, variant types.Variant) int64 {
+	score, err := s.rating.GetUserScore(userId, variant)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("failed to get user '%s' score: %s", userId, err.Error()))
+		return elo.BaseScore
+	}
+	return score
+}
@@ -0,0 +1,25 @@
+package match
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+var (
+	ErrUserSanctioned = errors.New("user is not allowed to play rated games")
+	ErrUserBlocked    = errors.New("user is blocked")
+)
+
+type SanctionService interface {
+	// Has returns true if the user has an active sanction of any of the types.
+	Has(ctx context.Context, userId types.ObjectId, kinds ...types.SanctionType) (bool, error)
+}
+
+type BlockService interface {
+	// Blocked returns true if any of the users blocked the other one.
+	Blocked(ctx context.Context, user1, user2 types.ObjectId) (bool, error)
+	// BlockedUsers returns the users that the user blocked or are blocked by.
+	BlockedUsers(ctx context.Context, userId types.ObjectId) ([]types.ObjectId, error)
+}
@@ -3,5 +3,6 @@ package match
 import "github.com/alikarimi999/shahboard/types"
 
 type RatingService interface {
-	GetUserScore(id types.ObjectId) (int64, error)
+	// GetUserScore returns the user's score in the given variant.
+	GetUserScore(id types.ObjectId, variant types.Variant) (int64, error)
 }
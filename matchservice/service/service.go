@@ -7,23 +7,39 @@ import (
 	"time"
 
 	"github.com/alikarimi999/shahboard/event"
-	"github.com/alikarimi999/shahboard/pkg/elo"
 	"github.com/alikarimi999/shahboard/pkg/log"
 	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
 )
 
 type Config struct {
 	EngineTicker       int `json:"engine_ticker"`
 	MatchRequestTicker int `json:"match_request_ticker"`
+
+	// users that abort AbortLimit games in AbortPeriod seconds can't queue for AbortCooldown seconds,
+	// zero limit disables the cooldown
+	AbortLimit    int64  `json:"abort_limit"`
+	AbortPeriod   uint64 `json:"abort_period"`
+	AbortCooldown uint64 `json:"abort_cooldown"`
+
+	// RematchWindow is the number of seconds after a game ends that its players can rematch,
+	// zero disables the rematches
+	RematchWindow uint64 `json:"rematch_window"`
 }
 
 type Service struct {
 	cfg Config
 	e   *engine
 
-	p      event.Publisher
-	rating RatingService
-	game   GameService
+	p         event.Publisher
+	rating    RatingService
+	game      GameService
+	sanctions SanctionService
+	blocks    BlockService
+
+	sm        *event.SubscriptionManager
+	aborts    *abortTracker
+	rematches *rematchStore
 
 	stopCh chan struct{}
 	wg     sync.WaitGroup
@@ -31,15 +47,35 @@ type Service struct {
 	l log.Logger
 }
 
-func NewService(cfg Config, p event.Publisher, score RatingService, game GameService, l log.Logger) (*Service, error) {
+func NewService(cfg Config, p event.Publisher, sub event.Subscriber, rc *redis.Client, score RatingService,
+	game GameService, sanctions SanctionService, blocks BlockService, l log.Logger) (*Service, error) {
 	s := &Service{
-		cfg:    cfg,
-		e:      newEngine(time.Duration(cfg.EngineTicker) * time.Second),
-		p:      p,
-		rating: score,
-		game:   game,
-		stopCh: make(chan struct{}),
-		l:      l,
+		cfg:       cfg,
+		e:         newEngine(time.Duration(cfg.EngineTicker) * time.Second),
+		p:         p,
+		rating:    score,
+		game:      game,
+		sanctions: sanctions,
+		blocks:    blocks,
+		stopCh:    make(chan struct{}),
+		l:         l,
+	}
+
+	if cfg.AbortLimit > 0 {
+		if cfg.AbortPeriod == 0 || cfg.AbortCooldown == 0 {
+			return nil, fmt.Errorf("abort period and abort cooldown are required")
+		}
+		s.aborts = newAbortTracker(rc, cfg)
+	}
+
+	if cfg.RematchWindow > 0 {
+		s.rematches = newRematchStore(rc, time.Duration(cfg.RematchWindow)*time.Second)
+	}
+
+	if s.aborts != nil || s.rematches != nil {
+		s.sm = event.NewManager(l, s.handleEvents)
+		s.sm.AddSubscription(sub.Subscribe(event.TopicGameCreated))
+		s.sm.AddSubscription(sub.Subscribe(event.TopicGameEnded))
 	}
 
 	s.run()
@@ -47,27 +83,30 @@ func NewService(cfg Config, p event.Publisher, score RatingService, game GameSer
 	return s, nil
 }
 
-func (s *Service) NewMatchRequest(ctx context.Context, userId types.ObjectId) (*event.EventUsersMatchCreated, error) {
-	t := time.NewTicker(time.Duration(s.cfg.MatchRequestTicker) * time.Second)
-
-	currentGameId, err := s.game.GetUserLiveGameID(ctx, userId)
+// NewMatchRequest queues the user until it's matched with a player of the same variant and start position,
+// fen is the start position of the game and empty for the start position of the variant.
+func (s *Service) NewMatchRequest(ctx context.Context, userId types.ObjectId, variant types.Variant, fen string) (*event.EventUsersMatchCreated, error) {
+	fen, err := startPosition(variant, fen)
 	if err != nil {
-		s.l.Error(fmt.Sprintf("failed to get user '%s' live game id: %s", userId, err.Error()))
-		return nil, fmt.Errorf("internal error")
+		return nil, err
 	}
 
-	if currentGameId != types.ObjectZero {
-		s.l.Debug(fmt.Sprintf("user '%s' is already in a game", userId))
-		return nil, fmt.Errorf("user is already in a game")
+	t := time.NewTicker(time.Duration(s.cfg.MatchRequestTicker) * time.Second)
+
+	// all matches are rated
+	if err := s.checkCanPlay(ctx, userId); err != nil {
+		return nil, err
 	}
 
-	score, err := s.rating.GetUserScore(userId)
+	// the users that blocked each other are not paired
+	blocked, err := s.blocks.BlockedUsers(ctx, userId)
 	if err != nil {
-		s.l.Error(fmt.Sprintf("failed to get user '%s' level: %s", userId, err.Error()))
-		score = elo.BaseScore
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("internal error")
 	}
 
-	req, ok := s.e.addToQueue(userId, score)
+	score := s.getScore(userId, variant)
+	req, ok := s.e.addToQueue(userId, score, variant, fen, blocked)
 	if !ok {
 		return nil, fmt.Errorf("user '%s' already has a match request", userId)
 	}
@@ -98,7 +137,7 @@ func (s *Service) run() {
 				}
 				events := make([]event.Event, 0, len(ms))
 				for _, m := range ms {
-					s.l.Debug(fmt.Sprintf("match '%s' for user '%s' and '%s'", m.ID, m.User1.ID, m.User2.ID))
+					s.l.Debug(fmt.Sprintf("match '%s' for user '%s' and '%s' in '%s'", m.ID, m.User1.ID, m.User2.ID, m.Variant))
 					events = append(events, m)
 				}
 
@@ -116,6 +155,9 @@ func (s *Service) run() {
 }
 
 func (s *Service) Stop() {
+	if s.sm != nil {
+		s.sm.Stop()
+	}
 	close(s.stopCh)
 	s.wg.Wait()
 }
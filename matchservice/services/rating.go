@@ -18,8 +18,8 @@ func NewRatingService(client *grpc.ClientConn) *RatingService {
 	}
 }
 
-func (s *RatingService) GetUserScore(id types.ObjectId) (int64, error) {
-	res, err := s.c.GetUserRating(context.Background(), &pb.GetUserRatingRequest{UserId: id.String()})
+func (s *RatingService) GetUserScore(id types.ObjectId, variant types.Variant) (int64, error) {
+	res, err := s.c.GetUserRating(context.Background(), &pb.GetUserRatingRequest{UserId: id.String(), Variant: variant.String()})
 	if err != nil {
 		return 0, err
 	}
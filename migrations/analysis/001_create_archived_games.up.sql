@@ -0,0 +1,6 @@
+-- PGNs of the finished games, Game Service only keeps the games for a while after they end
+CREATE TABLE IF NOT EXISTS archived_games (
+    game_id VARCHAR(64) PRIMARY KEY,
+    pgn TEXT NOT NULL,
+    ended_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,30 @@
+-- fair play reports of the analyzed games, the refund columns are set once when a moderator refunds the game
+CREATE TABLE IF NOT EXISTS fair_play_reports (
+    game_id VARCHAR(64) PRIMARY KEY,
+    report JSONB NOT NULL,
+    refunded_by VARCHAR(64),
+    refunded_at TIMESTAMPTZ,
+    analyzed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- engine correlation of the counted games of each user
+CREATE TABLE IF NOT EXISTS fair_play_accounts (
+    user_id VARCHAR(64) PRIMARY KEY,
+    games BIGINT NOT NULL DEFAULT 0,
+    moves BIGINT NOT NULL DEFAULT 0,
+    matched BIGINT NOT NULL DEFAULT 0,
+    cpl BIGINT NOT NULL DEFAULT 0,
+    -- number of the games when the account was reviewed last time
+    reviewed_games BIGINT NOT NULL DEFAULT 0,
+    -- NULL if the account is not in the review queue
+    flagged_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_fair_play_accounts_flagged_at ON fair_play_accounts (flagged_at)
+WHERE flagged_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS fair_play_suspicious_games (
+    user_id VARCHAR(64) NOT NULL,
+    game_id VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, game_id)
+);
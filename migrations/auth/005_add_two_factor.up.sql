@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS two_factor (
+    user_id VARCHAR(64) PRIMARY KEY,
+    -- encrypted TOTP secret
+    secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- codes of this time step and before are rejected, so a code can't be used twice
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    enabled_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id VARCHAR(64) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, code_hash)
+);
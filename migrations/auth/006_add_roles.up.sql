@@ -0,0 +1,6 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+-- the access tokens that are issued by the refresh token carry the role
+ALTER TABLE refresh_tokens
+ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
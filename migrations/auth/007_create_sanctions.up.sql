@@ -0,0 +1,28 @@
+-- bans are sanctions too, so the account state is only in this table
+CREATE TABLE IF NOT EXISTS sanctions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    issued_by VARCHAR(64) NOT NULL,
+    -- NULL for permanent sanctions
+    expires_at TIMESTAMPTZ,
+    lifted_by VARCHAR(64),
+    lifted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sanctions_user_id ON sanctions (user_id);
+
+-- append only log of who applied or lifted each sanction
+CREATE TABLE IF NOT EXISTS sanction_audit_log (
+    id VARCHAR(64) PRIMARY KEY,
+    sanction_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(64) NOT NULL,
+    actor_id VARCHAR(64) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sanction_audit_log_user_id ON sanction_audit_log (user_id);
@@ -0,0 +1,3 @@
+-- guests that are claimed by a password account keep their identity until the email is verified
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS pending_guest_claim BOOLEAN NOT NULL DEFAULT FALSE;
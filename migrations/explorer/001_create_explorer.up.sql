@@ -0,0 +1,31 @@
+CREATE TABLE explorer_games (
+    game_id VARCHAR(64) PRIMARY KEY,
+    white_id VARCHAR(64) NOT NULL,
+    black_id VARCHAR(64) NOT NULL,
+    white_rating BIGINT NOT NULL DEFAULT 0,
+    black_rating BIGINT NOT NULL DEFAULT 0,
+    level SMALLINT NOT NULL,
+    time_control BIGINT NOT NULL DEFAULT 0,
+    eco VARCHAR(8) NOT NULL DEFAULT '',
+    opening VARCHAR(255) NOT NULL DEFAULT '',
+    outcome VARCHAR(8) NOT NULL,
+    plies INT NOT NULL DEFAULT 0,
+    ended_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_explorer_games_eco ON explorer_games(eco);
+
+-- next move statistics of every position, positions are identified by their zobrist hash
+CREATE TABLE explorer_moves (
+    hash BIGINT NOT NULL,
+    level SMALLINT NOT NULL,
+    time_control BIGINT NOT NULL,
+    move VARCHAR(8) NOT NULL,
+    san VARCHAR(16) NOT NULL,
+    games BIGINT NOT NULL DEFAULT 0,
+    white_wins BIGINT NOT NULL DEFAULT 0,
+    draws BIGINT NOT NULL DEFAULT 0,
+    black_wins BIGINT NOT NULL DEFAULT 0,
+    rating_sum BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (hash, level, time_control, move)
+);
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    data JSONB,
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_created_at ON notifications (user_id, created_at);
+
+-- channels of the kinds that the users changed, the other kinds use the defaults of the service
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id VARCHAR(64) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    channels JSONB NOT NULL,
+    PRIMARY KEY (user_id, kind)
+);
+
+-- contact data of the users, the email and the name come from the user events
+CREATE TABLE IF NOT EXISTS recipients (
+    user_id VARCHAR(64) PRIMARY KEY,
+    email TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    webhook_url TEXT NOT NULL DEFAULT '',
+    webhook_secret TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    endpoint TEXT PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    p256dh TEXT NOT NULL,
+    auth TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user_id ON push_subscriptions (user_id);
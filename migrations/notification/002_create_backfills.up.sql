@@ -0,0 +1,5 @@
+-- backfills that are done once, like the recipients of the users that are created before the service
+CREATE TABLE IF NOT EXISTS backfills (
+    name VARCHAR(64) PRIMARY KEY,
+    done_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,37 @@
+CREATE TABLE puzzles (
+    id VARCHAR(64) PRIMARY KEY,
+    source_id VARCHAR(64) NOT NULL UNIQUE,
+    fen TEXT NOT NULL,
+    moves TEXT NOT NULL,
+    rating BIGINT NOT NULL DEFAULT 1500,
+    themes TEXT[] NOT NULL DEFAULT '{}',
+    plays BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_puzzles_rating ON puzzles(rating);
+
+CREATE TABLE puzzle_ratings (
+    user_id VARCHAR(64) PRIMARY KEY,
+    current_score BIGINT NOT NULL DEFAULT 1000,
+    best_score BIGINT NOT NULL DEFAULT 1000,
+    solved BIGINT NOT NULL DEFAULT 0,
+    failed BIGINT NOT NULL DEFAULT 0,
+    streak BIGINT NOT NULL DEFAULT 0,
+    best_streak BIGINT NOT NULL DEFAULT 0,
+    last_updated TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE puzzle_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    puzzle_id VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    ply INT NOT NULL DEFAULT 0,
+    rating_change BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, puzzle_id)
+);
+
+CREATE INDEX idx_puzzle_attempts_user_id ON puzzle_attempts(user_id);
@@ -0,0 +1,8 @@
+ALTER TABLE ratings
+ADD COLUMN variant VARCHAR(16) NOT NULL DEFAULT 'standard';
+
+ALTER TABLE ratings DROP CONSTRAINT ratings_pkey;
+ALTER TABLE ratings ADD PRIMARY KEY (user_id, variant);
+
+ALTER TABLE game_elo_changes
+ADD COLUMN variant VARCHAR(16) NOT NULL DEFAULT 'standard';
@@ -0,0 +1,12 @@
+-- rating that the users lost in the games against cheaters and is given back, once per game and user
+CREATE TABLE IF NOT EXISTS rating_refunds (
+    user_id VARCHAR(64) NOT NULL,
+    game_id VARCHAR(64) NOT NULL,
+    cheater_id VARCHAR(64) NOT NULL,
+    variant VARCHAR(16) NOT NULL DEFAULT 'standard',
+    amount BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, game_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_elo_changes_game_id ON game_elo_changes(game_id);
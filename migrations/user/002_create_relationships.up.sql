@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id VARCHAR(64) NOT NULL,
+    followee_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows (followee_id);
+
+CREATE TABLE IF NOT EXISTS blocks (
+    blocker_id VARCHAR(64) NOT NULL,
+    blocked_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
+
+-- friends are the users that follow each other, each pair has a row for both users
+CREATE OR REPLACE VIEW friends AS
+SELECT f1.follower_id AS user_id, f1.followee_id AS friend_id, GREATEST(f1.created_at, f2.created_at) AS created_at
+FROM follows f1
+JOIN follows f2 ON f1.follower_id = f2.followee_id AND f1.followee_id = f2.follower_id;
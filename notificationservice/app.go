@@ -0,0 +1,83 @@
+package notificationservice
+
+import (
+	"context"
+
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/notificationservice/channel"
+	"github.com/alikarimi999/shahboard/notificationservice/delivery/http"
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/notificationservice/repository"
+	notification "github.com/alikarimi999/shahboard/notificationservice/service"
+	"github.com/alikarimi999/shahboard/pkg/block"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/mail"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+	"github.com/redis/go-redis/v9"
+)
+
+type application struct {
+	*notification.Service
+	*http.Router
+}
+
+func SetupApplication(cfg Config) (*application, error) {
+	l := log.NewLogger(cfg.Log.File, cfg.Log.Verbose)
+
+	v, err := jwt.NewValidator(cfg.JwtValidator)
+	if err != nil {
+		return nil, err
+	}
+
+	p, sub, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := postgres.Setup(cfg.NotificationDB)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	v.WithDenylist(jwt.NewRedisDenylist(rc))
+
+	ms, err := mail.NewSender(cfg.Mail, l)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := map[entity.Channel]notification.Channel{
+		entity.ChannelEmail:   channel.NewEmail(cfg.Email, ms),
+		entity.ChannelWebhook: channel.NewWebhook(cfg.Webhook),
+		entity.ChannelWebPush: channel.NewWebPush(cfg.WebPush, l),
+	}
+
+	usersDB, err := postgres.Setup(cfg.UsersDB)
+	if err != nil {
+		return nil, err
+	}
+
+	s := notification.NewService(cfg.Notification, repository.NewNotificationRepo(db, l), repository.NewUserRepo(usersDB),
+		channels, p, sub, rc, block.NewRegistry(rc, sub, l), l)
+
+	if err := s.BackfillRecipients(context.Background()); err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRouter(cfg.Http, s, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &application{Service: s, Router: r}, nil
+}
+
+func (a *application) Run() error {
+	return a.Router.Run()
+}
@@ -0,0 +1,58 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/mail"
+)
+
+type EmailConfig struct {
+	// BaseURL is the address of the web client that the links of the emails point to
+	BaseURL string `json:"base_url"`
+}
+
+// Email sends the notifications to the email of the recipients.
+type Email struct {
+	cfg    EmailConfig
+	sender mail.Sender
+}
+
+func NewEmail(cfg EmailConfig, sender mail.Sender) *Email {
+	return &Email{
+		cfg:    cfg,
+		sender: sender,
+	}
+}
+
+func (e *Email) Send(ctx context.Context, r *entity.Recipient, n *entity.Notification) error {
+	// the guests don't have an email
+	if r.Email == "" {
+		return nil
+	}
+
+	body := n.Body
+	if url := dataURL(n.Data); url != "" && e.cfg.BaseURL != "" {
+		body += "\n\n" + strings.TrimSuffix(e.cfg.BaseURL, "/") + url
+	}
+
+	return e.sender.Send(ctx, mail.Message{
+		To:      r.Email,
+		Subject: n.Title,
+		Body:    body,
+	})
+}
+
+// dataURL returns the path in the web client that the notification refers to.
+func dataURL(data json.RawMessage) string {
+	var d struct {
+		URL string `json:"url"`
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	json.Unmarshal(data, &d)
+	return d.URL
+}
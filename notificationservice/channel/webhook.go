@@ -0,0 +1,148 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const (
+	HeaderWebhookEvent     = "X-Shahboard-Event"
+	HeaderWebhookSignature = "X-Shahboard-Signature"
+)
+
+type WebhookConfig struct {
+	// number of seconds that a request to a webhook can take
+	Timeout int `json:"timeout"`
+}
+
+// Webhook posts the notifications to the webhook of the recipients,
+// the body is signed by the secret of the webhook so the receivers can verify it.
+//
+// The webhooks are set by the users, so only the https urls of the public addresses are requested
+// and the redirects are not followed, the address is checked when it's dialed so a host that
+// resolves to an internal address is rejected too.
+type Webhook struct {
+	client *http.Client
+}
+
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+
+	dialer := &net.Dialer{
+		Timeout: time.Duration(cfg.Timeout) * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		},
+	}
+
+	return &Webhook{
+		client: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
+			Transport: &http.Transport{
+				// a proxy would dial the addresses instead of the checked dialer
+				Proxy:               nil,
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: time.Duration(cfg.Timeout) * time.Second,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// cgnat is the shared address space of the carrier-grade NATs, RFC 6598.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// PublicIP reports whether the ip is a public unicast address, the loopback, private, link-local
+// (which has the metadata services of the clouds), unspecified and multicast addresses are not public.
+func PublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnat.Contains(ip) &&
+		!ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+type webhookPayload struct {
+	ID        types.ObjectId  `json:"id"`
+	UserId    types.ObjectId  `json:"user_id"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt int64           `json:"created_at"`
+}
+
+func (w *Webhook) Send(ctx context.Context, r *entity.Recipient, n *entity.Notification) error {
+	if r.WebhookURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(r.WebhookURL)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("webhook url is not https")
+	}
+
+	b, err := json.Marshal(webhookPayload{
+		ID:        n.ID,
+		UserId:    n.UserId,
+		Kind:      n.Kind.String(),
+		Title:     n.Title,
+		Body:      n.Body,
+		Data:      n.Data,
+		CreatedAt: n.CreatedAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.WebhookURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, n.Kind.String())
+	req.Header.Set(HeaderWebhookSignature, "sha256="+Sign(r.WebhookSecret, b))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the body with the secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,61 @@
+package channel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicIP(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("%s: expected public %v, got %v", tt.ip, tt.public, got)
+		}
+	}
+}
+
+func TestWebhookRejectsInternalAddresses(t *testing.T) {
+	called := false
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(WebhookConfig{Timeout: 2})
+	n := &entity.Notification{ID: types.NewObjectId(), UserId: types.NewObjectId(), CreatedAt: time.Now()}
+
+	for _, u := range []string{srv.URL, "http://" + srv.Listener.Addr().String()} {
+		r := &entity.Recipient{WebhookURL: u, WebhookSecret: "secret"}
+		if err := w.Send(context.Background(), r, n); err == nil {
+			t.Errorf("webhook %s is requested", u)
+		}
+	}
+	if called {
+		t.Error("internal server got the webhook")
+	}
+}
@@ -0,0 +1,139 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type WebPushConfig struct {
+	// RelayURL is the push relay that encrypts the payloads and sends them to the push services
+	// with the VAPID keys, the payloads are only logged if it's empty.
+	RelayURL string `json:"relay_url"`
+	// number of seconds that the push services keep the messages of the offline browsers
+	TTL int `json:"ttl"`
+	// number of seconds that a request to the relay can take
+	Timeout int `json:"timeout"`
+}
+
+// WebPush generates the payloads of the service workers for each subscription of the recipients.
+type WebPush struct {
+	cfg    WebPushConfig
+	client *http.Client
+	l      log.Logger
+}
+
+func NewWebPush(cfg WebPushConfig, l log.Logger) *WebPush {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * 60 * 60
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+
+	return &WebPush{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		l:      l,
+	}
+}
+
+// PushPayload is the message that the service worker of the web client shows.
+type PushPayload struct {
+	Title string      `json:"title"`
+	Body  string      `json:"body"`
+	Tag   string      `json:"tag"`
+	Data  PayloadData `json:"data"`
+}
+
+type PayloadData struct {
+	NotificationId types.ObjectId  `json:"notification_id"`
+	Kind           string          `json:"kind"`
+	URL            string          `json:"url,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+type pushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type relayRequest struct {
+	Subscription pushSubscription `json:"subscription"`
+	Payload      PushPayload      `json:"payload"`
+	TTL          int              `json:"ttl"`
+}
+
+func (w *WebPush) Send(ctx context.Context, r *entity.Recipient, n *entity.Notification) error {
+	if len(r.Subscriptions) == 0 {
+		return nil
+	}
+
+	payload := PushPayload{
+		Title: n.Title,
+		Body:  n.Body,
+		Tag:   n.Kind.String(),
+		Data: PayloadData{
+			NotificationId: n.ID,
+			Kind:           n.Kind.String(),
+			URL:            dataURL(n.Data),
+			Data:           n.Data,
+		},
+	}
+
+	var errs []error
+	for _, s := range r.Subscriptions {
+		req := relayRequest{Payload: payload, TTL: w.cfg.TTL}
+		req.Subscription.Endpoint = s.Endpoint
+		req.Subscription.Keys.P256dh = s.P256dh
+		req.Subscription.Keys.Auth = s.Auth
+
+		if err := w.push(ctx, req); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint '%s': %w", s.Endpoint, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *WebPush) push(ctx context.Context, r relayRequest) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if w.cfg.RelayURL == "" {
+		w.l.Info(fmt.Sprintf("web push to '%s': %s", r.Subscription.Endpoint, b))
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.RelayURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("push relay responded with status %d", res.StatusCode)
+	}
+	return nil
+}
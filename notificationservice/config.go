@@ -0,0 +1,37 @@
+package notificationservice
+
+import (
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/notificationservice/channel"
+	"github.com/alikarimi999/shahboard/notificationservice/delivery/http"
+	notification "github.com/alikarimi999/shahboard/notificationservice/service"
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/mail"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+)
+
+type Config struct {
+	Notification   notification.Config   `json:"notification_service"`
+	Http           http.Config           `json:"http"`
+	Kafka          kafka.Config          `json:"kafka"`
+	Log            LogConfig             `json:"log"`
+	JwtValidator   jwt.ValidatorConfig   `json:"jwt_validator"`
+	NotificationDB postgres.Config       `json:"notification_db"`
+	UsersDB        postgres.Config       `json:"users_db"`
+	Redis          RedisConfig           `json:"redis"`
+	Mail           mail.Config           `json:"mail"`
+	Email          channel.EmailConfig   `json:"email"`
+	Webhook        channel.WebhookConfig `json:"webhook"`
+	WebPush        channel.WebPushConfig `json:"web_push"`
+}
+
+type LogConfig struct {
+	File    string `json:"file"`
+	Verbose bool   `json:"verbose"`
+}
+
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
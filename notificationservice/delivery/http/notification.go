@@ -0,0 +1,238 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	notification "github.com/alikarimi999/shahboard/notificationservice/service"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) setupNotificationRoutes() {
+	inbox := r.gin.Group("/inbox")
+	inbox.GET("/", r.getInbox)
+	inbox.GET("/unread", r.getUnreadCount)
+	inbox.POST("/read", r.markAllRead)
+	inbox.POST("/:id/read", r.markRead)
+
+	r.gin.GET("/preferences", r.getPreferences)
+	r.gin.PUT("/preferences", r.setPreferences)
+
+	channels := r.gin.Group("/channels")
+	channels.PUT("/webhook", r.setWebhook)
+	channels.POST("/push", r.addPushSubscription)
+	channels.DELETE("/push", r.removePushSubscription)
+}
+
+// getInbox returns the notifications of the user from the newest,
+// e.g. /inbox?page=1&limit=20&unread=true
+func (r *Router) getInbox(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	p := &paginate.Paginated{
+		Filters: make(map[paginate.FilterParameter]paginate.Filter),
+	}
+	if ls, ok := c.GetQuery("limit"); ok {
+		if li, err := strconv.Atoi(ls); err == nil && li > 0 {
+			p.PerPage = uint64(li)
+		}
+	}
+	if ps, ok := c.GetQuery("page"); ok {
+		if pi, err := strconv.Atoi(ps); err == nil && pi > 0 {
+			p.Page = uint64(pi)
+		}
+	}
+	p.Validate()
+
+	res, err := r.s.GetInbox(c.Request.Context(), usr.ID, c.Query("unread") == "true", p)
+	if err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) getUnreadCount(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	n, err := r.s.UnreadCount(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": n})
+}
+
+func (r *Router) markRead(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	id, err := types.ParseObjectId(c.Param("id"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid notification ID"))
+		return
+	}
+
+	if err := r.s.MarkRead(c.Request.Context(), usr.ID, id); err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification marked as read"})
+}
+
+func (r *Router) markAllRead(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	if err := r.s.MarkAllRead(c.Request.Context(), usr.ID); err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notifications marked as read"})
+}
+
+func (r *Router) getPreferences(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	res, err := r.s.GetPreferences(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// setPreferences sets the channels of the kinds in the body and returns the preferences of all kinds,
+// e.g. {"direct_message": ["in_app", "web_push"], "new_follower": []}
+func (r *Router) setPreferences(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req notification.PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid request"))
+		return
+	}
+
+	if err := r.s.SetPreferences(c.Request.Context(), usr.ID, req); err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	res, err := r.s.GetPreferences(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// setWebhook sets the webhook of the user and returns the secret that signs the requests,
+// an empty url removes the webhook.
+func (r *Router) setWebhook(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req notification.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid request"))
+		return
+	}
+
+	res, err := r.s.SetWebhook(c.Request.Context(), usr.ID, req)
+	if err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (r *Router) addPushSubscription(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	var req notification.PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid request"))
+		return
+	}
+
+	if err := r.s.AddPushSubscription(c.Request.Context(), usr.ID, req); err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "push subscription added"})
+}
+
+// removePushSubscription removes the subscription of the endpoint query.
+func (r *Router) removePushSubscription(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	endpoint := c.Query("endpoint")
+	if endpoint == "" {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid endpoint"))
+		return
+	}
+
+	if err := r.s.RemovePushSubscription(c.Request.Context(), usr.ID, endpoint); err != nil {
+		c.JSON(errs.HTTP(notificationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "push subscription removed"})
+}
+
+func notificationErrCode(err error) string {
+	switch {
+	case errors.Is(err, notification.ErrInvalidInput):
+		return errs.CodeInvalidInput
+	case errors.Is(err, notification.ErrNotFound):
+		return errs.CodeNotFound
+	default:
+		return errs.CodeInternalError
+	}
+}
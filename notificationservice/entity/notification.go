@@ -0,0 +1,101 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// Kind is the type of a notification, the users choose the channels of each kind.
+type Kind string
+
+const (
+	KindRematchOffered       Kind = "rematch_offered"
+	KindFollowingGameStarted Kind = "following_game_started"
+	KindNewFollower          Kind = "new_follower"
+	KindDirectMessage        Kind = "direct_message"
+	KindAccountSanctioned    Kind = "account_sanctioned"
+)
+
+var Kinds = []Kind{
+	KindRematchOffered,
+	KindFollowingGameStarted,
+	KindNewFollower,
+	KindDirectMessage,
+	KindAccountSanctioned,
+}
+
+func ParseKind(s string) (Kind, error) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("invalid notification kind '%s'", s)
+}
+
+func (k Kind) String() string {
+	return string(k)
+}
+
+// Channel delivers the notifications, in-app notifications are kept in the inbox and are sent to the sessions
+// of the online users. The other channels are used when the user has no session.
+type Channel string
+
+const (
+	ChannelInApp   Channel = "in_app"
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+	ChannelWebPush Channel = "web_push"
+)
+
+var Channels = []Channel{ChannelInApp, ChannelEmail, ChannelWebhook, ChannelWebPush}
+
+func ParseChannel(s string) (Channel, error) {
+	for _, c := range Channels {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("invalid notification channel '%s'", s)
+}
+
+func (c Channel) String() string {
+	return string(c)
+}
+
+type Notification struct {
+	ID     types.ObjectId
+	UserId types.ObjectId
+	Kind   Kind
+	Title  string
+	Body   string
+	// Data is the payload of the kind that the clients use, like the id of the game
+	Data      json.RawMessage
+	Read      bool
+	ReadAt    time.Time
+	CreatedAt time.Time
+}
+
+// Preferences are the channels of each kind that the user has changed, the other kinds use the default channels.
+type Preferences map[Kind][]Channel
+
+// Recipient is the contact data of a user that the offline channels use.
+type Recipient struct {
+	UserId        types.ObjectId
+	Email         string
+	Name          string
+	WebhookURL    string
+	WebhookSecret string
+	Subscriptions []PushSubscription
+}
+
+// PushSubscription is the web push subscription of a browser of the user.
+type PushSubscription struct {
+	Endpoint  string
+	P256dh    string
+	Auth      string
+	CreatedAt time.Time
+}
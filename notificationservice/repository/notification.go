@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	pagesql "github.com/alikarimi999/shahboard/pkg/paginate/sql"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type notificationRepo struct {
+	db *sql.DB
+	l  log.Logger
+}
+
+func NewNotificationRepo(db *sql.DB, l log.Logger) *notificationRepo {
+	return &notificationRepo{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *notificationRepo) Add(ctx context.Context, n *entity.Notification) error {
+	query := `INSERT INTO notifications (id, user_id, kind, title, body, data, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.ExecContext(ctx, query, n.ID.String(), n.UserId.String(), n.Kind.String(), n.Title, n.Body,
+		nullJSON(n.Data), n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add notification for user %s: %w", n.UserId, err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) GetNotifications(ctx context.Context, p *paginate.Paginated) ([]*entity.Notification, uint64, error) {
+	limit := p.PerPage
+	offset := (p.Page - 1) * limit
+
+	q, cq, args := pagesql.WriteQuery("notifications", p.Filters, p.SortColumn, p.Decscending, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	var list []*entity.Notification
+	for rows.Next() {
+		var n entity.Notification
+		var data []byte
+		var readAt sql.NullTime
+		err := rows.Scan(&n.ID, &n.UserId, &n.Kind, &n.Title, &n.Body, &data, &n.Read, &readAt, &n.CreatedAt)
+		if err != nil {
+			r.l.Error(fmt.Sprintf("failed to scan row: %v", err))
+			continue
+		}
+		n.Data = data
+		n.ReadAt = readAt.Time
+		list = append(list, &n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate over rows: %v", err)
+	}
+
+	// the count query has the arguments of the filters without the limit and the offset
+	var total uint64
+	if err := r.db.QueryRowContext(ctx, cq, args[:len(args)-2]...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to execute count query: %v", err)
+	}
+
+	return list, total, nil
+}
+
+func (r *notificationRepo) UnreadCount(ctx context.Context, userId types.ObjectId) (uint64, error) {
+	query := "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read = FALSE"
+	var n uint64
+	if err := r.db.QueryRowContext(ctx, query, userId.String()).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications of user %s: %w", userId, err)
+	}
+	return n, nil
+}
+
+func (r *notificationRepo) MarkRead(ctx context.Context, userId, id types.ObjectId, t time.Time) (bool, error) {
+	query := `UPDATE notifications SET read = TRUE, read_at = COALESCE(read_at, $1) WHERE id = $2 AND user_id = $3`
+	res, err := r.db.ExecContext(ctx, query, t, id.String(), userId.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification %s as read: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *notificationRepo) MarkAllRead(ctx context.Context, userId types.ObjectId, t time.Time) error {
+	query := `UPDATE notifications SET read = TRUE, read_at = $1 WHERE user_id = $2 AND read = FALSE`
+	if _, err := r.db.ExecContext(ctx, query, t, userId.String()); err != nil {
+		return fmt.Errorf("failed to mark notifications of user %s as read: %w", userId, err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) GetPreferences(ctx context.Context, userId types.ObjectId) (entity.Preferences, error) {
+	query := "SELECT kind, channels FROM notification_preferences WHERE user_id = $1"
+	rows, err := r.db.QueryContext(ctx, query, userId.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences of user %s: %w", userId, err)
+	}
+	defer rows.Close()
+
+	p := make(entity.Preferences)
+	for rows.Next() {
+		var kind entity.Kind
+		var channels []byte
+		if err := rows.Scan(&kind, &channels); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		var cs []entity.Channel
+		if err := json.Unmarshal(channels, &cs); err != nil {
+			r.l.Error(fmt.Sprintf("invalid channels of kind '%s' of user '%s': %v", kind, userId, err))
+			continue
+		}
+		p[kind] = cs
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return p, nil
+}
+
+func (r *notificationRepo) SetPreferences(ctx context.Context, userId types.ObjectId, p entity.Preferences) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO notification_preferences (user_id, kind, channels) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, kind) DO UPDATE SET channels = EXCLUDED.channels`
+	for k, cs := range p {
+		if cs == nil {
+			cs = []entity.Channel{}
+		}
+		b, _ := json.Marshal(cs)
+		if _, err := tx.ExecContext(ctx, query, userId.String(), k.String(), b); err != nil {
+			return fmt.Errorf("failed to set preference of kind %s for user %s: %w", k, userId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) SaveRecipient(ctx context.Context, userId types.ObjectId, email, name string) error {
+	query := `INSERT INTO recipients (user_id, email, name) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET email = EXCLUDED.email, name = EXCLUDED.name`
+	if _, err := r.db.ExecContext(ctx, query, userId.String(), email, name); err != nil {
+		return fmt.Errorf("failed to save recipient %s: %w", userId, err)
+	}
+	return nil
+}
+
+// AddRecipients adds the users in a transaction, the existing recipients get only the email and the name
+// that they don't have, like the ones that are added by setting a channel.
+func (r *notificationRepo) AddRecipients(ctx context.Context, rs []*entity.Recipient) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO recipients (user_id, email, name) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET email = EXCLUDED.email, name = EXCLUDED.name WHERE recipients.email = ''`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rc := range rs {
+		if _, err := stmt.ExecContext(ctx, rc.UserId.String(), rc.Email, rc.Name); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", rc.UserId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) IsBackfilled(ctx context.Context, name string) (bool, error) {
+	var done bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM backfills WHERE name = $1)", name).Scan(&done)
+	if err != nil {
+		return false, fmt.Errorf("failed to get backfill %s: %w", name, err)
+	}
+	return done, nil
+}
+
+func (r *notificationRepo) SetBackfilled(ctx context.Context, name string) error {
+	query := "INSERT INTO backfills (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"
+	if _, err := r.db.ExecContext(ctx, query, name); err != nil {
+		return fmt.Errorf("failed to set backfill %s: %w", name, err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) GetRecipient(ctx context.Context, userId types.ObjectId) (*entity.Recipient, error) {
+	query := "SELECT user_id, email, name, webhook_url, webhook_secret FROM recipients WHERE user_id = $1"
+	var rc entity.Recipient
+	err := r.db.QueryRowContext(ctx, query, userId.String()).Scan(&rc.UserId, &rc.Email, &rc.Name,
+		&rc.WebhookURL, &rc.WebhookSecret)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get recipient %s: %w", userId, err)
+	}
+
+	query = "SELECT endpoint, p256dh, auth, created_at FROM push_subscriptions WHERE user_id = $1"
+	rows, err := r.db.QueryContext(ctx, query, userId.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get push subscriptions of user %s: %w", userId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s entity.PushSubscription
+		if err := rows.Scan(&s.Endpoint, &s.P256dh, &s.Auth, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		rc.Subscriptions = append(rc.Subscriptions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return &rc, nil
+}
+
+// SetWebhook adds the recipient if it doesn't exist, the guests have no recipient until they set a channel.
+func (r *notificationRepo) SetWebhook(ctx context.Context, userId types.ObjectId, url, secret string) error {
+	query := `INSERT INTO recipients (user_id, webhook_url, webhook_secret) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) DO UPDATE SET webhook_url = EXCLUDED.webhook_url, webhook_secret = EXCLUDED.webhook_secret`
+	if _, err := r.db.ExecContext(ctx, query, userId.String(), url, secret); err != nil {
+		return fmt.Errorf("failed to set webhook of user %s: %w", userId, err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) AddPushSubscription(ctx context.Context, userId types.ObjectId, s entity.PushSubscription) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO recipients (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, userId.String()); err != nil {
+		return fmt.Errorf("failed to add recipient %s: %w", userId, err)
+	}
+
+	// the endpoint belongs to the browser, it moves to the user that is logged in now
+	query = `INSERT INTO push_subscriptions (endpoint, user_id, p256dh, auth, created_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (endpoint) DO UPDATE SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth`
+	if _, err := tx.ExecContext(ctx, query, s.Endpoint, userId.String(), s.P256dh, s.Auth, s.CreatedAt); err != nil {
+		return fmt.Errorf("failed to add push subscription of user %s: %w", userId, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *notificationRepo) RemovePushSubscription(ctx context.Context, userId types.ObjectId, endpoint string) (bool, error) {
+	query := "DELETE FROM push_subscriptions WHERE endpoint = $1 AND user_id = $2"
+	res, err := r.db.ExecContext(ctx, query, endpoint, userId.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to remove push subscription of user %s: %w", userId, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return []byte(b)
+}
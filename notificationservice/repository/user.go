@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// userRepo reads the users table of the profile service.
+type userRepo struct {
+	db *sql.DB
+}
+
+func NewUserRepo(db *sql.DB) *userRepo {
+	return &userRepo{db: db}
+}
+
+func (r *userRepo) GetUsers(ctx context.Context, after types.ObjectId, limit int) ([]*entity.Recipient, error) {
+	query := "SELECT id, email, name FROM users WHERE id > $1 ORDER BY id LIMIT $2"
+	rows, err := r.db.QueryContext(ctx, query, after.String(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*entity.Recipient
+	for rows.Next() {
+		var rc entity.Recipient
+		if err := rows.Scan(&rc.UserId, &rc.Email, &rc.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		list = append(list, &rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return list, nil
+}
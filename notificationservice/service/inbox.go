@@ -0,0 +1,203 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// GetInbox returns the notifications of the user, the newest first.
+func (s *Service) GetInbox(ctx context.Context, userId types.ObjectId, unreadOnly bool, p *paginate.Paginated) (*InboxResponse, error) {
+	p.Filters["user_id"] = paginate.Filter{
+		Operator: paginate.FilterOperatorEqual,
+		Values:   []interface{}{userId.String()},
+	}
+	if unreadOnly {
+		p.Filters["read"] = paginate.Filter{
+			Operator: paginate.FilterOperatorEqual,
+			Values:   []interface{}{false},
+		}
+	}
+	p.SortColumn = "created_at"
+	p.Decscending = true
+
+	list, total, err := s.repo.GetNotifications(ctx, p)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	unread, err := s.repo.UnreadCount(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	res := &InboxResponse{
+		PaginatedResponseBase: paginate.PaginatedResponseBase{
+			CurrentPage:  p.Page,
+			PageSize:     uint64(len(list)),
+			TotalNumbers: total,
+			TotalPages:   (total + p.PerPage - 1) / p.PerPage,
+		},
+		Unread: unread,
+		List:   make([]NotificationResponse, 0, len(list)),
+	}
+
+	for _, n := range list {
+		r := NotificationResponse{
+			ID:        n.ID.String(),
+			Kind:      n.Kind.String(),
+			Title:     n.Title,
+			Body:      n.Body,
+			Data:      n.Data,
+			Read:      n.Read,
+			CreatedAt: n.CreatedAt.Unix(),
+		}
+		if n.Read {
+			r.ReadAt = n.ReadAt.Unix()
+		}
+		res.List = append(res.List, r)
+	}
+
+	return res, nil
+}
+
+func (s *Service) UnreadCount(ctx context.Context, userId types.ObjectId) (uint64, error) {
+	return s.repo.UnreadCount(ctx, userId)
+}
+
+func (s *Service) MarkRead(ctx context.Context, userId, id types.ObjectId) error {
+	ok, err := s.repo.MarkRead(ctx, userId, id, time.Now())
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Service) MarkAllRead(ctx context.Context, userId types.ObjectId) error {
+	if err := s.repo.MarkAllRead(ctx, userId, time.Now()); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetPreferences returns the channels of all kinds for the user.
+func (s *Service) GetPreferences(ctx context.Context, userId types.ObjectId) (PreferencesResponse, error) {
+	p, err := s.repo.GetPreferences(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	res := make(PreferencesResponse, len(entity.Kinds))
+	for _, k := range entity.Kinds {
+		cs, ok := p[k]
+		if !ok {
+			cs = s.defaults[k]
+		}
+
+		res[k.String()] = make([]string, 0, len(cs))
+		for _, c := range cs {
+			res[k.String()] = append(res[k.String()], c.String())
+		}
+	}
+
+	return res, nil
+}
+
+func (s *Service) SetPreferences(ctx context.Context, userId types.ObjectId, req PreferencesRequest) error {
+	p := make(entity.Preferences, len(req))
+	for ks, css := range req {
+		k, err := entity.ParseKind(ks)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+
+		cs := make([]entity.Channel, 0, len(css))
+		for _, c := range css {
+			ch, err := entity.ParseChannel(c)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+			}
+			cs = append(cs, ch)
+		}
+		p[k] = cs
+	}
+
+	if err := s.repo.SetPreferences(ctx, userId, p); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// SetWebhook sets the webhook of the user and returns the secret that signs its requests,
+// an empty url removes the webhook.
+func (s *Service) SetWebhook(ctx context.Context, userId types.ObjectId, req WebhookRequest) (*WebhookResponse, error) {
+	var secret string
+	if req.URL != "" {
+		u, err := url.Parse(req.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("%w: invalid webhook url", ErrInvalidInput)
+		}
+
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	if err := s.repo.SetWebhook(ctx, userId, req.URL, secret); err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	return &WebhookResponse{URL: req.URL, Secret: secret}, nil
+}
+
+func (s *Service) AddPushSubscription(ctx context.Context, userId types.ObjectId, req PushSubscriptionRequest) error {
+	u, err := url.Parse(req.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: invalid push endpoint", ErrInvalidInput)
+	}
+	if req.Keys.P256dh == "" || req.Keys.Auth == "" {
+		return fmt.Errorf("%w: push subscription keys are required", ErrInvalidInput)
+	}
+
+	if err := s.repo.AddPushSubscription(ctx, userId, entity.PushSubscription{
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Service) RemovePushSubscription(ctx context.Context, userId types.ObjectId, endpoint string) error {
+	ok, err := s.repo.RemovePushSubscription(ctx, userId, endpoint)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/notificationservice/entity"
+	"github.com/alikarimi999/shahboard/pkg/block"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNotFound     = errors.New("notification not found")
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+type Repository interface {
+	Add(ctx context.Context, n *entity.Notification) error
+	GetNotifications(context.Context, *paginate.Paginated) ([]*entity.Notification, uint64, error)
+	UnreadCount(ctx context.Context, userId types.ObjectId) (uint64, error)
+	// MarkRead returns false if the notification doesn't exist or is not the user's.
+	MarkRead(ctx context.Context, userId, id types.ObjectId, t time.Time) (bool, error)
+	MarkAllRead(ctx context.Context, userId types.ObjectId, t time.Time) error
+
+	GetPreferences(ctx context.Context, userId types.ObjectId) (entity.Preferences, error)
+	SetPreferences(ctx context.Context, userId types.ObjectId, p entity.Preferences) error
+
+	// SaveRecipient adds the user or updates its email and name.
+	SaveRecipient(ctx context.Context, userId types.ObjectId, email, name string) error
+	// return nil,nil if the recipient is not found
+	GetRecipient(ctx context.Context, userId types.ObjectId) (*entity.Recipient, error)
+	SetWebhook(ctx context.Context, userId types.ObjectId, url, secret string) error
+	AddPushSubscription(ctx context.Context, userId types.ObjectId, s entity.PushSubscription) error
+	// RemovePushSubscription returns false if the subscription is not the user's.
+	RemovePushSubscription(ctx context.Context, userId types.ObjectId, endpoint string) (bool, error)
+
+	// AddRecipients adds the users that are not recipients yet, the existing ones are not changed.
+	AddRecipients(ctx context.Context, rs []*entity.Recipient) error
+	// IsBackfilled returns true if the backfill of the name is done.
+	IsBackfilled(ctx context.Context, name string) (bool, error)
+	SetBackfilled(ctx context.Context, name string) error
+}
+
+// UserRepository reads the users of the profile service, it's used to backfill the recipients
+// of the users that are created before the notification service.
+type UserRepository interface {
+	// GetUsers returns the users after the user id, ordered by the id.
+	GetUsers(ctx context.Context, after types.ObjectId, limit int) ([]*entity.Recipient, error)
+}
+
+// Channel delivers the notifications to the users that are not online, like email and webhooks.
+type Channel interface {
+	Send(ctx context.Context, r *entity.Recipient, n *entity.Notification) error
+}
+
+type Config struct {
+	// Defaults are the channels of the kinds that the users haven't changed, they're merged with the built in defaults.
+	Defaults map[entity.Kind][]entity.Channel `json:"defaults"`
+	// number of seconds that the delivery of a notification to a channel can take
+	SendTimeout int `json:"send_timeout"`
+	// DeliveryWorkers deliver the notifications to the channels, DeliveryQueueSize is the number of
+	// the notifications that wait for them, the events wait when the queue is full.
+	DeliveryWorkers   int `json:"delivery_workers"`
+	DeliveryQueueSize int `json:"delivery_queue_size"`
+}
+
+const (
+	defaultSendTimeout       = 10
+	defaultDeliveryWorkers   = 8
+	defaultDeliveryQueueSize = 1000
+
+	backfillRecipients          = "recipients"
+	backfillRecipientsBatchSize = 500
+)
+
+var defaultPreferences = entity.Preferences{
+	entity.KindRematchOffered:       {entity.ChannelInApp, entity.ChannelWebPush},
+	entity.KindFollowingGameStarted: {entity.ChannelInApp, entity.ChannelWebPush},
+	entity.KindNewFollower:          {entity.ChannelInApp},
+	entity.KindDirectMessage:        {entity.ChannelInApp, entity.ChannelEmail, entity.ChannelWebPush},
+	entity.KindAccountSanctioned:    {entity.ChannelInApp, entity.ChannelEmail, entity.ChannelWebhook},
+}
+
+type Service struct {
+	cfg      Config
+	repo     Repository
+	channels map[entity.Channel]Channel
+	defaults entity.Preferences
+	online   *onlineUsers
+	users    UserRepository
+	blocks   *block.Registry
+
+	deliveries chan delivery
+	wg         sync.WaitGroup
+	stopCh     chan struct{}
+
+	p  event.Publisher
+	sm *event.SubscriptionManager
+	l  log.Logger
+}
+
+// delivery is a notification that waits for the channels.
+type delivery struct {
+	n        *entity.Notification
+	channels []entity.Channel
+}
+
+func NewService(cfg Config, repo Repository, users UserRepository, channels map[entity.Channel]Channel,
+	p event.Publisher, sub event.Subscriber, rc *redis.Client, blocks *block.Registry, l log.Logger) *Service {
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = defaultSendTimeout
+	}
+	if cfg.DeliveryWorkers <= 0 {
+		cfg.DeliveryWorkers = defaultDeliveryWorkers
+	}
+	if cfg.DeliveryQueueSize <= 0 {
+		cfg.DeliveryQueueSize = defaultDeliveryQueueSize
+	}
+
+	defaults := make(entity.Preferences, len(defaultPreferences))
+	for k, cs := range defaultPreferences {
+		defaults[k] = cs
+	}
+	for k, cs := range cfg.Defaults {
+		defaults[k] = cs
+	}
+
+	s := &Service{
+		cfg:      cfg,
+		repo:     repo,
+		channels: channels,
+		defaults: defaults,
+		online:   newOnlineUsers(rc),
+		users:    users,
+		blocks:   blocks,
+
+		deliveries: make(chan delivery, cfg.DeliveryQueueSize),
+		stopCh:     make(chan struct{}),
+
+		p: p,
+		l: l,
+	}
+
+	for i := 0; i < cfg.DeliveryWorkers; i++ {
+		s.wg.Add(1)
+		go s.runDeliveries()
+	}
+
+	s.sm = event.NewManager(l, s.handleEvent)
+	s.sm.AddSubscription(sub.Subscribe(event.TopicUser))
+	s.sm.AddSubscription(sub.Subscribe(event.TopicRematchOffered))
+	s.sm.AddSubscription(sub.Subscribe(event.TopicUserFollowed))
+	s.sm.AddSubscription(sub.Subscribe(event.TopicFollowingGameStarted))
+	s.sm.AddSubscription(sub.Subscribe(event.TopicDirectChatMsgSent))
+
+	return s
+}
+
+func (s *Service) handleEvent(e event.Event) {
+	ctx := context.Background()
+
+	switch e := e.(type) {
+	case *event.EventUserCreated:
+		s.saveRecipient(ctx, e.UserID, e.Email, e.Name)
+	case *event.EventUserGuestClaimed:
+		s.saveRecipient(ctx, e.UserID, e.Email, e.Name)
+
+	case *event.EventRematchOffered:
+		s.notify(ctx, e.To, entity.KindRematchOffered, "Rematch offered",
+			fmt.Sprintf("%s wants a rematch", s.userName(ctx, e.From)),
+			map[string]any{"game_id": e.GameID, "from": e.From, "expires_at": e.ExpiresAt, "url": "/play.html"})
+	case *event.EventUserFollowed:
+		s.notify(ctx, e.FolloweeID, entity.KindNewFollower, "New follower",
+			fmt.Sprintf("%s started following you", s.userName(ctx, e.FollowerID)),
+			map[string]any{"user_id": e.FollowerID, "url": "/profile.html?userId=" + e.FollowerID.String()})
+	case *event.EventFollowingGameStarted:
+		body := fmt.Sprintf("%s started a game", s.userName(ctx, e.PlayerID))
+		data := map[string]any{"game_id": e.GameID, "player_id": e.PlayerID, "variant": e.Variant,
+			"url": "/view.html?game_id=" + e.GameID.String()}
+		for _, userId := range e.Followers {
+			s.notify(ctx, userId, entity.KindFollowingGameStarted, "Game started", body, data)
+		}
+	case *event.EventDirectChatMsgSent:
+		// the messages between the blocked users are not notified, neither if the block can't be checked
+		blocked, err := s.blocks.Blocked(ctx, e.SenderId, e.ReceiverId)
+		if err != nil {
+			s.l.Error(err.Error())
+			return
+		}
+		if blocked {
+			return
+		}
+		s.notify(ctx, e.ReceiverId, entity.KindDirectMessage,
+			fmt.Sprintf("Message from %s", s.userName(ctx, e.SenderId)), e.Content,
+			map[string]any{"chat_id": e.ChatID, "sender_id": e.SenderId})
+	case *event.EventUserSanctioned:
+		body := fmt.Sprintf("Your account got a %s", e.Type)
+		if e.ExpiresAt > 0 {
+			body += fmt.Sprintf(" until %s", time.Unix(e.ExpiresAt, 0).UTC().Format(time.RFC1123))
+		}
+		if e.Reason != "" {
+			body += fmt.Sprintf(", reason: %s", e.Reason)
+		}
+		s.notify(ctx, e.UserID, entity.KindAccountSanctioned, "Account sanctioned", body,
+			map[string]any{"type": e.Type, "expires_at": e.ExpiresAt})
+	}
+}
+
+// notify adds the notification to the inbox and sends it to the sessions of the user if the user is online,
+// otherwise it's delivered by the other channels that the user chose for the kind.
+func (s *Service) notify(ctx context.Context, userId types.ObjectId, kind entity.Kind, title, body string, data any) {
+	channels, err := s.userChannels(ctx, userId, kind)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if len(channels) == 0 {
+		return
+	}
+
+	b, _ := json.Marshal(data)
+	n := &entity.Notification{
+		ID:        types.NewObjectId(),
+		UserId:    userId,
+		Kind:      kind,
+		Title:     title,
+		Body:      body,
+		Data:      b,
+		CreatedAt: time.Now(),
+	}
+
+	online, onlineErr := s.online.has(ctx, userId)
+	if onlineErr != nil {
+		// the notification is delivered by both ways
+		s.l.Error(onlineErr.Error())
+	}
+
+	inApp := false
+	for _, c := range channels {
+		if c == entity.ChannelInApp {
+			inApp = true
+		}
+	}
+
+	if inApp {
+		if err := s.repo.Add(ctx, n); err != nil {
+			s.l.Error(err.Error())
+			return
+		}
+
+		if online || onlineErr != nil {
+			if err := s.p.Publish(event.EventNotificationCreated{
+				ID:             types.NewObjectId(),
+				NotificationID: n.ID,
+				UserID:         userId,
+				Kind:           kind.String(),
+				Title:          title,
+				Body:           body,
+				Data:           n.Data,
+				Timestamp:      n.CreatedAt.Unix(),
+			}); err != nil {
+				s.l.Error(err.Error())
+			}
+		}
+	}
+
+	if online {
+		return
+	}
+
+	// the slow channels don't block the events, they wait only if the queue is full
+	select {
+	case s.deliveries <- delivery{n: n, channels: channels}:
+	case <-s.stopCh:
+	}
+}
+
+func (s *Service) runDeliveries() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case d := <-s.deliveries:
+			s.deliver(context.Background(), d.n, d.channels)
+		}
+	}
+}
+
+// deliver sends the notification by the channels other than in-app.
+func (s *Service) deliver(ctx context.Context, n *entity.Notification, channels []entity.Channel) {
+	var r *entity.Recipient
+	for _, name := range channels {
+		c, ok := s.channels[name]
+		if !ok || name == entity.ChannelInApp {
+			continue
+		}
+
+		if r == nil {
+			var err error
+			r, err = s.repo.GetRecipient(ctx, n.UserId)
+			if err != nil {
+				s.l.Error(err.Error())
+				return
+			}
+			if r == nil {
+				// the user doesn't have any contact data
+				return
+			}
+		}
+
+		cctx, cancel := context.WithTimeout(ctx, time.Duration(s.cfg.SendTimeout)*time.Second)
+		if err := c.Send(cctx, r, n); err != nil {
+			s.l.Error(fmt.Sprintf("failed to send notification '%s' to user '%s' by %s: %v", n.ID, n.UserId, name, err))
+		}
+		cancel()
+	}
+}
+
+// userChannels returns the channels that the user chose for the kind, or the defaults.
+func (s *Service) userChannels(ctx context.Context, userId types.ObjectId, kind entity.Kind) ([]entity.Channel, error) {
+	p, err := s.repo.GetPreferences(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cs, ok := p[kind]; ok {
+		return cs, nil
+	}
+	return s.defaults[kind], nil
+}
+
+func (s *Service) saveRecipient(ctx context.Context, userId types.ObjectId, email, name string) {
+	if err := s.repo.SaveRecipient(ctx, userId, email, name); err != nil {
+		s.l.Error(err.Error())
+	}
+}
+
+// BackfillRecipients adds the users of the profile service that are created before the notification service
+// as recipients, the others are added by the user events. It's done once, an interrupted backfill is
+// done again at the next startup.
+func (s *Service) BackfillRecipients(ctx context.Context) error {
+	done, err := s.repo.IsBackfilled(ctx, backfillRecipients)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	var after types.ObjectId
+	total := 0
+	for {
+		list, err := s.users.GetUsers(ctx, after, backfillRecipientsBatchSize)
+		if err != nil {
+			s.l.Error(err.Error())
+			return err
+		}
+		if len(list) == 0 {
+			break
+		}
+
+		if err := s.repo.AddRecipients(ctx, list); err != nil {
+			s.l.Error(err.Error())
+			return err
+		}
+		total += len(list)
+		after = list[len(list)-1].UserId
+	}
+
+	if err := s.repo.SetBackfilled(ctx, backfillRecipients); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Info(fmt.Sprintf("recipients backfilled with %d users", total))
+	return nil
+}
+
+// userName returns the name of the user, or a generic name if the user is not known.
+func (s *Service) userName(ctx context.Context, userId types.ObjectId) string {
+	r, err := s.repo.GetRecipient(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+	}
+	if r == nil || r.Name == "" {
+		return "A player"
+	}
+	return r.Name
+}
+
+func (s *Service) Stop() {
+	s.sm.Stop()
+	close(s.stopCh)
+	s.wg.Wait()
+}
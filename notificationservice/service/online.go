@@ -0,0 +1,30 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPresencePrefix is the presence of the users that WS Gateway keeps in the shared redis,
+// it expires with the sessions of the user so a crashed gateway doesn't leave the user online.
+const keyPresencePrefix = "presence:"
+
+// onlineUsers reports whether the users have a session on WS Gateway.
+type onlineUsers struct {
+	c *redis.Client
+}
+
+func newOnlineUsers(c *redis.Client) *onlineUsers {
+	return &onlineUsers{c: c}
+}
+
+func (o *onlineUsers) has(ctx context.Context, userId types.ObjectId) (bool, error) {
+	n, err := o.c.Exists(ctx, keyPresencePrefix+userId.String()).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to get presence of user '%s': %w", userId, err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,50 @@
+package notification
+
+import (
+	"encoding/json"
+
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+)
+
+type NotificationResponse struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Read      bool            `json:"read"`
+	ReadAt    int64           `json:"read_at,omitempty"`
+	CreatedAt int64           `json:"created_at"`
+}
+
+type InboxResponse struct {
+	paginate.PaginatedResponseBase
+	Unread uint64                 `json:"unread"`
+	List   []NotificationResponse `json:"list"`
+}
+
+// PreferencesRequest sets the channels of the kinds, the kinds that are not in the request are not changed.
+// An empty list mutes the kind.
+type PreferencesRequest map[string][]string
+
+// PreferencesResponse has the channels of all kinds.
+type PreferencesResponse map[string][]string
+
+type WebhookRequest struct {
+	URL string `json:"url"`
+}
+
+type WebhookResponse struct {
+	URL string `json:"url"`
+	// Secret signs the body of the requests, the signature is sent by the X-Shahboard-Signature header
+	Secret string `json:"secret"`
+}
+
+// PushSubscriptionRequest is the PushSubscription of the browser.
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
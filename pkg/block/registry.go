@@ -0,0 +1,165 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// users that the user blocked
+	keyBlockedPrefix = "blocked:"
+	// users that blocked the user
+	keyBlockedByPrefix = "blocked_by:"
+)
+
+// Registry keeps the blocks between the users in redis, so all instances and services that share
+// the redis see the same state. It's fed by the block events of the profile service.
+//
+// A block works in both directions, the users can't challenge, message or be paired with each other.
+type Registry struct {
+	c  *redis.Client
+	sm *event.SubscriptionManager
+	l  log.Logger
+}
+
+func NewRegistry(c *redis.Client, sub event.Subscriber, l log.Logger) *Registry {
+	r := &Registry{
+		c: c,
+		l: l,
+	}
+
+	r.sm = event.NewManager(l, r.handleEvent)
+	r.sm.AddSubscription(sub.Subscribe(event.TopicUserBlocked))
+	r.sm.AddSubscription(sub.Subscribe(event.TopicUserUnblocked))
+	return r
+}
+
+// Blocked returns true if any of the users blocked the other one.
+func (r *Registry) Blocked(ctx context.Context, user1, user2 types.ObjectId) (bool, error) {
+	var c1, c2 *redis.BoolCmd
+	_, err := r.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		c1 = p.SIsMember(ctx, keyBlockedPrefix+user1.String(), user2.String())
+		c2 = p.SIsMember(ctx, keyBlockedPrefix+user2.String(), user1.String())
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check blocks of users '%s' and '%s': %w", user1, user2, err)
+	}
+
+	return c1.Val() || c2.Val(), nil
+}
+
+// BlockedUsers returns the users that the user blocked or are blocked by.
+func (r *Registry) BlockedUsers(ctx context.Context, userId types.ObjectId) ([]types.ObjectId, error) {
+	ids, err := r.c.SUnion(ctx, keyBlockedPrefix+userId.String(), keyBlockedByPrefix+userId.String()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users of user '%s': %w", userId, err)
+	}
+
+	users := make([]types.ObjectId, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, types.ObjectId(id))
+	}
+	return users, nil
+}
+
+// Entry is a block of the registry.
+type Entry struct {
+	BlockerID types.ObjectId
+	BlockedID types.ObjectId
+}
+
+// Seed replaces the blocks of the registry with the blocks of the database, it's used at the startup of
+// the profile service to restore the registry if the redis has lost it or some events were missed.
+// The blocks that are not in the list are removed, then the list is added.
+func Seed(ctx context.Context, c *redis.Client, blocks []Entry) error {
+	current := make(map[Entry]bool, len(blocks))
+	for _, b := range blocks {
+		current[b] = true
+	}
+
+	var stale []Entry
+	iter := c.Scan(ctx, 0, keyBlockedPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		k := iter.Val()
+		members, err := c.SMembers(ctx, k).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get blocks of '%s': %w", k, err)
+		}
+
+		blocker := types.ObjectId(strings.TrimPrefix(k, keyBlockedPrefix))
+		for _, m := range members {
+			if e := (Entry{BlockerID: blocker, BlockedID: types.ObjectId(m)}); !current[e] {
+				stale = append(stale, e)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan blocks: %w", err)
+	}
+
+	_, err := c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, e := range stale {
+			p.SRem(ctx, keyBlockedPrefix+e.BlockerID.String(), e.BlockedID.String())
+			p.SRem(ctx, keyBlockedByPrefix+e.BlockedID.String(), e.BlockerID.String())
+		}
+		for _, e := range blocks {
+			p.SAdd(ctx, keyBlockedPrefix+e.BlockerID.String(), e.BlockedID.String())
+			p.SAdd(ctx, keyBlockedByPrefix+e.BlockedID.String(), e.BlockerID.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to seed blocks: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) Stop() {
+	r.sm.Stop()
+}
+
+func (r *Registry) handleEvent(e event.Event) {
+	switch e := e.(type) {
+	case *event.EventUserBlocked:
+		r.add(e)
+	case *event.EventUserUnblocked:
+		r.remove(e)
+	}
+}
+
+func (r *Registry) add(e *event.EventUserBlocked) {
+	ctx := context.Background()
+	_, err := r.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.SAdd(ctx, keyBlockedPrefix+e.BlockerID.String(), e.BlockedID.String())
+		p.SAdd(ctx, keyBlockedByPrefix+e.BlockedID.String(), e.BlockerID.String())
+		return nil
+	})
+	if err != nil {
+		r.l.Error(fmt.Sprintf("failed to add block of user '%s' by '%s': %v", e.BlockedID, e.BlockerID, err))
+		return
+	}
+
+	r.l.Debug(fmt.Sprintf("user '%s' blocked by '%s'", e.BlockedID, e.BlockerID))
+}
+
+func (r *Registry) remove(e *event.EventUserUnblocked) {
+	ctx := context.Background()
+	_, err := r.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.SRem(ctx, keyBlockedPrefix+e.BlockerID.String(), e.BlockedID.String())
+		p.SRem(ctx, keyBlockedByPrefix+e.BlockedID.String(), e.BlockerID.String())
+		return nil
+	})
+	if err != nil {
+		r.l.Error(fmt.Sprintf("failed to remove block of user '%s' by '%s': %v", e.BlockedID, e.BlockerID, err))
+		return
+	}
+
+	r.l.Debug(fmt.Sprintf("user '%s' unblocked by '%s'", e.BlockedID, e.BlockerID))
+}
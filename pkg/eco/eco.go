@@ -0,0 +1,71 @@
+// Package eco finds the ECO code and the name of the opening of a game or a position.
+package eco
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/alikarimi999/shahboard/pkg/zobrist"
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/opening"
+)
+
+type Opening struct {
+	ECO  string `json:"eco"`
+	Name string `json:"name"`
+}
+
+var (
+	once sync.Once
+	book *opening.BookECO
+	// openings by the zobrist hash of their final position
+	positions map[uint64]Opening
+)
+
+// Load parses the opening book, it takes a few seconds so services can call it at startup.
+// Otherwise it's called on the first use.
+func Load() {
+	once.Do(func() {
+		book = opening.NewBookECO()
+		positions = make(map[uint64]Opening)
+
+		start := chess.NewGame().Position()
+		for _, o := range book.Possible(nil) {
+			pos := start
+			// the book keeps the moves of the openings in UCI notation
+			for _, s := range strings.Fields(o.PGN()) {
+				m, err := chess.UCINotation{}.Decode(pos, s)
+				if err != nil {
+					pos = nil
+					break
+				}
+				pos = pos.Update(m)
+			}
+			if pos == nil {
+				continue
+			}
+
+			positions[zobrist.Hash(pos)] = Opening{ECO: o.Code(), Name: o.Title()}
+		}
+	})
+}
+
+// Find returns the most specific opening of the moves that are played from the standard starting position.
+func Find(moves []*chess.Move) (Opening, bool) {
+	Load()
+
+	o := book.Find(moves)
+	if o == nil {
+		return Opening{}, false
+	}
+
+	return Opening{ECO: o.Code(), Name: o.Title()}, true
+}
+
+// FindPosition returns the opening that ends in the position, transpositions are found too.
+func FindPosition(pos *chess.Position) (Opening, bool) {
+	Load()
+
+	o, ok := positions[zobrist.Hash(pos)]
+	return o, ok
+}
@@ -0,0 +1,112 @@
+package errors
+
+import (
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is the domain of the gRPC error details.
+const Domain = "shahboard"
+
+// Entry is an error of the catalog, a code has the same status and message key on the HTTP, gRPC and WebSocket APIs.
+type Entry struct {
+	Code       string
+	HTTPStatus int
+	GRPCCode   codes.Code
+	// MsgKey is the key of the message that the clients show to the users
+	MsgKey  string
+	Message string
+}
+
+var catalog = map[string]Entry{
+	CodeInvalidInput:     {CodeInvalidInput, http.StatusBadRequest, codes.InvalidArgument, "error.invalid_input", MsgInvalidInput},
+	CodeValidationError:  {CodeValidationError, http.StatusBadRequest, codes.InvalidArgument, "error.validation", MsgValidationError},
+	CodeUnauthorized:     {CodeUnauthorized, http.StatusUnauthorized, codes.Unauthenticated, "error.unauthorized", MsgUnauthorized},
+	CodePermissionDenied: {CodePermissionDenied, http.StatusForbidden, codes.PermissionDenied, "error.permission_denied", MsgPermissionDenied},
+	CodeNotFound:         {CodeNotFound, http.StatusNotFound, codes.NotFound, "error.not_found", MsgNotFound},
+	CodeConflict:         {CodeConflict, http.StatusConflict, codes.AlreadyExists, "error.conflict", MsgConflict},
+	CodeRateLimited:      {CodeRateLimited, http.StatusTooManyRequests, codes.ResourceExhausted, "error.rate_limited", MsgRateLimited},
+	CodeTimeout:          {CodeTimeout, http.StatusGatewayTimeout, codes.DeadlineExceeded, "error.timeout", MsgTimeout},
+	CodeInternalError:    {CodeInternalError, http.StatusInternalServerError, codes.Internal, "error.internal", MsgInternalError},
+}
+
+// codes of the gRPC errors that have no details, like the errors of the other servers
+var grpcCodes = map[codes.Code]string{
+	codes.InvalidArgument:   CodeInvalidInput,
+	codes.Unauthenticated:   CodeUnauthorized,
+	codes.PermissionDenied:  CodePermissionDenied,
+	codes.NotFound:          CodeNotFound,
+	codes.AlreadyExists:     CodeConflict,
+	codes.Aborted:           CodeConflict,
+	codes.ResourceExhausted: CodeRateLimited,
+	codes.DeadlineExceeded:  CodeTimeout,
+}
+
+// Lookup returns the entry of the code, the unknown codes are internal errors.
+func Lookup(code string) Entry {
+	if e, ok := catalog[code]; ok {
+		return e
+	}
+	return catalog[CodeInternalError]
+}
+
+// CodeOf returns the code of the error, it's the code of a WrappedError or of the details of a gRPC status.
+func CodeOf(err error) string {
+	var we *WrappedError
+	if As(err, &we) && we.Code != "" {
+		return Lookup(we.Code).Code
+	}
+
+	if st, ok := status.FromError(err); ok && st.Code() != codes.OK {
+		for _, d := range st.Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+				return Lookup(info.Reason).Code
+			}
+		}
+		if code, ok := grpcCodes[st.Code()]; ok {
+			return code
+		}
+	}
+
+	return CodeInternalError
+}
+
+// HTTPError is the body of the error responses of the HTTP APIs, Error is the message of the error.
+type HTTPError struct {
+	Code       string `json:"code"`
+	MessageKey string `json:"message_key"`
+	Error      string `json:"error"`
+}
+
+// HTTP returns the status and the body of the error response of the code, it's used as ctx.JSON(errors.HTTP(code, msg)).
+// The message of the catalog is used if msg is empty.
+func HTTP(code, msg string) (int, HTTPError) {
+	e := Lookup(code)
+	if msg == "" {
+		msg = e.Message
+	}
+	return e.HTTPStatus, HTTPError{Code: e.Code, MessageKey: e.MsgKey, Error: msg}
+}
+
+// GRPC returns the status error of the code, the code and the message key are sent as ErrorInfo details.
+// The message of the catalog is used if msg is empty.
+func GRPC(code, msg string) error {
+	e := Lookup(code)
+	if msg == "" {
+		msg = e.Message
+	}
+
+	st := status.New(e.GRPCCode, msg)
+	ds, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   e.Code,
+		Domain:   Domain,
+		Metadata: map[string]string{"message_key": e.MsgKey},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return ds.Err()
+}
@@ -15,6 +15,7 @@ const (
 	CodeValidationError  = "VALIDATION_ERROR"
 	CodeTimeout          = "TIMEOUT"
 	CodePermissionDenied = "PERMISSION_DENIED"
+	CodeRateLimited      = "RATE_LIMITED"
 )
 
 // Predefined error messages
@@ -27,6 +28,7 @@ const (
 	MsgValidationError  = "validation failed for the input data"
 	MsgTimeout          = "the operation timed out"
 	MsgPermissionDenied = "permission denied"
+	MsgRateLimited      = "too many requests"
 )
 
 // Layer represents the layer in which the error occurred
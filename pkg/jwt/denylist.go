@@ -0,0 +1,47 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const denylistKeyPrefix = "jwt_denylist:"
+
+// Denylist keeps the id (jti) of the revoked tokens until they expire.
+type Denylist interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type redisDenylist struct {
+	c *redis.Client
+}
+
+// NewRedisDenylist returns a denylist that is shared between all services that use the same redis.
+func NewRedisDenylist(c *redis.Client) Denylist {
+	return &redisDenylist{c: c}
+}
+
+func (d *redisDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// token is already expired
+		return nil
+	}
+
+	if err := d.c.Set(ctx, denylistKeyPrefix+jti, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token '%s': %w", jti, err)
+	}
+	return nil
+}
+
+func (d *redisDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.c.Exists(ctx, denylistKeyPrefix+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token '%s': %w", jti, err)
+	}
+	return n > 0, nil
+}
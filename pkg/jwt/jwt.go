@@ -1,13 +1,17 @@
 package jwt
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/alikarimi999/shahboard/pkg/log"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/golang-jwt/jwt"
 )
@@ -15,95 +19,295 @@ import (
 type GeneratorConfig struct {
 	PrivateKeyPath string `json:"private_key_path"`
 	Expiration     uint   `json:"expiration_in_seconds"`
+
+	// KeysDir enables the key rotation, the signing keys are kept in this directory
+	// and PrivateKeyPath is ignored.
+	KeysDir string `json:"keys_dir"`
+	// default is 7 days
+	RotationInterval uint `json:"rotation_interval_in_seconds"`
 }
 
 type Generator struct {
-	privateKey *rsa.PrivateKey
+	cfg        GeneratorConfig
+	keys       *keyring
 	expiration time.Duration
+	rotation   time.Duration
+	l          log.Logger
 }
 
-func NewGenerator(cfg GeneratorConfig) (*Generator, error) {
+func NewGenerator(cfg GeneratorConfig, l log.Logger) (*Generator, error) {
 	if cfg.Expiration == 0 {
 		return nil, errors.New("expiration must be greater than 0")
 	}
 
-	privateKey, err := loadPrivateKey(cfg.PrivateKeyPath)
-	if err != nil {
+	g := &Generator{
+		cfg:        cfg,
+		keys:       &keyring{},
+		expiration: time.Duration(cfg.Expiration) * time.Second,
+		rotation:   defaultRotationInterval,
+		l:          l,
+	}
+
+	if cfg.RotationInterval > 0 {
+		g.rotation = time.Duration(cfg.RotationInterval) * time.Second
+	}
+
+	if cfg.KeysDir == "" {
+		privateKey, err := loadPrivateKey(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		g.keys.set([]*signingKey{{
+			kid: keyId(&privateKey.PublicKey),
+			key: privateKey,
+		}})
+		return g, nil
+	}
+
+	if err := os.MkdirAll(cfg.KeysDir, 0700); err != nil {
 		return nil, err
 	}
 
-	return &Generator{
-		privateKey: privateKey,
-		expiration: time.Duration(cfg.Expiration) * time.Second,
-	}, nil
+	if err := g.rotate(); err != nil {
+		return nil, err
+	}
+	go g.runRotation()
+
+	return g, nil
+}
+
+// JWKS returns the public keys that the tokens are signed with.
+func (g *Generator) JWKS() JWKS {
+	return g.keys.jwks()
 }
 
-func (g *Generator) GenerateJWT(u types.User) string {
+// Validator returns a validator that uses the keys of the generator directly.
+func (g *Generator) Validator() *Validator {
+	return &Validator{ring: g.keys}
+}
+
+// Claims are the claims of an access token.
+type Claims struct {
+	types.User
+	// TokenId is the unique id of the token (jti), it's used to revoke the token before it expires.
+	TokenId string
+	// SessionId is the login session that the token is issued for (sid).
+	SessionId types.ObjectId
+	ExpiresAt time.Time
+}
+
+// GenerateJWT generates a short-lived access token for the user in the login session.
+func (g *Generator) GenerateJWT(u types.User, sessionId types.ObjectId) (string, Claims) {
 	t := time.Now()
 
+	c := Claims{
+		User:      u,
+		TokenId:   types.NewObjectId().String(),
+		SessionId: sessionId,
+		ExpiresAt: t.Add(g.expiration),
+	}
+
 	claims := jwt.MapClaims{
 		"id":       u.ID.String(),
 		"email":    u.Email,
 		"is_guest": u.IsGuest,
-		"exp":      t.Add(g.expiration).Unix(),
+		"role":     u.Role.String(),
+		"jti":      c.TokenId,
+		"sid":      sessionId.String(),
+		"exp":      c.ExpiresAt.Unix(),
 		"iat":      t.Unix(),
 	}
 
+	k := g.keys.signing()
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.kid
 
-	signedToken, _ := token.SignedString(g.privateKey)
+	signedToken, _ := token.SignedString(k.key)
 
-	return signedToken
+	return signedToken, c
 }
 
+const (
+	defaultJwksRefreshInterval = time.Hour
+	// unknown kids can't trigger fetching the key set more than once in this interval
+	minJwksRefreshInterval = 10 * time.Second
+)
+
 type ValidatorConfig struct {
+	// PublicKeyPath is the static key, it's used for the tokens without kid
+	// and when the key set is not configured or not reachable.
 	PublicKeyPath string `json:"public_key_path"`
+	// JwksUrl is the url of the key set that is published by the auth service.
+	JwksUrl string `json:"jwks_url"`
+	// default is 1 hour
+	JwksRefreshInterval uint `json:"jwks_refresh_interval_in_seconds"`
+	// LegacyTokensUntil is the cutover (unix seconds) until which the tokens without jti are accepted,
+	// they can't be revoked, so they are rejected by default.
+	LegacyTokensUntil int64 `json:"legacy_tokens_until"`
 }
 
 type Validator struct {
-	publicKey *rsa.PublicKey
+	cfg             ValidatorConfig
+	refreshInterval time.Duration
+
+	staticKey *rsa.PublicKey
+	// keys of the local generator, it's only set in the auth service
+	ring *keyring
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey // map by kid
+	fetchedAt   time.Time
+	fetchMu     sync.Mutex
+	lastFetchAt time.Time
+
+	denylist Denylist
 }
 
 func NewValidator(cfg ValidatorConfig) (*Validator, error) {
-	publicKey, err := loadPublicKey(cfg.PublicKeyPath)
+	if cfg.PublicKeyPath == "" && cfg.JwksUrl == "" {
+		return nil, errors.New("public key path or jwks url is required")
+	}
+
+	v := &Validator{
+		cfg:             cfg,
+		refreshInterval: defaultJwksRefreshInterval,
+		keys:            make(map[string]*rsa.PublicKey),
+	}
+
+	if cfg.JwksRefreshInterval > 0 {
+		v.refreshInterval = time.Duration(cfg.JwksRefreshInterval) * time.Second
+	}
+
+	if cfg.PublicKeyPath != "" {
+		publicKey, err := loadPublicKey(cfg.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		v.staticKey = publicKey
+		v.keys[keyId(publicKey)] = publicKey
+	}
+
+	if cfg.JwksUrl != "" {
+		// the auth service may not be up yet, the key set is fetched again on the first unknown kid
+		if err := v.refreshKeys(); err != nil && v.staticKey == nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (v *Validator) getKey(kid string) (*rsa.PublicKey, error) {
+	if v.ring != nil {
+		return v.ring.publicKey(kid)
+	}
+
+	if kid == "" {
+		// tokens that are issued before the key rotation don't have kid
+		if v.staticKey != nil {
+			return v.staticKey, nil
+		}
+		return nil, errors.New("missing kid in token header")
+	}
+
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := v.cfg.JwksUrl != "" && time.Since(v.fetchedAt) > v.refreshInterval
+	v.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if v.cfg.JwksUrl != "" {
+		if err := v.refreshKeys(); err != nil && !ok {
+			return nil, err
+		}
+
+		v.mu.RLock()
+		key, ok = v.keys[kid]
+		v.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key '%s'", kid)
+	}
+	return key, nil
+}
+
+// refreshKeys fetches the key set, the keys that are removed from the set are removed from the cache too.
+func (v *Validator) refreshKeys() error {
+	v.fetchMu.Lock()
+	defer v.fetchMu.Unlock()
+
+	if time.Since(v.lastFetchAt) < minJwksRefreshInterval {
+		return nil
+	}
+	v.lastFetchAt = time.Now()
+
+	keys, err := fetchJWKS(v.cfg.JwksUrl)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if v.staticKey != nil {
+		keys[keyId(v.staticKey)] = v.staticKey
 	}
 
-	return &Validator{
-		publicKey: publicKey,
-	}, nil
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+// WithDenylist makes the validator reject the tokens that are revoked in the denylist.
+func (v *Validator) WithDenylist(d Denylist) *Validator {
+	v.denylist = d
+	return v
 }
 
 func (v *Validator) ValidateJWT(tokenString string) (types.User, error) {
+	c, err := v.ParseJWT(tokenString)
+	if err != nil {
+		return types.User{}, err
+	}
+	return c.User, nil
+}
+
+// ParseJWT validates the token and returns all of its claims.
+func (v *Validator) ParseJWT(tokenString string) (Claims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return v.publicKey, nil
+		kid, _ := token.Header["kid"].(string)
+		return v.getKey(kid)
 	})
 
 	if err != nil {
-		return types.User{}, err
+		return Claims{}, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return types.User{}, errors.New("invalid token")
+		return Claims{}, errors.New("invalid token")
 	}
 
 	exp, ok := claims["exp"].(float64)
 	if !ok {
-		return types.User{}, errors.New("exp claim missing or invalid")
+		return Claims{}, errors.New("exp claim missing or invalid")
 	}
 	if time.Now().Unix() > int64(exp) {
-		return types.User{}, errors.New("token has expired")
+		return Claims{}, errors.New("token has expired")
 	}
 
 	id, idOk := claims["id"].(string)
 	email, emailOk := claims["email"].(string)
 	if !idOk || !emailOk {
-		return types.User{}, errors.New("missing or invalid id or email claim")
+		return Claims{}, errors.New("missing or invalid id or email claim")
 	}
 
 	var isGuest bool
@@ -111,11 +315,45 @@ func (v *Validator) ValidateJWT(tokenString string) (types.User, error) {
 		isGuest = claims["is_guest"].(bool)
 	}
 
-	return types.User{
-		ID:      types.ObjectId(id),
-		Email:   email,
-		IsGuest: isGuest,
-	}, nil
+	// tokens that are issued before the roles have the user role
+	role := types.RoleUser
+	if r, ok := claims["role"].(string); ok && r != "" {
+		role = types.Role(r)
+	}
+
+	c := Claims{
+		User: types.User{
+			ID:      types.ObjectId(id),
+			Email:   email,
+			IsGuest: isGuest,
+			Role:    role,
+		},
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}
+
+	// tokens that are issued before the refresh tokens don't have jti and sid,
+	// they are not in the denylist, so they are only accepted until the cutover
+	if jti, ok := claims["jti"].(string); ok {
+		c.TokenId = jti
+	}
+	if c.TokenId == "" && time.Now().Unix() >= v.cfg.LegacyTokensUntil {
+		return Claims{}, errors.New("missing jti claim")
+	}
+	if sid, ok := claims["sid"].(string); ok {
+		c.SessionId = types.ObjectId(sid)
+	}
+
+	if v.denylist != nil && c.TokenId != "" {
+		revoked, err := v.denylist.IsRevoked(context.Background(), c.TokenId)
+		if err != nil {
+			return Claims{}, err
+		}
+		if revoked {
+			return Claims{}, errors.New("token has been revoked")
+		}
+	}
+
+	return c, nil
 }
 
 func loadPrivateKey(privateKeyPath string) (*rsa.PrivateKey, error) {
@@ -0,0 +1,235 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRotationInterval      = 7 * 24 * time.Hour
+	defaultRotationCheckInterval = time.Minute
+	rsaKeySize                   = 2048
+
+	// a new key is published in the key set this long before it signs the tokens, so the validators,
+	// which refetch the key set at most every minJwksRefreshInterval, know it before they see its tokens
+	keyActivationDelay = time.Minute
+
+	// the PEM header of the key files that keeps the creation time of the key,
+	// the modification time of a file changes when it's copied or restored
+	createdAtHeader = "Created-At"
+)
+
+type signingKey struct {
+	kid       string
+	key       *rsa.PrivateKey
+	createdAt time.Time
+}
+
+// keyring keeps the active keys of the generator, sorted by creation time.
+// The newest key that is older than keyActivationDelay signs the new tokens, the newer one is
+// only published yet and the older ones are published until the tokens that are signed by them expire.
+type keyring struct {
+	mu   sync.RWMutex
+	keys []*signingKey
+}
+
+func (r *keyring) set(keys []*signingKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+}
+
+func (r *keyring) signing() *signingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if time.Since(r.keys[i].createdAt) >= keyActivationDelay {
+			return r.keys[i]
+		}
+	}
+	// the first key signs immediately, no validator knows any key yet
+	return r.keys[0]
+}
+
+func (r *keyring) publicKey(kid string) (*rsa.PublicKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.keys {
+		// tokens without kid are signed by the static key, which is the only key
+		if k.kid == kid || (kid == "" && len(r.keys) == 1) {
+			return &k.key.PublicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key '%s'", kid)
+}
+
+func (r *keyring) jwks() JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(r.keys))}
+	for _, k := range r.keys {
+		set.Keys = append(set.Keys, NewJWK(k.kid, &k.key.PublicKey))
+	}
+	return set
+}
+
+// rotate reloads the keys from the keys directory, generates a new signing key if the newest one
+// is older than the rotation interval and removes the keys that no valid token is signed by.
+// The directory can be shared between the instances of the auth service.
+func (g *Generator) rotate() error {
+	keys, err := loadKeysDir(g.cfg.KeysDir)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 || time.Since(keys[len(keys)-1].createdAt) >= g.rotation {
+		k, err := generateKey(g.cfg.KeysDir)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		g.l.Info(fmt.Sprintf("new jwt signing key '%s' generated", k.kid))
+	}
+
+	active := make([]*signingKey, 0, len(keys))
+	for i, k := range keys {
+		// a key stops signing when the next key is activated, and the tokens that are signed
+		// by it are valid for the token expiration after that.
+		if i < len(keys)-1 && time.Since(keys[i+1].createdAt) > keyActivationDelay+g.expiration+time.Minute {
+			if err := os.Remove(filepath.Join(g.cfg.KeysDir, k.kid+".pem")); err != nil && !os.IsNotExist(err) {
+				g.l.Error(err.Error())
+			}
+			g.l.Info(fmt.Sprintf("jwt signing key '%s' retired", k.kid))
+			continue
+		}
+		active = append(active, k)
+	}
+
+	g.keys.set(active)
+	return nil
+}
+
+func (g *Generator) runRotation() {
+	ticker := time.NewTicker(defaultRotationCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := g.rotate(); err != nil {
+			g.l.Error(fmt.Sprintf("failed to rotate jwt keys: %v", err))
+		}
+	}
+}
+
+func loadKeysDir(dir string) ([]*signingKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*signingKey{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".pem") {
+			continue
+		}
+
+		k, err := loadKeyFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key '%s': %w", e.Name(), err)
+		}
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].createdAt.Equal(keys[j].createdAt) {
+			return keys[i].kid < keys[j].kid
+		}
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	return keys, nil
+}
+
+// loadKeyFile loads a signing key and its creation time from the PEM header.
+// The keys that are written before the header was added get the modification time of their file,
+// and the file is written again with the header so the time doesn't change anymore.
+func loadKeyFile(path string) (*signingKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, fmt.Errorf("invalid private key format")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &signingKey{kid: keyId(&key.PublicKey), key: key}
+	if v, ok := block.Headers[createdAtHeader]; ok {
+		if k.createdAt, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, fmt.Errorf("invalid creation time: %w", err)
+		}
+		return k, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	k.createdAt = info.ModTime().UTC().Truncate(time.Second)
+
+	if err := writeKeyFile(path, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func generateKey(dir string) (*signingKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &signingKey{
+		kid:       keyId(&key.PublicKey),
+		key:       key,
+		createdAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if err := writeKeyFile(filepath.Join(dir, k.kid+".pem"), k); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+func writeKeyFile(path string, k *signingKey) error {
+	b := pem.EncodeToMemory(&pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: map[string]string{createdAtHeader: k.createdAt.Format(time.RFC3339)},
+		Bytes:   x509.MarshalPKCS1PrivateKey(k.key),
+	})
+
+	// write to a temp file first, so other instances never load a partial key
+	tmp := strings.TrimSuffix(path, ".pem") + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+)
+
+// fileSender writes the emails to a file instead of sending them, it's for local testing.
+type fileSender struct {
+	mu   sync.Mutex
+	from string
+	file string
+	l    log.Logger
+}
+
+func newFileSender(from, file string, l log.Logger) *fileSender {
+	return &fileSender{
+		from: from,
+		file: file,
+		l:    l,
+	}
+}
+
+func (s *fileSender) Send(_ context.Context, msg Message) error {
+	if s.file == "" {
+		s.l.Info(fmt.Sprintf("mail to '%s': %s\n%s", msg.To, msg.Subject, msg.Body))
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\r\n%s\r\n\r\n", time.Now().Format(time.RFC1123Z), encode(s.from, msg))
+	return err
+}
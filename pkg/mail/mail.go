@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers the emails, SMTP is used in production and the file sender for local testing.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	// smtp or file, default is file
+	Driver string     `json:"driver"`
+	From   string     `json:"from"`
+	SMTP   SMTPConfig `json:"smtp"`
+	// the file that the file sender appends the emails to, they're only logged if it's empty
+	File string `json:"file"`
+}
+
+func NewSender(cfg Config, l log.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return newSMTPSender(cfg.From, cfg.SMTP)
+	case DriverFile, "":
+		return newFileSender(cfg.From, cfg.File, l), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver '%s'", cfg.Driver)
+	}
+}
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type smtpSender struct {
+	from string
+	// address of the sender without the name, for the smtp envelope
+	envelopeFrom string
+	addr         string
+	auth         smtp.Auth
+}
+
+func newSMTPSender(from string, cfg SMTPConfig) (*smtpSender, error) {
+	if cfg.Host == "" || cfg.Port == 0 {
+		return nil, errors.New("smtp host and port are required")
+	}
+	if from == "" {
+		return nil, errors.New("mail from address is required")
+	}
+
+	a, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail from address: %w", err)
+	}
+
+	s := &smtpSender{
+		from:         from,
+		envelopeFrom: a.Address,
+		addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+	}
+
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return s, nil
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(s.addr, s.auth, s.envelopeFrom, []string{msg.To}, encode(s.from, msg)); err != nil {
+		return fmt.Errorf("failed to send mail to '%s': %w", msg.To, err)
+	}
+	return nil
+}
+
+func encode(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole aborts the request if the user doesn't have the role, or a role above it.
+// It must be used after ParsUserHeader or ParseQueryToken.
+func RequireRole(role types.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := ExtractUser(ctx)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if user.IsGuest || !user.Role.Includes(role) {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		ctx.Next()
+	}
+}
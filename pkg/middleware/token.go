@@ -3,8 +3,8 @@ package middleware
 import (
 	"encoding/base64"
 	"encoding/json"
-	"net/http"
 
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/gin-gonic/gin"
@@ -14,23 +14,32 @@ func ParseQueryToken(v *jwt.Validator) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		token := ctx.Query("token")
 		if token == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "token is required"))
 			ctx.Abort()
 			return
 		}
 
-		user, err := v.ValidateJWT(token)
+		claims, err := v.ParseJWT(token)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			ctx.JSON(errs.HTTP(errs.CodeUnauthorized, err.Error()))
 			ctx.Abort()
 			return
 		}
 
-		ctx.Set(userKey, user)
+		ctx.Set(userKey, claims.User)
+		ctx.Set(claimsKey, claims)
 		ctx.Next()
 	}
 }
 
+func ExtractClaims(ctx *gin.Context) (jwt.Claims, bool) {
+	c, ok := ctx.Get(claimsKey)
+	if !ok {
+		return jwt.Claims{}, false
+	}
+	return c.(jwt.Claims), true
+}
+
 func parseToken(token string) (types.User, error) {
 	b, err := base64.StdEncoding.DecodeString(token)
 	if err != nil {
@@ -8,7 +8,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-const userKey = "user"
+const (
+	userKey   = "user"
+	claimsKey = "claims"
+)
 
 func ParsUserHeader(v *jwt.Validator) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
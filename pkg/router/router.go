@@ -9,6 +9,9 @@ import (
 
 type Config struct {
 	Port int `json:"port"`
+	// TrustedProxies are the addresses allowed to set the client IP by the
+	// X-Forwarded-For header, none is trusted by default.
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 type Router struct {
@@ -19,6 +22,9 @@ type Router struct {
 func NewRouter(cfg Config) (*Router, error) {
 	// gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 	engine.Use(middleware.Cors())
 
 	r := &Router{
@@ -0,0 +1,146 @@
+package sanction
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "sanctions:"
+
+// Registry keeps the active sanctions of the users in redis, so all instances and services
+// that share the redis see the same state. It's fed by the sanction events of the auth service.
+//
+// Each user and sanction type has a sorted set of the sanction ids scored by their expiration,
+// permanent sanctions have an infinite score.
+type Registry struct {
+	c  *redis.Client
+	sm *event.SubscriptionManager
+	l  log.Logger
+}
+
+func NewRegistry(c *redis.Client, sub event.Subscriber, l log.Logger) *Registry {
+	r := &Registry{
+		c: c,
+		l: l,
+	}
+
+	r.sm = event.NewManager(l, r.handleEvent)
+	r.sm.AddSubscription(sub.Subscribe(event.TopicUserSanctioned))
+	r.sm.AddSubscription(sub.Subscribe(event.TopicUserSanctionLifted))
+	return r
+}
+
+// Has returns true if the user has an active sanction of any of the types.
+func (r *Registry) Has(ctx context.Context, userId types.ObjectId, kinds ...types.SanctionType) (bool, error) {
+	now := fmt.Sprintf("(%d", time.Now().UnixMilli())
+
+	cmds := make([]*redis.IntCmd, 0, len(kinds))
+	_, err := r.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, k := range kinds {
+			cmds = append(cmds, p.ZCount(ctx, key(userId, k), now, "+inf"))
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check sanctions of user '%s': %w", userId, err)
+	}
+
+	for _, c := range cmds {
+		if c.Val() > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Entry is a sanction of the registry.
+type Entry struct {
+	SanctionID types.ObjectId
+	UserID     types.ObjectId
+	Type       types.SanctionType
+	// zero for permanent sanctions
+	ExpiresAt time.Time
+}
+
+// Seed adds the active sanctions and removes the lifted ones, it's used at the startup of the auth service
+// to restore the registry from the database if the redis has lost it or some events were missed.
+// Both are idempotent, so the events that are handled meanwhile aren't undone.
+func Seed(ctx context.Context, c *redis.Client, active, lifted []Entry) error {
+	_, err := c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, e := range active {
+			p.ZAdd(ctx, key(e.UserID, e.Type), redis.Z{Score: score(e.ExpiresAt), Member: e.SanctionID.String()})
+		}
+		for _, e := range lifted {
+			p.ZRem(ctx, key(e.UserID, e.Type), e.SanctionID.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to seed sanctions: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) Stop() {
+	r.sm.Stop()
+}
+
+func (r *Registry) handleEvent(e event.Event) {
+	switch e := e.(type) {
+	case *event.EventUserSanctioned:
+		r.add(e)
+	case *event.EventUserSanctionLifted:
+		r.remove(e)
+	}
+}
+
+func (r *Registry) add(e *event.EventUserSanctioned) {
+	ctx := context.Background()
+	k := key(e.UserID, e.Type)
+
+	var expiresAt time.Time
+	if e.ExpiresAt > 0 {
+		expiresAt = time.Unix(e.ExpiresAt, 0)
+	}
+
+	_, err := r.c.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZAdd(ctx, k, redis.Z{Score: score(expiresAt), Member: e.SanctionID.String()})
+		// drop the expired ones
+		p.ZRemRangeByScore(ctx, k, "-inf", fmt.Sprintf("%d", time.Now().UnixMilli()))
+		return nil
+	})
+	if err != nil {
+		r.l.Error(fmt.Sprintf("failed to add sanction '%s' of user '%s': %v", e.SanctionID, e.UserID, err))
+		return
+	}
+
+	r.l.Debug(fmt.Sprintf("sanction '%s' of type '%s' added for user '%s'", e.SanctionID, e.Type, e.UserID))
+}
+
+func (r *Registry) remove(e *event.EventUserSanctionLifted) {
+	if err := r.c.ZRem(context.Background(), key(e.UserID, e.Type), e.SanctionID.String()).Err(); err != nil {
+		r.l.Error(fmt.Sprintf("failed to remove sanction '%s' of user '%s': %v", e.SanctionID, e.UserID, err))
+		return
+	}
+
+	r.l.Debug(fmt.Sprintf("sanction '%s' of type '%s' lifted for user '%s'", e.SanctionID, e.Type, e.UserID))
+}
+
+// score of the sanction is its expiration, permanent sanctions never expire.
+func score(expiresAt time.Time) float64 {
+	if expiresAt.IsZero() {
+		return math.Inf(1)
+	}
+	return float64(expiresAt.UnixMilli())
+}
+
+func key(userId types.ObjectId, t types.SanctionType) string {
+	return keyPrefix + userId.String() + ":" + t.String()
+}
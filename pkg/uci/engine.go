@@ -0,0 +1,256 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Engine is a client for a chess engine process that speaks UCI (e.g. stockfish).
+// It's safe for concurrent use but only one search runs at a time.
+type Engine struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	mu     sync.Mutex
+
+	multiPV int
+}
+
+type Config struct {
+	// Path of the engine binary
+	Path string `json:"path"`
+	// UCI options that are set after starting the engine (e.g. Threads, Hash)
+	Options map[string]string `json:"options"`
+}
+
+// NewEngine starts the engine process and sets the configured UCI options.
+func NewEngine(cfg Config) (*Engine, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("engine path is required")
+	}
+
+	cmd := exec.Command(cfg.Path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  bufio.NewReader(stdout),
+		multiPV: 1,
+	}
+
+	if err := e.send("uci"); err != nil {
+		e.Close()
+		return nil, err
+	}
+	if err := e.waitFor("uciok"); err != nil {
+		e.Close()
+		return nil, err
+	}
+
+	for k, v := range cfg.Options {
+		if err := e.send(fmt.Sprintf("setoption name %s value %s", k, v)); err != nil {
+			e.Close()
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Analyze searches the position to the given depth and returns the best lines, the first line is the best one.
+// Scores are from the side to move point of view.
+// If ctx is done before the search is finished, the search is stopped and the best lines found so far are returned.
+func (e *Engine) Analyze(ctx context.Context, fen string, depth, multiPV int) ([]Line, error) {
+	if multiPV < 1 {
+		multiPV = 1
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if multiPV != e.multiPV {
+		if err := e.send(fmt.Sprintf("setoption name MultiPV value %d", multiPV)); err != nil {
+			return nil, err
+		}
+		e.multiPV = multiPV
+	}
+
+	if err := e.send("isready"); err != nil {
+		return nil, err
+	}
+	if err := e.waitFor("readyok"); err != nil {
+		return nil, err
+	}
+
+	if err := e.send("position fen " + fen); err != nil {
+		return nil, err
+	}
+	if err := e.send(fmt.Sprintf("go depth %d", depth)); err != nil {
+		return nil, err
+	}
+
+	// stop the search if the context is done, the engine still sends the bestmove
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			e.send("stop")
+		case <-done:
+		}
+	}()
+
+	lines := make([]Line, multiPV)
+	for {
+		l, err := e.stdout.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		l = strings.TrimSpace(l)
+
+		if strings.HasPrefix(l, "bestmove") {
+			break
+		}
+
+		if info, ok := parseInfo(l); ok && info.MultiPV <= multiPV && info.Depth >= lines[info.MultiPV-1].Depth {
+			lines[info.MultiPV-1] = info
+		}
+	}
+
+	res := []Line{}
+	for _, l := range lines {
+		if len(l.PV) > 0 {
+			res = append(res, l)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("engine returned no lines")
+	}
+
+	return res, ctx.Err()
+}
+
+func (e *Engine) Close() error {
+	e.send("quit")
+	e.stdin.Close()
+	return e.cmd.Wait()
+}
+
+func (e *Engine) send(cmd string) error {
+	_, err := e.stdin.Write([]byte(cmd + "\n"))
+	return err
+}
+
+func (e *Engine) waitFor(expected string) error {
+	for {
+		line, err := e.stdout.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), expected) {
+			return nil
+		}
+	}
+}
+
+// Line is a principal variation found by the engine.
+type Line struct {
+	MultiPV int
+	Depth   int
+	Score   Score
+	// moves in UCI notation (e.g. e2e4)
+	PV []string
+}
+
+// Score of a position in centipawns or moves to mate, positive is good for the side to move.
+type Score struct {
+	CP   int
+	Mate int
+}
+
+// IsMate returns true if the score is a forced mate.
+func (s Score) IsMate() bool {
+	return s.Mate != 0
+}
+
+// Negate returns the score from the other side point of view.
+func (s Score) Negate() Score {
+	return Score{CP: -s.CP, Mate: -s.Mate}
+}
+
+// parseInfo parses an info line like:
+// info depth 20 seldepth 27 multipv 1 score cp 31 nodes 1234 nps 5678 time 100 pv e2e4 e7e5
+//
+// bound scores (lowerbound/upperbound) are ignored.
+func parseInfo(l string) (Line, bool) {
+	fields := strings.Fields(l)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Line{}, false
+	}
+
+	info := Line{MultiPV: 1}
+	hasScore := false
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			if i+1 < len(fields) {
+				info.Depth, _ = strconv.Atoi(fields[i+1])
+				i++
+			}
+		case "multipv":
+			if i+1 < len(fields) {
+				info.MultiPV, _ = strconv.Atoi(fields[i+1])
+				i++
+			}
+		case "score":
+			if i+2 >= len(fields) {
+				return Line{}, false
+			}
+			v, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				return Line{}, false
+			}
+			switch fields[i+1] {
+			case "cp":
+				info.Score.CP = v
+			case "mate":
+				info.Score.Mate = v
+			default:
+				return Line{}, false
+			}
+			hasScore = true
+			i += 2
+		case "lowerbound", "upperbound":
+			return Line{}, false
+		case "pv":
+			info.PV = fields[i+1:]
+			i = len(fields)
+		}
+	}
+
+	if !hasScore || len(info.PV) == 0 || info.MultiPV < 1 {
+		return Line{}, false
+	}
+
+	return info, true
+}
@@ -0,0 +1,95 @@
+// Package zobrist implements Zobrist hashing of chess positions.
+//
+// The random keys are generated from a fixed seed, so hashes are stable between
+// processes and releases and can be stored.
+package zobrist
+
+import (
+	"math/rand/v2"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	seed1 = 0x5348414842524431 // "SHAHBRD1"
+	seed2 = 0x7a6f6272697374   // "zobrist"
+)
+
+var (
+	// pieces[piece][square], indexes of pieces are the chess.Piece values
+	pieces    [13][64]uint64
+	blackTurn uint64
+	// castling rights in the order of white king side, white queen side, black king side, black queen side
+	castling [4]uint64
+	// en passant file
+	enPassant [8]uint64
+)
+
+func init() {
+	r := rand.New(rand.NewPCG(seed1, seed2))
+	for p := range pieces {
+		for sq := range pieces[p] {
+			pieces[p][sq] = r.Uint64()
+		}
+	}
+	blackTurn = r.Uint64()
+	for i := range castling {
+		castling[i] = r.Uint64()
+	}
+	for i := range enPassant {
+		enPassant[i] = r.Uint64()
+	}
+}
+
+// Hash returns the Zobrist hash of the position, the move counters are not part of the hash.
+func Hash(pos *chess.Position) uint64 {
+	var h uint64
+	for sq, p := range pos.Board().SquareMap() {
+		h ^= pieces[p][sq]
+	}
+
+	if pos.Turn() == chess.Black {
+		h ^= blackTurn
+	}
+
+	cr := pos.CastleRights()
+	if cr.CanCastle(chess.White, chess.KingSide) {
+		h ^= castling[0]
+	}
+	if cr.CanCastle(chess.White, chess.QueenSide) {
+		h ^= castling[1]
+	}
+	if cr.CanCastle(chess.Black, chess.KingSide) {
+		h ^= castling[2]
+	}
+	if cr.CanCastle(chess.Black, chess.QueenSide) {
+		h ^= castling[3]
+	}
+
+	if sq := pos.EnPassantSquare(); sq != chess.NoSquare && canCaptureEnPassant(pos, sq) {
+		h ^= enPassant[sq.File()]
+	}
+
+	return h
+}
+
+// canCaptureEnPassant reports whether a pawn of the side to move is next to the pawn that just moved two squares.
+// The en passant square is set after every two squares pawn move, but it only changes the position
+// if it can be captured, otherwise transpositions get different hashes.
+func canCaptureEnPassant(pos *chess.Position, sq chess.Square) bool {
+	pawn, rank := chess.WhitePawn, chess.Rank5
+	if pos.Turn() == chess.Black {
+		pawn, rank = chess.BlackPawn, chess.Rank4
+	}
+
+	b := pos.Board()
+	f := sq.File()
+	if f > chess.FileA && b.Piece(chess.NewSquare(f-1, rank)) == pawn {
+		return true
+	}
+	if f < chess.FileH && b.Piece(chess.NewSquare(f+1, rank)) == pawn {
+		return true
+	}
+
+	return false
+}
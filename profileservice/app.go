@@ -1,6 +1,8 @@
 package profileservice
 
 import (
+	"context"
+
 	"github.com/alikarimi999/shahboard/event/kafka"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/pkg/log"
@@ -9,22 +11,25 @@ import (
 	"github.com/alikarimi999/shahboard/profileservice/delivery/http"
 	"github.com/alikarimi999/shahboard/profileservice/repository"
 	"github.com/alikarimi999/shahboard/profileservice/service/rating"
+	"github.com/alikarimi999/shahboard/profileservice/service/relation"
 	"github.com/alikarimi999/shahboard/profileservice/service/user"
+	"github.com/redis/go-redis/v9"
 )
 
 type application struct {
-	rating *rating.Service
-	user   *user.Service
-	http   *http.Handler
-	grpc   *grpc.Server
-	l      log.Logger
+	rating   *rating.Service
+	user     *user.Service
+	relation *relation.Service
+	http     *http.Handler
+	grpc     *grpc.Server
+	l        log.Logger
 }
 
 func SetupApplication(cfg Config) (*application, error) {
 
 	l := log.NewLogger(cfg.Log.File, cfg.Log.Verbose)
 
-	_, s, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
+	p, s, err := kafka.NewKafkaPublisherAndSubscriber(cfg.Kafka, l)
 	if err != nil {
 		return nil, err
 	}
@@ -41,16 +46,29 @@ func SetupApplication(cfg Config) (*application, error) {
 
 	userRepo := repository.NewUserRepo(userDB)
 	ratingRepo := repository.NewRatingRepo(ratingDB, l)
+	relationRepo := repository.NewRelationRepo(userDB, l)
+
+	rc := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
 
 	ratingService := rating.NewService(cfg.Rating, ratingRepo, s, l)
 	userService := user.NewService(cfg.User, userRepo, s, ratingService, l)
+	relationService := relation.NewService(cfg.Relation, relationRepo, userRepo, p, s, rc, l)
+
+	if err := relationService.SeedBlocks(context.Background()); err != nil {
+		return nil, err
+	}
 
 	v, err := jwt.NewValidator(cfg.JwtValidator)
 	if err != nil {
 		return nil, err
 	}
+	v.WithDenylist(jwt.NewRedisDenylist(rc))
 
-	h, err := http.NewHandler(cfg.Http, userService, ratingService, v, l)
+	h, err := http.NewHandler(cfg.Http, userService, ratingService, relationService, v, l)
 	if err != nil {
 		return nil, err
 	}
@@ -61,10 +79,11 @@ func SetupApplication(cfg Config) (*application, error) {
 	}
 
 	return &application{
-		rating: ratingService,
-		user:   userService,
-		http:   h,
-		grpc:   grpcServer,
+		rating:   ratingService,
+		user:     userService,
+		relation: relationService,
+		http:     h,
+		grpc:     grpcServer,
 	}, nil
 }
 
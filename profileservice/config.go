@@ -7,12 +7,14 @@ import (
 	"github.com/alikarimi999/shahboard/pkg/router"
 	"github.com/alikarimi999/shahboard/profileservice/delivery/grpc"
 	"github.com/alikarimi999/shahboard/profileservice/service/rating"
+	"github.com/alikarimi999/shahboard/profileservice/service/relation"
 	"github.com/alikarimi999/shahboard/profileservice/service/user"
 )
 
 type Config struct {
 	User         user.Config         `json:"user_service"`
 	Rating       rating.Config       `json:"rating_service"`
+	Relation     relation.Config     `json:"relation_service"`
 	Kafka        kafka.Config        `json:"kafka"`
 	Log          LogConfig           `json:"log"`
 	JwtValidator jwt.ValidatorConfig `json:"jwt_validator"`
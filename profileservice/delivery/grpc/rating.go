@@ -3,21 +3,25 @@ package grpc
 import (
 	"context"
 
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	pb "github.com/alikarimi999/shahboard/proto/rating/ratingpb"
 	"github.com/alikarimi999/shahboard/types"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (s *Server) GetUserRating(ctx context.Context, req *pb.GetUserRatingRequest) (*pb.GetUserRatingResponse, error) {
 	userId, err := types.ParseObjectId(req.UserId)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid user id")
+		return nil, errs.GRPC(errs.CodeInvalidInput, "invalid user id")
 	}
 
-	rating, err := s.rating.GetUserRating(ctx, userId)
+	variant, err := types.ParseVariant(req.Variant)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get user rating")
+		return nil, errs.GRPC(errs.CodeInvalidInput, "invalid variant")
+	}
+
+	rating, err := s.rating.GetUserRating(ctx, userId, variant)
+	if err != nil {
+		return nil, errs.GRPC(errs.CodeInternalError, "failed to get user rating")
 	}
 
 	return &pb.GetUserRatingResponse{
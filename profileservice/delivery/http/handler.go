@@ -6,17 +6,19 @@ import (
 	"github.com/alikarimi999/shahboard/pkg/middleware"
 	"github.com/alikarimi999/shahboard/pkg/router"
 	"github.com/alikarimi999/shahboard/profileservice/service/rating"
+	"github.com/alikarimi999/shahboard/profileservice/service/relation"
 	"github.com/alikarimi999/shahboard/profileservice/service/user"
 )
 
 type Handler struct {
 	*router.Router
-	user   *user.Service
-	rating *rating.Service
-	l      log.Logger
+	user     *user.Service
+	rating   *rating.Service
+	relation *relation.Service
+	l        log.Logger
 }
 
-func NewHandler(cfg router.Config, u *user.Service, r *rating.Service,
+func NewHandler(cfg router.Config, u *user.Service, r *rating.Service, rel *relation.Service,
 	v *jwt.Validator, l log.Logger) (*Handler, error) {
 	router, err := router.NewRouter(cfg)
 	if err != nil {
@@ -25,10 +27,11 @@ func NewHandler(cfg router.Config, u *user.Service, r *rating.Service,
 
 	router.Use(middleware.ParsUserHeader(v))
 	h := &Handler{
-		Router: router,
-		user:   u,
-		rating: r,
-		l:      l,
+		Router:   router,
+		user:     u,
+		rating:   r,
+		relation: rel,
+		l:        l,
 	}
 
 	return h, h.setup()
@@ -41,5 +44,6 @@ func (h *Handler) Run() error {
 func (h *Handler) setup() error {
 	h.setupUserRoutes()
 	h.setupRatingRoutes()
+	h.setupRelationRoutes()
 	return nil
 }
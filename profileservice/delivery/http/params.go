@@ -3,13 +3,14 @@ package http
 import "github.com/alikarimi999/shahboard/pkg/paginate"
 
 type UserRatingResponse struct {
-	CurrentScore int64 `json:"current_score"`
-	BestScore    int64 `json:"best_score"`
-	GamesPlayed  int64 `json:"games_played"`
-	GamesWon     int64 `json:"games_won"`
-	GamesLost    int64 `json:"games_lost"`
-	GamesDraw    int64 `json:"games_draw"`
-	LastUpdated  int64 `json:"last_updated"`
+	Variant      string `json:"variant"`
+	CurrentScore int64  `json:"current_score"`
+	BestScore    int64  `json:"best_score"`
+	GamesPlayed  int64  `json:"games_played"`
+	GamesWon     int64  `json:"games_won"`
+	GamesLost    int64  `json:"games_lost"`
+	GamesDraw    int64  `json:"games_draw"`
+	LastUpdated  int64  `json:"last_updated"`
 }
 
 type UserInfoResponse struct {
@@ -35,5 +36,25 @@ type UserGameEloChange struct {
 	OpponentId string `json:"opponent_id"`
 	Change     int64  `json:"change"`
 	Result     string `json:"result"`
+	Variant    string `json:"variant"`
 	Timestamp  int64  `json:"timestamp"`
 }
+
+type RelationResponse struct {
+	UserId     string `json:"user_id"`
+	Following  bool   `json:"following"`
+	FollowedBy bool   `json:"followed_by"`
+	Blocked    bool   `json:"blocked"`
+	BlockedBy  bool   `json:"blocked_by"`
+}
+
+type RelationListResponse struct {
+	paginate.PaginatedResponseBase
+	List []RelatedUser `json:"list"`
+}
+
+type RelatedUser struct {
+	UserId string `json:"user_id"`
+	// Since is the time that the relation is added
+	Since int64 `json:"since"`
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"strconv"
 
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/pkg/paginate"
 	"github.com/alikarimi999/shahboard/types"
 	"github.com/gin-gonic/gin"
@@ -18,17 +19,24 @@ func (h *Handler) getUserRating(c *gin.Context) {
 
 	userId, err := types.ParseObjectId(sid)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID"})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
 		return
 	}
 
-	rating, err := h.rating.GetUserRating(c, userId)
+	variant, err := types.ParseVariant(c.Query("variant"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	rating, err := h.rating.GetUserRating(c, userId, variant)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
 	c.JSON(200, UserRatingResponse{
+		Variant:      rating.Variant.String(),
 		CurrentScore: rating.CurrentScore,
 		BestScore:    rating.BestScore,
 		GamesPlayed:  rating.GamesPlayed,
@@ -43,10 +51,19 @@ func (h *Handler) getUserRatingHistory(c *gin.Context) {
 	sid := c.Param("userId")
 	userId, err := types.ParseObjectId(sid)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID"})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
 		return
 	}
 
+	var variant types.Variant
+	if v, ok := c.GetQuery("variant"); ok {
+		variant, err = types.ParseVariant(v)
+		if err != nil {
+			c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+			return
+		}
+	}
+
 	p := &paginate.Paginated{
 		Filters:     make(map[paginate.FilterParameter]paginate.Filter),
 		Decscending: true,
@@ -69,12 +86,12 @@ func (h *Handler) getUserRatingHistory(c *gin.Context) {
 	}
 
 	if err := p.Validate(); err != nil {
-		c.JSON(400, gin.H{"error": "invalid pagination parameters"})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid pagination parameters"))
 	}
 
-	history, total, err := h.rating.GetUserChangeHistory(c, userId, p)
+	history, total, err := h.rating.GetUserChangeHistory(c, userId, variant, p)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
@@ -95,6 +112,7 @@ func (h *Handler) getUserRatingHistory(c *gin.Context) {
 			OpponentId: change.OpponentId.String(),
 			Change:     change.EloChange,
 			Result:     change.Result.String(),
+			Variant:    change.Variant.String(),
 			Timestamp:  change.UpdatedAt.Unix(),
 		})
 	}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/profileservice/service/relation"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) setupRelationRoutes() {
+	u := h.Group("/users")
+	u.GET("/:userId/followers", h.getFollowers)
+	u.GET("/:userId/following", h.getFollowing)
+	u.GET("/:userId/friends", h.getFriends)
+	u.GET("/:userId/relation", h.getRelation)
+	u.POST("/:userId/follow", h.follow)
+	u.DELETE("/:userId/follow", h.unfollow)
+	u.POST("/:userId/block", h.block)
+	u.DELETE("/:userId/block", h.unblock)
+
+	h.Group("/blocks").GET("/", h.getBlocked)
+}
+
+func (h *Handler) follow(c *gin.Context) {
+	h.updateRelation(c, h.relation.Follow, "User followed successfully")
+}
+
+func (h *Handler) unfollow(c *gin.Context) {
+	h.updateRelation(c, h.relation.Unfollow, "User unfollowed successfully")
+}
+
+func (h *Handler) block(c *gin.Context) {
+	h.updateRelation(c, h.relation.Block, "User blocked successfully")
+}
+
+func (h *Handler) unblock(c *gin.Context) {
+	h.updateRelation(c, h.relation.Unblock, "User unblocked successfully")
+}
+
+func (h *Handler) updateRelation(c *gin.Context, update func(ctx context.Context, userId, targetId types.ObjectId) error,
+	msg string) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	targetId, err := types.ParseObjectId(c.Param("userId"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	if err := update(c, usr.ID, targetId); err != nil {
+		c.JSON(errs.HTTP(relationErrCode(err), err.Error()))
+		return
+	}
+
+	c.JSON(200, gin.H{"message": msg})
+}
+
+// getRelation returns the relation of the user to the target user.
+func (h *Handler) getRelation(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	targetId, err := types.ParseObjectId(c.Param("userId"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	r, err := h.relation.GetRelation(c, usr.ID, targetId)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	c.JSON(200, RelationResponse{
+		UserId:     targetId.String(),
+		Following:  r.Following,
+		FollowedBy: r.FollowedBy,
+		Blocked:    r.Blocked,
+		BlockedBy:  r.BlockedBy,
+	})
+}
+
+func (h *Handler) getFollowers(c *gin.Context) {
+	userId, p, ok := parseRelationListRequest(c)
+	if !ok {
+		return
+	}
+
+	follows, total, err := h.relation.GetFollowers(c, userId, p)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	res := RelationListResponse{
+		PaginatedResponseBase: paginatedResponse(p, len(follows), total),
+		List:                  make([]RelatedUser, 0, len(follows)),
+	}
+	for _, f := range follows {
+		res.List = append(res.List, RelatedUser{UserId: f.FollowerId.String(), Since: f.CreatedAt.Unix()})
+	}
+	c.JSON(200, res)
+}
+
+func (h *Handler) getFollowing(c *gin.Context) {
+	userId, p, ok := parseRelationListRequest(c)
+	if !ok {
+		return
+	}
+
+	follows, total, err := h.relation.GetFollowing(c, userId, p)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	res := RelationListResponse{
+		PaginatedResponseBase: paginatedResponse(p, len(follows), total),
+		List:                  make([]RelatedUser, 0, len(follows)),
+	}
+	for _, f := range follows {
+		res.List = append(res.List, RelatedUser{UserId: f.FolloweeId.String(), Since: f.CreatedAt.Unix()})
+	}
+	c.JSON(200, res)
+}
+
+func (h *Handler) getFriends(c *gin.Context) {
+	userId, p, ok := parseRelationListRequest(c)
+	if !ok {
+		return
+	}
+
+	friends, total, err := h.relation.GetFriends(c, userId, p)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	res := RelationListResponse{
+		PaginatedResponseBase: paginatedResponse(p, len(friends), total),
+		List:                  make([]RelatedUser, 0, len(friends)),
+	}
+	for _, f := range friends {
+		res.List = append(res.List, RelatedUser{UserId: f.FriendId.String(), Since: f.CreatedAt.Unix()})
+	}
+	c.JSON(200, res)
+}
+
+// getBlocked returns the users that the user blocked.
+func (h *Handler) getBlocked(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	p := parsePagination(c)
+	blocks, total, err := h.relation.GetBlocked(c, usr.ID, p)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	res := RelationListResponse{
+		PaginatedResponseBase: paginatedResponse(p, len(blocks), total),
+		List:                  make([]RelatedUser, 0, len(blocks)),
+	}
+	for _, b := range blocks {
+		res.List = append(res.List, RelatedUser{UserId: b.BlockedId.String(), Since: b.CreatedAt.Unix()})
+	}
+	c.JSON(200, res)
+}
+
+func parseRelationListRequest(c *gin.Context) (types.ObjectId, *paginate.Paginated, bool) {
+	userId, err := types.ParseObjectId(c.Param("userId"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
+		return types.ObjectZero, nil, false
+	}
+
+	return userId, parsePagination(c), true
+}
+
+// parsePagination parses the page and limit queries, the newest items are listed first.
+func parsePagination(c *gin.Context) *paginate.Paginated {
+	p := &paginate.Paginated{
+		Filters:     make(map[paginate.FilterParameter]paginate.Filter),
+		Decscending: true,
+	}
+
+	if ls, ok := c.GetQuery("limit"); ok {
+		if li, err := strconv.Atoi(ls); err == nil && li > 0 {
+			p.PerPage = uint64(li)
+		}
+	}
+
+	if ps, ok := c.GetQuery("page"); ok {
+		if pi, err := strconv.Atoi(ps); err == nil && pi > 0 {
+			p.Page = uint64(pi)
+		}
+	}
+
+	p.Validate()
+	return p
+}
+
+func paginatedResponse(p *paginate.Paginated, size int, total uint64) paginate.PaginatedResponseBase {
+	return paginate.PaginatedResponseBase{
+		CurrentPage:  p.Page,
+		PageSize:     uint64(size),
+		TotalNumbers: total,
+		TotalPages:   (total + p.PerPage - 1) / p.PerPage,
+	}
+}
+
+func relationErrCode(err error) string {
+	switch {
+	case errors.Is(err, relation.ErrSelfRelation):
+		return errs.CodeInvalidInput
+	case errors.Is(err, relation.ErrUserNotFound):
+		return errs.CodeNotFound
+	case errors.Is(err, relation.ErrUserBlocked):
+		return errs.CodePermissionDenied
+	default:
+		return errs.CodeInternalError
+	}
+}
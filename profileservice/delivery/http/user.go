@@ -2,6 +2,7 @@ package http
 
 import (
 	"github.com/alikarimi999/shahboard/pkg/elo"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/pkg/middleware"
 	"github.com/alikarimi999/shahboard/profileservice/service/user"
 	"github.com/alikarimi999/shahboard/types"
@@ -18,18 +19,18 @@ func (h *Handler) getUserInfo(c *gin.Context) {
 	sid := c.Param("userId")
 	userId, err := types.ParseObjectId(sid)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid user ID"})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
 		return
 	}
 
 	u, r, err := h.user.GetUserInfo(c, userId)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
 	if u == nil {
-		c.JSON(404, gin.H{"error": "User not found"})
+		c.JSON(errs.HTTP(errs.CodeNotFound, "User not found"))
 		return
 	}
 
@@ -56,18 +57,18 @@ func (h *Handler) getUserInfo(c *gin.Context) {
 func (h *Handler) updateUserInfo(c *gin.Context) {
 	usr, ok := middleware.ExtractUser(c)
 	if !ok {
-		c.JSON(401, gin.H{"error": "Unauthorized"})
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
 		return
 	}
 
 	var req user.UpdateUserRequest
 	if err := c.Bind(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
 		return
 	}
 
 	if err := h.user.UpdateUser(c, usr, req); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
 		return
 	}
 
@@ -8,6 +8,7 @@ import (
 
 type Rating struct {
 	UserId       types.ObjectId
+	Variant      types.Variant
 	CurrentScore int64
 	BestScore    int64
 	GamesPlayed  int64
@@ -43,5 +44,16 @@ type GameEloChange struct {
 	GameId     types.ObjectId
 	OpponentId types.ObjectId
 	Result     GameResult
+	Variant    types.Variant
 	UpdatedAt  time.Time
 }
+
+// RatingRefund gives back the rating that the user lost in a game against a cheater.
+type RatingRefund struct {
+	UserId    types.ObjectId
+	GameId    types.ObjectId
+	CheaterId types.ObjectId
+	Variant   types.Variant
+	Amount    int64
+	CreatedAt time.Time
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type Follow struct {
+	FollowerId types.ObjectId
+	FolloweeId types.ObjectId
+	CreatedAt  time.Time
+}
+
+// Friend is a user that follows the user back, CreatedAt is the time that the second follow is added.
+type Friend struct {
+	UserId    types.ObjectId
+	FriendId  types.ObjectId
+	CreatedAt time.Time
+}
+
+type Block struct {
+	BlockerId types.ObjectId
+	BlockedId types.ObjectId
+	CreatedAt time.Time
+}
+
+// Relation is the relation of a user to another user.
+type Relation struct {
+	Following  bool
+	FollowedBy bool
+	Blocked    bool
+	BlockedBy  bool
+}
@@ -24,11 +24,11 @@ func NewRatingRepo(db *sql.DB, l log.Logger) *ratingRepo {
 	}
 }
 
-func (r *ratingRepo) GetByUserId(ctx context.Context, id types.ObjectId) (*entity.Rating, error) {
-	query := "SELECT user_id, current_score, best_score, games_played, games_won, games_lost, games_draw, last_updated FROM ratings WHERE user_id = $1"
-	row := r.db.QueryRowContext(ctx, query, id)
+func (r *ratingRepo) GetByUserId(ctx context.Context, id types.ObjectId, variant types.Variant) (*entity.Rating, error) {
+	query := "SELECT user_id, variant, current_score, best_score, games_played, games_won, games_lost, games_draw, last_updated FROM ratings WHERE user_id = $1 AND variant = $2"
+	row := r.db.QueryRowContext(ctx, query, id, variant.String())
 	var rating entity.Rating
-	err := row.Scan(&rating.UserId, &rating.CurrentScore, &rating.BestScore, &rating.GamesPlayed,
+	err := row.Scan(&rating.UserId, &rating.Variant, &rating.CurrentScore, &rating.BestScore, &rating.GamesPlayed,
 		&rating.GamesWon, &rating.GamesLost, &rating.GamesDraw, &rating.LastUpdated)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -48,8 +48,8 @@ func (r *ratingRepo) Update(ctx context.Context, ratings []*entity.Rating, chang
 	// Update ratings table
 	for _, r := range ratings {
 		query := `
-            INSERT INTO ratings (user_id, current_score, best_score, games_played, games_won, games_lost, games_draw, last_updated)
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (user_id)
+            INSERT INTO ratings (user_id, current_score, best_score, games_played, games_won, games_lost, games_draw, last_updated, variant)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (user_id, variant)
 				DO UPDATE SET 
 				current_score = EXCLUDED.current_score,
 				best_score = EXCLUDED.best_score,
@@ -60,7 +60,7 @@ func (r *ratingRepo) Update(ctx context.Context, ratings []*entity.Rating, chang
 				last_updated = EXCLUDED.last_updated
         `
 		_, err := tx.ExecContext(ctx, query, r.UserId, r.CurrentScore, r.BestScore, r.GamesPlayed,
-			r.GamesWon, r.GamesLost, r.GamesDraw, r.LastUpdated)
+			r.GamesWon, r.GamesLost, r.GamesDraw, r.LastUpdated, r.Variant.String())
 		if err != nil {
 			return fmt.Errorf("failed to update rating for user %s: %w", r.UserId, err)
 		}
@@ -69,11 +69,11 @@ func (r *ratingRepo) Update(ctx context.Context, ratings []*entity.Rating, chang
 	// Insert game Elo changes into the game_elo_changes table
 	for _, c := range changes {
 		query := `
-            INSERT INTO game_elo_changes (user_id, game_id, opponent_id, elo_change, result, updated_at)
-            VALUES ($1, $2, $3, $4, $5, $6)
+            INSERT INTO game_elo_changes (user_id, game_id, opponent_id, elo_change, result, updated_at, variant)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
         `
 		_, err := tx.ExecContext(ctx, query, c.UserId.String(), c.GameId.String(), c.OpponentId.String(),
-			c.EloChange, c.Result, c.UpdatedAt)
+			c.EloChange, c.Result, c.UpdatedAt, c.Variant.String())
 		if err != nil {
 			return fmt.Errorf("failed to insert Elo change for game %s: %w", c.GameId, err)
 		}
@@ -86,8 +86,62 @@ func (r *ratingRepo) Update(ctx context.Context, ratings []*entity.Rating, chang
 	return nil
 }
 
+func (r *ratingRepo) GetGameEloChange(ctx context.Context, gameId, userId types.ObjectId) (*entity.GameEloChange, error) {
+	query := "SELECT id, user_id, game_id, opponent_id, elo_change, result, variant, updated_at FROM game_elo_changes WHERE game_id = $1 AND user_id = $2"
+	var c entity.GameEloChange
+	err := r.db.QueryRowContext(ctx, query, gameId.String(), userId.String()).Scan(&c.Id, &c.UserId, &c.GameId,
+		&c.OpponentId, &c.EloChange, &c.Result, &c.Variant, &c.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Refund adds the amount to the user's rating, it returns false if the game is already refunded for the user.
+func (r *ratingRepo) Refund(ctx context.Context, refund *entity.RatingRefund) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO rating_refunds (user_id, game_id, cheater_id, variant, amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (user_id, game_id) DO NOTHING
+	`
+	res, err := tx.ExecContext(ctx, query, refund.UserId.String(), refund.GameId.String(), refund.CheaterId.String(),
+		refund.Variant.String(), refund.Amount, refund.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert refund of game %s: %w", refund.GameId, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	query = `
+		UPDATE ratings SET
+		current_score = current_score + $1,
+		best_score = GREATEST(best_score, current_score + $1),
+		last_updated = $2
+		WHERE user_id = $3 AND variant = $4
+	`
+	_, err = tx.ExecContext(ctx, query, refund.Amount, refund.CreatedAt, refund.UserId.String(), refund.Variant.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to refund rating for user %s: %w", refund.UserId, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
 func (r *ratingRepo) GetGameEloChangesByUserId(ctx context.Context, userId types.ObjectId) ([]*entity.GameEloChange, error) {
-	query := "SELECT id, user_id, game_id, opponent_id, elo_change, result, updated_at FROM game_elo_changes WHERE user_id = $1"
+	query := "SELECT id, user_id, game_id, opponent_id, elo_change, result, variant, updated_at FROM game_elo_changes WHERE user_id = $1"
 	rows, err := r.db.QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, err
@@ -96,7 +150,7 @@ func (r *ratingRepo) GetGameEloChangesByUserId(ctx context.Context, userId types
 	var changes []*entity.GameEloChange
 	for rows.Next() {
 		var c entity.GameEloChange
-		err := rows.Scan(&c.Id, &c.UserId, &c.GameId, &c.OpponentId, &c.EloChange, &c.Result, &c.UpdatedAt)
+		err := rows.Scan(&c.Id, &c.UserId, &c.GameId, &c.OpponentId, &c.EloChange, &c.Result, &c.Variant, &c.UpdatedAt)
 		if err != nil {
 			r.l.Error(fmt.Sprintf("failed to scan row: %v", err))
 			continue
@@ -126,7 +180,7 @@ func (r *ratingRepo) GetGameEloChanges(c context.Context, p *paginate.Paginated)
 	var changes []*entity.GameEloChange
 	for rows.Next() {
 		var c entity.GameEloChange
-		err := rows.Scan(&c.Id, &c.UserId, &c.GameId, &c.OpponentId, &c.EloChange, &c.UpdatedAt, &c.Result)
+		err := rows.Scan(&c.Id, &c.UserId, &c.GameId, &c.OpponentId, &c.EloChange, &c.UpdatedAt, &c.Result, &c.Variant)
 		if err != nil {
 			r.l.Error(fmt.Sprintf("failed to scan row: %v", err))
 			continue
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	pagesql "github.com/alikarimi999/shahboard/pkg/paginate/sql"
+	"github.com/alikarimi999/shahboard/profileservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type relationRepo struct {
+	db *sql.DB
+	l  log.Logger
+}
+
+func NewRelationRepo(db *sql.DB, l log.Logger) *relationRepo {
+	return &relationRepo{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *relationRepo) Follow(ctx context.Context, f *entity.Follow) (bool, error) {
+	query := `INSERT INTO follows (follower_id, followee_id, created_at) VALUES ($1, $2, $3)
+	ON CONFLICT (follower_id, followee_id) DO NOTHING`
+	res, err := r.db.ExecContext(ctx, query, f.FollowerId.String(), f.FolloweeId.String(), f.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to add follow of user %s by %s: %w", f.FolloweeId, f.FollowerId, err)
+	}
+	return affected(res)
+}
+
+func (r *relationRepo) Unfollow(ctx context.Context, followerId, followeeId types.ObjectId) (bool, error) {
+	query := "DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2"
+	res, err := r.db.ExecContext(ctx, query, followerId.String(), followeeId.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to remove follow of user %s by %s: %w", followeeId, followerId, err)
+	}
+	return affected(res)
+}
+
+func (r *relationRepo) Block(ctx context.Context, b *entity.Block) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO blocks (blocker_id, blocked_id, created_at) VALUES ($1, $2, $3)
+	ON CONFLICT (blocker_id, blocked_id) DO NOTHING`
+	res, err := tx.ExecContext(ctx, query, b.BlockerId.String(), b.BlockedId.String(), b.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to add block of user %s by %s: %w", b.BlockedId, b.BlockerId, err)
+	}
+	if ok, err := affected(res); err != nil || !ok {
+		return false, err
+	}
+
+	query = `DELETE FROM follows WHERE (follower_id = $1 AND followee_id = $2) OR (follower_id = $2 AND followee_id = $1)`
+	if _, err := tx.ExecContext(ctx, query, b.BlockerId.String(), b.BlockedId.String()); err != nil {
+		return false, fmt.Errorf("failed to remove follows between users %s and %s: %w", b.BlockerId, b.BlockedId, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *relationRepo) Unblock(ctx context.Context, blockerId, blockedId types.ObjectId) (bool, error) {
+	query := "DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2"
+	res, err := r.db.ExecContext(ctx, query, blockerId.String(), blockedId.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to remove block of user %s by %s: %w", blockedId, blockerId, err)
+	}
+	return affected(res)
+}
+
+// GetAllBlocks returns all blocks between the users, it's used to seed the block registry.
+func (r *relationRepo) GetAllBlocks(ctx context.Context) ([]*entity.Block, error) {
+	query := "SELECT blocker_id, blocked_id, created_at FROM blocks"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var blocks []*entity.Block
+	for rows.Next() {
+		b := &entity.Block{}
+		if err := rows.Scan(&b.BlockerId, &b.BlockedId, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		blocks = append(blocks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+	return blocks, nil
+}
+
+func (r *relationRepo) GetRelation(ctx context.Context, userId, targetId types.ObjectId) (*entity.Relation, error) {
+	query := `SELECT
+		EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2),
+		EXISTS (SELECT 1 FROM follows WHERE follower_id = $2 AND followee_id = $1),
+		EXISTS (SELECT 1 FROM blocks WHERE blocker_id = $1 AND blocked_id = $2),
+		EXISTS (SELECT 1 FROM blocks WHERE blocker_id = $2 AND blocked_id = $1)`
+
+	var rel entity.Relation
+	err := r.db.QueryRowContext(ctx, query, userId.String(), targetId.String()).Scan(&rel.Following, &rel.FollowedBy,
+		&rel.Blocked, &rel.BlockedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relation of users %s and %s: %w", userId, targetId, err)
+	}
+	return &rel, nil
+}
+
+func (r *relationRepo) GetFollows(ctx context.Context, p *paginate.Paginated) ([]*entity.Follow, uint64, error) {
+	var follows []*entity.Follow
+	total, err := r.paginate(ctx, "follows", p, func(rows *sql.Rows) error {
+		var f entity.Follow
+		if err := rows.Scan(&f.FollowerId, &f.FolloweeId, &f.CreatedAt); err != nil {
+			return err
+		}
+		follows = append(follows, &f)
+		return nil
+	})
+	return follows, total, err
+}
+
+func (r *relationRepo) GetFriends(ctx context.Context, p *paginate.Paginated) ([]*entity.Friend, uint64, error) {
+	var friends []*entity.Friend
+	total, err := r.paginate(ctx, "friends", p, func(rows *sql.Rows) error {
+		var f entity.Friend
+		if err := rows.Scan(&f.UserId, &f.FriendId, &f.CreatedAt); err != nil {
+			return err
+		}
+		friends = append(friends, &f)
+		return nil
+	})
+	return friends, total, err
+}
+
+func (r *relationRepo) GetBlocks(ctx context.Context, p *paginate.Paginated) ([]*entity.Block, uint64, error) {
+	var blocks []*entity.Block
+	total, err := r.paginate(ctx, "blocks", p, func(rows *sql.Rows) error {
+		var b entity.Block
+		if err := rows.Scan(&b.BlockerId, &b.BlockedId, &b.CreatedAt); err != nil {
+			return err
+		}
+		blocks = append(blocks, &b)
+		return nil
+	})
+	return blocks, total, err
+}
+
+func (r *relationRepo) GetFollowersId(ctx context.Context, userId, after types.ObjectId, limit int) ([]types.ObjectId, error) {
+	query := "SELECT follower_id FROM follows WHERE followee_id = $1 AND follower_id > $2 ORDER BY follower_id LIMIT $3"
+	rows, err := r.db.QueryContext(ctx, query, userId.String(), after.String(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers of user %s: %w", userId, err)
+	}
+	defer rows.Close()
+
+	var ids []types.ObjectId
+	for rows.Next() {
+		var id types.ObjectId
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// paginate runs the paginated query of the table and scans each row, it returns the total number of the rows.
+func (r *relationRepo) paginate(ctx context.Context, table string, p *paginate.Paginated,
+	scan func(*sql.Rows) error) (uint64, error) {
+	limit := p.PerPage
+	offset := (p.Page - 1) * limit
+
+	q, cq, args := pagesql.WriteQuery(table, p.Filters, p.SortColumn, p.Decscending, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			r.l.Error(fmt.Sprintf("failed to scan row: %v", err))
+			continue
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate over rows: %v", err)
+	}
+
+	// the count query has the arguments of the filters without the limit and the offset
+	var total uint64
+	if err := r.db.QueryRowContext(ctx, cq, args[:len(args)-2]...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to execute count query: %v", err)
+	}
+
+	return total, nil
+}
+
+func affected(res sql.Result) (bool, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -46,6 +46,22 @@ func (r *userRepo) Create(ctx context.Context, user *entity.UserInfo) error {
 	return err
 }
 
+// Claim replaces the guest name and email, the name is only replaced if the guest hasn't changed it.
+func (r *userRepo) Claim(ctx context.Context, user *entity.UserInfo) error {
+	query := `INSERT INTO users (id, email, name, avatar_url, bio, country, created_at, last_active_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (id) DO UPDATE SET
+		email = EXCLUDED.email,
+		name = CASE WHEN users.name = 'guest_' || users.id THEN EXCLUDED.name ELSE users.name END,
+		avatar_url = CASE WHEN COALESCE(users.avatar_url, '') = '' THEN EXCLUDED.avatar_url ELSE users.avatar_url END`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.Name,
+		user.AvatarUrl, user.Bio, user.Country, user.CreatedAt, user.LastActiveAt)
+	if err != nil {
+		return fmt.Errorf("failed to claim guest profile %s: %w", user.ID, err)
+	}
+	return nil
+}
+
 func (r *userRepo) Update(ctx context.Context, userId types.ObjectId, req user.UpdateUserRequest) error {
 	fields := []string{}
 	args := []interface{}{}
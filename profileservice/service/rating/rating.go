@@ -15,12 +15,17 @@ import (
 
 type Repository interface {
 	// return nil if not found
-	GetByUserId(ctx context.Context, id types.ObjectId) (*entity.Rating, error)
+	GetByUserId(ctx context.Context, id types.ObjectId, variant types.Variant) (*entity.Rating, error)
 	// update ratings and game elo changes atomically
 	Update(ctx context.Context, ratings []*entity.Rating, changes []*entity.GameEloChange) error
 
 	GetGameEloChangesByUserId(ctx context.Context, userId types.ObjectId) ([]*entity.GameEloChange, error)
 
+	// return nil if not found
+	GetGameEloChange(ctx context.Context, gameId, userId types.ObjectId) (*entity.GameEloChange, error)
+	// returns false if the game is already refunded for the user
+	Refund(context.Context, *entity.RatingRefund) (bool, error)
+
 	GetGameEloChanges(context.Context, *paginate.Paginated) ([]*entity.GameEloChange, uint64, error)
 }
 
@@ -46,13 +51,16 @@ func NewService(cfg Config, repo Repository, sub event.Subscriber, l log.Logger)
 
 	s.sm = event.NewManager(l, s.handleEvent)
 	s.sm.AddSubscription(s.sub.Subscribe(event.TopicGame))
+	s.sm.AddSubscription(s.sub.Subscribe(event.TopicFairPlayRatingRefund))
 
 	return s
 }
 
-// If user not found, create a new rating for the user with base rating
-func (s *Service) GetUserRating(ctx context.Context, userId types.ObjectId) (*entity.Rating, error) {
-	r, err := s.repo.GetByUserId(ctx, userId)
+// If user not found, create a new rating for the user with base rating.
+// Each variant has a separate rating.
+func (s *Service) GetUserRating(ctx context.Context, userId types.ObjectId, variant types.Variant) (*entity.Rating, error) {
+	variant = variant.Normalize()
+	r, err := s.repo.GetByUserId(ctx, userId, variant)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +68,7 @@ func (s *Service) GetUserRating(ctx context.Context, userId types.ObjectId) (*en
 	if r == nil {
 		r = &entity.Rating{
 			UserId:       userId,
+			Variant:      variant,
 			CurrentScore: elo.BaseScore,
 		}
 	}
@@ -67,13 +76,21 @@ func (s *Service) GetUserRating(ctx context.Context, userId types.ObjectId) (*en
 	return r, nil
 }
 
-func (s *Service) GetUserChangeHistory(ctx context.Context, userId types.ObjectId,
+// GetUserChangeHistory returns the user's rating changes, if variant is empty changes of all variants are returned.
+func (s *Service) GetUserChangeHistory(ctx context.Context, userId types.ObjectId, variant types.Variant,
 	p *paginate.Paginated) ([]*entity.GameEloChange, uint64, error) {
 	p.Filters["user_id"] = paginate.Filter{
 		Operator: paginate.FilterOperatorEqual,
 		Values:   []interface{}{userId},
 	}
 
+	if variant != "" {
+		p.Filters["variant"] = paginate.Filter{
+			Operator: paginate.FilterOperatorEqual,
+			Values:   []interface{}{variant.String()},
+		}
+	}
+
 	return s.repo.GetGameEloChanges(ctx, p)
 }
 
@@ -84,12 +101,63 @@ func (s *Service) handleEvent(e event.Event) {
 		case event.ActionEnded:
 			s.handleGameEnded(e.(*event.EventGameEnded))
 		}
+	case event.DomainFairPlay:
+		switch e.GetTopic().Action() {
+		case event.ActionFairPlayRatingRefund:
+			s.handleRatingRefund(e.(*event.EventFairPlayRatingRefund))
+		}
+	}
+}
+
+// handleRatingRefund gives back the rating that the opponent of the cheater lost in the game.
+func (s *Service) handleRatingRefund(e *event.EventFairPlayRatingRefund) {
+	ctx := context.Background()
+	c, err := s.repo.GetGameEloChange(ctx, e.GameID, e.CheaterID)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if c == nil {
+		s.l.Debug(fmt.Sprintf("game '%s' of '%s' has no rating change to refund", e.GameID, e.CheaterID))
+		return
+	}
+
+	oc, err := s.repo.GetGameEloChange(ctx, e.GameID, c.OpponentId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	if oc == nil || oc.EloChange >= 0 {
+		return
+	}
+
+	ok, err := s.repo.Refund(ctx, &entity.RatingRefund{
+		UserId:    oc.UserId,
+		GameId:    e.GameID,
+		CheaterId: e.CheaterID,
+		Variant:   oc.Variant,
+		Amount:    -oc.EloChange,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+
+	if ok {
+		s.l.Info(fmt.Sprintf("user '%s' refunded %d rating of game '%s'", oc.UserId, -oc.EloChange, e.GameID))
 	}
 }
 
 func (s *Service) handleGameEnded(e *event.EventGameEnded) {
+	// aborted games have no result
+	if !e.Outcome.IsResult() {
+		return
+	}
+
 	ctx := context.Background()
-	r1, err := s.repo.GetByUserId(ctx, e.Player1.ID)
+	variant := e.Variant.Normalize()
+	r1, err := s.repo.GetByUserId(ctx, e.Player1.ID, variant)
 	if err != nil {
 		// TODO: handle this situation better
 		s.l.Error(err.Error())
@@ -99,11 +167,12 @@ func (s *Service) handleGameEnded(e *event.EventGameEnded) {
 	if r1 == nil {
 		r1 = &entity.Rating{
 			UserId:       e.Player1.ID,
+			Variant:      variant,
 			CurrentScore: elo.BaseScore,
 		}
 	}
 
-	r2, err := s.repo.GetByUserId(ctx, e.Player2.ID)
+	r2, err := s.repo.GetByUserId(ctx, e.Player2.ID, variant)
 	if err != nil {
 		// TODO: handle this situation better
 		s.l.Error(err.Error())
@@ -113,6 +182,7 @@ func (s *Service) handleGameEnded(e *event.EventGameEnded) {
 	if r2 == nil {
 		r2 = &entity.Rating{
 			UserId:       e.Player2.ID,
+			Variant:      variant,
 			CurrentScore: elo.BaseScore,
 		}
 	}
@@ -140,6 +210,7 @@ func (s *Service) handleGameEnded(e *event.EventGameEnded) {
 		GameId:     e.GameID,
 		OpponentId: e.Player2.ID,
 		Result:     result1,
+		Variant:    variant,
 		UpdatedAt:  t,
 	}
 	c2 := &entity.GameEloChange{
@@ -148,6 +219,7 @@ func (s *Service) handleGameEnded(e *event.EventGameEnded) {
 		GameId:     e.GameID,
 		OpponentId: e.Player1.ID,
 		Result:     result2,
+		Variant:    variant,
 		UpdatedAt:  t,
 	}
 
@@ -173,7 +245,7 @@ func (s *Service) handleGameEnded(e *event.EventGameEnded) {
 		return
 	}
 
-	s.l.Debug(fmt.Sprintf("Game '%s' ended, players ratings updated", e.GameID))
+	s.l.Debug(fmt.Sprintf("Game '%s' ended, players '%s' ratings updated", e.GameID, variant))
 }
 
 func calcScore1(o types.GameOutcome, p1Color types.Color) float64 {
@@ -0,0 +1,334 @@
+package relation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/block"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/profileservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultNotifyBatchSize = 500
+
+var (
+	ErrSelfRelation = errors.New("users can't follow or block themselves")
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserBlocked  = errors.New("user is blocked")
+)
+
+type Repository interface {
+	// Follow returns false if the user already follows the followee.
+	Follow(ctx context.Context, f *entity.Follow) (bool, error)
+	// Unfollow returns false if the user doesn't follow the followee.
+	Unfollow(ctx context.Context, followerId, followeeId types.ObjectId) (bool, error)
+
+	// Block adds the block and removes the follows between the users atomically,
+	// it returns false if the user is already blocked.
+	Block(ctx context.Context, b *entity.Block) (bool, error)
+	// Unblock returns false if the user is not blocked.
+	Unblock(ctx context.Context, blockerId, blockedId types.ObjectId) (bool, error)
+
+	GetRelation(ctx context.Context, userId, targetId types.ObjectId) (*entity.Relation, error)
+
+	GetFollows(context.Context, *paginate.Paginated) ([]*entity.Follow, uint64, error)
+	GetFriends(context.Context, *paginate.Paginated) ([]*entity.Friend, uint64, error)
+	GetBlocks(context.Context, *paginate.Paginated) ([]*entity.Block, uint64, error)
+	// GetAllBlocks returns all blocks between the users.
+	GetAllBlocks(ctx context.Context) ([]*entity.Block, error)
+
+	// GetFollowersId returns the followers of the user after the follower id, ordered by the id.
+	GetFollowersId(ctx context.Context, userId, after types.ObjectId, limit int) ([]types.ObjectId, error)
+}
+
+type UserRepository interface {
+	// return nil,nil if user not found
+	GetByID(ctx context.Context, id types.ObjectId) (*entity.UserInfo, error)
+}
+
+type Config struct {
+	// max number of the followers of an EventFollowingGameStarted
+	NotifyBatchSize int `json:"notify_batch_size"`
+}
+
+type Service struct {
+	cfg   Config
+	repo  Repository
+	users UserRepository
+	p     event.Publisher
+	rc    *redis.Client
+	sm    *event.SubscriptionManager
+	l     log.Logger
+}
+
+func NewService(cfg Config, repo Repository, users UserRepository, p event.Publisher, sub event.Subscriber,
+	rc *redis.Client, l log.Logger) *Service {
+	if cfg.NotifyBatchSize <= 0 {
+		cfg.NotifyBatchSize = defaultNotifyBatchSize
+	}
+
+	s := &Service{
+		cfg:   cfg,
+		repo:  repo,
+		users: users,
+		p:     p,
+		rc:    rc,
+		l:     l,
+	}
+
+	s.sm = event.NewManager(l, s.handleEvent)
+	s.sm.AddSubscription(sub.Subscribe(event.TopicGameCreated))
+	return s
+}
+
+func (s *Service) Follow(ctx context.Context, userId, targetId types.ObjectId) error {
+	if err := s.checkTarget(ctx, userId, targetId); err != nil {
+		return err
+	}
+
+	r, err := s.repo.GetRelation(ctx, userId, targetId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if r.Blocked || r.BlockedBy {
+		return ErrUserBlocked
+	}
+
+	ok, err := s.repo.Follow(ctx, &entity.Follow{FollowerId: userId, FolloweeId: targetId, CreatedAt: time.Now()})
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	if err := s.p.Publish(event.EventUserFollowed{
+		ID:         types.NewObjectId(),
+		FollowerID: userId,
+		FolloweeID: targetId,
+		Timestamp:  time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	s.l.Debug(fmt.Sprintf("user '%s' followed '%s'", userId, targetId))
+	return nil
+}
+
+func (s *Service) Unfollow(ctx context.Context, userId, targetId types.ObjectId) error {
+	ok, err := s.repo.Unfollow(ctx, userId, targetId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	if err := s.p.Publish(event.EventUserUnfollowed{
+		ID:         types.NewObjectId(),
+		FollowerID: userId,
+		FolloweeID: targetId,
+		Timestamp:  time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	s.l.Debug(fmt.Sprintf("user '%s' unfollowed '%s'", userId, targetId))
+	return nil
+}
+
+// Block blocks the target and removes the follows between the users, the other services
+// get the block by the event and don't let the users challenge, message or be paired with each other.
+func (s *Service) Block(ctx context.Context, userId, targetId types.ObjectId) error {
+	if err := s.checkTarget(ctx, userId, targetId); err != nil {
+		return err
+	}
+
+	// the event is published again if the user is already blocked, so a retry of a request
+	// whose event failed publishes it, the registry handles the event idempotently
+	if _, err := s.repo.Block(ctx, &entity.Block{BlockerId: userId, BlockedId: targetId, CreatedAt: time.Now()}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if err := s.p.Publish(event.EventUserBlocked{
+		ID:        types.NewObjectId(),
+		BlockerID: userId,
+		BlockedID: targetId,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("user '%s' blocked '%s'", userId, targetId))
+	return nil
+}
+
+func (s *Service) Unblock(ctx context.Context, userId, targetId types.ObjectId) error {
+	// like the block, the event is published again if the user is not blocked anymore
+	if _, err := s.repo.Unblock(ctx, userId, targetId); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	if err := s.p.Publish(event.EventUserUnblocked{
+		ID:        types.NewObjectId(),
+		BlockerID: userId,
+		BlockedID: targetId,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Debug(fmt.Sprintf("user '%s' unblocked '%s'", userId, targetId))
+	return nil
+}
+
+// SeedBlocks restores the shared block registry from the database.
+func (s *Service) SeedBlocks(ctx context.Context) error {
+	list, err := s.repo.GetAllBlocks(ctx)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	blocks := make([]block.Entry, 0, len(list))
+	for _, b := range list {
+		blocks = append(blocks, block.Entry{BlockerID: b.BlockerId, BlockedID: b.BlockedId})
+	}
+
+	if err := block.Seed(ctx, s.rc, blocks); err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+
+	s.l.Info(fmt.Sprintf("block registry seeded with %d blocks", len(blocks)))
+	return nil
+}
+
+func (s *Service) GetRelation(ctx context.Context, userId, targetId types.ObjectId) (*entity.Relation, error) {
+	return s.repo.GetRelation(ctx, userId, targetId)
+}
+
+// GetFollowers returns the users that follow the user, the newest first.
+func (s *Service) GetFollowers(ctx context.Context, userId types.ObjectId, p *paginate.Paginated) ([]*entity.Follow, uint64, error) {
+	p.Filters["followee_id"] = paginate.Filter{
+		Operator: paginate.FilterOperatorEqual,
+		Values:   []interface{}{userId.String()},
+	}
+	p.SortColumn = "created_at"
+
+	return s.repo.GetFollows(ctx, p)
+}
+
+// GetFollowing returns the users that the user follows, the newest first.
+func (s *Service) GetFollowing(ctx context.Context, userId types.ObjectId, p *paginate.Paginated) ([]*entity.Follow, uint64, error) {
+	p.Filters["follower_id"] = paginate.Filter{
+		Operator: paginate.FilterOperatorEqual,
+		Values:   []interface{}{userId.String()},
+	}
+	p.SortColumn = "created_at"
+
+	return s.repo.GetFollows(ctx, p)
+}
+
+// GetFriends returns the users that the user and they follow each other.
+func (s *Service) GetFriends(ctx context.Context, userId types.ObjectId, p *paginate.Paginated) ([]*entity.Friend, uint64, error) {
+	p.Filters["user_id"] = paginate.Filter{
+		Operator: paginate.FilterOperatorEqual,
+		Values:   []interface{}{userId.String()},
+	}
+	p.SortColumn = "created_at"
+
+	return s.repo.GetFriends(ctx, p)
+}
+
+// GetBlocked returns the users that the user blocked, they're only visible to the user.
+func (s *Service) GetBlocked(ctx context.Context, userId types.ObjectId, p *paginate.Paginated) ([]*entity.Block, uint64, error) {
+	p.Filters["blocker_id"] = paginate.Filter{
+		Operator: paginate.FilterOperatorEqual,
+		Values:   []interface{}{userId.String()},
+	}
+	p.SortColumn = "created_at"
+
+	return s.repo.GetBlocks(ctx, p)
+}
+
+func (s *Service) checkTarget(ctx context.Context, userId, targetId types.ObjectId) error {
+	if userId == targetId {
+		return ErrSelfRelation
+	}
+
+	u, err := s.users.GetByID(ctx, targetId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return err
+	}
+	if u == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *Service) handleEvent(e event.Event) {
+	switch e := e.(type) {
+	case *event.EventGameCreated:
+		s.notifyFollowers(e, e.Player1.ID, e.Player2.ID)
+		s.notifyFollowers(e, e.Player2.ID, e.Player1.ID)
+	}
+}
+
+// notifyFollowers lets the followers of the player know that the player started the game, so they can spectate it.
+// The opponent is not notified even if they follow the player.
+func (s *Service) notifyFollowers(e *event.EventGameCreated, playerId, opponentId types.ObjectId) {
+	ctx := context.Background()
+
+	after := types.ObjectZero
+	for {
+		ids, err := s.repo.GetFollowersId(ctx, playerId, after, s.cfg.NotifyBatchSize)
+		if err != nil {
+			s.l.Error(err.Error())
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+		after = ids[len(ids)-1]
+
+		followers := make([]types.ObjectId, 0, len(ids))
+		for _, id := range ids {
+			if id != opponentId {
+				followers = append(followers, id)
+			}
+		}
+
+		if len(followers) > 0 {
+			if err := s.p.Publish(event.EventFollowingGameStarted{
+				ID:        types.NewObjectId(),
+				GameID:    e.GameID,
+				PlayerID:  playerId,
+				Variant:   e.Variant,
+				Followers: followers,
+				Timestamp: time.Now().Unix(),
+			}); err != nil {
+				s.l.Error(err.Error())
+				return
+			}
+		}
+
+		if len(ids) < s.cfg.NotifyBatchSize {
+			return
+		}
+	}
+}
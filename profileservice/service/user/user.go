@@ -21,10 +21,14 @@ type Repository interface {
 	// It ensures atomicity using a transaction, committing only after a successful operation.
 	UpdateNX(ctx context.Context, user types.ObjectId, email string, req UpdateUserRequest) error
 	UpdateLastActiveAt(ctx context.Context, id types.ObjectId, lastActiveAt time.Time) error
+
+	// Claim converts the guest profile into the profile of a registered user, the fields
+	// that the guest has already changed are kept. It inserts the profile if it doesn't exist.
+	Claim(ctx context.Context, user *entity.UserInfo) error
 }
 
 type RatingService interface {
-	GetUserRating(ctx context.Context, userId types.ObjectId) (*entity.Rating, error)
+	GetUserRating(ctx context.Context, userId types.ObjectId, variant types.Variant) (*entity.Rating, error)
 }
 
 type Config struct {
@@ -62,7 +66,7 @@ func (s *Service) GetUserInfo(ctx context.Context, id types.ObjectId) (*entity.U
 		return nil, nil, nil
 	}
 
-	r, err := s.rs.GetUserRating(ctx, id)
+	r, err := s.rs.GetUserRating(ctx, id, types.VariantStandard)
 	if err != nil || r == nil {
 		s.l.Error(fmt.Sprintf("failed to get user rating: %v", err))
 		return u, nil, nil
@@ -90,6 +94,8 @@ func (s *Service) handleEvent(e event.Event) {
 			s.handleUserCreated(e.(*event.EventUserCreated))
 		case event.ActionLoggedIn:
 			s.handleUserLoggedIn(e.(*event.EventUserLoggedIn))
+		case event.ActionGuestClaimed:
+			s.handleGuestClaimed(e.(*event.EventUserGuestClaimed))
 		}
 	}
 }
@@ -130,6 +136,26 @@ func wrapUserInfoForGuest(e *event.EventUserCreated) *entity.UserInfo {
 
 }
 
+// handleGuestClaimed converts the guest profile into a full one, the ratings are kept
+// since the registered account has the same id.
+func (s *Service) handleGuestClaimed(e *event.EventUserGuestClaimed) {
+	t := time.Now()
+	u := &entity.UserInfo{
+		ID:           e.UserID,
+		Email:        e.Email,
+		Name:         e.Name,
+		AvatarUrl:    e.Picture,
+		CreatedAt:    t,
+		LastActiveAt: t,
+	}
+
+	if err := s.repo.Claim(context.Background(), u); err != nil {
+		s.l.Error(err.Error())
+		return
+	}
+	s.l.Debug(fmt.Sprintf("guest profile claimed with UID: '%s' Email: '%s'", e.UserID, e.Email))
+}
+
 func (s *Service) handleUserLoggedIn(e *event.EventUserLoggedIn) {
 	if err := s.repo.UpdateLastActiveAt(context.Background(), e.UserID, time.Now()); err != nil {
 		s.l.Error(err.Error())
@@ -22,6 +22,7 @@ const (
 	GameService_GetUserLiveGameID_FullMethodName  = "/game.GameService/GetUserLiveGameID"
 	GameService_GetUserLiveGamePGN_FullMethodName = "/game.GameService/GetUserLiveGamePGN"
 	GameService_GetLiveGamePGN_FullMethodName     = "/game.GameService/GetLiveGamePGN"
+	GameService_GetGamePGN_FullMethodName         = "/game.GameService/GetGamePGN"
 )
 
 // GameServiceClient is the client API for GameService service.
@@ -31,6 +32,7 @@ type GameServiceClient interface {
 	GetUserLiveGameID(ctx context.Context, in *GetUserLiveGameIdRequest, opts ...grpc.CallOption) (*GetUserLiveGameIdResponse, error)
 	GetUserLiveGamePGN(ctx context.Context, in *GetUserLiveGamePgnRequest, opts ...grpc.CallOption) (*GetLiveGamePGNResponse, error)
 	GetLiveGamePGN(ctx context.Context, in *GetLiveGamePGNRequest, opts ...grpc.CallOption) (*GetLiveGamePGNResponse, error)
+	GetGamePGN(ctx context.Context, in *GetGamePGNRequest, opts ...grpc.CallOption) (*GetGamePGNResponse, error)
 }
 
 type gameServiceClient struct {
@@ -71,6 +73,16 @@ func (c *gameServiceClient) GetLiveGamePGN(ctx context.Context, in *GetLiveGameP
 	return out, nil
 }
 
+func (c *gameServiceClient) GetGamePGN(ctx context.Context, in *GetGamePGNRequest, opts ...grpc.CallOption) (*GetGamePGNResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetGamePGNResponse)
+	err := c.cc.Invoke(ctx, GameService_GetGamePGN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GameServiceServer is the server API for GameService service.
 // All implementations must embed UnimplementedGameServiceServer
 // for forward compatibility.
@@ -78,6 +90,7 @@ type GameServiceServer interface {
 	GetUserLiveGameID(context.Context, *GetUserLiveGameIdRequest) (*GetUserLiveGameIdResponse, error)
 	GetUserLiveGamePGN(context.Context, *GetUserLiveGamePgnRequest) (*GetLiveGamePGNResponse, error)
 	GetLiveGamePGN(context.Context, *GetLiveGamePGNRequest) (*GetLiveGamePGNResponse, error)
+	GetGamePGN(context.Context, *GetGamePGNRequest) (*GetGamePGNResponse, error)
 	mustEmbedUnimplementedGameServiceServer()
 }
 
@@ -97,6 +110,9 @@ func (UnimplementedGameServiceServer) GetUserLiveGamePGN(context.Context, *GetUs
 func (UnimplementedGameServiceServer) GetLiveGamePGN(context.Context, *GetLiveGamePGNRequest) (*GetLiveGamePGNResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetLiveGamePGN not implemented")
 }
+func (UnimplementedGameServiceServer) GetGamePGN(context.Context, *GetGamePGNRequest) (*GetGamePGNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGamePGN not implemented")
+}
 func (UnimplementedGameServiceServer) mustEmbedUnimplementedGameServiceServer() {}
 func (UnimplementedGameServiceServer) testEmbeddedByValue()                     {}
 
@@ -172,6 +188,24 @@ func _GameService_GetLiveGamePGN_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GameService_GetGamePGN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGamePGNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GameServiceServer).GetGamePGN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GameService_GetGamePGN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GameServiceServer).GetGamePGN(ctx, req.(*GetGamePGNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GameService_ServiceDesc is the grpc.ServiceDesc for GameService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,6 +225,10 @@ var GameService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetLiveGamePGN",
 			Handler:    _GameService_GetLiveGamePGN_Handler,
 		},
+		{
+			MethodName: "GetGamePGN",
+			Handler:    _GameService_GetGamePGN_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "game.proto",
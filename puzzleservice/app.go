@@ -0,0 +1,79 @@
+package puzzleservice
+
+import (
+	"context"
+	"os"
+
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+	"github.com/alikarimi999/shahboard/puzzleservice/delivery/http"
+	"github.com/alikarimi999/shahboard/puzzleservice/repository"
+	puzzle "github.com/alikarimi999/shahboard/puzzleservice/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type application struct {
+	puzzle *puzzle.Service
+	http   *http.Handler
+}
+
+func SetupApplication(cfg Config) (*application, error) {
+	s, l, err := setupService(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := jwt.NewValidator(cfg.JwtValidator)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	v.WithDenylist(jwt.NewRedisDenylist(rc))
+
+	h, err := http.NewHandler(cfg.Http, s, v, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return &application{
+		puzzle: s,
+		http:   h,
+	}, nil
+}
+
+func (a *application) Run() error {
+	return a.http.Run()
+}
+
+// ImportPuzzles imports the puzzles of a CSV file, see puzzle.Service.Import for the format.
+func ImportPuzzles(cfg Config, file string) (puzzle.ImportResult, error) {
+	s, _, err := setupService(cfg)
+	if err != nil {
+		return puzzle.ImportResult{}, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return puzzle.ImportResult{}, err
+	}
+	defer f.Close()
+
+	return s.Import(context.Background(), f)
+}
+
+func setupService(cfg Config) (*puzzle.Service, log.Logger, error) {
+	l := log.NewLogger(cfg.Log.File, cfg.Log.Verbose)
+
+	db, err := postgres.Setup(cfg.PuzzleDB)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return puzzle.NewService(cfg.Puzzle, repository.NewPuzzleRepo(db, l), l), l, nil
+}
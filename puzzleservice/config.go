@@ -0,0 +1,28 @@
+package puzzleservice
+
+import (
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/postgres"
+	"github.com/alikarimi999/shahboard/pkg/router"
+	puzzle "github.com/alikarimi999/shahboard/puzzleservice/service"
+)
+
+type Config struct {
+	Puzzle       puzzle.Config       `json:"puzzle_service"`
+	Log          LogConfig           `json:"log"`
+	JwtValidator jwt.ValidatorConfig `json:"jwt_validator"`
+	PuzzleDB     postgres.Config     `json:"puzzle_db"`
+	Http         router.Config       `json:"http"`
+	Redis        RedisConfig         `json:"redis"`
+}
+
+type LogConfig struct {
+	File    string `json:"file"`
+	Verbose bool   `json:"verbose"`
+}
+
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"github.com/alikarimi999/shahboard/pkg/jwt"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/pkg/router"
+	puzzle "github.com/alikarimi999/shahboard/puzzleservice/service"
+)
+
+type Handler struct {
+	*router.Router
+	puzzle *puzzle.Service
+	l      log.Logger
+}
+
+func NewHandler(cfg router.Config, p *puzzle.Service, v *jwt.Validator, l log.Logger) (*Handler, error) {
+	router, err := router.NewRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	router.Use(middleware.ParsUserHeader(v))
+	h := &Handler{
+		Router: router,
+		puzzle: p,
+		l:      l,
+	}
+
+	return h, h.setup()
+}
+
+func (h *Handler) Run() error {
+	return h.Router.Run()
+}
+
+func (h *Handler) setup() error {
+	h.setupPuzzleRoutes()
+	return nil
+}
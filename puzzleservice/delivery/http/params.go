@@ -0,0 +1,30 @@
+package http
+
+import "github.com/alikarimi999/shahboard/pkg/paginate"
+
+type MoveRequest struct {
+	// UCI or algebraic notation
+	Move string `json:"move"`
+}
+
+type PuzzleRatingResponse struct {
+	CurrentScore int64 `json:"current_score"`
+	BestScore    int64 `json:"best_score"`
+	Solved       int64 `json:"solved"`
+	Failed       int64 `json:"failed"`
+	Streak       int64 `json:"streak"`
+	BestStreak   int64 `json:"best_streak"`
+	LastUpdated  int64 `json:"last_updated"`
+}
+
+type PuzzleHistoryResponse struct {
+	paginate.PaginatedResponseBase
+	List []PuzzleAttempt `json:"list"`
+}
+
+type PuzzleAttempt struct {
+	PuzzleId     string `json:"puzzle_id"`
+	Status       string `json:"status"`
+	RatingChange int64  `json:"rating_change"`
+	Timestamp    int64  `json:"timestamp"`
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"strconv"
+
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) setupPuzzleRoutes() {
+	p := h.Group("/puzzles")
+	p.GET("/next", h.nextPuzzle)
+	p.POST("/:puzzleId/move", h.move)
+	p.GET("/rating/:userId", h.getUserRating)
+	p.GET("/history/:userId", h.getUserHistory)
+}
+
+func (h *Handler) nextPuzzle(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	res, err := h.puzzle.NextPuzzle(c, usr.ID, c.Query("theme"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) move(c *gin.Context) {
+	usr, ok := middleware.ExtractUser(c)
+	if !ok {
+		c.JSON(errs.HTTP(errs.CodeUnauthorized, "Unauthorized"))
+		return
+	}
+
+	puzzleId, err := types.ParseObjectId(c.Param("puzzleId"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid puzzle ID"))
+		return
+	}
+
+	var req MoveRequest
+	if err := c.Bind(&req); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	res, err := h.puzzle.Move(c, usr.ID, puzzleId, req.Move)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, err.Error()))
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+func (h *Handler) getUserRating(c *gin.Context) {
+	userId, err := types.ParseObjectId(c.Param("userId"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	r, err := h.puzzle.GetUserRating(c, userId)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	c.JSON(200, PuzzleRatingResponse{
+		CurrentScore: r.CurrentScore,
+		BestScore:    r.BestScore,
+		Solved:       r.Solved,
+		Failed:       r.Failed,
+		Streak:       r.Streak,
+		BestStreak:   r.BestStreak,
+		LastUpdated:  r.LastUpdated.Unix(),
+	})
+}
+
+func (h *Handler) getUserHistory(c *gin.Context) {
+	userId, err := types.ParseObjectId(c.Param("userId"))
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "Invalid user ID"))
+		return
+	}
+
+	p := &paginate.Paginated{
+		Filters:     make(map[paginate.FilterParameter]paginate.Filter),
+		Decscending: true,
+	}
+
+	if ls, ok := c.GetQuery("limit"); ok {
+		if li, err := strconv.Atoi(ls); err == nil {
+			p.PerPage = uint64(li)
+		}
+	}
+
+	if ps, ok := c.GetQuery("page"); ok {
+		if pi, err := strconv.Atoi(ps); err == nil {
+			p.Page = uint64(pi)
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		c.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid pagination parameters"))
+		return
+	}
+
+	history, total, err := h.puzzle.GetUserHistory(c, userId, p)
+	if err != nil {
+		c.JSON(errs.HTTP(errs.CodeInternalError, err.Error()))
+		return
+	}
+
+	res := PuzzleHistoryResponse{
+		PaginatedResponseBase: paginate.PaginatedResponseBase{
+			CurrentPage:  p.Page,
+			PageSize:     uint64(len(history)),
+			TotalNumbers: total,
+			TotalPages:   (total + p.PerPage - 1) / p.PerPage,
+		},
+		List: make([]PuzzleAttempt, 0, len(history)),
+	}
+
+	for _, a := range history {
+		res.List = append(res.List, PuzzleAttempt{
+			PuzzleId:     a.PuzzleId.String(),
+			Status:       string(a.Status),
+			RatingChange: a.RatingChange,
+			Timestamp:    a.UpdatedAt.Unix(),
+		})
+	}
+
+	c.JSON(200, res)
+}
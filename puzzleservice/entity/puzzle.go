@@ -0,0 +1,154 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/notnil/chess"
+)
+
+// Puzzle is a position with a single solution line.
+// The first move of the line is played by the side to move in the FEN (the solver),
+// then the moves alternate between the opponent and the solver.
+type Puzzle struct {
+	Id types.ObjectId
+	// id of the puzzle in the imported source, it's used to skip the duplicates
+	SourceId string
+	FEN      string
+	// solution in UCI notation
+	Moves     []string
+	Rating    int64
+	Themes    []string
+	Plays     int64
+	CreatedAt time.Time
+}
+
+// NewPuzzle validates the position and the solution line.
+func NewPuzzle(sourceId, fen string, moves []string, rating int64, themes []string) (*Puzzle, error) {
+	p := &Puzzle{
+		Id:        types.NewObjectId(),
+		SourceId:  sourceId,
+		FEN:       fen,
+		Moves:     moves,
+		Rating:    rating,
+		Themes:    themes,
+		CreatedAt: time.Now(),
+	}
+
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("solution is empty")
+	}
+
+	// every solver move should have an answer, so the line ends with a solver move
+	if len(moves)%2 == 0 {
+		return nil, fmt.Errorf("solution should end with a move of the solver")
+	}
+
+	if _, err := p.Position(len(moves)); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Position returns the position after the given number of moves of the solution.
+func (p *Puzzle) Position(ply int) (*chess.Position, error) {
+	f, err := chess.FEN(p.FEN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fen: %v", err)
+	}
+
+	if ply < 0 || ply > len(p.Moves) {
+		return nil, fmt.Errorf("invalid ply: %d", ply)
+	}
+
+	pos := chess.NewGame(f).Position()
+	for _, s := range p.Moves[:ply] {
+		m, err := findMove(pos, s)
+		if err != nil {
+			return nil, err
+		}
+		pos = pos.Update(m)
+	}
+
+	return pos, nil
+}
+
+// Color returns the color of the solver.
+func (p *Puzzle) Color() types.Color {
+	f, err := chess.FEN(p.FEN)
+	if err != nil || chess.NewGame(f).Position().Turn() == chess.White {
+		return types.ColorWhite
+	}
+	return types.ColorBlack
+}
+
+// CheckMove checks the solver move at the given ply, the move can be in UCI or algebraic notation.
+// Any move that mates is accepted as the last move, puzzles usually have more than one mating move.
+func (p *Puzzle) CheckMove(ply int, move string) (bool, error) {
+	if ply%2 != 0 || ply >= len(p.Moves) {
+		return false, fmt.Errorf("it's not the solver turn")
+	}
+
+	pos, err := p.Position(ply)
+	if err != nil {
+		return false, err
+	}
+
+	m, err := findMove(pos, move)
+	if err != nil {
+		return false, err
+	}
+
+	if m.String() == p.Moves[ply] {
+		return true, nil
+	}
+
+	return ply == len(p.Moves)-1 && pos.Update(m).Status() == chess.Checkmate, nil
+}
+
+// findMove returns the valid move in the position that matches the move in UCI or algebraic notation,
+// check and mate signs of algebraic notation are optional.
+func findMove(pos *chess.Position, s string) (*chess.Move, error) {
+	san := strings.TrimRight(s, "+#")
+	for _, m := range pos.ValidMoves() {
+		if m.String() == s || strings.TrimRight(chess.AlgebraicNotation{}.Encode(pos, m), "+#") == san {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid move: %s", s)
+}
+
+type PuzzleRating struct {
+	UserId       types.ObjectId
+	CurrentScore int64
+	BestScore    int64
+	Solved       int64
+	Failed       int64
+	Streak       int64
+	BestStreak   int64
+	LastUpdated  time.Time
+}
+
+type AttemptStatus string
+
+const (
+	AttemptStatusStarted AttemptStatus = "started"
+	AttemptStatusSolved  AttemptStatus = "solved"
+	AttemptStatusFailed  AttemptStatus = "failed"
+)
+
+// PuzzleAttempt is the first try of a user to solve a puzzle, only the first try is rated.
+type PuzzleAttempt struct {
+	Id       int64
+	UserId   types.ObjectId
+	PuzzleId types.ObjectId
+	Status   AttemptStatus
+	// number of played moves of the solution
+	Ply          int
+	RatingChange int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
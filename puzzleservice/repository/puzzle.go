@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	pagesql "github.com/alikarimi999/shahboard/pkg/paginate/sql"
+	"github.com/alikarimi999/shahboard/puzzleservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/lib/pq"
+)
+
+type puzzleRepo struct {
+	db *sql.DB
+	l  log.Logger
+}
+
+func NewPuzzleRepo(db *sql.DB, l log.Logger) *puzzleRepo {
+	return &puzzleRepo{
+		db: db,
+		l:  l,
+	}
+}
+
+// AddPuzzles inserts the puzzles and skips the ones that are already imported, it returns the number of inserted puzzles.
+func (r *puzzleRepo) AddPuzzles(ctx context.Context, puzzles []*entity.Puzzle) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, p := range puzzles {
+		query := `
+			INSERT INTO puzzles (id, source_id, fen, moves, rating, themes, plays, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (source_id) DO NOTHING
+		`
+		res, err := tx.ExecContext(ctx, query, p.Id.String(), p.SourceId, p.FEN, strings.Join(p.Moves, " "),
+			p.Rating, pq.Array(p.Themes), p.Plays, p.CreatedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert puzzle %s: %w", p.SourceId, err)
+		}
+
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return added, nil
+}
+
+func (r *puzzleRepo) GetPuzzle(ctx context.Context, id types.ObjectId) (*entity.Puzzle, error) {
+	query := "SELECT id, source_id, fen, moves, rating, themes, plays, created_at FROM puzzles WHERE id = $1"
+	p, err := scanPuzzle(r.db.QueryRowContext(ctx, query, id.String()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// FindPuzzle returns a random puzzle with a rating between min and max that the user has not tried yet.
+// If theme is not empty, only the puzzles with that theme are returned. It returns nil if not found.
+func (r *puzzleRepo) FindPuzzle(ctx context.Context, userId types.ObjectId, min, max int64, theme string) (*entity.Puzzle, error) {
+	query := `SELECT id, source_id, fen, moves, rating, themes, plays, created_at FROM puzzles
+		WHERE rating BETWEEN $1 AND $2
+		AND NOT EXISTS (SELECT 1 FROM puzzle_attempts a WHERE a.puzzle_id = puzzles.id AND a.user_id = $3)`
+	args := []interface{}{min, max, userId.String()}
+
+	if theme != "" {
+		args = append(args, theme)
+		query += fmt.Sprintf(" AND $%d = ANY(themes)", len(args))
+	}
+
+	query += " ORDER BY random() LIMIT 1"
+
+	p, err := scanPuzzle(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func scanPuzzle(row *sql.Row) (*entity.Puzzle, error) {
+	var (
+		p     entity.Puzzle
+		moves string
+	)
+
+	err := row.Scan(&p.Id, &p.SourceId, &p.FEN, &moves, &p.Rating, pq.Array(&p.Themes), &p.Plays, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.Moves = strings.Fields(moves)
+
+	return &p, nil
+}
+
+// GetRating returns nil if the user has not solved any puzzle yet.
+func (r *puzzleRepo) GetRating(ctx context.Context, userId types.ObjectId) (*entity.PuzzleRating, error) {
+	query := `SELECT user_id, current_score, best_score, solved, failed, streak, best_streak, last_updated
+		FROM puzzle_ratings WHERE user_id = $1`
+	var pr entity.PuzzleRating
+	err := r.db.QueryRowContext(ctx, query, userId.String()).Scan(&pr.UserId, &pr.CurrentScore, &pr.BestScore,
+		&pr.Solved, &pr.Failed, &pr.Streak, &pr.BestStreak, &pr.LastUpdated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetStartedAttempt returns the attempt of the user that is not finished yet, nil if not found.
+func (r *puzzleRepo) GetStartedAttempt(ctx context.Context, userId types.ObjectId) (*entity.PuzzleAttempt, error) {
+	query := `SELECT id, user_id, puzzle_id, status, ply, rating_change, created_at, updated_at
+		FROM puzzle_attempts WHERE user_id = $1 AND status = $2 ORDER BY id DESC LIMIT 1`
+	return r.getAttempt(ctx, query, userId.String(), string(entity.AttemptStatusStarted))
+}
+
+// GetAttempt returns nil if the user has not tried the puzzle.
+func (r *puzzleRepo) GetAttempt(ctx context.Context, userId, puzzleId types.ObjectId) (*entity.PuzzleAttempt, error) {
+	query := `SELECT id, user_id, puzzle_id, status, ply, rating_change, created_at, updated_at
+		FROM puzzle_attempts WHERE user_id = $1 AND puzzle_id = $2`
+	return r.getAttempt(ctx, query, userId.String(), puzzleId.String())
+}
+
+func (r *puzzleRepo) getAttempt(ctx context.Context, query string, args ...interface{}) (*entity.PuzzleAttempt, error) {
+	var a entity.PuzzleAttempt
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&a.Id, &a.UserId, &a.PuzzleId, &a.Status, &a.Ply,
+		&a.RatingChange, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// StartAttempt returns false if the user has already tried the puzzle.
+func (r *puzzleRepo) StartAttempt(ctx context.Context, a *entity.PuzzleAttempt) (bool, error) {
+	query := `
+		INSERT INTO puzzle_attempts (user_id, puzzle_id, status, ply, rating_change, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (user_id, puzzle_id) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, a.UserId.String(), a.PuzzleId.String(), string(a.Status), a.Ply,
+		a.RatingChange, a.CreatedAt, a.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpdateAttempt saves the progress of a started attempt, it returns false if the attempt
+// is changed since it was read (the ply is not prevPly anymore or it's finished).
+func (r *puzzleRepo) UpdateAttempt(ctx context.Context, a *entity.PuzzleAttempt, prevPly int) (bool, error) {
+	return updateAttempt(ctx, r.db, a, prevPly)
+}
+
+// FinishAttempt saves the finished attempt and updates the ratings of the user and the puzzle atomically.
+// The rows of the ratings are locked and read in the transaction and passed to update, so the concurrent
+// attempts of the user or the puzzle don't overwrite each other.
+func (r *puzzleRepo) FinishAttempt(ctx context.Context, a *entity.PuzzleAttempt, prevPly int, puzzle *entity.Puzzle,
+	update func(*entity.PuzzleRating, *entity.Puzzle)) (*entity.PuzzleRating, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// the first attempt of the user starts from the default rating of the table
+	query := "INSERT INTO puzzle_ratings (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING"
+	if _, err := tx.ExecContext(ctx, query, a.UserId.String()); err != nil {
+		return nil, false, fmt.Errorf("failed to create puzzle rating for user %s: %w", a.UserId, err)
+	}
+
+	query = `SELECT user_id, current_score, best_score, solved, failed, streak, best_streak, last_updated
+		FROM puzzle_ratings WHERE user_id = $1 FOR UPDATE`
+	var rating entity.PuzzleRating
+	err = tx.QueryRowContext(ctx, query, a.UserId.String()).Scan(&rating.UserId, &rating.CurrentScore,
+		&rating.BestScore, &rating.Solved, &rating.Failed, &rating.Streak, &rating.BestStreak, &rating.LastUpdated)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get puzzle rating for user %s: %w", a.UserId, err)
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT rating FROM puzzles WHERE id = $1 FOR UPDATE", puzzle.Id.String()).
+		Scan(&puzzle.Rating)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get puzzle %s: %w", puzzle.Id, err)
+	}
+
+	update(&rating, puzzle)
+
+	ok, err := updateAttempt(ctx, tx, a, prevPly)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	query = `UPDATE puzzle_ratings SET current_score = $1, best_score = $2, solved = $3, failed = $4, streak = $5,
+		best_streak = $6, last_updated = $7 WHERE user_id = $8`
+	_, err = tx.ExecContext(ctx, query, rating.CurrentScore, rating.BestScore, rating.Solved, rating.Failed,
+		rating.Streak, rating.BestStreak, rating.LastUpdated, rating.UserId.String())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update puzzle rating for user %s: %w", rating.UserId, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE puzzles SET rating = $1, plays = plays + 1 WHERE id = $2",
+		puzzle.Rating, puzzle.Id.String())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update puzzle %s: %w", puzzle.Id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &rating, true, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func updateAttempt(ctx context.Context, db execer, a *entity.PuzzleAttempt, prevPly int) (bool, error) {
+	query := `UPDATE puzzle_attempts SET status = $1, ply = $2, rating_change = $3, updated_at = $4
+		WHERE id = $5 AND ply = $6 AND status = $7`
+	res, err := db.ExecContext(ctx, query, string(a.Status), a.Ply, a.RatingChange, a.UpdatedAt,
+		a.Id, prevPly, string(entity.AttemptStatusStarted))
+	if err != nil {
+		return false, fmt.Errorf("failed to update attempt %d: %w", a.Id, err)
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *puzzleRepo) GetAttempts(c context.Context, p *paginate.Paginated) ([]*entity.PuzzleAttempt, uint64, error) {
+	limit := p.PerPage
+	offset := (p.Page - 1) * limit
+
+	q, cq, args := pagesql.WriteQuery("puzzle_attempts", p.Filters, p.SortColumn, p.Decscending, limit, offset)
+
+	rows, err := r.db.QueryContext(c, q, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	attempts := []*entity.PuzzleAttempt{}
+	for rows.Next() {
+		var a entity.PuzzleAttempt
+		err := rows.Scan(&a.Id, &a.UserId, &a.PuzzleId, &a.Status, &a.Ply, &a.RatingChange, &a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			r.l.Error(fmt.Sprintf("failed to scan row: %v", err))
+			continue
+		}
+		attempts = append(attempts, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate over rows: %v", err)
+	}
+
+	var totalCount int
+	cArgs := []interface{}{}
+	if len(args) > 2 {
+		cArgs = append(cArgs, args[:len(args)-2]...)
+	}
+
+	err = r.db.QueryRowContext(c, cq, cArgs...).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute count query: %v", err)
+	}
+
+	return attempts, uint64(totalCount), nil
+}
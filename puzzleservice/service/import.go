@@ -0,0 +1,163 @@
+package puzzle
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/puzzleservice/entity"
+	"github.com/notnil/chess"
+)
+
+const (
+	importBatchSize     = 500
+	defaultPuzzleRating = 1500
+)
+
+type ImportResult struct {
+	Imported int `json:"imported"`
+	// duplicated puzzles that are already imported
+	Duplicated int `json:"duplicated"`
+	// invalid rows
+	Invalid int `json:"invalid"`
+}
+
+// Import reads puzzles from a CSV with a header row, the columns are matched by name (case insensitive):
+//   - fen (required): the puzzle position
+//   - moves (required): the solution line in UCI notation separated by spaces
+//   - themes: separated by spaces
+//   - rating: default is 1500
+//   - id: id of the puzzle in the source, the hash of the FEN and the moves is used if it's empty
+//
+// The lichess puzzle database is supported too, it's detected by the PuzzleId column.
+// In lichess puzzles the FEN is the position before the opponent move, so the first move
+// is applied to the FEN and removed from the solution.
+func (s *Service) Import(ctx context.Context, r io.Reader) (ImportResult, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	_, lichess := cols["puzzleid"]
+	if lichess {
+		cols["id"] = cols["puzzleid"]
+	}
+
+	if _, ok := cols["fen"]; !ok {
+		return ImportResult{}, fmt.Errorf("fen column is required")
+	}
+	if _, ok := cols["moves"]; !ok {
+		return ImportResult{}, fmt.Errorf("moves column is required")
+	}
+
+	res := ImportResult{}
+	batch := []*entity.Puzzle{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := s.repo.AddPuzzles(ctx, batch)
+		if err != nil {
+			return err
+		}
+		res.Imported += n
+		res.Duplicated += len(batch) - n
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			res.Invalid++
+			continue
+		}
+
+		p, err := parsePuzzle(row, cols, lichess)
+		if err != nil {
+			s.l.Debug(fmt.Sprintf("invalid puzzle row %v: %v", row, err))
+			res.Invalid++
+			continue
+		}
+
+		batch = append(batch, p)
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				s.l.Error(err.Error())
+				return res, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		s.l.Error(err.Error())
+		return res, err
+	}
+
+	s.l.Info(fmt.Sprintf("puzzles imported: %d, duplicated: %d, invalid: %d", res.Imported, res.Duplicated, res.Invalid))
+
+	return res, nil
+}
+
+func parsePuzzle(row []string, cols map[string]int, lichess bool) (*entity.Puzzle, error) {
+	get := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	fen := get("fen")
+	moves := strings.Fields(get("moves"))
+
+	if lichess {
+		if len(moves) < 2 {
+			return nil, fmt.Errorf("solution is empty")
+		}
+
+		f, err := chess.FEN(fen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fen: %v", err)
+		}
+
+		g := chess.NewGame(f, chess.UseNotation(chess.UCINotation{}))
+		if err := g.MoveStr(moves[0]); err != nil {
+			return nil, fmt.Errorf("invalid move %s: %v", moves[0], err)
+		}
+		fen, moves = g.Position().String(), moves[1:]
+	}
+
+	rating := int64(defaultPuzzleRating)
+	if r := get("rating"); r != "" {
+		v, err := strconv.ParseInt(r, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rating: %s", r)
+		}
+		rating = v
+	}
+
+	id := get("id")
+	if id == "" {
+		h := sha1.Sum([]byte(fen + "|" + strings.Join(moves, " ")))
+		id = hex.EncodeToString(h[:])
+	}
+
+	return entity.NewPuzzle(id, fen, moves, rating, strings.Fields(get("themes")))
+}
@@ -0,0 +1,35 @@
+package puzzle
+
+import (
+	"github.com/alikarimi999/shahboard/puzzleservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+type PuzzleResponse struct {
+	Id types.ObjectId `json:"id"`
+	// current position of the puzzle, it's the starting position if no move is played
+	FEN string `json:"fen"`
+	// color of the solver
+	Color  string   `json:"color"`
+	Rating int64    `json:"rating"`
+	Themes []string `json:"themes"`
+	// moves of the solution that are played so far, in UCI notation
+	Played []string `json:"played"`
+}
+
+type MoveResponse struct {
+	PuzzleId types.ObjectId       `json:"puzzle_id"`
+	Correct  bool                 `json:"correct"`
+	Status   entity.AttemptStatus `json:"status"`
+	// opponent answer in UCI notation
+	Reply string `json:"reply,omitempty"`
+	// position after the opponent answer, only set if the puzzle is not finished
+	FEN string `json:"fen,omitempty"`
+	// whole solution, only set if the puzzle is failed
+	Solution []string `json:"solution,omitempty"`
+
+	// only set if the puzzle is finished
+	RatingChange int64 `json:"rating_change"`
+	Rating       int64 `json:"rating,omitempty"`
+	Streak       int64 `json:"streak"`
+}
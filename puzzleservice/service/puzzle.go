@@ -0,0 +1,296 @@
+package puzzle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/pkg/elo"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/paginate"
+	"github.com/alikarimi999/shahboard/puzzleservice/entity"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+const defaultRatingRange = 100
+
+type Repository interface {
+	// returns the number of added puzzles, duplicated puzzles are skipped
+	AddPuzzles(ctx context.Context, puzzles []*entity.Puzzle) (int, error)
+	// return nil if not found
+	GetPuzzle(ctx context.Context, id types.ObjectId) (*entity.Puzzle, error)
+	// return nil if not found
+	FindPuzzle(ctx context.Context, userId types.ObjectId, min, max int64, theme string) (*entity.Puzzle, error)
+
+	// return nil if not found
+	GetRating(ctx context.Context, userId types.ObjectId) (*entity.PuzzleRating, error)
+
+	// return nil if not found
+	GetStartedAttempt(ctx context.Context, userId types.ObjectId) (*entity.PuzzleAttempt, error)
+	// return nil if not found
+	GetAttempt(ctx context.Context, userId, puzzleId types.ObjectId) (*entity.PuzzleAttempt, error)
+	StartAttempt(ctx context.Context, a *entity.PuzzleAttempt) (bool, error)
+	UpdateAttempt(ctx context.Context, a *entity.PuzzleAttempt, prevPly int) (bool, error)
+	// update the attempt and the ratings atomically, update is called with the locked ratings of the user and the puzzle
+	FinishAttempt(ctx context.Context, a *entity.PuzzleAttempt, prevPly int, puzzle *entity.Puzzle,
+		update func(*entity.PuzzleRating, *entity.Puzzle)) (*entity.PuzzleRating, bool, error)
+	GetAttempts(context.Context, *paginate.Paginated) ([]*entity.PuzzleAttempt, uint64, error)
+}
+
+type Config struct {
+	// RatingRange is the initial distance between the user's rating and the puzzle rating,
+	// it's doubled until a puzzle is found. Default is 100.
+	RatingRange int64 `json:"rating_range"`
+}
+
+type Service struct {
+	cfg  Config
+	repo Repository
+	l    log.Logger
+}
+
+func NewService(cfg Config, repo Repository, l log.Logger) *Service {
+	if cfg.RatingRange <= 0 {
+		cfg.RatingRange = defaultRatingRange
+	}
+
+	return &Service{
+		cfg:  cfg,
+		repo: repo,
+		l:    l,
+	}
+}
+
+// NextPuzzle returns a puzzle near the user's puzzle rating that the user has not tried yet.
+// If the user has an unfinished puzzle, it's returned instead.
+func (s *Service) NextPuzzle(ctx context.Context, userId types.ObjectId, theme string) (PuzzleResponse, error) {
+	a, err := s.repo.GetStartedAttempt(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return PuzzleResponse{}, err
+	}
+
+	if a != nil {
+		p, err := s.getPuzzle(ctx, a.PuzzleId)
+		if err != nil {
+			return PuzzleResponse{}, err
+		}
+		return puzzleToResponse(p, a)
+	}
+
+	r, err := s.GetUserRating(ctx, userId)
+	if err != nil {
+		return PuzzleResponse{}, err
+	}
+
+	var p *entity.Puzzle
+	for d := s.cfg.RatingRange; d <= 16*s.cfg.RatingRange; d *= 2 {
+		p, err = s.repo.FindPuzzle(ctx, userId, r.CurrentScore-d, r.CurrentScore+d, theme)
+		if err != nil {
+			s.l.Error(err.Error())
+			return PuzzleResponse{}, err
+		}
+		if p != nil {
+			break
+		}
+	}
+
+	if p == nil {
+		return PuzzleResponse{}, fmt.Errorf("no puzzle found")
+	}
+
+	t := time.Now()
+	a = &entity.PuzzleAttempt{
+		UserId:    userId,
+		PuzzleId:  p.Id,
+		Status:    entity.AttemptStatusStarted,
+		CreatedAt: t,
+		UpdatedAt: t,
+	}
+
+	ok, err := s.repo.StartAttempt(ctx, a)
+	if err != nil {
+		s.l.Error(err.Error())
+		return PuzzleResponse{}, err
+	}
+	if !ok {
+		return PuzzleResponse{}, fmt.Errorf("puzzle is already tried, try again")
+	}
+
+	return puzzleToResponse(p, a)
+}
+
+// Move checks the move of the user in the puzzle and plays the opponent answer.
+// The first wrong move fails the puzzle, the rating is updated when the puzzle is solved or failed.
+func (s *Service) Move(ctx context.Context, userId, puzzleId types.ObjectId, move string) (MoveResponse, error) {
+	a, err := s.repo.GetAttempt(ctx, userId, puzzleId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return MoveResponse{}, err
+	}
+
+	if a == nil {
+		return MoveResponse{}, fmt.Errorf("puzzle is not started")
+	}
+
+	if a.Status != entity.AttemptStatusStarted {
+		return MoveResponse{}, fmt.Errorf("puzzle is already %s", a.Status)
+	}
+
+	p, err := s.getPuzzle(ctx, puzzleId)
+	if err != nil {
+		return MoveResponse{}, err
+	}
+
+	correct, err := p.CheckMove(a.Ply, move)
+	if err != nil {
+		return MoveResponse{}, err
+	}
+
+	prevPly := a.Ply
+	res := MoveResponse{PuzzleId: p.Id, Correct: correct}
+	a.UpdatedAt = time.Now()
+
+	if !correct {
+		a.Status = entity.AttemptStatusFailed
+		// the solution is sent back so the client can show it
+		res.Solution = p.Moves
+	} else {
+		// the solver move and the opponent answer
+		a.Ply++
+		if a.Ply < len(p.Moves) {
+			res.Reply = p.Moves[a.Ply]
+			a.Ply++
+		}
+		if a.Ply >= len(p.Moves) {
+			a.Status = entity.AttemptStatusSolved
+		}
+	}
+
+	res.Status = a.Status
+	if a.Status == entity.AttemptStatusStarted {
+		ok, err := s.repo.UpdateAttempt(ctx, a, prevPly)
+		if err != nil {
+			s.l.Error(err.Error())
+			return MoveResponse{}, err
+		}
+		if !ok {
+			return MoveResponse{}, fmt.Errorf("puzzle is changed, try again")
+		}
+
+		pos, err := p.Position(a.Ply)
+		if err != nil {
+			return MoveResponse{}, err
+		}
+		res.FEN = pos.String()
+
+		return res, nil
+	}
+
+	r, ok, err := s.repo.FinishAttempt(ctx, a, prevPly, p, func(r *entity.PuzzleRating, p *entity.Puzzle) {
+		updateRatings(r, p, a)
+	})
+	if err != nil {
+		s.l.Error(err.Error())
+		return MoveResponse{}, err
+	}
+	if !ok {
+		return MoveResponse{}, fmt.Errorf("puzzle is changed, try again")
+	}
+
+	s.l.Debug(fmt.Sprintf("user '%s' %s puzzle '%s'", userId, a.Status, p.Id))
+
+	res.RatingChange = a.RatingChange
+	res.Rating = r.CurrentScore
+	res.Streak = r.Streak
+
+	return res, nil
+}
+
+// updateRatings updates the user and the puzzle ratings and the user's streak by the result of the attempt.
+func updateRatings(r *entity.PuzzleRating, p *entity.Puzzle, a *entity.PuzzleAttempt) {
+	score := 0.0
+	if a.Status == entity.AttemptStatusSolved {
+		score = 1
+	}
+
+	userScore := elo.CalculateElo(r.CurrentScore, p.Rating, score)
+	p.Rating = elo.CalculateElo(p.Rating, r.CurrentScore, 1-score)
+
+	a.RatingChange = userScore - r.CurrentScore
+	r.CurrentScore = userScore
+	if r.BestScore < userScore {
+		r.BestScore = userScore
+	}
+
+	if score == 1 {
+		r.Solved++
+		r.Streak++
+		if r.BestStreak < r.Streak {
+			r.BestStreak = r.Streak
+		}
+	} else {
+		r.Failed++
+		r.Streak = 0
+	}
+
+	r.LastUpdated = a.UpdatedAt
+}
+
+// GetUserRating returns the base rating if the user has not tried any puzzle yet.
+func (s *Service) GetUserRating(ctx context.Context, userId types.ObjectId) (*entity.PuzzleRating, error) {
+	r, err := s.repo.GetRating(ctx, userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	if r == nil {
+		r = &entity.PuzzleRating{
+			UserId:       userId,
+			CurrentScore: elo.BaseScore,
+			BestScore:    elo.BaseScore,
+		}
+	}
+
+	return r, nil
+}
+
+func (s *Service) GetUserHistory(ctx context.Context, userId types.ObjectId, p *paginate.Paginated) ([]*entity.PuzzleAttempt, uint64, error) {
+	p.Filters["user_id"] = paginate.Filter{
+		Operator: paginate.FilterOperatorEqual,
+		Values:   []interface{}{userId.String()},
+	}
+
+	return s.repo.GetAttempts(ctx, p)
+}
+
+func (s *Service) getPuzzle(ctx context.Context, id types.ObjectId) (*entity.Puzzle, error) {
+	p, err := s.repo.GetPuzzle(ctx, id)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, err
+	}
+
+	if p == nil {
+		return nil, fmt.Errorf("puzzle not found")
+	}
+
+	return p, nil
+}
+
+func puzzleToResponse(p *entity.Puzzle, a *entity.PuzzleAttempt) (PuzzleResponse, error) {
+	pos, err := p.Position(a.Ply)
+	if err != nil {
+		return PuzzleResponse{}, err
+	}
+
+	return PuzzleResponse{
+		Id:     p.Id,
+		FEN:    pos.String(),
+		Color:  p.Color().String(),
+		Rating: p.Rating,
+		Themes: p.Themes,
+		Played: p.Moves[:a.Ply],
+	}, nil
+}
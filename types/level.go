@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 type Level uint8
 
 const (
@@ -29,3 +31,12 @@ func (l Level) String() string {
 		return "pawn"
 	}
 }
+
+func ParseLevel(s string) (Level, error) {
+	for l := LevelPawn; l <= LevelKing; l++ {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid level: %s", s)
+}
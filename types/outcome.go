@@ -12,12 +12,19 @@ const (
 	BlackWon GameOutcome = "0-1"
 	// Draw indicates that game was a draw.
 	Draw GameOutcome = "1/2-1/2"
+	// Aborted indicates that game was canceled before both sides moved, it has no result.
+	Aborted GameOutcome = "aborted"
 )
 
 func (o GameOutcome) String() string {
 	return string(o)
 }
 
+// IsResult returns true if the game ended with a win or a draw, only these games are rated.
+func (o GameOutcome) IsResult() bool {
+	return o == WhiteWon || o == BlackWon || o == Draw
+}
+
 func ParseOutcome(s string) GameOutcome {
 	switch s {
 	case "1-0":
@@ -26,6 +33,8 @@ func ParseOutcome(s string) GameOutcome {
 		return BlackWon
 	case "1/2-1/2":
 		return Draw
+	case "aborted":
+		return Aborted
 	default:
 		return NoOutcome
 	}
@@ -0,0 +1,20 @@
+package types
+
+type PresenceStatus string
+
+const (
+	// PresenceOffline is the status of the users without any connected session.
+	PresenceOffline PresenceStatus = "offline"
+	// PresenceOnline is the status of the users that are connected and active.
+	PresenceOnline PresenceStatus = "online"
+	// PresenceInGame is the status of the users that play a live game.
+	PresenceInGame PresenceStatus = "in_game"
+	// PresenceSpectating is the status of the users that view the live games of the others.
+	PresenceSpectating PresenceStatus = "spectating"
+	// PresenceIdle is the status of the users whose sessions are connected but not used for a while.
+	PresenceIdle PresenceStatus = "idle"
+)
+
+func (s PresenceStatus) String() string {
+	return string(s)
+}
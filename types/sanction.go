@@ -0,0 +1,31 @@
+package types
+
+type SanctionType string
+
+const (
+	// SanctionBan blocks the account, it's permanent unless an expiry is set.
+	SanctionBan SanctionType = "ban"
+	// SanctionSuspension blocks the account until it expires.
+	SanctionSuspension SanctionType = "suspension"
+	// SanctionChatMute drops the chat messages of the user.
+	SanctionChatMute SanctionType = "chat_mute"
+	// SanctionRatedBan stops the user from queueing for rated games.
+	SanctionRatedBan SanctionType = "rated_ban"
+)
+
+func ParseSanctionType(s string) (SanctionType, bool) {
+	switch t := SanctionType(s); t {
+	case SanctionBan, SanctionSuspension, SanctionChatMute, SanctionRatedBan:
+		return t, true
+	}
+	return "", false
+}
+
+// BlocksAccount returns true if the sanction stops the user from logging in and playing.
+func (t SanctionType) BlocksAccount() bool {
+	return t == SanctionBan || t == SanctionSuspension
+}
+
+func (t SanctionType) String() string {
+	return string(t)
+}
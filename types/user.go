@@ -4,5 +4,39 @@ type User struct {
 	ID      ObjectId `json:"id"`
 	Email   string   `json:"email"`
 	IsGuest bool     `json:"is_guest"`
+	Role    Role     `json:"role"`
 	Score   int64    `json:"score"`
 }
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func ParseRole(s string) (Role, bool) {
+	r := Role(s)
+	_, ok := roleLevels[r]
+	return r, ok
+}
+
+// Includes returns true if the role has the permissions of the required role,
+// each role has the permissions of the roles below it. Empty role is the user role.
+func (r Role) Includes(required Role) bool {
+	if r == "" {
+		r = RoleUser
+	}
+	return roleLevels[r] >= roleLevels[required]
+}
+
+func (r Role) String() string {
+	return string(r)
+}
@@ -0,0 +1,38 @@
+package types
+
+import "fmt"
+
+// Variant is the chess variant of a game, each variant has its own matchmaking queue and rating.
+type Variant string
+
+const (
+	VariantStandard Variant = "standard"
+	VariantChess960 Variant = "chess960"
+)
+
+func (v Variant) String() string {
+	if v == "" {
+		return string(VariantStandard)
+	}
+	return string(v)
+}
+
+// Normalize returns VariantStandard for the empty variant,
+// events and records that created before variants were added don't have one.
+func (v Variant) Normalize() Variant {
+	if v == "" {
+		return VariantStandard
+	}
+	return v
+}
+
+func ParseVariant(s string) (Variant, error) {
+	switch Variant(s) {
+	case "", VariantStandard:
+		return VariantStandard, nil
+	case VariantChess960:
+		return VariantChess960, nil
+	default:
+		return "", fmt.Errorf("invalid variant: %s", s)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/pkg/block"
 	"github.com/alikarimi999/shahboard/pkg/grpc"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/pkg/log"
@@ -16,6 +17,8 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const defaultFanoutGroupID = "wsgateway_fanout"
+
 type application struct {
 	cfg        Config
 	server     *ws.Server
@@ -36,11 +39,25 @@ func SetupApplication(cfg Config) (*application, error) {
 		return nil, err
 	}
 
+	if cfg.Fanout.GroupID == "" {
+		cfg.Fanout.GroupID = defaultFanoutGroupID
+	}
+	shared, err := kafka.NewKafkaSubscriber(kafka.Config{
+		Brokers: cfg.Kafka.Brokers,
+		GroupID: cfg.Fanout.GroupID,
+	}, l)
+	if err != nil {
+		return nil, err
+	}
+
 	// gin.SetMode(gin.ReleaseMode)
 	// e := gin.Default()
 
 	e := gin.New()
 	e.Use(gin.Recovery(), middleware.Cors())
+	if err := e.SetTrustedProxies(cfg.Http.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
 	c := redis.NewClient(&redis.Options{
 		Addr:     cfg.Redis.Addr,
@@ -53,12 +70,14 @@ func SetupApplication(cfg Config) (*application, error) {
 		return nil, err
 	}
 
+	v.WithDenylist(jwt.NewRedisDenylist(c))
+
 	client, err := grpc.NewClient(cfg.GameService, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	server, err := ws.NewServer(e, s, p, game.NewService(client), &cfg.Ws, c, v, l)
+	server, err := ws.NewServer(e, s, shared, p, game.NewService(client), &cfg.Ws, c, v, block.NewRegistry(c, s, l), l)
 	if err != nil {
 		return nil, err
 	}
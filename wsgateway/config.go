@@ -12,6 +12,7 @@ import (
 type Config struct {
 	Ws           ws.WsConfigs        `json:"ws"`
 	Kafka        kafka.Config        `json:"kafka"`
+	Fanout       FanoutConfig        `json:"fanout"`
 	Log          LogConfig           `json:"log"`
 	Http         HttpConfig          `json:"http"`
 	Redis        RedisConfig         `json:"redis"`
@@ -20,6 +21,12 @@ type Config struct {
 	GameService  grpc.Config         `json:"game_service_grpc"`
 }
 
+// FanoutConfig is the consumer group of the game events that is shared by all instances,
+// each event is consumed by one instance and relayed to the others by redis.
+type FanoutConfig struct {
+	GroupID string `json:"group_id"`
+}
+
 type LogConfig struct {
 	File    string `json:"file"`
 	Verbose bool   `json:"verbose"`
@@ -27,6 +34,9 @@ type LogConfig struct {
 
 type HttpConfig struct {
 	Port int `json:"port"`
+	// TrustedProxies are the proxies whose X-Forwarded-For header is used for the client ip,
+	// the header of the other peers is ignored.
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 type RedisConfig struct {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	pb "github.com/alikarimi999/shahboard/proto/wsgateway/wsgatewaypb"
 	"github.com/alikarimi999/shahboard/wsgateway/ws"
 
@@ -53,7 +54,7 @@ func (s *Server) GetLiveGamesViewersNumber(ctx context.Context,
 
 	m, err := s.wsServer.GetLiveGamesViewersNumber(ctx)
 	if err != nil {
-		return nil, err
+		return nil, errs.GRPC(errs.CodeInternalError, fmt.Sprintf("failed to get live games viewers: %v", err))
 	}
 
 	mr := make(map[string]int64, len(m))
@@ -1,16 +1,53 @@
 package ws
 
 var (
-	defaultMaxConnections  = 1000
-	defaultUserSessionsCap = 3
+	defaultMaxConnections    = 1000
+	defaultUserSessionsCap   = 3
+	defaultStreamBufferSize  = 200
+	defaultStreamTTL         = 3600
+	defaultSpectateRateLimit = 60
+	defaultLongPollTimeout   = 25
+	defaultSpectateStreams   = 5
+	defaultMaxMessageSize    = int64(4096)
+	defaultPresenceIdle      = 300
 )
 
 type WsConfigs struct {
 	MaxConnections  int `json:"max_connections"`
 	UserSessionsCap int `json:"user_sessions_cap"`
+
+	// StreamBufferSize is the number of the last game and chat messages of each game that are kept
+	// for the reconnecting clients, StreamTTL is the number of seconds they are kept after the last message.
+	StreamBufferSize int `json:"stream_buffer_size"`
+	StreamTTL        int `json:"stream_ttl"`
+
+	// SpectateRateLimit is the number of the requests of the anonymous spectators per minute of each ip,
+	// SpectateStreamsPerIP is the number of the open event streams of each ip,
+	// LongPollTimeout is the number of seconds that a poll waits for the messages.
+	SpectateRateLimit    int `json:"spectate_rate_limit"`
+	SpectateStreamsPerIP int `json:"spectate_streams_per_ip"`
+	LongPollTimeout      int `json:"long_poll_timeout"`
+
+	// MaxMessageSize is the max size of the frames that the clients send, the larger frames close the connection.
+	// RateLimits are the limits of the messages of each type, the types that are not set use the default limits.
+	MaxMessageSize int64                    `json:"max_message_size"`
+	RateLimits     map[MsgType]MsgRateLimit `json:"rate_limits"`
+
+	// PresenceIdleTimeout is the number of seconds without any message from the client that the session is idle.
+	PresenceIdleTimeout int `json:"presence_idle_timeout"`
 }
 
 var defaultConfigs = &WsConfigs{
-	MaxConnections:  defaultMaxConnections,
-	UserSessionsCap: defaultUserSessionsCap,
+	MaxConnections:    defaultMaxConnections,
+	UserSessionsCap:   defaultUserSessionsCap,
+	StreamBufferSize:  defaultStreamBufferSize,
+	StreamTTL:         defaultStreamTTL,
+	SpectateRateLimit: defaultSpectateRateLimit,
+	LongPollTimeout:   defaultLongPollTimeout,
+	MaxMessageSize:    defaultMaxMessageSize,
+	RateLimits:        defaultRateLimits,
+
+	SpectateStreamsPerIP: defaultSpectateStreams,
+
+	PresenceIdleTimeout: defaultPresenceIdle,
 }
@@ -102,6 +102,7 @@ func (c *sessionCleaner) removeSessionsInBatch(ctx context.Context, sesstions []
 
 func (s *Server) removeSession(ctx context.Context, se *session) {
 	s.sm.remove(se.userId, se.id)
+	s.presence.remove(ctx, se)
 
 	ids := se.getAllViewGames()
 	if len(ids) > 0 {
@@ -0,0 +1,273 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/proto/wsgateway/wsgatewaypb"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// subprotocols that the clients negotiate on /ws, connections without a subprotocol use JSON.
+const (
+	SubprotocolJSON    = "shahboard.v1.json"
+	SubprotocolMsgpack = "shahboard.v1.msgpack"
+	SubprotocolProto   = "shahboard.v1.proto"
+)
+
+// msgCodec encodes the messages of a connection. The payloads are JSON inside the gateway,
+// the binary codecs send them as native values so the clients don't decode JSON in base64.
+type msgCodec interface {
+	encode(m *Msg) ([]byte, error)
+	decode(b []byte) (*Msg, error)
+	// frame type of the encoded messages
+	frame() int
+}
+
+var codecs = map[string]msgCodec{
+	SubprotocolJSON:    jsonCodec{},
+	SubprotocolMsgpack: newMsgpackCodec(),
+	SubprotocolProto:   protoCodec{},
+}
+
+// getCodec returns the codec of the negotiated subprotocol.
+func getCodec(subprotocol string) msgCodec {
+	if c, ok := codecs[subprotocol]; ok {
+		return c
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) encode(m *Msg) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (jsonCodec) decode(b []byte) (*Msg, error) {
+	var m Msg
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (jsonCodec) frame() int {
+	return websocket.TextMessage
+}
+
+// wireMsg is the message of the binary codecs, Data is the decoded payload.
+type wireMsg struct {
+	ID        types.ObjectId `codec:"id"`
+	Type      MsgType        `codec:"type"`
+	Seq       int64          `codec:"seq,omitempty"`
+	Timestamp int64          `codec:"timestamp"`
+	Data      any            `codec:"data"`
+}
+
+func toWire(m *Msg) wireMsg {
+	return wireMsg{
+		ID:        m.ID,
+		Type:      m.Type,
+		Seq:       m.Seq,
+		Timestamp: m.Timestamp,
+		Data:      wirePayload(m.Type, m.Data),
+	}
+}
+
+func fromWire(w wireMsg) (*Msg, error) {
+	data, err := json.Marshal(w.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+
+	return &Msg{
+		MsgBase: MsgBase{
+			ID:        w.ID,
+			Type:      w.Type,
+			Seq:       w.Seq,
+			Timestamp: w.Timestamp,
+		},
+		Data: data,
+	}, nil
+}
+
+// wirePayload decodes the JSON payload, payloads that are not JSON like the welcome are sent as strings.
+// The numbers are kept as json.Number, each codec converts them without losing the int64 precision.
+func wirePayload(t MsgType, data []byte) any {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return string(data)
+	}
+
+	// the replayed messages of a resumed game have their payloads in base64
+	if t == MsgTypeResumeGame || t == MsgTypeViewGame {
+		if res, ok := v.(map[string]any); ok {
+			if replay, ok := res["replay"].([]any); ok {
+				for _, r := range replay {
+					msg, ok := r.(map[string]any)
+					if !ok {
+						continue
+					}
+					s, _ := msg["data"].(string)
+					b, err := base64.StdEncoding.DecodeString(s)
+					if err != nil {
+						continue
+					}
+					mt, _ := msg["type"].(string)
+					msg["data"] = wirePayload(MsgType(mt), b)
+				}
+			}
+		}
+	}
+
+	return v
+}
+
+func decodeJSON(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("invalid json: data after the value")
+	}
+	return v, nil
+}
+
+// convertNumbers replaces the json.Number values of the decoded payload.
+func convertNumbers(v any, f func(json.Number) any) any {
+	switch v := v.(type) {
+	case json.Number:
+		return f(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = convertNumbers(e, f)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = convertNumbers(e, f)
+		}
+	}
+	return v
+}
+
+// nativeNumber keeps the integers as int64, msgpack has native integers.
+func nativeNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// maxExactFloat is the largest integer that a float64 holds exactly.
+const maxExactFloat = 1 << 53
+
+// protoNumber sends the integers that a double can't hold exactly as strings,
+// google.protobuf.Value only has double numbers, like int64 in the JSON mapping of proto3.
+func protoNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		if i > maxExactFloat || i < -maxExactFloat {
+			return strconv.FormatInt(i, 10)
+		}
+		return float64(i)
+	}
+	if !strings.ContainsAny(n.String(), ".eE") {
+		// integers out of the int64 range
+		return n.String()
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+type msgpackCodec struct {
+	h *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() msgpackCodec {
+	h := &codec.MsgpackHandle{}
+	h.WriteExt = true
+	h.RawToString = true
+	h.MapType = reflect.TypeOf(map[string]any(nil))
+	return msgpackCodec{h: h}
+}
+
+func (c msgpackCodec) encode(m *Msg) ([]byte, error) {
+	w := toWire(m)
+	w.Data = convertNumbers(w.Data, nativeNumber)
+
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, c.h).Encode(w); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (c msgpackCodec) decode(b []byte) (*Msg, error) {
+	var w wireMsg
+	if err := codec.NewDecoderBytes(b, c.h).Decode(&w); err != nil {
+		return nil, err
+	}
+	return fromWire(w)
+}
+
+func (msgpackCodec) frame() int {
+	return websocket.BinaryMessage
+}
+
+// protoCodec encodes the WsMsg message of proto/wsgateway/ws.proto, the payload is a google.protobuf.Value.
+// Integers beyond 2^53 are sent as strings, since the numbers of the Value are doubles.
+type protoCodec struct{}
+
+func (protoCodec) encode(m *Msg) ([]byte, error) {
+	w := toWire(m)
+	data, err := structpb.NewValue(convertNumbers(w.Data, protoNumber))
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+
+	return proto.Marshal(&wsgatewaypb.WsMsg{
+		Id:        w.ID.String(),
+		Type:      string(w.Type),
+		Seq:       w.Seq,
+		Timestamp: w.Timestamp,
+		Data:      data,
+	})
+}
+
+func (protoCodec) decode(b []byte) (*Msg, error) {
+	var pm wsgatewaypb.WsMsg
+	if err := proto.Unmarshal(b, &pm); err != nil {
+		return nil, err
+	}
+
+	return fromWire(wireMsg{
+		ID:        types.ObjectId(pm.GetId()),
+		Type:      MsgType(pm.GetType()),
+		Seq:       pm.GetSeq(),
+		Timestamp: pm.GetTimestamp(),
+		Data:      pm.GetData().AsInterface(),
+	})
+}
+
+func (protoCodec) frame() int {
+	return websocket.BinaryMessage
+}
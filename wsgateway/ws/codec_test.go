@@ -0,0 +1,157 @@
+package ws
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// equalJSON compares the payloads by value, the binary codecs don't keep the order of the keys.
+func equalJSON(t *testing.T, expected, got []byte) bool {
+	t.Helper()
+
+	e, err := decodeJSON(expected)
+	if err != nil {
+		t.Fatalf("invalid expected payload %s: %v", expected, err)
+	}
+	g, err := decodeJSON(got)
+	if err != nil {
+		t.Fatalf("invalid payload %s: %v", got, err)
+	}
+	return reflect.DeepEqual(e, g)
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	replayed := base64.StdEncoding.EncodeToString([]byte(`{"index":9007199254740993,"move":"e4"}`))
+
+	tests := []struct {
+		name string
+		typ  MsgType
+		seq  int64
+		data string
+		// payload after the proto codec, the integers beyond 2^53 come back as strings
+		proto string
+	}{
+		{
+			name:  "small numbers",
+			typ:   MsgTypePlayerMove,
+			seq:   12,
+			data:  `{"game_id":"1","index":3,"clock":-1500,"eval":0.25,"ok":true,"none":null}`,
+			proto: `{"game_id":"1","index":3,"clock":-1500,"eval":0.25,"ok":true,"none":null}`,
+		},
+		{
+			name:  "int64 above 2^53",
+			typ:   MsgTypePlayerMove,
+			data:  `{"game_id":9007199254740993,"player_id":-9223372036854775808,"max":9223372036854775807}`,
+			proto: `{"game_id":"9007199254740993","player_id":"-9223372036854775808","max":"9223372036854775807"}`,
+		},
+		{
+			name:  "2^53 is exact",
+			typ:   MsgTypePlayerMove,
+			data:  `{"id":9007199254740992}`,
+			proto: `{"id":9007199254740992}`,
+		},
+		{
+			name:  "nested",
+			typ:   MsgTypePlayerMove,
+			data:  `{"users":[{"id":9007199254740993,"rating":1500}],"empty":[],"obj":{}}`,
+			proto: `{"users":[{"id":"9007199254740993","rating":1500}],"empty":[],"obj":{}}`,
+		},
+		{
+			name:  "replayed payload",
+			typ:   MsgTypeResumeGame,
+			data:  `{"replay":[{"type":"move","data":"` + replayed + `"}]}`,
+			proto: `{"replay":[{"type":"move","data":{"index":"9007199254740993","move":"e4"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		m := &Msg{
+			MsgBase: MsgBase{ID: types.ObjectId("7393390521806708737"), Type: tt.typ, Seq: tt.seq, Timestamp: 1700000000},
+			Data:    []byte(tt.data),
+		}
+
+		for name, c := range codecs {
+			b, err := c.encode(m)
+			if err != nil {
+				t.Fatalf("%s %s: failed to encode: %v", tt.name, name, err)
+			}
+			got, err := c.decode(b)
+			if err != nil {
+				t.Fatalf("%s %s: failed to decode: %v", tt.name, name, err)
+			}
+
+			if got.MsgBase != m.MsgBase {
+				t.Errorf("%s %s: expected %+v, got %+v", tt.name, name, m.MsgBase, got.MsgBase)
+			}
+
+			expected := tt.data
+			switch name {
+			case SubprotocolProto:
+				expected = tt.proto
+			case SubprotocolMsgpack:
+				// the replayed payloads are decoded by the binary codecs
+				if tt.typ == MsgTypeResumeGame {
+					expected = `{"replay":[{"type":"move","data":{"index":9007199254740993,"move":"e4"}}]}`
+				}
+			}
+			if !equalJSON(t, []byte(expected), got.Data) {
+				t.Errorf("%s %s: expected payload %s, got %s", tt.name, name, expected, got.Data)
+			}
+		}
+	}
+}
+
+func TestCodecsTextPayload(t *testing.T) {
+	m := &Msg{MsgBase: MsgBase{Type: MsgTypeWelcome, Timestamp: 1}, Data: []byte("welcome")}
+
+	for name, c := range codecs {
+		b, err := c.encode(m)
+		if err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+		got, err := c.decode(b)
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+
+		expected := `"welcome"`
+		if name == SubprotocolJSON {
+			expected = "welcome"
+		}
+		if string(got.Data) != expected {
+			t.Errorf("%s: expected payload %s, got %s", name, expected, got.Data)
+		}
+	}
+}
+
+func TestProtoCodecInvalid(t *testing.T) {
+	if _, err := (protoCodec{}).decode([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Error("expected truncated message to fail")
+	}
+
+	// unknown fields are skipped
+	b, err := (protoCodec{}).encode(&Msg{MsgBase: MsgBase{Type: MsgTypePlayerMove}, Data: []byte(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, 0x30, 0x01)
+	got, err := (protoCodec{}).decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != MsgTypePlayerMove {
+		t.Errorf("expected type %s, got %s", MsgTypePlayerMove, got.Type)
+	}
+}
+
+func TestGetCodec(t *testing.T) {
+	if _, ok := getCodec("unknown").(jsonCodec); !ok {
+		t.Error("expected JSON codec for unknown subprotocol")
+	}
+	if _, ok := getCodec(SubprotocolProto).(protoCodec); !ok {
+		t.Error("expected proto codec")
+	}
+}
@@ -1,6 +1,8 @@
 package ws
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -12,15 +14,22 @@ import (
 var (
 	defaultfindMatchExpireTreshold = 1 * time.Minute
 	defaultBroadcastInterval       = 500 * time.Millisecond
+
+	// the failed relays are retried with a growing delay up to the max delay
+	relayRetryDelay    = 100 * time.Millisecond
+	relayMaxRetryDelay = 5 * time.Second
 )
 
 type sessionsEventsHandler struct {
 	directChatSub event.Subscription
 
+	// consumed by the shared consumer group of the instances and relayed by the fanout
 	gameSub     event.Subscription
 	gameChatSub event.Subscription
+	fan         *gameFanout
 
-	em *endedGamesList
+	em     *endedGamesList
+	stream *gameStream
 
 	findMatchExpireTreshold time.Duration
 
@@ -44,11 +53,14 @@ type sessionsEventsHandler struct {
 	stopCh chan struct{}
 }
 
-func newSessionsEventsHandler(s event.Subscriber, em *endedGamesList, l log.Logger) *sessionsEventsHandler {
+func newSessionsEventsHandler(s, shared event.Subscriber, fan *gameFanout, em *endedGamesList,
+	stream *gameStream, l log.Logger) *sessionsEventsHandler {
 	m := &sessionsEventsHandler{
-		gameSub:       s.Subscribe(event.TopicGame),
-		gameChatSub:   s.Subscribe(event.TopicGameChat),
+		gameSub:       shared.Subscribe(event.TopicGame),
+		gameChatSub:   shared.Subscribe(event.TopicGameChat),
+		fan:           fan,
 		em:            em,
+		stream:        stream,
 		directChatSub: s.Subscribe(event.TopicDirectChat),
 
 		findMatchExpireTreshold: defaultfindMatchExpireTreshold,
@@ -84,62 +96,98 @@ func (h *sessionsEventsHandler) startEventListener() {
 				h.l.Debug("event listener stopped")
 				return
 			case e := <-h.gameSub.Event():
-				switch e.GetTopic().Action() {
-				case event.ActionCreated:
-					eve, ok := e.(*event.EventGameCreated)
-					if !ok {
-						h.l.Warn("invalid event type for ActionCreated")
-						continue
-					}
+				h.relayEvent(e)
+			case e := <-h.gameChatSub.Event():
+				h.relayEvent(e)
+			case m := <-h.fan.events():
+				e, seq, err := parseFanoutPayload(m.Payload)
+				if err != nil {
+					h.l.Error(err.Error())
+					continue
+				}
+				h.handleGameEvent(e, seq)
+			}
+		}
+	}()
+}
 
-					// broadcast to all sessisons
-					h.mmu.Lock()
-					for _, s := range h.matchSubSessions[eve.MatchID] {
-						s.consume(e)
-					}
-					h.mmu.Unlock()
-
-					// store created game events for situations where the user websocket connection
-					// request for the evnet after the event is received
-					h.gmu.Lock()
-					h.createdGameEvents[eve.MatchID] = e
-					h.gmu.Unlock()
-				default:
-
-					gameID, err := types.ParseObjectId(e.GetTopic().Resource())
-					if err != nil {
-						continue
-					}
+// relayEvent publishes the consumed game or chat event to the instances that follow the game.
+// The event is consumed once by the shared consumer group, so a failed relay is retried
+// until it succeeds or the handler is stopped, the retries keep the order of the events.
+func (h *sessionsEventsHandler) relayEvent(e event.Event) {
+	delay := relayRetryDelay
+	for {
+		err := h.relay(e)
+		if err == nil {
+			return
+		}
+		h.l.Error(fmt.Sprintf("failed to relay event '%s', retrying in %s: %v", e.GetTopic(), delay, err))
 
-					if e.GetTopic().Action() == event.ActionEnded {
-						h.em.add(gameID)
-					}
+		select {
+		case <-h.stopCh:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, relayMaxRetryDelay)
+	}
+}
 
-					h.gcmu.RLock()
-					gs, ok := h.gameWithChatSubSessions[gameID]
-					h.gcmu.RUnlock()
+func (h *sessionsEventsHandler) relay(e event.Event) error {
+	if e.GetTopic().Domain() == event.DomainGame && e.GetTopic().Action() == event.ActionCreated {
+		return h.fan.publishCreated(context.Background(), e)
+	}
 
-					if ok {
-						gs.sendEvent(e)
-					}
-				}
+	gameID, err := types.ParseObjectId(e.GetTopic().Resource())
+	if err != nil {
+		return nil
+	}
 
-			case e := <-h.gameChatSub.Event():
-				gameID, err := types.ParseObjectId(e.GetTopic().Resource())
-				if err != nil {
-					continue
-				}
+	// the events that are not sent to the sessions are not relayed, an event that
+	// is already appended by a failed attempt is not published again
+	_, err = h.stream.append(context.Background(), gameID, e)
+	return err
+}
 
-				h.gcmu.RLock()
-				gs, ok := h.gameWithChatSubSessions[gameID]
-				h.gcmu.RUnlock()
+// handleGameEvent sends the relayed event to the sessions of this instance.
+func (h *sessionsEventsHandler) handleGameEvent(e event.Event, seq int64) {
+	if e.GetTopic().Domain() == event.DomainGame && e.GetTopic().Action() == event.ActionCreated {
+		eve, ok := e.(*event.EventGameCreated)
+		if !ok {
+			h.l.Warn("invalid event type for ActionCreated")
+			return
+		}
 
-				if ok {
-					gs.sendEvent(e)
-				}
-			}
+		// broadcast to all sessisons
+		h.mmu.Lock()
+		for _, s := range h.matchSubSessions[eve.MatchID] {
+			s.consume(e)
 		}
-	}()
+		h.mmu.Unlock()
+
+		// store created game events for situations where the user websocket connection
+		// request for the evnet after the event is received
+		h.gmu.Lock()
+		h.createdGameEvents[eve.MatchID] = e
+		h.gmu.Unlock()
+		return
+	}
+
+	gameID, err := types.ParseObjectId(e.GetTopic().Resource())
+	if err != nil {
+		return
+	}
+
+	if e.GetTopic().Domain() == event.DomainGame && e.GetTopic().Action() == event.ActionEnded {
+		h.em.add(gameID)
+	}
+
+	h.gcmu.RLock()
+	gs, ok := h.gameWithChatSubSessions[gameID]
+	h.gcmu.RUnlock()
+
+	if ok {
+		gs.sendEvent(gameID, seq, e)
+	}
 }
 
 func (h *sessionsEventsHandler) startCleanupRoutine() {
@@ -194,7 +242,7 @@ func (h *sessionsEventsHandler) publishGamesViewersList(
 		h.gcmu.RUnlock()
 
 		if ok {
-			gs.sendMsg(&Msg{
+			gs.sendMsg(gameId, &Msg{
 				MsgBase: MsgBase{
 					ID:        types.NewObjectId(),
 					Type:      MsgTypeViewersList,
@@ -212,6 +260,9 @@ func (h *sessionsEventsHandler) publishGamesViewersList(
 
 func (m *sessionsEventsHandler) stop() {
 	close(m.stopCh)
+	if err := m.fan.close(); err != nil {
+		m.l.Error(err.Error())
+	}
 	m.broadcastTicker.Stop()
 	m.cleanupTicker.Stop()
 }
@@ -232,7 +283,7 @@ func (h *sessionsEventsHandler) subscribeToMatch(s *session) {
 		})
 }
 
-func (m *sessionsEventsHandler) subscribeToGameWithChat(s *session, gameId types.ObjectId) {
+func (m *sessionsEventsHandler) subscribeToGameWithChat(s gameSubscriber, gameId types.ObjectId) {
 	m.gcmu.Lock()
 	defer m.gcmu.Unlock()
 
@@ -241,6 +292,7 @@ func (m *sessionsEventsHandler) subscribeToGameWithChat(s *session, gameId types
 	} else {
 		m.gameWithChatSubSessions[gameId] = newGameSubscribers()
 		m.gameWithChatSubSessions[gameId].add(s)
+		m.fan.follow(gameId)
 	}
 }
 
@@ -274,68 +326,88 @@ func (m *sessionsEventsHandler) unsubscribeFromMatch(s *session) {
 	}
 }
 
-func (m *sessionsEventsHandler) unsubscribeFromGameWithChat(s *session, gamesId ...types.ObjectId) {
-	toRemove := make([]*gameSubscribers, 0, len(gamesId))
+// unsubscribeFromGameWithChat removes the subscriber from the games,
+// this instance stops following the games that have no subscribers anymore.
+func (m *sessionsEventsHandler) unsubscribeFromGameWithChat(s gameSubscriber, gamesId ...types.ObjectId) {
+	m.gcmu.Lock()
+	defer m.gcmu.Unlock()
 
-	m.gcmu.RLock()
+	unfollow := make([]types.ObjectId, 0, len(gamesId))
 	for _, gameId := range gamesId {
 		if ss, ok := m.gameWithChatSubSessions[gameId]; ok {
-			toRemove = append(toRemove, ss)
+			if ss.remove(s) == 0 {
+				delete(m.gameWithChatSubSessions, gameId)
+				unfollow = append(unfollow, gameId)
+			}
 		}
 	}
-	m.gcmu.RUnlock()
 
-	for _, ss := range toRemove {
-		ss.remove(s)
-	}
+	m.fan.unfollow(unfollow...)
 }
 
 func (m *sessionsEventsHandler) deleteGameSubscribers(gamesId ...types.ObjectId) {
 	m.gcmu.Lock()
 	defer m.gcmu.Unlock()
+
+	unfollow := make([]types.ObjectId, 0, len(gamesId))
 	for _, gameId := range gamesId {
-		delete(m.gameWithChatSubSessions, gameId)
+		if _, ok := m.gameWithChatSubSessions[gameId]; ok {
+			delete(m.gameWithChatSubSessions, gameId)
+			unfollow = append(unfollow, gameId)
+		}
 	}
+
+	m.fan.unfollow(unfollow...)
+}
+
+// gameSubscriber receives the game and chat events of the games that it's subscribed to,
+// the subscribers are the websocket sessions and the anonymous spectators.
+type gameSubscriber interface {
+	subscriberId() types.ObjectId
+	consumeStream(e event.Event, gameId types.ObjectId, seq int64)
+	sendGameMsg(gameId types.ObjectId, msg *Msg)
 }
 
 type gameSubscribers struct {
 	sync.RWMutex
-	subscribers map[types.ObjectId]*session // map by sessionId
+	subscribers map[types.ObjectId]gameSubscriber // map by subscriberId
 }
 
 func newGameSubscribers() *gameSubscribers {
 	return &gameSubscribers{
-		subscribers: make(map[types.ObjectId]*session),
+		subscribers: make(map[types.ObjectId]gameSubscriber),
 	}
 }
 
-func (g *gameSubscribers) add(s *session) {
+func (g *gameSubscribers) add(s gameSubscriber) {
 	g.Lock()
 	defer g.Unlock()
-	g.subscribers[s.id] = s
+	g.subscribers[s.subscriberId()] = s
 }
 
-func (g *gameSubscribers) remove(s *session) {
+// remove returns the number of the remaining subscribers.
+func (g *gameSubscribers) remove(s gameSubscriber) int {
 	g.Lock()
 	defer g.Unlock()
-	delete(g.subscribers, s.id)
+	delete(g.subscribers, s.subscriberId())
+	return len(g.subscribers)
 }
 
-func (g *gameSubscribers) sendEvent(e event.Event) {
+func (g *gameSubscribers) sendEvent(gameId types.ObjectId, seq int64, e event.Event) {
 	g.RLock()
 	defer g.RUnlock()
 
 	for _, s := range g.subscribers {
-		s.consume(e)
+		s.consumeStream(e, gameId, seq)
 	}
 }
 
-func (g *gameSubscribers) sendMsg(msg *Msg) {
+func (g *gameSubscribers) sendMsg(gameId types.ObjectId, msg *Msg) {
 	g.RLock()
 	defer g.RUnlock()
 
 	for _, s := range g.subscribers {
-		s.send(msg)
+		s.sendGameMsg(gameId, msg)
 	}
 }
 
@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/event/kafka"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyGameChannelPrefix = "game_events:"
+	// the created games are published to all instances, the sessions wait for them by the match id
+	keyCreatedGamesChannel = "game_events_created"
+)
+
+func gameChannel(gameId types.ObjectId) string {
+	return keyGameChannelPrefix + gameId.String()
+}
+
+// fanoutPayload is the event in the channels as "<domain> <action> <data>",
+// the sequence number of the event is prepended to it by the publisher.
+func fanoutPayload(e event.Event) string {
+	return fmt.Sprintf("%s %s %s", e.GetTopic().Domain(), e.GetTopic().Action(), e.Encode())
+}
+
+func parseFanoutPayload(payload string) (event.Event, int64, error) {
+	parts := strings.SplitN(payload, " ", 4)
+	if len(parts) != 4 {
+		return nil, 0, fmt.Errorf("invalid fanout payload")
+	}
+
+	seq, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid fanout sequence number: %w", err)
+	}
+
+	e, err := kafka.DecodeEvent(parts[1], parts[2], []byte(parts[3]))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return e, seq, nil
+}
+
+// gameFanout relays the game and chat events between the gateway instances. Each event is consumed from kafka
+// by one instance of the shared consumer group, which sequences and publishes it to the redis channel of the game,
+// and every instance listens only to the channels of the games that its sessions follow.
+type gameFanout struct {
+	c  *redis.Client
+	ps *redis.PubSub
+	l  log.Logger
+}
+
+func newGameFanout(c *redis.Client, l log.Logger) *gameFanout {
+	return &gameFanout{
+		c:  c,
+		ps: c.Subscribe(context.Background(), keyCreatedGamesChannel),
+		l:  l,
+	}
+}
+
+// follow starts listening to the events of the game.
+func (f *gameFanout) follow(gameId types.ObjectId) {
+	if err := f.ps.Subscribe(context.Background(), gameChannel(gameId)); err != nil {
+		f.l.Error(fmt.Sprintf("failed to follow game '%s': %v", gameId, err))
+	}
+}
+
+// unfollow stops listening to the events of the games, when no session of this instance follows them.
+func (f *gameFanout) unfollow(gamesId ...types.ObjectId) {
+	if len(gamesId) == 0 {
+		return
+	}
+
+	channels := make([]string, 0, len(gamesId))
+	for _, id := range gamesId {
+		channels = append(channels, gameChannel(id))
+	}
+
+	if err := f.ps.Unsubscribe(context.Background(), channels...); err != nil {
+		f.l.Error(fmt.Sprintf("failed to unfollow games: %v", err))
+	}
+}
+
+func (f *gameFanout) publishCreated(ctx context.Context, e event.Event) error {
+	if err := f.c.Publish(ctx, keyCreatedGamesChannel, "0 "+fanoutPayload(e)).Err(); err != nil {
+		return fmt.Errorf("failed to publish created game: %w", err)
+	}
+	return nil
+}
+
+func (f *gameFanout) events() <-chan *redis.Message {
+	return f.ps.Channel()
+}
+
+func (f *gameFanout) close() error {
+	return f.ps.Close()
+}
@@ -38,6 +38,17 @@ func (m *sessionsManager) get(userId, sessId types.ObjectId) *session {
 	return nil
 }
 
+func (m *sessionsManager) getUserSessions(userId types.ObjectId) []*session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]*session, 0, len(m.sessions[userId]))
+	for _, s := range m.sessions[userId] {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
 func (m *sessionsManager) remove(userId, sessId types.ObjectId) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -3,6 +3,7 @@ package ws
 import (
 	"encoding/json"
 
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/types"
 )
 
@@ -28,15 +29,65 @@ const (
 
 	MsgTypePlayerResigned MsgType = "player_resigned"
 
+	// messages of the anonymous spectators, the game snapshot is the state that they start from
+	MsgTypeViewersCount MsgType = "viewers_count"
+	MsgTypeGameSnapshot MsgType = "game_snapshot"
+
+	// presence of the users that the client subscribes to, the changes are sent after the current presence
+	MsgTypeSubscribePresence MsgType = "subscribe_presence"
+	MsgTypePresenceChanged   MsgType = "presence_changed"
+
+	MsgTypeRematchOffered  MsgType = "rematch_offered"
+	MsgTypeRematchDeclined MsgType = "rematch_declined"
+	MsgTypeRematchAccepted MsgType = "rematch_accepted"
+
+	// an item of the inbox of the user that the notification service created
+	MsgTypeNotification MsgType = "notification"
+
 	MsgDataInternalErrorr string = "internal error"
 	MsgDataBadRequest     string = "bad request"
 	MsgDataNotFound       string = "not found"
 )
 
+// errors that are sent to the clients by MsgTypeError, the codes are the codes of the catalog of pkg/errors
+var (
+	errInvalidData       = newDataError(errs.CodeInvalidInput, "invalid data")
+	errInternal          = newDataError(errs.CodeInternalError, MsgDataInternalErrorr)
+	errNotFound          = newDataError(errs.CodeNotFound, MsgDataNotFound)
+	errRateLimited       = newDataError(errs.CodeRateLimited, "")
+	errAlreadySubscribed = newDataError(errs.CodeConflict, "already subscribed to a game")
+	errAlreadyViewing    = newDataError(errs.CodeConflict, "already subscribed to this game")
+	errViewCapReached    = newDataError(errs.CodeConflict, "view cap reached")
+	errMoveNotAllowed    = newDataError(errs.CodePermissionDenied, "not allowed to move")
+	errResignNotAllowed  = newDataError(errs.CodePermissionDenied, "not allowed to resign")
+	errSendMsgNotAllowed = newDataError(errs.CodePermissionDenied, "not allowed to send message")
+)
+
+// newDataError returns the error of the code, the message of the catalog is used if msg is empty.
+func newDataError(code, msg string) *DataError {
+	e := errs.Lookup(code)
+	if msg == "" {
+		msg = e.Message
+	}
+	return &DataError{Code: e.Code, MessageKey: e.MsgKey, Message: msg}
+}
+
+// dataErrorOf returns the error of a failed call to the other services, the internal errors are not sent as is.
+func dataErrorOf(err error) *DataError {
+	code := errs.CodeOf(err)
+	if code == errs.CodeInternalError {
+		return errInternal
+	}
+	return newDataError(code, "")
+}
+
 type MsgBase struct {
-	ID        types.ObjectId `json:"id"`
-	Type      MsgType        `json:"type"`
-	Timestamp int64          `json:"timestamp"`
+	ID   types.ObjectId `json:"id"`
+	Type MsgType        `json:"type"`
+	// Seq is the sequence number of the game and chat messages in their game,
+	// clients resume the game from the last seq they received.
+	Seq       int64 `json:"seq,omitempty"`
+	Timestamp int64 `json:"timestamp"`
 }
 
 type Msg struct {
@@ -49,56 +100,85 @@ func (m *Msg) Encode() []byte {
 	return b
 }
 
+// request is the data of the messages that the clients send.
+type request interface {
+	Validate() error
+}
+
+// decodeRequest decodes and validates the data of a request.
+func decodeRequest(data []byte, r request) *DataError {
+	if err := json.Unmarshal(data, r); err != nil {
+		return errInvalidData
+	}
+	if err := r.Validate(); err != nil {
+		return newDataError(errs.CodeValidationError, err.Error())
+	}
+	return nil
+}
+
 func (s *session) handleMsg(sess *session, msg *Msg) {
+	if !sess.allowMsg(msg.Type) {
+		sess.sendErr(msg.ID, errRateLimited)
+		return
+	}
+
 	switch msg.Type {
 	case MsgTypeFindMatch:
 		var d DataFindMatchRequest
-		if err := json.Unmarshal(msg.Data, &d); err != nil {
-			sess.sendErr(msg.ID, "invalid data")
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
 			return
 		}
 
 		sess.handleFindMatchRequest(msg.ID, d)
 	case MsgTypeResumeGame:
 		var d DataResumeGameRequest
-		if err := json.Unmarshal(msg.Data, &d); err != nil {
-			sess.sendErr(msg.ID, "invalid data")
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
 			return
 		}
 
 		sess.handleResumeGameRequest(msg.ID, d)
 	case MsgTypeViewGame:
 		var d DataGameViewRequest
-		if err := json.Unmarshal(msg.Data, &d); err != nil {
-			sess.sendErr(msg.ID, "invalid data")
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
 			return
 		}
 
 		sess.handleViewGameRequest(msg.ID, d)
 	case MsgTypePlayerMove:
 		var d DataGamePlayerMoveRequest
-		if err := json.Unmarshal(msg.Data, &d); err != nil {
-			sess.sendErr(msg.ID, "invalid data")
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
 			return
 		}
 
 		sess.handleMoveRequest(msg.ID, d)
 	case MsgTypePlayerResigned:
 		var d DataGamePlayerResignRequest
-		if err := json.Unmarshal(msg.Data, &d); err != nil {
-			sess.sendErr(msg.ID, "invalid data")
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
 			return
 		}
 
 		sess.handlePlayerResignRequest(msg.ID, d)
 	case MsgTypeChatMsgSend:
 		var d DataGameChatMsgSend
-		if err := json.Unmarshal(msg.Data, &d); err != nil {
-			sess.sendErr(msg.ID, "invalid data")
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
 			return
 		}
 
 		sess.handleSendMsg(msg.ID, d)
+	case MsgTypeSubscribePresence:
+		var d DataPresenceSubscribeRequest
+		if err := decodeRequest(msg.Data, &d); err != nil {
+			sess.sendErr(msg.ID, err)
+			return
+		}
+
+		sess.handleSubscribePresence(msg.ID, d)
 	case MsgTypeData:
 
 		// handle data message
@@ -2,11 +2,43 @@ package ws
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/alikarimi999/shahboard/event"
 	"github.com/alikarimi999/shahboard/types"
 )
 
+const (
+	// the longest move in SAN like "exd8=Q+" and UCI like "e7e8q" is shorter than this
+	maxMoveLength    = 10
+	maxChatMsgLength = 500
+)
+
+// validId reports whether the id is a valid non-zero object id.
+func validId(id types.ObjectId) bool {
+	return id.Int64() > 0
+}
+
+// DataError is the data of the error messages, Code and MessageKey are of the catalog of pkg/errors.
+// The error message has the id of the request that failed.
+type DataError struct {
+	Code       string `json:"code"`
+	MessageKey string `json:"message_key"`
+	Message    string `json:"message"`
+}
+
+func (d DataError) Type() MsgType {
+	return MsgTypeError
+}
+
+func (d DataError) Encode() []byte {
+	b, _ := json.Marshal(d)
+	return b
+}
+
 type DataFindMatchRequest struct {
 	event.EventUsersMatchCreated
 }
@@ -20,8 +52,20 @@ func (d DataFindMatchRequest) Encode() []byte {
 	return b
 }
 
+func (d DataFindMatchRequest) Validate() error {
+	if !validId(d.ID) {
+		return errors.New("invalid match id")
+	}
+	if !validId(d.User1.ID) || !validId(d.User2.ID) {
+		return errors.New("invalid match users")
+	}
+	return nil
+}
+
 type DataGameViewRequest struct {
 	GameId types.ObjectId `json:"game_id"`
+	// LastSeq is the last seq of the game that the client received, zero requests a snapshot
+	LastSeq int64 `json:"last_seq,omitempty"`
 }
 
 func (d DataGameViewRequest) Type() MsgType {
@@ -33,6 +77,16 @@ func (d DataGameViewRequest) Encode() []byte {
 	return b
 }
 
+func (d DataGameViewRequest) Validate() error {
+	if !validId(d.GameId) {
+		return errors.New("invalid game id")
+	}
+	if d.LastSeq < 0 {
+		return errors.New("invalid last seq")
+	}
+	return nil
+}
+
 type DataGamePlayerMoveRequest struct {
 	event.EventGamePlayerMoved
 }
@@ -46,6 +100,22 @@ func (d DataGamePlayerMoveRequest) Encode() []byte {
 	return b
 }
 
+func (d DataGamePlayerMoveRequest) Validate() error {
+	if !validId(d.GameID) {
+		return errors.New("invalid game id")
+	}
+	if !validId(d.PlayerID) {
+		return errors.New("invalid player id")
+	}
+	if d.Move == "" || len(d.Move) > maxMoveLength {
+		return errors.New("invalid move")
+	}
+	if d.Index < 0 {
+		return errors.New("invalid move index")
+	}
+	return nil
+}
+
 type DataGameChatMsgSend struct {
 	event.EventGameChatMsgeSent
 }
@@ -59,8 +129,26 @@ func (d DataGameChatMsgSend) Encode() []byte {
 	return b
 }
 
+func (d DataGameChatMsgSend) Validate() error {
+	if !validId(d.GameID) {
+		return errors.New("invalid game id")
+	}
+	if !validId(d.SenderID) {
+		return errors.New("invalid sender id")
+	}
+	if strings.TrimSpace(d.Content) == "" {
+		return errors.New("empty message")
+	}
+	if utf8.RuneCountInString(d.Content) > maxChatMsgLength {
+		return errors.New("message is too long")
+	}
+	return nil
+}
+
 type DataResumeGameRequest struct {
 	GameId types.ObjectId `json:"game_id"`
+	// LastSeq is the last seq of the game that the client received, zero requests a snapshot
+	LastSeq int64 `json:"last_seq,omitempty"`
 }
 
 func (m DataResumeGameRequest) Type() MsgType {
@@ -72,10 +160,24 @@ func (m DataResumeGameRequest) Encode() []byte {
 	return b
 }
 
+func (m DataResumeGameRequest) Validate() error {
+	if !validId(m.GameId) {
+		return errors.New("invalid game id")
+	}
+	if m.LastSeq < 0 {
+		return errors.New("invalid last seq")
+	}
+	return nil
+}
+
+// DataResumeGameResponse has the snapshot of the game if the client needs it, the PGN is empty when
+// the missed messages are replayed. Seq is the seq that the state is at, the replay has the messages after it.
 type DataResumeGameResponse struct {
 	GameId               types.ObjectId        `json:"game_id"`
-	Pgn                  string                `json:"pgn"`
+	Pgn                  string                `json:"pgn,omitempty"`
 	PlayersDisconnection []PlayerDisconnection `json:"players_disconnection"`
+	Seq                  int64                 `json:"seq"`
+	Replay               []*Msg                `json:"replay,omitempty"`
 }
 
 func (m DataResumeGameResponse) Type() MsgType {
@@ -89,8 +191,10 @@ func (m DataResumeGameResponse) Encode() []byte {
 
 type DataGameViewResponse struct {
 	GameId               types.ObjectId        `json:"game_id"`
-	Pgn                  string                `json:"pgn"`
+	Pgn                  string                `json:"pgn,omitempty"`
 	PlayersDisconnection []PlayerDisconnection `json:"players_disconnection"`
+	Seq                  int64                 `json:"seq"`
+	Replay               []*Msg                `json:"replay,omitempty"`
 }
 
 func (m DataGameViewResponse) Type() MsgType {
@@ -115,6 +219,35 @@ func (m DataViwersListResponse) Encode() []byte {
 	return b
 }
 
+type DataViewersCount struct {
+	GameId types.ObjectId `json:"game_id"`
+	Count  int            `json:"count"`
+}
+
+func (m DataViewersCount) Type() MsgType {
+	return MsgTypeViewersCount
+}
+
+func (m DataViewersCount) Encode() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+type DataGameSnapshot struct {
+	GameId types.ObjectId `json:"game_id"`
+	Pgn    string         `json:"pgn"`
+	Seq    int64          `json:"seq"`
+}
+
+func (m DataGameSnapshot) Type() MsgType {
+	return MsgTypeGameSnapshot
+}
+
+func (m DataGameSnapshot) Encode() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
 type DataGamePlayerResignRequest struct {
 	event.EventGamePlayerResigned
 }
@@ -127,3 +260,83 @@ func (m DataGamePlayerResignRequest) Encode() []byte {
 	b, _ := json.Marshal(m)
 	return b
 }
+
+func (m DataGamePlayerResignRequest) Validate() error {
+	if !validId(m.GameID) {
+		return errors.New("invalid game id")
+	}
+	if !validId(m.PlayerID) {
+		return errors.New("invalid player id")
+	}
+	return nil
+}
+
+// DataPresenceSubscribeRequest replaces the users that the session watches, an empty list unsubscribes.
+type DataPresenceSubscribeRequest struct {
+	UsersId []types.ObjectId `json:"user_ids"`
+}
+
+func (m DataPresenceSubscribeRequest) Type() MsgType {
+	return MsgTypeSubscribePresence
+}
+
+func (m DataPresenceSubscribeRequest) Encode() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+func (m DataPresenceSubscribeRequest) Validate() error {
+	if len(m.UsersId) > maxPresenceUsers {
+		return fmt.Errorf("at most %d users", maxPresenceUsers)
+	}
+	for _, id := range m.UsersId {
+		if !validId(id) {
+			return errors.New("invalid user id")
+		}
+	}
+	return nil
+}
+
+type DataPresenceList struct {
+	List []UserPresence `json:"list"`
+}
+
+func (m DataPresenceList) Type() MsgType {
+	return MsgTypeSubscribePresence
+}
+
+func (m DataPresenceList) Encode() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+type DataPresenceChanged struct {
+	UserPresence
+}
+
+func (m DataPresenceChanged) Type() MsgType {
+	return MsgTypePresenceChanged
+}
+
+func (m DataPresenceChanged) Encode() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+type DataNotification struct {
+	NotificationId types.ObjectId  `json:"notification_id"`
+	Kind           string          `json:"kind"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Data           json.RawMessage `json:"data,omitempty"`
+	CreatedAt      int64           `json:"created_at"`
+}
+
+func (m DataNotification) Type() MsgType {
+	return MsgTypeNotification
+}
+
+func (m DataNotification) Encode() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
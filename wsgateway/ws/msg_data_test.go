@@ -0,0 +1,72 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+func TestRequestsValidate(t *testing.T) {
+	id := types.NewObjectId()
+	user := types.User{ID: types.NewObjectId()}
+	move := func(m string, index int) request {
+		return DataGamePlayerMoveRequest{event.EventGamePlayerMoved{GameID: id, PlayerID: id, Move: m, Index: index}}
+	}
+	chat := func(content string) request {
+		return DataGameChatMsgSend{event.EventGameChatMsgeSent{GameID: id, SenderID: id, Content: content}}
+	}
+	users := func(n int) request {
+		ids := make([]types.ObjectId, n)
+		for i := range ids {
+			ids[i] = types.NewObjectId()
+		}
+		return DataPresenceSubscribeRequest{UsersId: ids}
+	}
+
+	tests := []struct {
+		name  string
+		req   request
+		valid bool
+	}{
+		{"find match", DataFindMatchRequest{event.EventUsersMatchCreated{ID: id, User1: user, User2: user}}, true},
+		{"find match without id", DataFindMatchRequest{event.EventUsersMatchCreated{User1: user, User2: user}}, false},
+		{"find match without user", DataFindMatchRequest{event.EventUsersMatchCreated{ID: id, User1: user}}, false},
+
+		{"view game", DataGameViewRequest{GameId: id, LastSeq: 5}, true},
+		{"view game without id", DataGameViewRequest{}, false},
+		{"view game with negative seq", DataGameViewRequest{GameId: id, LastSeq: -1}, false},
+
+		{"resume game", DataResumeGameRequest{GameId: id}, true},
+		{"resume game without id", DataResumeGameRequest{LastSeq: 1}, false},
+		{"resume game with negative seq", DataResumeGameRequest{GameId: id, LastSeq: -1}, false},
+
+		{"move", move("e2e4", 0), true},
+		{"empty move", move("", 0), false},
+		{"long move", move(strings.Repeat("e", maxMoveLength+1), 0), false},
+		{"negative move index", move("e2e4", -1), false},
+		{"move without player", DataGamePlayerMoveRequest{event.EventGamePlayerMoved{GameID: id, Move: "e2e4"}}, false},
+
+		{"chat", chat("hi"), true},
+		{"blank chat", chat("  \n"), false},
+		{"chat at the limit", chat(strings.Repeat("ش", maxChatMsgLength)), true},
+		{"long chat", chat(strings.Repeat("a", maxChatMsgLength+1)), false},
+
+		{"resign", DataGamePlayerResignRequest{event.EventGamePlayerResigned{GameID: id, PlayerID: id}}, true},
+		{"resign without game", DataGamePlayerResignRequest{event.EventGamePlayerResigned{PlayerID: id}}, false},
+
+		{"presence", users(maxPresenceUsers), true},
+		{"presence unsubscribe", DataPresenceSubscribeRequest{}, true},
+		{"presence over the limit", users(maxPresenceUsers + 1), false},
+		{"presence with zero id", DataPresenceSubscribeRequest{UsersId: []types.ObjectId{id, types.ObjectZero}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// handleNotificationEvents sends the notifications of the inbox to the sessions of the users in real time,
+// the notification service only publishes them for the users that are online.
+func (s *Server) handleNotificationEvents() {
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case e := <-s.notifySub.Event():
+			eve, ok := e.(*event.EventNotificationCreated)
+			if !ok {
+				continue
+			}
+
+			s.sendToUsers(&Msg{
+				MsgBase: MsgBase{
+					ID:        types.NewObjectId(),
+					Type:      MsgTypeNotification,
+					Timestamp: time.Now().Unix(),
+				},
+				Data: DataNotification{
+					NotificationId: eve.NotificationID,
+					Kind:           eve.Kind,
+					Title:          eve.Title,
+					Body:           eve.Body,
+					Data:           eve.Data,
+					CreatedAt:      eve.Timestamp,
+				}.Encode(),
+			}, eve.UserID)
+		}
+	}
+}
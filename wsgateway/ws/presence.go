@@ -0,0 +1,460 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/block"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/pkg/middleware"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPresencePrefix         = "presence:"
+	keyPresenceSessionsPrefix = "presence_sessions:"
+
+	// the sessions of the instances that are stopped without cleaning the cache are ignored after this
+	presenceTTL = 2 * time.Minute
+	// the presence of the sessions is checked by this interval, and it's written again at least by the refresh interval
+	presenceSweepInterval   = 5 * time.Second
+	presenceRefreshInterval = time.Minute
+
+	// max number of the users of a batch query or a subscription
+	maxPresenceUsers = 200
+)
+
+// updatePresenceScript writes the presence of a session, or deletes it if the value is empty, and aggregates
+// the presence of all sessions of the user. It returns the new presence of the user as "<status> <game_id>"
+// if it has changed, the stale sessions of the stopped instances are removed.
+const updatePresenceScript = `
+if ARGV[2] == '' then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+else
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local rank = {idle = 1, online = 2, spectating = 3, in_game = 4}
+
+local best = nil
+local sessions = redis.call('HGETALL', KEYS[1])
+for i = 1, #sessions, 2 do
+	local ok, p = pcall(cjson.decode, sessions[i + 1])
+	if ok and now - (tonumber(p['updated_at']) or 0) <= ttl then
+		if best == nil or (rank[p['status']] or 0) > (rank[best['status']] or 0) then
+			best = p
+		end
+	else
+		redis.call('HDEL', KEYS[1], sessions[i])
+	end
+end
+
+local old = redis.call('GET', KEYS[2])
+if best == nil then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	if old == false then
+		return false
+	end
+	return 'offline '
+end
+
+local value = best['status'] .. ' ' .. (best['game_id'] or '')
+redis.call('SET', KEYS[2], value, 'PX', ttl)
+redis.call('PEXPIRE', KEYS[1], ttl)
+if old == value then
+	return false
+end
+return value
+`
+
+// UserPresence is the presence of a user across all of its sessions, GameId is the game that the user plays.
+type UserPresence struct {
+	UserId types.ObjectId       `json:"user_id"`
+	Status types.PresenceStatus `json:"status"`
+	GameId types.ObjectId       `json:"game_id,omitempty"`
+}
+
+func parsePresence(userId types.ObjectId, value string) UserPresence {
+	status, gameId, _ := strings.Cut(value, " ")
+	if status == "" {
+		status = string(types.PresenceOffline)
+	}
+	return UserPresence{
+		UserId: userId,
+		Status: types.PresenceStatus(status),
+		GameId: types.ObjectId(gameId),
+	}
+}
+
+// sessionPresence is the presence of a session that is written to the cache.
+type sessionPresence struct {
+	Status    types.PresenceStatus `json:"status"`
+	GameId    types.ObjectId       `json:"game_id,omitempty"`
+	UpdatedAt int64                `json:"updated_at"`
+}
+
+// presence returns the presence of the session, the players and the viewers are not idle while the game is live.
+func (s *session) presence(idleTimeout time.Duration) sessionPresence {
+	if gameId := s.playGameId.Load(); !gameId.IsZero() {
+		return sessionPresence{Status: types.PresenceInGame, GameId: gameId}
+	}
+
+	s.vmu.RLock()
+	viewing := len(s.viewGamesId) > 0
+	s.vmu.RUnlock()
+	if viewing {
+		return sessionPresence{Status: types.PresenceSpectating}
+	}
+
+	if time.Since(s.lastActivity.Load()) > idleTimeout {
+		return sessionPresence{Status: types.PresenceIdle}
+	}
+
+	return sessionPresence{Status: types.PresenceOnline}
+}
+
+// presenceTracker keeps the presence of the users in the cache from the sessions of all instances,
+// publishes the changes and sends them to the sessions that watch the users.
+type presenceTracker struct {
+	c           *redis.Client
+	p           event.Publisher
+	sub         event.Subscription
+	blocks      *block.Registry
+	idleTimeout time.Duration
+
+	mu       sync.RWMutex
+	watchers map[types.ObjectId]map[types.ObjectId]*session // map by the watched userId and sessionId
+
+	l      log.Logger
+	stopCh chan struct{}
+}
+
+func newPresenceTracker(c *redis.Client, s event.Subscriber, p event.Publisher, blocks *block.Registry,
+	idleTimeout time.Duration, l log.Logger) *presenceTracker {
+	t := &presenceTracker{
+		c:           c,
+		p:           p,
+		sub:         s.Subscribe(event.TopicPresence),
+		blocks:      blocks,
+		idleTimeout: idleTimeout,
+		watchers:    make(map[types.ObjectId]map[types.ObjectId]*session),
+		l:           l,
+		stopCh:      make(chan struct{}),
+	}
+	go t.run()
+
+	return t
+}
+
+// run sends the presence changes that are published by all instances to the watchers of this instance.
+func (t *presenceTracker) run() {
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case e := <-t.sub.Event():
+			eve, ok := e.(*event.EventUserPresenceChanged)
+			if !ok {
+				continue
+			}
+
+			msg := &Msg{
+				MsgBase: MsgBase{
+					Type:      MsgTypePresenceChanged,
+					Timestamp: time.Now().Unix(),
+				},
+				Data: DataPresenceChanged{
+					UserPresence: UserPresence{UserId: eve.UserID, Status: eve.Status, GameId: eve.GameID},
+				}.Encode(),
+			}
+
+			t.mu.RLock()
+			watchers := make([]*session, 0, len(t.watchers[eve.UserID]))
+			for _, se := range t.watchers[eve.UserID] {
+				watchers = append(watchers, se)
+			}
+			t.mu.RUnlock()
+			if len(watchers) == 0 {
+				continue
+			}
+
+			// the users that blocked each other don't see the presence of the other one
+			blocked, err := t.blockedUsers(context.Background(), eve.UserID)
+			if err != nil {
+				t.l.Error(err.Error())
+				continue
+			}
+
+			for _, se := range watchers {
+				if blocked[se.userId] {
+					continue
+				}
+				// only the last presence of each user is kept in the queue
+				se.enqueue(priorityViewers, keyPresencePrefix+eve.UserID.String(), msg)
+			}
+		}
+	}
+}
+
+// update writes the presence of the session if it has changed, or if force is true.
+func (t *presenceTracker) update(ctx context.Context, se *session, force bool) {
+	se.presenceMu.Lock()
+	defer se.presenceMu.Unlock()
+
+	if se.presenceClosed {
+		return
+	}
+
+	p := se.presence(t.idleTimeout)
+	if !force && p.Status == se.lastPresence.Status && p.GameId == se.lastPresence.GameId &&
+		time.Since(se.lastPresenceAt) < presenceRefreshInterval {
+		return
+	}
+
+	p.UpdatedAt = time.Now().UnixMilli()
+	if err := t.write(ctx, se.userId, se.id, p); err != nil {
+		t.l.Error(err.Error())
+		return
+	}
+
+	se.lastPresence = p
+	se.lastPresenceAt = time.Now()
+}
+
+// remove deletes the presence of the stopped session, and its subscriptions.
+func (t *presenceTracker) remove(ctx context.Context, se *session) {
+	t.unwatch(se)
+
+	se.presenceMu.Lock()
+	defer se.presenceMu.Unlock()
+
+	se.presenceClosed = true
+	if err := t.write(ctx, se.userId, se.id, sessionPresence{}); err != nil {
+		t.l.Error(err.Error())
+	}
+}
+
+// write writes the presence of the session, the empty presence deletes it. The change of the user's presence
+// is published.
+func (t *presenceTracker) write(ctx context.Context, userId, sessionId types.ObjectId, p sessionPresence) error {
+	var value string
+	if p.Status != "" {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to serialize presence: %v", err)
+		}
+		value = string(b)
+	}
+
+	res, err := t.c.Eval(ctx, updatePresenceScript,
+		[]string{keyPresenceSessionsPrefix + userId.String(), keyPresencePrefix + userId.String()},
+		sessionId.String(), value, time.Now().UnixMilli(), presenceTTL.Milliseconds()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update presence of user '%s': %v", userId, err)
+	}
+
+	up := parsePresence(userId, res)
+	if err := t.p.Publish(event.EventUserPresenceChanged{
+		ID:        types.NewObjectId(),
+		UserID:    userId,
+		Status:    up.Status,
+		GameID:    up.GameId,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		return fmt.Errorf("failed to publish presence of user '%s': %v", userId, err)
+	}
+
+	return nil
+}
+
+// get returns the presence of the users, the users that are not in the cache are offline.
+func (t *presenceTracker) get(ctx context.Context, usersId []types.ObjectId) ([]UserPresence, error) {
+	if len(usersId) == 0 {
+		return []UserPresence{}, nil
+	}
+
+	keys := make([]string, 0, len(usersId))
+	for _, id := range usersId {
+		keys = append(keys, keyPresencePrefix+id.String())
+	}
+
+	values, err := t.c.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presence: %v", err)
+	}
+
+	list := make([]UserPresence, 0, len(usersId))
+	for i, id := range usersId {
+		v, _ := values[i].(string)
+		list = append(list, parsePresence(id, v))
+	}
+
+	return list, nil
+}
+
+// getFor returns the presence of the users to the user, the users that blocked the user or are blocked by
+// the user are offline to it.
+func (t *presenceTracker) getFor(ctx context.Context, userId types.ObjectId, usersId []types.ObjectId) ([]UserPresence, error) {
+	list, err := t.get(ctx, usersId)
+	if err != nil || len(list) == 0 {
+		return list, err
+	}
+
+	blocked, err := t.blockedUsers(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		if blocked[list[i].UserId] {
+			list[i] = parsePresence(list[i].UserId, "")
+		}
+	}
+	return list, nil
+}
+
+func (t *presenceTracker) blockedUsers(ctx context.Context, userId types.ObjectId) (map[types.ObjectId]bool, error) {
+	ids, err := t.blocks.BlockedUsers(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make(map[types.ObjectId]bool, len(ids))
+	for _, id := range ids {
+		blocked[id] = true
+	}
+	return blocked, nil
+}
+
+// watch replaces the users that the session watches.
+func (t *presenceTracker) watch(se *session, usersId []types.ObjectId) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeWatcher(se)
+	for _, id := range usersId {
+		if _, ok := t.watchers[id]; !ok {
+			t.watchers[id] = make(map[types.ObjectId]*session)
+		}
+		t.watchers[id][se.id] = se
+	}
+	se.watchUsersId = usersId
+}
+
+func (t *presenceTracker) unwatch(se *session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeWatcher(se)
+}
+
+// removeWatcher must be called with the lock held.
+func (t *presenceTracker) removeWatcher(se *session) {
+	for _, id := range se.watchUsersId {
+		if ws, ok := t.watchers[id]; ok {
+			delete(ws, se.id)
+			if len(ws) == 0 {
+				delete(t.watchers, id)
+			}
+		}
+	}
+	se.watchUsersId = nil
+}
+
+func (t *presenceTracker) stop() {
+	close(t.stopCh)
+}
+
+// trackPresence checks the presence of the sessions of this instance, the changes by the game events,
+// the viewed games and the activity of the clients are written by the sweep interval.
+func (s *Server) trackPresence() {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			for _, se := range s.sm.getAll() {
+				s.presence.update(context.Background(), se, false)
+			}
+		}
+	}
+}
+
+// GetUsersPresence returns the presence of the users.
+func (s *Server) GetUsersPresence(ctx context.Context, usersId []types.ObjectId) ([]UserPresence, error) {
+	return s.presence.get(ctx, usersId)
+}
+
+type PresenceResponse struct {
+	List []UserPresence `json:"list"`
+}
+
+// handlePresence returns the presence of the users of the user_ids query to the authenticated user,
+// it's a comma separated list.
+func (s *Server) handlePresence(ctx *gin.Context) {
+	var usersId []types.ObjectId
+	for _, v := range strings.Split(ctx.Query("user_ids"), ",") {
+		if v == "" {
+			continue
+		}
+		id, err := types.ParseObjectId(v)
+		if err != nil {
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid user id"))
+			return
+		}
+		usersId = append(usersId, id)
+	}
+
+	if len(usersId) > maxPresenceUsers {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, fmt.Sprintf("at most %d users", maxPresenceUsers)))
+		return
+	}
+
+	user, _ := middleware.ExtractUser(ctx)
+	list, err := s.presence.getFor(ctx.Request.Context(), user.ID, usersId)
+	if err != nil {
+		s.l.Error(err.Error())
+		ctx.JSON(errs.HTTP(errs.CodeInternalError, MsgDataInternalErrorr))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, PresenceResponse{List: list})
+}
+
+func (s *session) handleSubscribePresence(msgId types.ObjectId, req DataPresenceSubscribeRequest) {
+	s.presenceTracker.watch(s, req.UsersId)
+
+	list, err := s.presenceTracker.getFor(context.Background(), s.userId, req.UsersId)
+	if err != nil {
+		s.l.Error(err.Error())
+		s.sendErr(msgId, errInternal)
+		return
+	}
+
+	s.send(&Msg{
+		MsgBase: MsgBase{
+			ID:        msgId,
+			Type:      MsgTypeSubscribePresence,
+			Timestamp: time.Now().Unix(),
+		},
+		Data: DataPresenceList{List: list}.Encode(),
+	})
+}
@@ -0,0 +1,133 @@
+package ws
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// msgPriority is the class of a message in the send queue of a session, the lower values are sent first.
+type msgPriority int
+
+const (
+	// messages of the session's own game, the responses and the errors
+	priorityGame msgPriority = iota
+	// chat of the session's own game
+	priorityChat
+	// messages of the games that the session views
+	prioritySpectate
+	// viewers lists, only the last list of each game is kept
+	priorityViewers
+	priorityCount
+)
+
+var priorityNames = [priorityCount]string{"game", "chat", "spectate", "viewers"}
+
+// max number of the queued messages of each class
+var queueLimits = [priorityCount]int{256, 64, 64, 16}
+
+// sessions that stay over the limits of the queue for this long are disconnected
+const slowConsumerTimeout = 10 * time.Second
+
+// metrics of the send queues, they're served at /debug/vars
+var (
+	metricDroppedMsgs      = expvar.NewMap("wsgateway_dropped_messages")
+	metricCoalescedMsgs    = expvar.NewInt("wsgateway_coalesced_messages")
+	metricSlowDisconnected = expvar.NewInt("wsgateway_slow_consumers_disconnected")
+)
+
+type queuedMsg struct {
+	key string
+	b   []byte
+}
+
+// sendQueue is the bounded queue of the encoded messages of a session. A message with a key replaces
+// the queued message of its class with the same key (e.g. the viewers list or the snapshot of a game), the oldest message of a full class is dropped,
+// and the session must be disconnected if its own game's class is full or it stays over the limits.
+type sendQueue struct {
+	mu   sync.Mutex
+	msgs [priorityCount][]queuedMsg
+	// when a class of the queue got full, zero if all classes are under their limits
+	overSince time.Time
+
+	notify chan struct{}
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push returns false if the session is too slow and must be disconnected.
+func (q *sendQueue) push(p msgPriority, key string, b []byte) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// the queued message is removed and the new one is added to the end, so a snapshot
+	// is sent after the messages of its game that were queued before it
+	if key != "" {
+		for i := range q.msgs[p] {
+			if q.msgs[p][i].key == key {
+				q.msgs[p] = append(q.msgs[p][:i], q.msgs[p][i+1:]...)
+				metricCoalescedMsgs.Add(1)
+				break
+			}
+		}
+	}
+
+	if len(q.msgs[p]) >= queueLimits[p] {
+		metricDroppedMsgs.Add(priorityNames[p], 1)
+		if p == priorityGame {
+			return false
+		}
+
+		if q.overSince.IsZero() {
+			q.overSince = time.Now()
+		} else if time.Since(q.overSince) > slowConsumerTimeout {
+			return false
+		}
+
+		q.msgs[p][0] = queuedMsg{}
+		q.msgs[p] = q.msgs[p][1:]
+	}
+	q.msgs[p] = append(q.msgs[p], queuedMsg{key: key, b: b})
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop returns the next message of the highest priority.
+func (q *sendQueue) pop() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for p := range q.msgs {
+		if len(q.msgs[p]) == 0 {
+			continue
+		}
+
+		m := q.msgs[p][0]
+		q.msgs[p][0] = queuedMsg{}
+		q.msgs[p] = q.msgs[p][1:]
+
+		if !q.overSince.IsZero() && q.underLimits() {
+			q.overSince = time.Time{}
+		}
+		return m.b, true
+	}
+
+	return nil, false
+}
+
+func (q *sendQueue) underLimits() bool {
+	for p := range q.msgs {
+		if len(q.msgs[p]) >= queueLimits[p] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package ws
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func popAll(q *sendQueue) []string {
+	var msgs []string
+	for {
+		b, ok := q.pop()
+		if !ok {
+			return msgs
+		}
+		msgs = append(msgs, string(b))
+	}
+}
+
+func TestSendQueuePriority(t *testing.T) {
+	q := newSendQueue()
+	q.push(priorityViewers, "", []byte("viewers"))
+	q.push(prioritySpectate, "", []byte("spectate"))
+	q.push(priorityChat, "", []byte("chat"))
+	q.push(priorityGame, "", []byte("game 1"))
+	q.push(priorityGame, "", []byte("game 2"))
+
+	expected := []string{"game 1", "game 2", "chat", "spectate", "viewers"}
+	got := popAll(q)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestSendQueueCoalesce(t *testing.T) {
+	q := newSendQueue()
+	q.push(prioritySpectate, "game", []byte("snapshot 1"))
+	q.push(prioritySpectate, "", []byte("move"))
+	q.push(prioritySpectate, "game", []byte("snapshot 2"))
+	q.push(prioritySpectate, "other", []byte("other snapshot"))
+
+	// the newer snapshot is sent after the messages that were queued before it
+	expected := []string{"move", "snapshot 2", "other snapshot"}
+	got := popAll(q)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestSendQueueDropOldest(t *testing.T) {
+	q := newSendQueue()
+	limit := queueLimits[prioritySpectate]
+	for i := 0; i < limit+2; i++ {
+		if !q.push(prioritySpectate, "", []byte(fmt.Sprint(i))) {
+			t.Fatalf("session is disconnected by the message %d", i)
+		}
+	}
+
+	got := popAll(q)
+	if len(got) != limit {
+		t.Fatalf("expected %d messages, got %d", limit, len(got))
+	}
+	if got[0] != "2" || got[limit-1] != fmt.Sprint(limit+1) {
+		t.Errorf("expected the messages 2 to %d, got %s to %s", limit+1, got[0], got[limit-1])
+	}
+	if !q.overSince.IsZero() {
+		t.Error("queue is still over the limits after it's drained")
+	}
+}
+
+func TestSendQueueOwnGameFull(t *testing.T) {
+	q := newSendQueue()
+	for i := 0; i < queueLimits[priorityGame]; i++ {
+		if !q.push(priorityGame, "", []byte("move")) {
+			t.Fatalf("session is disconnected before the queue is full")
+		}
+	}
+
+	if q.push(priorityGame, "", []byte("move")) {
+		t.Error("session isn't disconnected when its own game is full")
+	}
+}
+
+func TestSendQueueSlowConsumer(t *testing.T) {
+	q := newSendQueue()
+	for i := 0; i < queueLimits[priorityChat]+1; i++ {
+		q.push(priorityChat, "", []byte("chat"))
+	}
+	if q.overSince.IsZero() {
+		t.Fatal("queue over the limits isn't marked")
+	}
+
+	if !q.push(priorityChat, "", []byte("chat")) {
+		t.Fatal("session is disconnected before the timeout")
+	}
+
+	q.overSince = time.Now().Add(-slowConsumerTimeout - time.Second)
+	if q.push(priorityChat, "", []byte("chat")) {
+		t.Error("session isn't disconnected after staying over the limits")
+	}
+}
@@ -0,0 +1,159 @@
+package ws
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alikarimi999/shahboard/pkg/log"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyMsgRatePrefix = "ws_rate:"
+	// the reader of the session waits for the bucket of the user at most this long,
+	// the message is allowed if redis doesn't answer in time
+	userRateTimeout = 200 * time.Millisecond
+)
+
+// MsgRateLimit is the token bucket of a message type, Rate is the number of the messages per second and Burst
+// is the size of the bucket. UserRate and UserBurst are the bucket that is shared by all sessions of a user.
+type MsgRateLimit struct {
+	Rate      float64 `json:"rate"`
+	Burst     int     `json:"burst"`
+	UserRate  float64 `json:"user_rate"`
+	UserBurst int     `json:"user_burst"`
+}
+
+var defaultRateLimits = map[MsgType]MsgRateLimit{
+	MsgTypeFindMatch:      {Rate: 0.2, Burst: 2, UserRate: 0.5, UserBurst: 3},
+	MsgTypeResumeGame:     {Rate: 1, Burst: 5, UserRate: 2, UserBurst: 10},
+	MsgTypeViewGame:       {Rate: 2, Burst: 10, UserRate: 4, UserBurst: 20},
+	MsgTypePlayerMove:     {Rate: 5, Burst: 10, UserRate: 8, UserBurst: 15},
+	MsgTypePlayerResigned: {Rate: 0.2, Burst: 2, UserRate: 0.5, UserBurst: 3},
+	MsgTypeChatMsgSend:    {Rate: 1, Burst: 5, UserRate: 2, UserBurst: 8},
+
+	MsgTypeSubscribePresence: {Rate: 0.5, Burst: 3, UserRate: 1, UserBurst: 5},
+}
+
+var metricRateLimitedMsgs = expvar.NewMap("wsgateway_rate_limited_messages")
+
+// userRateScript takes a token from the bucket of the user and returns 1, or 0 if the bucket is empty.
+// The clock of redis is used so the instances of the gateway share the same bucket.
+const userRateScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return allowed
+`
+
+// tokenBucket is the in-memory bucket of a session.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// msgRateLimiter limits the messages that the clients send per session and per user, the types without a limit are not limited.
+type msgRateLimiter struct {
+	c      *redis.Client
+	limits map[MsgType]MsgRateLimit
+	l      log.Logger
+}
+
+func newMsgRateLimiter(c *redis.Client, limits map[MsgType]MsgRateLimit, l log.Logger) *msgRateLimiter {
+	return &msgRateLimiter{
+		c:      c,
+		limits: limits,
+		l:      l,
+	}
+}
+
+// sessionBuckets returns the buckets of a new session, they're used only by the reader of the session.
+func (r *msgRateLimiter) sessionBuckets() map[MsgType]*tokenBucket {
+	buckets := make(map[MsgType]*tokenBucket, len(r.limits))
+	for t, l := range r.limits {
+		if l.Rate > 0 && l.Burst > 0 {
+			buckets[t] = newTokenBucket(l.Rate, l.Burst)
+		}
+	}
+	return buckets
+}
+
+// allowUser takes a token from the bucket of the user, the messages are not blocked by the cache errors.
+func (r *msgRateLimiter) allowUser(ctx context.Context, userId types.ObjectId, t MsgType) bool {
+	l, ok := r.limits[t]
+	if !ok || l.UserRate <= 0 || l.UserBurst <= 0 {
+		return true
+	}
+
+	allowed, err := r.c.Eval(ctx, userRateScript, []string{fmt.Sprintf("%s%s:%s", keyMsgRatePrefix, userId, t)},
+		l.UserRate, l.UserBurst).Int()
+	if err != nil {
+		r.l.Error(fmt.Sprintf("failed to check rate limit of user '%s': %v", userId, err))
+		return true
+	}
+
+	return allowed == 1
+}
+
+// allowMsg reports whether the session may send a message of the type.
+func (s *session) allowMsg(t MsgType) bool {
+	if b, ok := s.buckets[t]; ok && !b.allow(time.Now()) {
+		metricRateLimitedMsgs.Add(string(t), 1)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), userRateTimeout)
+	defer cancel()
+
+	if !s.limiter.allowUser(ctx, s.userId, t) {
+		metricRateLimitedMsgs.Add(string(t), 1)
+		return false
+	}
+
+	return true
+}
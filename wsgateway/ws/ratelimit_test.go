@@ -0,0 +1,50 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	start := time.Now()
+	b := &tokenBucket{rate: 2, burst: 3, tokens: 3, last: start}
+
+	for i := 0; i < 3; i++ {
+		if !b.allow(start) {
+			t.Fatalf("message %d of the burst is not allowed", i)
+		}
+	}
+	if b.allow(start) {
+		t.Fatal("message over the burst is allowed")
+	}
+
+	// 2 messages per second, one token after half a second
+	if b.allow(start.Add(400 * time.Millisecond)) {
+		t.Error("message is allowed before a token is refilled")
+	}
+	if !b.allow(start.Add(500 * time.Millisecond)) {
+		t.Error("message is not allowed after a token is refilled")
+	}
+
+	// the bucket is not refilled over the burst
+	later := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !b.allow(later) {
+			t.Fatalf("message %d of the refilled burst is not allowed", i)
+		}
+	}
+	if b.allow(later) {
+		t.Error("bucket is refilled over the burst")
+	}
+}
+
+func TestNewTokenBucketIsFull(t *testing.T) {
+	b := newTokenBucket(0.2, 2)
+	now := time.Now()
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatal("new bucket is not full")
+	}
+	if b.allow(now) {
+		t.Error("message over the burst is allowed")
+	}
+}
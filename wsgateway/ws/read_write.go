@@ -1,10 +1,10 @@
 package ws
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/alikarimi999/shahboard/types"
 	"github.com/gorilla/websocket"
 )
 
@@ -28,12 +28,14 @@ func (s *session) readLoop() {
 			continue
 		}
 
-		if mt == websocket.TextMessage && len(recievedMsg) > 0 {
-			var msg Msg
-			if err := json.Unmarshal(recievedMsg, &msg); err != nil {
+		if mt == s.codec.frame() && len(recievedMsg) > 0 {
+			msg, err := s.codec.decode(recievedMsg)
+			if err != nil {
+				s.sendErr(types.ObjectZero, errInvalidData)
 				continue
 			}
-			s.handleMsg(s, &msg)
+			s.lastActivity.Store(time.Now())
+			s.handleMsg(s, msg)
 		}
 	}
 }
@@ -48,15 +50,18 @@ func (s *session) writeLoop() {
 		select {
 		case <-s.stopCh:
 			return
-		case message := <-s.msgCh:
-			if message == nil {
-				return
-			}
+		case <-s.queue.notify:
+			for {
+				message, ok := s.queue.pop()
+				if !ok {
+					break
+				}
 
-			if err := s.WriteMessage(websocket.TextMessage, message); err != nil {
-				// s.l.Debug(fmt.Sprintf("session '%s' write message error: %v", se.id, err))
-				s.Stop()
-				return
+				if err := s.WriteMessage(s.codec.frame(), message); err != nil {
+					// s.l.Debug(fmt.Sprintf("session '%s' write message error: %v", se.id, err))
+					s.Stop()
+					return
+				}
 			}
 		case <-s.pongCh:
 			if err := s.WriteMessage(websocket.BinaryMessage, []byte{0x1}); err != nil {
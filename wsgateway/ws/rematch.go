@@ -0,0 +1,80 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+)
+
+// handleMatchEvents relays the rematch offers to the sessions of the players, and subscribes
+// the sessions to the match of the accepted rematches.
+func (s *Server) handleMatchEvents() {
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case e := <-s.matchSub.Event():
+			switch eve := e.(type) {
+			case *event.EventRematchOffered:
+				s.sendToUsers(&Msg{
+					MsgBase: MsgBase{
+						ID:        types.NewObjectId(),
+						Type:      MsgTypeRematchOffered,
+						Timestamp: time.Now().Unix(),
+					},
+					Data: eve.Encode(),
+				}, eve.From, eve.To)
+			case *event.EventRematchDeclined:
+				s.sendToUsers(&Msg{
+					MsgBase: MsgBase{
+						ID:        types.NewObjectId(),
+						Type:      MsgTypeRematchDeclined,
+						Timestamp: time.Now().Unix(),
+					},
+					Data: eve.Encode(),
+				}, eve.By, eve.To)
+			case *event.EventUsersMatchCreated:
+				if eve.RematchOf.IsZero() {
+					continue
+				}
+				for _, userId := range []types.ObjectId{eve.User1.ID, eve.User2.ID} {
+					for _, se := range s.sm.getUserSessions(userId) {
+						se.joinRematch(eve)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) sendToUsers(msg *Msg, usersId ...types.ObjectId) {
+	for _, userId := range usersId {
+		for _, se := range s.sm.getUserSessions(userId) {
+			se.send(msg)
+		}
+	}
+}
+
+// joinRematch subscribes the session to the match of the accepted rematch, so the created game
+// is delivered like the games that are found by find_match. Sessions that are in a game are skipped.
+func (s *session) joinRematch(e *event.EventUsersMatchCreated) {
+	if !s.playGameId.Load().IsZero() {
+		return
+	}
+
+	if !s.matchId.Load().IsZero() {
+		s.h.unsubscribeFromMatch(s)
+	}
+	s.matchId.Store(e.ID)
+	s.h.subscribeToMatch(s)
+
+	s.send(&Msg{
+		MsgBase: MsgBase{
+			ID:        types.NewObjectId(),
+			Type:      MsgTypeRematchAccepted,
+			Timestamp: time.Now().Unix(),
+		},
+		Data: e.Encode(),
+	})
+}
@@ -2,11 +2,14 @@ package ws
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/pkg/block"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
 	"github.com/alikarimi999/shahboard/pkg/jwt"
 	"github.com/alikarimi999/shahboard/pkg/log"
 	"github.com/alikarimi999/shahboard/pkg/middleware"
@@ -20,6 +23,8 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
+	// the binary subprotocols are preferred by the clients that support them
+	Subprotocols: []string{SubprotocolProto, SubprotocolMsgpack, SubprotocolJSON},
 }
 
 // Server is an implementation of entity.StreamIn
@@ -29,10 +34,17 @@ type Server struct {
 	sm *sessionsManager
 	em *endedGamesList
 
-	h *sessionsEventsHandler
+	h         *sessionsEventsHandler
+	userSub   event.Subscription
+	matchSub  event.Subscription
+	notifySub event.Subscription
 
-	p     event.Publisher
-	cache *redisCache
+	p        event.Publisher
+	game     GameService
+	cache    *redisCache
+	stream   *gameStream
+	limiter  *msgRateLimiter
+	presence *presenceTracker
 
 	cleaner *sessionCleaner
 
@@ -42,20 +54,61 @@ type Server struct {
 	stopCh chan struct{}
 }
 
-func NewServer(e *gin.Engine, s event.Subscriber, p event.Publisher, game GameService,
-	cfg *WsConfigs, c *redis.Client, v *jwt.Validator, l log.Logger) (*Server, error) {
+// NewServer creates the websocket server, the game and chat events are consumed by the shared subscriber
+// whose consumer group is shared by all instances of the gateway.
+func NewServer(e *gin.Engine, s, shared event.Subscriber, p event.Publisher, game GameService,
+	cfg *WsConfigs, c *redis.Client, v *jwt.Validator, blocks *block.Registry, l log.Logger) (*Server, error) {
 	if cfg == nil {
 		cfg = defaultConfigs
 	}
 
+	if cfg.StreamBufferSize <= 0 {
+		cfg.StreamBufferSize = defaultStreamBufferSize
+	}
+	if cfg.StreamTTL <= 0 {
+		cfg.StreamTTL = defaultStreamTTL
+	}
+	if cfg.SpectateRateLimit <= 0 {
+		cfg.SpectateRateLimit = defaultSpectateRateLimit
+	}
+	if cfg.SpectateStreamsPerIP <= 0 {
+		cfg.SpectateStreamsPerIP = defaultSpectateStreams
+	}
+	if cfg.LongPollTimeout <= 0 {
+		cfg.LongPollTimeout = defaultLongPollTimeout
+	}
+	if cfg.PresenceIdleTimeout <= 0 {
+		cfg.PresenceIdleTimeout = defaultPresenceIdle
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+
+	// the configured limits replace the default limits of their types
+	rateLimits := make(map[MsgType]MsgRateLimit, len(defaultRateLimits))
+	for t, l := range defaultRateLimits {
+		rateLimits[t] = l
+	}
+	for t, l := range cfg.RateLimits {
+		rateLimits[t] = l
+	}
+
 	em := newEndedGamesList()
+	stream := newGameStream(c, cfg.StreamBufferSize, time.Duration(cfg.StreamTTL)*time.Second)
 	server := &Server{
 		cfg:          cfg,
 		cache:        newRedisCache(c, cfg.UserSessionsCap, l),
+		stream:       stream,
+		limiter:      newMsgRateLimiter(c, rateLimits, l),
+		presence:     newPresenceTracker(c, s, p, blocks, time.Duration(cfg.PresenceIdleTimeout)*time.Second, l),
 		sm:           newSessionsManager(),
 		em:           em,
-		h:            newSessionsEventsHandler(s, em, l),
+		h:            newSessionsEventsHandler(s, shared, newGameFanout(c, l), em, stream, l),
+		userSub:      s.Subscribe(event.TopicUser),
+		matchSub:     s.Subscribe(event.TopicMatch),
+		notifySub:    s.Subscribe(event.TopicNotificationCreated),
 		p:            p,
+		game:         game,
 		jwtValidator: v,
 		l:            l,
 		stopCh:       make(chan struct{}),
@@ -65,22 +118,39 @@ func NewServer(e *gin.Engine, s event.Subscriber, p event.Publisher, game GameSe
 	server.cleaner = newSessionCleaner(server, 10, 500*time.Millisecond)
 
 	go server.manageSessionsState()
+	go server.handleUserEvents()
+	go server.handleMatchEvents()
+	go server.handleNotificationEvents()
+	go server.trackPresence()
+
+	// metrics of the send queues of the sessions, only for the admins
+	e.GET("/debug/vars", middleware.ParsUserHeader(v), middleware.RequireRole(types.RoleAdmin),
+		gin.WrapH(expvar.Handler()))
+
+	// presence of the users, the changes are sent over the websocket
+	e.GET("/presence", middleware.ParsUserHeader(v), server.handlePresence)
+
+	// anonymous read-only transports for the spectators
+	e.GET("/games/:id/events", server.limitSpectators, server.limitSpectateStreams, server.handleSpectateEvents)
+	e.GET("/games/:id/poll", server.limitSpectators, server.handleSpectatePoll)
 
 	e.GET("/ws", middleware.ParseQueryToken(v), func(ctx *gin.Context) {
-		user, ok := middleware.ExtractUser(ctx)
+		claims, ok := middleware.ExtractClaims(ctx)
 		if !ok {
-			ctx.JSON(400, gin.H{"error": "failed to parse token"})
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "failed to parse token"))
 			ctx.Abort()
 			return
 		}
 
 		conn, er := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
 		if er != nil {
-			ctx.JSON(400, gin.H{"error": "failed to upgrade connection"})
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "failed to upgrade connection"))
 			ctx.Abort()
 			return
 		}
+		conn.SetReadLimit(cfg.MaxMessageSize)
 
+		user := claims.User
 		id := types.NewObjectId()
 		success, err := server.cache.addUserSessionId(context.Background(), user.ID, id)
 		if err != nil {
@@ -96,9 +166,10 @@ func NewServer(e *gin.Engine, s event.Subscriber, p event.Publisher, game GameSe
 			return
 		}
 
-		sess := newSession(id, conn, server.h, server.cache, user.ID, user.IsGuest,
-			types.ObjectZero, p, game, l, server.sessionCleanUp)
+		sess := newSession(id, conn, getCodec(conn.Subprotocol()), server.h, server.cache, server.stream, server.limiter,
+			server.presence, user.ID, user.IsGuest, claims.SessionId, types.ObjectZero, p, game, l, server.sessionCleanUp)
 		server.sm.add(sess)
+		server.presence.update(context.Background(), sess, true)
 
 		// go server.sessionReader(sess)
 		// go server.sessionWriter(sess)
@@ -110,6 +181,42 @@ func NewServer(e *gin.Engine, s event.Subscriber, p event.Publisher, game GameSe
 	return server, nil
 }
 
+// handleUserEvents closes the sessions that are authenticated by a revoked login session,
+// and all sessions of the users whose account is blocked by a sanction.
+func (s *Server) handleUserEvents() {
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case e := <-s.userSub.Event():
+			switch eve := e.(type) {
+			case *event.EventUserLoggedOut:
+				for _, se := range s.sm.getUserSessions(eve.UserID) {
+					if !eve.SessionID.IsZero() && se.authSessionId != eve.SessionID {
+						continue
+					}
+					s.closeSession(se, "session revoked")
+					s.l.Debug(fmt.Sprintf("session '%s' closed, user '%s' logged out", se.id, se.userId))
+				}
+			case *event.EventUserSanctioned:
+				if !eve.Type.BlocksAccount() {
+					continue
+				}
+				for _, se := range s.sm.getUserSessions(eve.UserID) {
+					s.closeSession(se, fmt.Sprintf("account %s", eve.Type))
+					s.l.Debug(fmt.Sprintf("session '%s' closed, user '%s' got a '%s'", se.id, se.userId, eve.Type))
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) closeSession(se *session, reason string) {
+	se.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(time.Second))
+	se.Stop()
+}
+
 func (s *Server) sessionCleanUp(sess *session) {
 	s.cleaner.clean(sess)
 }
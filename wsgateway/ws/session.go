@@ -15,6 +15,11 @@ import (
 
 const (
 	defaultViewGamesCap = 5
+
+	// the events of the session's own game wait this long for a full events channel,
+	// then the session is disconnected as a slow consumer
+	sessionEventsSize    = 64
+	sessionEventsTimeout = time.Second
 )
 
 // TODO: implement session in a separate package for better modularity
@@ -23,10 +28,17 @@ type session struct {
 	id      types.ObjectId
 	userId  types.ObjectId
 	isGuest bool
+	// login session of the token that the connection is authenticated with
+	authSessionId types.ObjectId
+
+	// codec of the subprotocol that is negotiated by the client
+	codec msgCodec
 
-	eventCh chan event.Event
-	msgCh   chan []byte
+	eventCh chan streamEvent
+	queue   *sendQueue
 	pongCh  chan struct{}
+	// set when the session is disconnected for being too slow
+	slow *atomic.Bool
 
 	// These variables may be updated multiple times during the session's lifetime.
 	// Access must be concurrency-safe to prevent race conditions.
@@ -39,6 +51,27 @@ type session struct {
 
 	rc *redisCache
 
+	stream *gameStream
+
+	// limiter limits the messages of the user, the buckets of the session are used only by readLoop
+	limiter *msgRateLimiter
+	buckets map[MsgType]*tokenBucket
+
+	presenceTracker *presenceTracker
+	// last message that is received from the client, the sessions without activity are idle
+	lastActivity *atomic.Time
+	// presence that is written to the cache for the session, guarded by presenceMu
+	presenceMu     sync.Mutex
+	lastPresence   sessionPresence
+	lastPresenceAt time.Time
+	presenceClosed bool
+	// users whose presence the session watches, guarded by the lock of the presence tracker
+	watchUsersId []types.ObjectId
+
+	// last sequence number that is sent of each game, guarded by streamMu
+	streamMu  sync.Mutex
+	streamSeq map[types.ObjectId]int64
+
 	h             *sessionsEventsHandler
 	lastHeartBeat *atomic.Time
 
@@ -53,28 +86,38 @@ type session struct {
 	stopCh  chan struct{}
 }
 
-func newSession(id types.ObjectId, conn *websocket.Conn, h *sessionsEventsHandler, rc *redisCache,
-	userId types.ObjectId, isGuest bool, gameId types.ObjectId, p event.Publisher,
+func newSession(id types.ObjectId, conn *websocket.Conn, c msgCodec, h *sessionsEventsHandler, rc *redisCache, stream *gameStream,
+	limiter *msgRateLimiter, presence *presenceTracker, userId types.ObjectId, isGuest bool, authSessionId types.ObjectId, gameId types.ObjectId, p event.Publisher,
 	game GameService, l log.Logger, cleanUP func(*session)) *session {
 	s := &session{
-		Conn:    conn,
-		id:      id,
-		userId:  userId,
-		isGuest: isGuest,
+		Conn:          conn,
+		id:            id,
+		userId:        userId,
+		isGuest:       isGuest,
+		authSessionId: authSessionId,
 
 		matchId:    types.NewAtomicObjectId(types.ObjectZero),
 		playGameId: types.NewAtomicObjectId(gameId),
 
-		eventCh: make(chan event.Event, 10),
-		msgCh:   make(chan []byte, 10),
+		codec:   c,
+		eventCh: make(chan streamEvent, sessionEventsSize),
+		queue:   newSendQueue(),
 		pongCh:  make(chan struct{}),
+		slow:    atomic.NewBool(false),
 
 		viewGamesId: make(map[types.ObjectId]struct{}),
 		viewCaps:    defaultViewGamesCap,
 
-		rc:            rc,
-		h:             h,
-		lastHeartBeat: atomic.NewTime(time.Now()),
+		rc:        rc,
+		stream:    stream,
+		streamSeq: make(map[types.ObjectId]int64),
+		limiter:   limiter,
+		buckets:   limiter.sessionBuckets(),
+
+		presenceTracker: presence,
+		lastActivity:    atomic.NewTime(time.Now()),
+		h:               h,
+		lastHeartBeat:   atomic.NewTime(time.Now()),
 
 		p: p,
 		l: l,
@@ -97,14 +140,51 @@ func (s *session) start() {
 	s.sendWelcome()
 }
 
+// streamEvent is an event of a game with its sequence number in the game, zero if it's not sequenced.
+type streamEvent struct {
+	event.Event
+	gameId types.ObjectId
+	seq    int64
+}
+
+func (s *session) subscriberId() types.ObjectId {
+	return s.id
+}
+
 func (s *session) consume(e event.Event) {
+	s.consumeStream(e, types.ObjectZero, 0)
+}
+
+func (s *session) consumeStream(e event.Event, gameId types.ObjectId, seq int64) {
 	defer func() {
 		if r := recover(); r != nil {
 			s.l.Warn(fmt.Sprintf("session '%s' consume(e event.Event) panicked: %v", s.id, r))
 		}
 	}()
 
-	s.eventCh <- e
+	se := streamEvent{Event: e, gameId: gameId, seq: seq}
+
+	select {
+	case s.eventCh <- se:
+		return
+	default:
+	}
+
+	// updates of the spectated games are dropped before the player's own game blocks the fanout,
+	// the client finds the gap by the seq and resyncs the game.
+	if seq > 0 && gameId != s.playGameId.Load() && e.GetTopic().Action() != event.ActionEnded {
+		metricDroppedMsgs.Add(priorityNames[prioritySpectate], 1)
+		return
+	}
+
+	t := time.NewTimer(sessionEventsTimeout)
+	defer t.Stop()
+
+	select {
+	case s.eventCh <- se:
+	case <-t.C:
+		s.closeSlow()
+	}
 }
 
 func (s *session) handleEvent() {
@@ -114,17 +194,31 @@ func (s *session) handleEvent() {
 		select {
 		case <-s.stopCh:
 			return
-		case e := <-s.eventCh:
+		case se := <-s.eventCh:
+			// the class is decided before the event changes the session's game
+			p := prioritySpectate
+			if se.gameId.IsZero() || se.gameId == s.playGameId.Load() {
+				p = priorityGame
+				if se.GetTopic().Domain() == event.DomainGameChat {
+					p = priorityChat
+				}
+			}
+
 			var msg *Msg
-			switch e.GetTopic().Domain() {
+			switch se.GetTopic().Domain() {
 			case event.DomainGame:
-				msg = s.handleGameEvent(e)
+				msg = s.handleGameEvent(se.Event)
 			case event.DomainGameChat:
-				msg = s.handleGameChatEvent(e)
+				msg = s.handleGameChatEvent(se.Event)
 			}
 
-			if msg != nil {
-				s.send(msg)
+			if msg == nil {
+				continue
+			}
+			if se.seq > 0 {
+				s.sendStream(se.gameId, se.seq, p, msg)
+			} else {
+				s.enqueue(p, "", msg)
 			}
 		}
 	}
@@ -147,7 +241,7 @@ func (s *session) handleGameEvent(e event.Event) *Msg {
 					Type:      MsgTypeError,
 					Timestamp: time.Now().Unix(),
 				},
-				Data: []byte(MsgDataInternalErrorr),
+				Data: errInternal.Encode(),
 			}
 		}
 
@@ -227,9 +321,9 @@ func (s *session) handleGameChatEvent(e event.Event) *Msg {
 }
 
 func (s *session) handleFindMatchRequest(msgId types.ObjectId, data DataFindMatchRequest) {
-	var errMsg string
+	var errMsg *DataError
 	defer func() {
-		if errMsg != "" {
+		if errMsg != nil {
 			s.sendErr(msgId, errMsg)
 		}
 	}()
@@ -246,19 +340,14 @@ func (s *session) handleFindMatchRequest(msgId types.ObjectId, data DataFindMatc
 		return
 	}
 
-	errMsg = "already subscribed to a game"
+	errMsg = errAlreadySubscribed
 }
 
 func (s *session) handleResumeGameRequest(msgId types.ObjectId, req DataResumeGameRequest) {
-	var errMsg string
-	var msg *Msg
+	var errMsg *DataError
 	defer func() {
-		if errMsg != "" {
+		if errMsg != nil {
 			s.sendErr(msgId, errMsg)
-			return
-		}
-		if msg != nil {
-			s.send(msg)
 		}
 	}()
 
@@ -267,102 +356,154 @@ func (s *session) handleResumeGameRequest(msgId types.ObjectId, req DataResumeGa
 	// 	return
 	// }
 
-	if s.matchId.Load().IsZero() && s.playGameId.Load().IsZero() {
-		g, err := s.game.GetUserLiveGamePGN(context.Background(), s.userId)
-		if err != nil {
-			s.l.Error(err.Error())
-			errMsg = MsgDataInternalErrorr
-			return
-		}
-
-		if g == nil || g.GameId.String() != req.GameId.String() {
-			errMsg = MsgDataNotFound
-			return
-		}
+	if !s.matchId.Load().IsZero() || !s.playGameId.Load().IsZero() {
+		errMsg = errAlreadySubscribed
+		return
+	}
 
-		s.playGameId.Store(req.GameId)
-		if err := s.rc.addGameIdToUserSessions(context.Background(), s.userId, s.id, req.GameId); err != nil {
-			s.l.Error(err.Error())
-			s.playGameId.SetZero()
-			errMsg = MsgDataInternalErrorr
-			return
-		}
+	// the snapshot is taken after this seq
+	base, err := s.stream.current(context.Background(), req.GameId)
+	if err != nil {
+		s.l.Error(err.Error())
+		errMsg = errInternal
+		return
+	}
 
-		s.h.subscribeToGameWithChat(s, req.GameId)
-		if err := s.p.Publish(event.EventGamePlayerJoined{
-			GameID:    req.GameId,
-			PlayerID:  s.userId,
-			Timestamp: time.Now().Unix(),
-		}); err != nil {
-			s.l.Error(err.Error())
-		}
+	g, err := s.game.GetUserLiveGamePGN(context.Background(), s.userId)
+	if err != nil {
+		s.l.Error(err.Error())
+		errMsg = dataErrorOf(err)
+		return
+	}
 
-		msg = &Msg{
-			MsgBase: MsgBase{
-				ID:        msgId,
-				Type:      MsgTypeResumeGame,
-				Timestamp: time.Now().Unix(),
-			},
-			Data: DataResumeGameResponse{
-				GameId:               req.GameId,
-				Pgn:                  g.Pgn,
-				PlayersDisconnection: g.PlayersDisconnections,
-			}.Encode(),
-		}
+	if g == nil || g.GameId.String() != req.GameId.String() {
+		errMsg = errNotFound
+		return
+	}
 
-		// s.l.Debug(fmt.Sprintf("session '%s' subscribed to game '%s'", s.id, req.GameId))
+	s.playGameId.Store(req.GameId)
+	if err := s.rc.addGameIdToUserSessions(context.Background(), s.userId, s.id, req.GameId); err != nil {
+		s.l.Error(err.Error())
+		s.playGameId.SetZero()
+		errMsg = errInternal
 		return
 	}
 
-	errMsg = "already subscribed to a game"
+	// the live messages of the game are sent after the response
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	r, err := s.resumeStream(context.Background(), req.GameId, req.LastSeq, base)
+	if err != nil {
+		s.l.Error(err.Error())
+		// the live messages are still delivered, the client gets the whole game
+		r = streamResume{seq: base, snapshot: true}
+	}
+
+	if err := s.p.Publish(event.EventGamePlayerJoined{
+		GameID:    req.GameId,
+		PlayerID:  s.userId,
+		Timestamp: time.Now().Unix(),
+	}); err != nil {
+		s.l.Error(err.Error())
+	}
+
+	d := DataResumeGameResponse{
+		GameId:               req.GameId,
+		PlayersDisconnection: g.PlayersDisconnections,
+		Seq:                  r.seq,
+		Replay:               r.replay,
+	}
+	if r.snapshot {
+		d.Pgn = g.Pgn
+	}
+
+	s.send(&Msg{
+		MsgBase: MsgBase{
+			ID:        msgId,
+			Type:      MsgTypeResumeGame,
+			Timestamp: time.Now().Unix(),
+		},
+		Data: d.Encode(),
+	})
+
+	// s.l.Debug(fmt.Sprintf("session '%s' subscribed to game '%s'", s.id, req.GameId))
 }
 
 func (s *session) handleViewGameRequest(msgId types.ObjectId, req DataGameViewRequest) {
-	var errMsg string
-	var msg *Msg
+	var errMsg *DataError
 	defer func() {
-		if errMsg != "" {
+		if errMsg != nil {
 			s.sendErr(msgId, errMsg)
-			return
-		}
-		if msg != nil {
-			s.send(msg)
 		}
 	}()
 
-	emsg := s.addViewGame(req.GameId)
-	if emsg != "" {
-		errMsg = emsg
+	// a viewer that missed messages resyncs the game by its last seq
+	resync := req.LastSeq > 0 && s.isViewingGame(req.GameId)
+	if !resync {
+		if emsg := s.addViewGame(req.GameId); emsg != nil {
+			errMsg = emsg
+			return
+		}
+	}
+
+	// the snapshot is taken after this seq
+	base, err := s.stream.current(context.Background(), req.GameId)
+	if err != nil {
+		s.l.Error(err.Error())
+		errMsg = errInternal
+		if !resync {
+			s.removeViewGames(req.GameId)
+		}
 		return
 	}
+
 	game, err := s.game.GetLiveGamePGN(context.Background(), req.GameId)
 	if err != nil {
 		s.l.Error(err.Error())
-		errMsg = MsgDataInternalErrorr
-		s.removeViewGames(req.GameId)
+		errMsg = dataErrorOf(err)
+		if !resync {
+			s.removeViewGames(req.GameId)
+		}
 		return
 	}
 
 	if game == nil || game.GameId.String() != req.GameId.String() {
-		errMsg = MsgDataNotFound
-		s.removeViewGames(req.GameId)
+		errMsg = errNotFound
+		if !resync {
+			s.removeViewGames(req.GameId)
+		}
 		return
 	}
 
-	msg = &Msg{
+	// the live messages of the game are sent after the response
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	r, err := s.resumeStream(context.Background(), req.GameId, req.LastSeq, base)
+	if err != nil {
+		s.l.Error(err.Error())
+		r = streamResume{seq: base, snapshot: true}
+	}
+
+	d := DataGameViewResponse{
+		GameId:               req.GameId,
+		PlayersDisconnection: game.PlayersDisconnections,
+		Seq:                  r.seq,
+		Replay:               r.replay,
+	}
+	if r.snapshot {
+		d.Pgn = game.Pgn
+	}
+
+	s.sendGameMsg(req.GameId, &Msg{
 		MsgBase: MsgBase{
 			ID:        msgId,
 			Type:      MsgTypeViewGame,
 			Timestamp: time.Now().Unix(),
 		},
-		Data: DataGameViewResponse{
-			GameId:               req.GameId,
-			Pgn:                  game.Pgn,
-			PlayersDisconnection: game.PlayersDisconnections,
-		}.Encode(),
-	}
-
-	s.h.subscribeToGameWithChat(s, req.GameId)
+		Data: d.Encode(),
+	})
 
 	// Offload adding game viewer lists to the cache to a server-side worker using batching,
 	// instead of handling it here for every individual connection.
@@ -375,9 +516,9 @@ func (s *session) handleViewGameRequest(msgId types.ObjectId, req DataGameViewRe
 }
 
 func (s *session) handleMoveRequest(msgId types.ObjectId, req DataGamePlayerMoveRequest) {
-	var errMsg string
+	var errMsg *DataError
 	defer func() {
-		if errMsg != "" {
+		if errMsg != nil {
 			s.sendErr(msgId, errMsg)
 		}
 	}()
@@ -389,13 +530,13 @@ func (s *session) handleMoveRequest(msgId types.ObjectId, req DataGamePlayerMove
 		return
 	}
 
-	errMsg = "not allowed to move"
+	errMsg = errMoveNotAllowed
 }
 
 func (s *session) handlePlayerResignRequest(msgId types.ObjectId, req DataGamePlayerResignRequest) {
-	var errMsg string
+	var errMsg *DataError
 	defer func() {
-		if errMsg != "" {
+		if errMsg != nil {
 			s.sendErr(msgId, errMsg)
 		}
 	}()
@@ -407,13 +548,13 @@ func (s *session) handlePlayerResignRequest(msgId types.ObjectId, req DataGamePl
 		return
 	}
 
-	errMsg = "not allowed to resign"
+	errMsg = errResignNotAllowed
 }
 
 func (s *session) handleSendMsg(msgId types.ObjectId, req DataGameChatMsgSend) {
-	var errMsg string
+	var errMsg *DataError
 	defer func() {
-		if errMsg != "" {
+		if errMsg != nil {
 			s.sendErr(msgId, errMsg)
 		}
 	}()
@@ -425,27 +566,65 @@ func (s *session) handleSendMsg(msgId types.ObjectId, req DataGameChatMsgSend) {
 		return
 	}
 
-	errMsg = "not allowed to send message"
+	errMsg = errSendMsgNotAllowed
 }
 
 func (s *session) send(msg *Msg) {
-	defer func() {
-		if r := recover(); r != nil {
-			s.l.Warn(fmt.Sprintf("session '%s' send(msg *Msg) panicked: %v", s.id, r))
-		}
-	}()
+	s.enqueue(priorityGame, "", msg)
+}
+
+// sendGameMsg sends the messages of the games that are not events, only the last viewers list
+// and the last snapshot of each viewed game are kept.
+func (s *session) sendGameMsg(gameId types.ObjectId, msg *Msg) {
+	switch msg.Type {
+	case MsgTypeViewersList:
+		s.enqueue(priorityViewers, gameId.String(), msg)
+	case MsgTypeViewGame:
+		// a newer snapshot of the game replaces the one that is not sent yet, and it's queued
+		// with the messages of the game so the client gets them after it
+		s.enqueue(prioritySpectate, gameId.String(), msg)
+	default:
+		s.send(msg)
+	}
+}
+
+func (s *session) enqueue(p msgPriority, key string, msg *Msg) {
+	b, err := s.codec.encode(msg)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("session '%s' failed to encode message '%s': %v", s.id, msg.Type, err))
+		return
+	}
+
+	if !s.queue.push(p, key, b) {
+		s.closeSlow()
+	}
+}
+
+// closeSlow disconnects the session that doesn't read its messages fast enough.
+func (s *session) closeSlow() {
+	if !s.slow.CompareAndSwap(false, true) {
+		return
+	}
 
-	s.msgCh <- msg.Encode()
+	metricSlowDisconnected.Add(1)
+	s.l.Warn(fmt.Sprintf("session '%s' of user '%s' is disconnected as a slow consumer", s.id, s.userId))
+
+	// it's called by the fanout, which must not wait for the connection
+	go func() {
+		s.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+			time.Now().Add(time.Second))
+		s.Stop()
+	}()
 }
 
-func (s *session) sendErr(id types.ObjectId, err string) {
+func (s *session) sendErr(id types.ObjectId, err *DataError) {
 	s.send(&Msg{
 		MsgBase: MsgBase{
 			ID:        id,
 			Type:      MsgTypeError,
 			Timestamp: time.Now().Unix(),
 		},
-		Data: []byte(err),
+		Data: err.Encode(),
 	})
 }
 
@@ -513,7 +692,6 @@ func (s *session) Stop() {
 
 			s.wg.Wait()
 			close(s.eventCh)
-			close(s.msgCh)
 			close(s.pongCh)
 
 			s.l.Debug(fmt.Sprintf("session '%s' stopped for user '%s'", s.id, s.userId))
@@ -523,21 +701,29 @@ func (s *session) Stop() {
 	})
 }
 
-func (s *session) addViewGame(gameId types.ObjectId) (msg string) {
+func (s *session) addViewGame(gameId types.ObjectId) *DataError {
 	s.vmu.Lock()
 	defer s.vmu.Unlock()
 
 	if _, ok := s.viewGamesId[gameId]; ok {
-		return "already subscribed to this game"
+		return errAlreadyViewing
 	}
 
 	if len(s.viewGamesId) >= s.viewCaps {
-		return "view cap reached"
+		return errViewCapReached
 	}
 
 	s.viewGamesId[gameId] = struct{}{}
 
-	return ""
+	return nil
+}
+
+func (s *session) isViewingGame(gameId types.ObjectId) bool {
+	s.vmu.RLock()
+	defer s.vmu.RUnlock()
+
+	_, ok := s.viewGamesId[gameId]
+	return ok
 }
 
 func (s *session) getAllViewGames() []types.ObjectId {
@@ -0,0 +1,422 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	errs "github.com/alikarimi999/shahboard/pkg/errors"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// number of the messages that are queued for a spectator, slow spectators are disconnected
+	// and they continue from their last seq
+	spectatorBufferSize  = 32
+	sseKeepAliveInterval = 15 * time.Second
+
+	keySpectateRatePrefix = "spectate_rate:"
+	spectateRateWindow    = time.Minute
+
+	// the open streams of each ip, a stream that isn't refreshed by its keep-alive
+	// (e.g. the instance is crashed) is expired after spectateStreamTTL
+	keySpectateStreamsPrefix = "spectate_streams:"
+	spectateStreamTTL        = 3 * sseKeepAliveInterval
+)
+
+// spectateRateScript counts the requests of the ip in the current window and returns the count.
+const spectateRateScript = `
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`
+
+// acquireStreamScript removes the expired streams of the ip and adds the stream if the ip has
+// less than the max streams, it returns 1 if the stream is added.
+const acquireStreamScript = `
+local now = tonumber(ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[4]), ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return 1
+`
+
+var errGameNotLive = errors.New("game is not live")
+
+// spectator is an anonymous read-only subscriber of a game, it receives the moves,
+// the end of the game and the number of the viewers.
+type spectator struct {
+	id     types.ObjectId
+	gameId types.ObjectId
+
+	msgCh chan *Msg
+	// closed if the spectator can't keep up with the game
+	done chan struct{}
+	once sync.Once
+}
+
+func newSpectator(gameId types.ObjectId) *spectator {
+	return &spectator{
+		id:     types.NewObjectId(),
+		gameId: gameId,
+		msgCh:  make(chan *Msg, spectatorBufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+func (sp *spectator) subscriberId() types.ObjectId {
+	return sp.id
+}
+
+func (sp *spectator) consumeStream(e event.Event, gameId types.ObjectId, seq int64) {
+	mt := streamMsgType(e)
+	if !isSpectatorMsg(mt) {
+		return
+	}
+
+	sp.sendGameMsg(gameId, &Msg{
+		MsgBase: MsgBase{
+			Type:      mt,
+			Seq:       seq,
+			Timestamp: time.Now().Unix(),
+		},
+		Data: e.Encode(),
+	})
+}
+
+func (sp *spectator) sendGameMsg(gameId types.ObjectId, msg *Msg) {
+	if msg.Type == MsgTypeViewersList {
+		// the spectators are not authenticated, they get only the number of the viewers
+		var d DataViwersListResponse
+		if err := json.Unmarshal(msg.Data, &d); err != nil {
+			return
+		}
+		msg = &Msg{
+			MsgBase: MsgBase{
+				Type:      MsgTypeViewersCount,
+				Timestamp: msg.Timestamp,
+			},
+			Data: DataViewersCount{GameId: d.GameId, Count: len(d.List)}.Encode(),
+		}
+	}
+
+	select {
+	case sp.msgCh <- msg:
+	case <-sp.done:
+	default:
+		sp.once.Do(func() { close(sp.done) })
+	}
+}
+
+func isSpectatorMsg(mt MsgType) bool {
+	return mt == MsgTypeMoveApproved || mt == MsgTypeGameEnd
+}
+
+// spectatorMsg is the message of the SSE and long-poll transports, the payload is plain JSON.
+type spectatorMsg struct {
+	Type      MsgType         `json:"type"`
+	Seq       int64           `json:"seq,omitempty"`
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func newSpectatorMsg(m *Msg) spectatorMsg {
+	return spectatorMsg{
+		Type:      m.Type,
+		Seq:       m.Seq,
+		Timestamp: m.Timestamp,
+		Data:      m.Data,
+	}
+}
+
+type SpectatePollResponse struct {
+	GameId types.ObjectId `json:"game_id"`
+	// Seq is the last_seq of the next poll
+	Seq  int64          `json:"seq"`
+	Msgs []spectatorMsg `json:"msgs"`
+}
+
+// startSpectating subscribes a spectator to the game and returns the messages that the client missed after lastSeq,
+// the first message is the snapshot of the game if lastSeq is zero or the missed messages are not buffered anymore.
+// The spectator must be unsubscribed by the caller.
+func (s *Server) startSpectating(ctx context.Context, gameId types.ObjectId, lastSeq int64) (*spectator, []*Msg, error) {
+	// the snapshot is taken after this seq
+	base, err := s.stream.current(ctx, gameId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	game, err := s.game.GetLiveGamePGN(ctx, gameId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if game == nil || game.GameId.String() != gameId.String() {
+		return nil, nil, errGameNotLive
+	}
+
+	sp := newSpectator(gameId)
+	s.h.subscribeToGameWithChat(sp, gameId)
+
+	r, _, err := s.stream.resume(ctx, gameId, lastSeq, base)
+	if err != nil {
+		s.h.unsubscribeFromGameWithChat(sp, gameId)
+		return nil, nil, err
+	}
+
+	msgs := make([]*Msg, 0, len(r.replay)+1)
+	if r.snapshot {
+		msgs = append(msgs, &Msg{
+			MsgBase: MsgBase{
+				Type:      MsgTypeGameSnapshot,
+				Seq:       r.seq,
+				Timestamp: time.Now().Unix(),
+			},
+			Data: DataGameSnapshot{GameId: gameId, Pgn: game.Pgn, Seq: r.seq}.Encode(),
+		})
+	}
+	for _, m := range r.replay {
+		if isSpectatorMsg(m.Type) {
+			msgs = append(msgs, m)
+		}
+	}
+
+	return sp, msgs, nil
+}
+
+// handleSpectateEvents streams the game to an anonymous spectator by server-sent events,
+// the seq of the messages is the event id so the browsers resume by the Last-Event-ID header.
+func (s *Server) handleSpectateEvents(ctx *gin.Context) {
+	gameId, lastSeq, ok := parseSpectateRequest(ctx, ctx.GetHeader("Last-Event-ID"))
+	if !ok {
+		return
+	}
+
+	sp, msgs, err := s.startSpectating(ctx.Request.Context(), gameId, lastSeq)
+	if err != nil {
+		s.spectateError(ctx, err)
+		return
+	}
+	defer s.h.unsubscribeFromGameWithChat(sp, gameId)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	var sent int64
+	write := func(m *Msg) bool {
+		if m.Seq > 0 {
+			// already sent by the replay
+			if m.Seq <= sent {
+				return true
+			}
+			sent = m.Seq
+			fmt.Fprintf(ctx.Writer, "id: %d\n", m.Seq)
+		}
+		fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", m.Type, m.Data)
+		ctx.Writer.Flush()
+		return m.Type != MsgTypeGameEnd
+	}
+
+	for _, m := range msgs {
+		if !write(m) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	streamKey, _ := ctx.Get(ctxKeySpectateStream)
+
+	for {
+		select {
+		case m := <-sp.msgCh:
+			if !write(m) {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(ctx.Writer, ": keep-alive\n\n")
+			ctx.Writer.Flush()
+			if stream, ok := streamKey.(spectateStream); ok {
+				s.refreshSpectateStream(stream)
+			}
+		case <-sp.done:
+			return
+		case <-ctx.Request.Context().Done():
+			return
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// handleSpectatePoll is the long-poll fallback of the SSE endpoint, it returns the messages after last_seq
+// as soon as there is any, or an empty list after the poll timeout.
+func (s *Server) handleSpectatePoll(ctx *gin.Context) {
+	gameId, lastSeq, ok := parseSpectateRequest(ctx, ctx.Query("last_seq"))
+	if !ok {
+		return
+	}
+
+	sp, msgs, err := s.startSpectating(ctx.Request.Context(), gameId, lastSeq)
+	if err != nil {
+		s.spectateError(ctx, err)
+		return
+	}
+	defer s.h.unsubscribeFromGameWithChat(sp, gameId)
+
+	if len(msgs) == 0 {
+		timeout := time.NewTimer(time.Duration(s.cfg.LongPollTimeout) * time.Second)
+		defer timeout.Stop()
+
+		select {
+		case m := <-sp.msgCh:
+			msgs = append(msgs, m)
+			// the messages that are received with it
+			for len(sp.msgCh) > 0 {
+				msgs = append(msgs, <-sp.msgCh)
+			}
+		case <-timeout.C:
+		case <-sp.done:
+		case <-ctx.Request.Context().Done():
+			return
+		case <-s.stopCh:
+		}
+	}
+
+	res := SpectatePollResponse{GameId: gameId, Seq: lastSeq, Msgs: make([]spectatorMsg, 0, len(msgs))}
+	for _, m := range msgs {
+		switch {
+		case m.Type == MsgTypeGameSnapshot:
+			res.Seq = m.Seq
+		case m.Seq > 0:
+			if m.Seq <= res.Seq {
+				continue
+			}
+			res.Seq = m.Seq
+		}
+		res.Msgs = append(res.Msgs, newSpectatorMsg(m))
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func parseSpectateRequest(ctx *gin.Context, lastSeq string) (types.ObjectId, int64, bool) {
+	gameId, err := types.ParseObjectId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid game id"))
+		return types.ObjectZero, 0, false
+	}
+
+	var seq int64
+	if lastSeq != "" {
+		seq, err = strconv.ParseInt(lastSeq, 10, 64)
+		if err != nil || seq < 0 {
+			ctx.JSON(errs.HTTP(errs.CodeInvalidInput, "invalid last seq"))
+			return types.ObjectZero, 0, false
+		}
+	}
+
+	return gameId, seq, true
+}
+
+func (s *Server) spectateError(ctx *gin.Context, err error) {
+	if errors.Is(err, errGameNotLive) {
+		ctx.JSON(errs.HTTP(errs.CodeNotFound, err.Error()))
+		return
+	}
+
+	s.l.Error(err.Error())
+	ctx.JSON(errs.HTTP(errs.CodeInternalError, MsgDataInternalErrorr))
+}
+
+// limitSpectators limits the requests of the spectator endpoints per ip.
+func (s *Server) limitSpectators(ctx *gin.Context) {
+	count, err := s.cache.c.Eval(ctx.Request.Context(), spectateRateScript,
+		[]string{keySpectateRatePrefix + ctx.ClientIP()}, spectateRateWindow.Milliseconds()).Int()
+	if err != nil {
+		// the spectators are not blocked by the cache errors
+		s.l.Error(fmt.Sprintf("failed to count spectate requests: %v", err))
+		ctx.Next()
+		return
+	}
+
+	if count > s.cfg.SpectateRateLimit {
+		ctx.JSON(errs.HTTP(errs.CodeRateLimited, "too many requests"))
+		ctx.Abort()
+		return
+	}
+
+	ctx.Next()
+}
+
+const ctxKeySpectateStream = "spectate_stream"
+
+// spectateStream is the slot of an open stream in the streams of its ip.
+type spectateStream struct {
+	key string
+	id  string
+}
+
+// limitSpectateStreams limits the number of the open event streams of each ip on all instances,
+// the slot of the stream is released when the stream is closed.
+func (s *Server) limitSpectateStreams(ctx *gin.Context) {
+	stream := spectateStream{
+		key: keySpectateStreamsPrefix + ctx.ClientIP(),
+		id:  types.NewObjectId().String(),
+	}
+
+	ok, err := s.cache.c.Eval(ctx.Request.Context(), acquireStreamScript, []string{stream.key},
+		time.Now().UnixMilli(), stream.id, s.cfg.SpectateStreamsPerIP, spectateStreamTTL.Milliseconds()).Int()
+	if err != nil {
+		// the spectators are not blocked by the cache errors
+		s.l.Error(fmt.Sprintf("failed to acquire spectate stream: %v", err))
+		ctx.Next()
+		return
+	}
+
+	if ok == 0 {
+		ctx.JSON(errs.HTTP(errs.CodeRateLimited, "too many open streams"))
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set(ctxKeySpectateStream, stream)
+	ctx.Next()
+
+	// the request context is canceled when the client is disconnected
+	c, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.cache.c.ZRem(c, stream.key, stream.id).Err(); err != nil {
+		s.l.Error(fmt.Sprintf("failed to release spectate stream: %v", err))
+	}
+}
+
+// refreshSpectateStream extends the expiration of an open stream.
+func (s *Server) refreshSpectateStream(stream spectateStream) {
+	c, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	expireAt := time.Now().Add(spectateStreamTTL)
+	pipe := s.cache.c.TxPipeline()
+	pipe.ZAddXX(c, stream.key, redis.Z{Score: float64(expireAt.UnixMilli()), Member: stream.id})
+	pipe.PExpire(c, stream.key, spectateStreamTTL)
+	if _, err := pipe.Exec(c); err != nil {
+		s.l.Error(fmt.Sprintf("failed to refresh spectate stream: %v", err))
+	}
+}
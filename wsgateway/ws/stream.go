@@ -0,0 +1,227 @@
+package ws
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alikarimi999/shahboard/event"
+	"github.com/alikarimi999/shahboard/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyStreamSeqPrefix = "game_stream_seq:"
+	keyStreamPrefix    = "game_stream:"
+	// sequence numbers of the appended events, the events that kafka delivers again keep their numbers
+	keyStreamIdsPrefix = "game_stream_ids:"
+)
+
+// appendStreamScript gives the event the next sequence number of the game, adds its message to the
+// replay buffer and publishes it to the channel of the game, so the followers receive the events in order.
+// An event that is already appended is not published again and keeps its sequence number.
+const appendStreamScript = `
+local seq = redis.call('HGET', KEYS[3], ARGV[1])
+if seq then
+	return tonumber(seq)
+end
+
+seq = redis.call('INCR', KEYS[1])
+redis.call('HSET', KEYS[3], ARGV[1], seq)
+redis.call('ZADD', KEYS[2], seq, ARGV[2])
+redis.call('ZREMRANGEBYRANK', KEYS[2], 0, -tonumber(ARGV[3]) - 1)
+for i = 1, 3 do
+	redis.call('PEXPIRE', KEYS[i], ARGV[4])
+end
+redis.call('PUBLISH', KEYS[4], seq .. ' ' .. ARGV[5])
+return seq
+`
+
+// gameStream keeps the sequenced game and chat messages of each game in a bounded buffer,
+// so the clients that reconnect get only the messages they missed.
+type gameStream struct {
+	c    *redis.Client
+	size int
+	ttl  time.Duration
+}
+
+func newGameStream(c *redis.Client, size int, ttl time.Duration) *gameStream {
+	return &gameStream{c: c, size: size, ttl: ttl}
+}
+
+// streamMsgType returns the message type of the events that are sequenced, other events are not sent to the sessions.
+func streamMsgType(e event.Event) MsgType {
+	switch e.GetTopic().Domain() {
+	case event.DomainGame:
+		switch e.GetTopic().Action() {
+		case event.ActionEnded:
+			return MsgTypeGameEnd
+		case event.ActionGameMoveApprove:
+			return MsgTypeMoveApproved
+		case event.ActionGamePlayerJoined:
+			return MsgTypePlayerJoined
+		case event.ActionGamePlayerLeft:
+			return MsgTypePlayerLeft
+		}
+	case event.DomainGameChat:
+		switch e.GetTopic().Action() {
+		case event.ActionCreated:
+			return MsgTypeChatCreated
+		case event.ActionMsgApproved:
+			return MsgTypeChatMsgApproved
+		}
+	}
+	return ""
+}
+
+// append publishes the event to the followers of the game and returns its sequence number,
+// the events that are not sequenced are not published and their sequence number is zero.
+func (gs *gameStream) append(ctx context.Context, gameId types.ObjectId, e event.Event) (int64, error) {
+	mt := streamMsgType(e)
+	if mt == "" {
+		return 0, nil
+	}
+
+	data := e.Encode()
+	h := sha1.Sum(append([]byte(e.GetTopic().String()), data...))
+
+	msg := &Msg{
+		MsgBase: MsgBase{
+			Type:      mt,
+			Timestamp: e.TimeStamp(),
+		},
+		Data: data,
+	}
+
+	seq, err := gs.c.Eval(ctx, appendStreamScript,
+		[]string{keyStreamSeqPrefix + gameId.String(), keyStreamPrefix + gameId.String(), keyStreamIdsPrefix + gameId.String(),
+			gameChannel(gameId)},
+		hex.EncodeToString(h[:]), msg.Encode(), gs.size, gs.ttl.Milliseconds(), fanoutPayload(e)).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to append event to stream of game '%s': %w", gameId, err)
+	}
+
+	return seq, nil
+}
+
+// read returns the buffered messages of the game in order, and the last sequence number of the game.
+func (gs *gameStream) read(ctx context.Context, gameId types.ObjectId) ([]*Msg, int64, error) {
+	pipe := gs.c.Pipeline()
+	seqCmd := pipe.Get(ctx, keyStreamSeqPrefix+gameId.String())
+	msgsCmd := pipe.ZRangeWithScores(ctx, keyStreamPrefix+gameId.String(), 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, fmt.Errorf("failed to read stream of game '%s': %w", gameId, err)
+	}
+
+	seq, err := seqCmd.Int64()
+	if err != nil && err != redis.Nil {
+		return nil, 0, fmt.Errorf("invalid sequence number of game '%s': %w", gameId, err)
+	}
+
+	msgs := make([]*Msg, 0, len(msgsCmd.Val()))
+	for _, z := range msgsCmd.Val() {
+		var msg Msg
+		if err := json.Unmarshal([]byte(z.Member.(string)), &msg); err != nil {
+			continue
+		}
+		msg.Seq = int64(z.Score)
+		msgs = append(msgs, &msg)
+	}
+
+	return msgs, seq, nil
+}
+
+// current returns the last sequence number of the game.
+func (gs *gameStream) current(ctx context.Context, gameId types.ObjectId) (int64, error) {
+	seq, err := gs.c.Get(ctx, keyStreamSeqPrefix+gameId.String()).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to get sequence number of game '%s': %w", gameId, err)
+	}
+	return seq, nil
+}
+
+// since returns the messages after lastSeq, it returns false if some of them are not in the buffer anymore.
+func since(msgs []*Msg, lastSeq, current int64) ([]*Msg, bool) {
+	if lastSeq <= 0 || lastSeq > current {
+		return nil, false
+	}
+
+	missed := after(msgs, lastSeq)
+	if int64(len(missed)) != current-lastSeq {
+		return nil, false
+	}
+	return missed, true
+}
+
+func after(msgs []*Msg, seq int64) []*Msg {
+	for i, msg := range msgs {
+		if msg.Seq > seq {
+			return msgs[i:]
+		}
+	}
+	return nil
+}
+
+// streamResume is the point that the client continues the stream of a game from.
+type streamResume struct {
+	// sequence number that the snapshot or the client's state is at, the replay has the messages after it
+	seq      int64
+	replay   []*Msg
+	snapshot bool
+}
+
+// resumeStream subscribes the session to the game and returns the messages that the client missed after lastSeq.
+// If lastSeq is zero or the missed messages are not in the buffer anymore, the client needs a snapshot of the game
+// that is taken after base, base is read before the snapshot so no message is lost between them.
+// The caller must hold streamMu until the response is sent, so the live messages are sent after it.
+func (s *session) resumeStream(ctx context.Context, gameId types.ObjectId, lastSeq, base int64) (streamResume, error) {
+	s.h.subscribeToGameWithChat(s, gameId)
+
+	r, current, err := s.stream.resume(ctx, gameId, lastSeq, base)
+	if err != nil {
+		return streamResume{}, err
+	}
+
+	s.streamSeq[gameId] = max(current, r.seq)
+	return r, nil
+}
+
+// resume returns the messages of the game after lastSeq, or after base if the client needs a snapshot,
+// and the last sequence number of the game.
+func (gs *gameStream) resume(ctx context.Context, gameId types.ObjectId, lastSeq, base int64) (streamResume, int64, error) {
+	msgs, current, err := gs.read(ctx, gameId)
+	if err != nil {
+		return streamResume{}, 0, err
+	}
+
+	r := streamResume{seq: lastSeq}
+	missed, ok := since(msgs, lastSeq, current)
+	if !ok {
+		r = streamResume{seq: base, snapshot: true}
+		missed = after(msgs, base)
+	}
+	r.replay = missed
+
+	return r, current, nil
+}
+
+// sendStream sends the sequenced message of the game, messages that the session already sent are dropped.
+func (s *session) sendStream(gameId types.ObjectId, seq int64, p msgPriority, msg *Msg) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	if seq <= s.streamSeq[gameId] {
+		return
+	}
+	s.streamSeq[gameId] = seq
+
+	msg.Seq = seq
+	s.enqueue(p, "", msg)
+
+	if msg.Type == MsgTypeGameEnd {
+		delete(s.streamSeq, gameId)
+	}
+}